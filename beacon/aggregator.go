@@ -0,0 +1,27 @@
+package beacon
+
+import (
+	"github.com/dedis/kyber/pairing"
+	"github.com/dedis/kyber/share"
+	"github.com/dedis/kyber/sign/tbls"
+)
+
+// Aggregator combines the partial signatures collected during a round into the
+// final signature of the round. The handler recovers it in process by default;
+// a deployment can instead delegate this step to a dedicated service, the
+// handler still collecting the partials and verifying the final signature
+// against the distributed public key before storing it.
+type Aggregator interface {
+	// Recover returns the signature over msg recovered from at least
+	// threshold of the n partial signatures, each verified against pub.
+	Recover(suite pairing.Suite, pub *share.PubPoly, msg []byte, partials [][]byte, threshold, n int) ([]byte, error)
+}
+
+// TBLSAggregator is the Aggregator recovering the final signature in process
+// with tbls.Recover. It is the default one.
+type TBLSAggregator struct{}
+
+// Recover returns tbls.Recover(suite, pub, msg, partials, threshold, n).
+func (TBLSAggregator) Recover(suite pairing.Suite, pub *share.PubPoly, msg []byte, partials [][]byte, threshold, n int) ([]byte, error) {
+	return tbls.Recover(suite, pub, msg, partials, threshold, n)
+}
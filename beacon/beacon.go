@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/dedis/kyber/sign/tbls"
 
 	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/log"
 	"github.com/dedis/drand/net"
 	"github.com/nikkolasg/slog"
 )
@@ -28,16 +31,12 @@ type Handler struct {
 	client net.InternalClient
 	// where to store the new randomness beacon
 	store Store
-	// to sign beacons
-	share *key.Share
-	// to verify incoming beacons
-	group *key.Group
-	// to verify incoming beacons with tbls
-	pub *share.PubPoly
+	// share and group the beacons are signed with, replaced by SetShare
+	keys *groupShare
+	// pairing and key group of the signatures, from the scheme of the group
+	scheme *key.Scheme
 	sync.Mutex
 
-	index int
-
 	// current round
 	round uint64
 	// previous signature generated at the previous round. Useful to generate
@@ -45,38 +44,105 @@ type Handler struct {
 	previousRand []byte
 	// stores some recent signature to avoid recreating them
 	cache *signatureCache
+	// partial signatures received for the recent rounds
+	states *roundStates
 	// signal if a beacon node is late, it waits for the next incoming request
 	// to start its own timer
 	catchup bool
 	// signal the beacon received from incoming request to the timer
 	catchupCh chan Beacon
+	// maximum number of missed rounds a node catches up on
+	maxCatchup uint64
+	// maximum number of missed rounds fetched in the background when catching
+	// up, the older ones being fetched on demand
+	catchupLimit int
+	// builds the message signed at each round
+	message MessageFunc
+	// combines the partial signatures into the final one
+	aggregator Aggregator
+	// estimates of the time the other nodes take to reply
+	latencies *latencies
+	// time the rounds take to reach the threshold
+	stats *roundStats
+	// time after which the slower nodes are asked for their partial
+	// signature too, zero to ask all the nodes at once
+	fanoutDelay time.Duration
+	// time after which a round not reaching the threshold fails, zero to
+	// wait until the next round
+	roundTimeout time.Duration
+	// what the loop does when a round fails
+	roundPolicy RoundPolicy
+	// maximum random delay before asking the other nodes for their partial
+	// signature, zero to ask them right away
+	jitter time.Duration
+	// period of the loop, once started
+	period time.Duration
 
-	ticker *time.Ticker
+	// source of time of the loop
+	clock Clock
+	// logs the progress of the rounds, with the address of the node
+	logger log.Logger
 	close  chan bool
 	addr   string
+	// closed to abort the rounds in progress
+	abort chan bool
+	// rounds in progress
+	rounds    sync.WaitGroup
+	closeOnce sync.Once
+	abortOnce sync.Once
+	storeOnce sync.Once
+	// if set, round N starts at genesis + N*period
+	genesis time.Time
+}
+
+// groupShare is the share of the node with the group it is a share of.
+type groupShare struct {
+	// to sign beacons
+	share *key.Share
+	// to verify incoming beacons
+	group *key.Group
+	// to verify incoming beacons with tbls
+	pub *share.PubPoly
+	// index of the node in the group
+	index int
 }
 
 // NewHandler returns a fresh handler ready to serve and create randomness
-// beacon
-func NewHandler(c net.InternalClient, priv *key.Pair, sh *key.Share, group *key.Group, s Store) *Handler {
+// beacon. It fails if the node is not in the group or the scheme of the group
+// is unknown.
+func NewHandler(c net.InternalClient, priv *key.Pair, sh *key.Share, group *key.Group, s Store) (*Handler, error) {
 	idx, exists := group.Index(priv.Public)
 	if !exists {
-		// XXX
-		panic("that's just plain wrong and I should be an error")
+		return nil, fmt.Errorf("beacon: node %s is not in the group", priv.Public.Address())
+	}
+	scheme, err := key.SchemeByName(group.Scheme)
+	if err != nil {
+		return nil, err
 	}
 	addr := group.Nodes[idx].Addr
 	return &Handler{
-		client:    c,
-		group:     group,
-		share:     sh,
-		pub:       share.NewPubPoly(key.G2, key.G2.Point().Base(), sh.Commits),
-		index:     idx,
-		store:     s,
-		close:     make(chan bool),
-		cache:     newSignatureCache(),
-		addr:      addr,
-		catchupCh: make(chan Beacon, 1),
-	}
+		client:     c,
+		scheme:     scheme,
+		store:      s,
+		close:      make(chan bool),
+		abort:      make(chan bool),
+		cache:      newSignatureCache(),
+		states:     newRoundStates(),
+		latencies:  newLatencies(),
+		stats:      new(roundStats),
+		addr:       addr,
+		catchupCh:  make(chan Beacon, 1),
+		message:    Message,
+		aggregator: TBLSAggregator{},
+		clock:      RealClock{},
+		logger:     log.DefaultLogger().With("node", addr),
+		keys: &groupShare{
+			share: sh,
+			group: group,
+			pub:   share.NewPubPoly(scheme.KeyGroup, scheme.KeyGroup.Point().Base(), sh.Commits),
+			index: idx,
+		},
+	}, nil
 }
 
 // ProcessBeacon receives a request for a beacon partial signature. It replies
@@ -96,14 +162,14 @@ func (h *Handler) ProcessBeacon(c context.Context, p *proto.BeaconRequest) (*pro
 	}
 
 	// 2- we dont catch up at least with invalid signature
-	msg := Message(p.PreviousRand, p.Round)
-	if err := tbls.Verify(key.Pairing, h.pub, msg, p.PartialRand); err != nil {
-		slog.Debugf("beacon: received invalid signature request")
+	msg := h.message(p.PreviousRand, p.Round)
+	if err := tbls.Verify(h.scheme.Pairing, h.keys.pub, msg, p.PartialRand); err != nil {
+		h.logger.Debug("beacon: received invalid signature request", "round", p.Round, "err", err)
 		return nil, err
 	}
 
 	// check if we have it in the saved signatures
-	signature, err := h.signature(p.Round, msg)
+	signature, err := h.signature(h.keys.share, p.Round, msg)
 	resp := &proto.BeaconResponse{
 		PartialRand: signature,
 	}
@@ -132,16 +198,36 @@ func (h *Handler) Loop(seed []byte, period time.Duration, catchup bool) {
 	h.savePreviousSignature(seed)
 
 	h.Lock()
-	h.ticker = time.NewTicker(period)
+	clock, genesis := h.clock, h.genesis
+	h.period = period
 	h.Unlock()
+	aligned := !genesis.IsZero()
+	if aligned {
+		// wait for the first round
+		if wait := TimeOfRound(1, genesis, period).Sub(clock.Now()); wait > 0 {
+			h.logger.Info("beacon: first round starts soon", "wait", wait)
+			select {
+			case <-clock.After(wait):
+			case <-h.close:
+				return
+			}
+		}
+	}
 
 	var goToNextRound bool = true // need to start one round anyway
 	var currentRoundFinished bool
+	// a failed round is retried past its tick, the next round starts as soon
+	// as it finishes
+	h.Lock()
+	retry := h.roundTimeout > 0 && h.roundPolicy == RetryRound
+	h.Unlock()
+	var overdue bool
 
 	var round uint64
 	var prevRand []byte
 	winCh := make(chan roundInfo)
 	closingCh := make(chan bool)
+	var tick <-chan time.Time
 
 	for {
 		if goToNextRound {
@@ -156,31 +242,48 @@ func (h *Handler) Loop(seed []byte, period time.Duration, catchup bool) {
 				// need to be up to date to continue so if we receive nothing we
 				// can't do anything else anyway.
 				b := <-h.catchupCh
-				slog.Infof("beacon: catched up on round %d (previous round %d)", b.Round, round)
+				h.logger.Info("beacon: caught up", "round", b.Round, "previous_round", round)
+				if h.checkCatchupGap(b.Round) {
+					// the current round starts right away while the
+					// missed rounds are fetched in the background
+					go h.syncGap(b)
+				}
 				// nextRound() automatically increases
 				h.setRound(b.Round - 1)
 				h.savePreviousSignature(b.PreviousRand)
 				catchup = false
+			} else if aligned {
+				h.alignRound(RoundAt(clock.Now(), genesis, period))
 			}
 
 			// take the next round and prev signature
 			round = h.nextRound()
 			prevRand = h.getPreviousSignature()
 
+			h.rounds.Add(1)
 			go h.run(round, prevRand, winCh, closingCh)
+			if aligned {
+				tick = clock.After(TimeOfRound(round+1, genesis, period).Sub(clock.Now()))
+			} else {
+				tick = clock.After(period)
+			}
 
 			goToNextRound = false
 			currentRoundFinished = false
 		}
 		// that way the execution starts directly, not after *one tick*
 		select {
-		case <-h.ticker.C:
+		case <-tick:
+			if !currentRoundFinished && retry {
+				overdue = true
+				continue
+			}
 			if !currentRoundFinished {
 				// the current round has not finished yet, so we must catchup
 				// first to get up-to-date info
 				catchup = true
 			}
-			// the ticker is king so we always start a new round at each tick
+			// the clock is king so we always start a new round at each tick
 			goToNextRound = true
 			continue
 		case roundInfo := <-winCh:
@@ -190,114 +293,225 @@ func (h *Handler) Loop(seed []byte, period time.Duration, catchup bool) {
 				// ahead by a few rounds
 				continue
 			}
+			if roundInfo.failed {
+				if retry {
+					h.logger.Info("beacon: retrying round", "round", round)
+					h.rounds.Add(1)
+					go h.run(round, prevRand, winCh, closingCh)
+					continue
+				}
+				// the round is skipped: the next round is built on top of
+				// the randomness of the last round that succeeded
+				currentRoundFinished = true
+				continue
+			}
 			// since it is the expected round number, we can set that signature
 			// as the basis for the next round
 			h.savePreviousSignature(roundInfo.signature)
 			// we signal that the round is finished and move on by waiting on
 			// the next tick,i.e. proper operational flow.
 			currentRoundFinished = true
+			if overdue {
+				goToNextRound = true
+				overdue = false
+			}
 		case <-h.close:
 			return
 		}
 	}
-	slog.Info("beacon: stopped loop")
+	h.logger.Info("beacon: stopped loop")
 }
 
 type roundInfo struct {
 	round     uint64
 	signature []byte
+	// true if the round did not reach the threshold before the round timeout
+	failed bool
 }
 
 func (h *Handler) run(round uint64, prevRand []byte, winCh chan roundInfo, closeCh chan bool) {
-	slog.Debugf("beacon %s: next tick for round %d", h.addr, round)
-	msg := Message(prevRand, round)
-	signature, err := h.signature(round, msg)
+	defer h.rounds.Done()
+	h.logger.Debug("beacon: next tick", "round", round)
+	if wait := h.broadcastDelay(); wait > 0 {
+		select {
+		case <-h.clock.After(wait):
+		case <-closeCh:
+			return
+		case <-h.abort:
+			return
+		}
+	}
+	roundStart := h.clock.Now()
+	k := h.current()
+	msg := h.message(prevRand, round)
+	signature, err := h.signature(k.share, round, msg)
 	if err != nil {
-		slog.Debugf("beacon: round %d err creating/caching signature %s", round, err)
+		h.logger.Error("beacon: could not create partial signature", "round", round, "err", err)
 		return
 	}
 
+	h.states.start(round, k.index, k.group.Threshold)
 	var sigs [][]byte
 	sigs = append(sigs, signature)
+	// partial signatures by share index, a duplicate making the recovery fail
+	seen := map[int]bool{k.share.Share.I: true}
 	request := &proto.BeaconRequest{
 		Round:        round,
 		PreviousRand: prevRand,
 		PartialRand:  signature,
 	}
-	respCh := make(chan *proto.BeaconResponse, h.group.Len())
-	// send all requests in parallel
-	for _, id := range h.group.Nodes {
-		if h.index == id.Index {
-			continue
+	respCh := make(chan *proto.BeaconResponse, k.group.Len())
+	failCh := make(chan bool, k.group.Len())
+	// send the requests in parallel
+	send := func(nodes []*key.IndexedPublic) {
+		for _, id := range nodes {
+			// this go routine sends the packet to one node. It will always
+			// return assuming there's a timeout on the connection
+			go func(idx int, i *key.Identity) {
+				//slog.Debugf("beacon: %s round %d: request new beacon to %s", h.addr, round, i.Address())
+				start := h.clock.Now()
+				resp, err := h.client.NewBeacon(i, request)
+				if err != nil {
+					h.logger.Debug("beacon: no partial signature", "round", round, "from", i.Address(), "err", err)
+					failCh <- true
+					return
+				}
+				if err := tbls.Verify(h.scheme.Pairing, k.pub, msg, resp.PartialRand); err != nil {
+					h.logger.Debug("beacon: invalid partial signature", "round", round, "from", i.Address(), "err", err)
+					failCh <- true
+					return
+				}
+				h.latencies.observe(idx, h.clock.Now().Sub(start))
+				h.logger.Debug("beacon: valid partial signature", "round", round, "from", i.Address())
+				h.states.add(round, idx)
+				respCh <- resp
+			}(id.Index, id.Identity)
 		}
-		// this go routine sends the packet to one node. It will always
-		// return assuming there's a timeout on the connection
-		go func(i *key.Identity) {
-			//slog.Debugf("beacon: %s round %d: request new beacon to %s", h.addr, round, i.Address())
-			resp, err := h.client.NewBeacon(i, request)
-			if err != nil {
-				slog.Debugf("beacon: %s round %d err receiving response from %s: %s", h.addr, round, i.Address(), err)
-				return
-			}
-			if err := tbls.Verify(key.Pairing, h.pub, msg, resp.PartialRand); err != nil {
-				slog.Debugf("beacon: invalid beacon response: %s", err)
-				return
-			}
-			slog.Debugf("beacon: %s round %d valid response from %s", h.addr, round, i.Address())
-			respCh <- resp
-		}(id.Identity)
 	}
+	first, rest := h.fanout(k)
+	send(first)
+	// the other nodes are asked if the first ones fail or are too slow
+	var fanout <-chan time.Time
+	var expired <-chan time.Time
+	h.Lock()
+	if len(rest) > 0 {
+		fanout = h.clock.After(h.fanoutDelay)
+	}
+	if h.roundTimeout > 0 {
+		expired = h.clock.After(h.roundTimeout)
+	}
+	h.Unlock()
 	// wait for a threshold of replies or if the timeout occured
-	for len(sigs) < h.group.Threshold {
+	for len(sigs) < k.group.Threshold {
 		select {
 		case resp := <-respCh:
+			idx, err := tbls.SigShare(resp.PartialRand).Index()
+			if err != nil || seen[idx] {
+				h.logger.Debug("beacon: duplicate partial signature", "round", round, "index", idx)
+				continue
+			}
+			seen[idx] = true
 			sigs = append(sigs, resp.PartialRand)
-			slog.Debugf("beacon: %s round %d received %d/%d response", h.addr, round, len(sigs), h.group.Threshold)
+			h.logger.Debug("beacon: partial signatures received", "round", round, "received", len(sigs), "threshold", k.group.Threshold)
+		case <-failCh:
+			if fanout != nil {
+				send(rest)
+				fanout = nil
+			}
+		case <-fanout:
+			h.logger.Debug("beacon: asking the slower nodes", "round", round, "nodes", len(rest))
+			send(rest)
+			fanout = nil
+		case <-expired:
+			h.logger.Warn(fmt.Sprintf("beacon: round %d failed: only got %d/%d signatures", round, len(sigs), k.group.Threshold), "round", round)
+			select {
+			case winCh <- roundInfo{round: round, failed: true}:
+			case <-closeCh:
+			case <-h.close:
+			}
+			return
 		case <-closeCh:
 			// it's already time to go to the next, there has been not
 			// enough time or nodes are too slow. In any case it's a
 			// problem.
-			slog.Infof("beacon: quitting prematurely round %d.", round)
-			slog.Infof("beacon: might be a problem with the nodes or the beacon period is too short")
+			h.logger.Warn("beacon: round not finished before the next one: the nodes may have a problem or the beacon period may be too short", "round", round)
+			return
+		case <-h.abort:
+			h.logger.Info("beacon: stopped during round", "round", round)
 			return
 		}
 	}
+	h.stats.observe(h.clock.Now().Sub(roundStart))
 	//slog.Debugf("beacon: %s round %d -> out of the waiting loop (%d sigs)", h.addr, round, len(sigs))
-	finalSig, err := tbls.Recover(key.Pairing, h.pub, msg, sigs, h.group.Threshold, h.group.Len())
+	finalSig, err := h.aggregator.Recover(h.scheme.Pairing, k.pub, msg, sigs, k.group.Threshold, k.group.Len())
 	if err != nil {
-		slog.Infof("beacon: could not reconstruct final beacon: %s", err)
+		h.logger.Error("beacon: could not reconstruct final beacon", "round", round, "err", err)
 		return
 	}
-	if err := bls.Verify(key.Pairing, h.pub.Commit(), msg, finalSig); err != nil {
-		slog.Print("beacon: invalid reconstructed beacon signature ? That's BAD")
+	if err := bls.Verify(h.scheme.Pairing, k.pub.Commit(), msg, finalSig); err != nil {
+		h.logger.Error("beacon: invalid reconstructed beacon signature", "round", round, "err", err)
 		return
 	}
 
 	beacon := &Beacon{
 		Round:        round,
 		PreviousRand: prevRand,
-		Randomness:   finalSig,
+		Signature:    finalSig,
 	}
 	//slog.Debugf("beacon: %s round %d -> SAVING beacon in store ", h.addr, round)
 	// we can always store it even if it is too late, since it is valid anyway
 	if err := h.store.Put(beacon); err != nil {
-		slog.Infof("beacon: error storing beacon randomness: %s", err)
+		h.logger.Error("beacon: could not store beacon", "round", round, "err", err)
 		return
 	}
 	//slog.Debugf("beacon: %s round %d -> saved beacon in store sucessfully", h.addr, round)
-	slog.Infof("beacon: round %d finished: %x", round, finalSig)
-	slog.Debugf("beacon: %s round %d finished: \n\tfinal: %x\n\tprev: %x\n", h.addr, round, finalSig, prevRand)
-	winCh <- roundInfo{round: round, signature: finalSig}
+	h.states.done(round)
+	h.logger.Info("beacon: round finished", "round", round, "randomness", beacon.Randomness())
+	h.logger.Debug("beacon: round finished", "round", round, "previous", prevRand)
+	select {
+	case winCh <- roundInfo{round: round, signature: finalSig}:
+	case <-h.close:
+		// the loop is stopped
+	}
 }
 
+// Stop stops the beacon loop, aborts the round in progress and closes the
+// store.
 func (h *Handler) Stop() {
-	h.Lock()
-	defer h.Unlock()
-	if h.ticker != nil {
-		h.ticker.Stop()
+	h.StopLoop()
+	h.abortOnce.Do(func() { close(h.abort) })
+	h.closeStore()
+}
+
+// Shutdown stops the beacon loop so that no new round starts, and waits for the
+// round in progress to finish and be saved before closing the store. If the
+// context is done first, the round is aborted and the context error returned.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.StopLoop()
+	done := make(chan bool)
+	go func() {
+		h.rounds.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.abortOnce.Do(func() { close(h.abort) })
 	}
-	close(h.close)
-	h.store.Close()
+	h.closeStore()
+	return err
+}
+
+// StopLoop stops the loop from starting new rounds, and lets the round in
+// progress finish. Unlike Stop and Shutdown, the store is left open.
+func (h *Handler) StopLoop() {
+	h.closeOnce.Do(func() { close(h.close) })
+}
+
+func (h *Handler) closeStore() {
+	h.storeOnce.Do(h.store.Close)
 }
 
 // nextRound increase the round counter and evicts the cache from old entries.
@@ -306,9 +520,20 @@ func (h *Handler) nextRound() uint64 {
 	defer h.Unlock()
 	h.round++
 	h.cache.Evict(h.round)
+	h.states.evict(h.round)
 	return h.round
 }
 
+// alignRound makes the given round the next one, unless the handler is
+// already at or past it, so that rounds keep increasing.
+func (h *Handler) alignRound(r uint64) {
+	h.Lock()
+	defer h.Unlock()
+	if r > h.round+1 {
+		h.round = r - 1
+	}
+}
+
 func (h *Handler) setRound(r uint64) {
 	h.Lock()
 	defer h.Unlock()
@@ -327,11 +552,43 @@ func (h *Handler) getPreviousSignature() []byte {
 	return h.previousRand
 }
 
-func (h *Handler) signature(round uint64, msg []byte) ([]byte, error) {
+// SetShare replaces the share of the node and the group it is a share of, e.g.
+// after the distributed key has been reshared by another process. The rounds
+// starting from then on use them, while the rounds in progress finish with the
+// previous ones. The share must be the share of this node in the group, and of
+// the same distributed key for the chain to keep verifying.
+func (h *Handler) SetShare(sh *key.Share, group *key.Group) error {
+	if scheme, err := key.SchemeByName(group.Scheme); err != nil || scheme.Name != h.scheme.Name {
+		return errors.New("beacon: the group uses another scheme")
+	}
+	idx := sh.Share.I
+	if idx < 0 || idx >= group.Len() || group.Public(idx).Address() != h.addr {
+		return errors.New("beacon: the share is not the share of this node in the group")
+	}
+	pub := share.NewPubPoly(h.scheme.KeyGroup, h.scheme.KeyGroup.Point().Base(), sh.Commits)
+	if !pub.Commit().Equal(h.current().pub.Commit()) {
+		return errors.New("beacon: the share is of another distributed key")
+	}
+	h.Lock()
+	h.keys = &groupShare{share: sh, group: group, pub: pub, index: idx}
+	h.Unlock()
+	// the estimates are kept per index in the group
+	h.latencies.reset()
+	return nil
+}
+
+// current returns the share and the group the rounds are run with.
+func (h *Handler) current() *groupShare {
+	h.Lock()
+	defer h.Unlock()
+	return h.keys
+}
+
+func (h *Handler) signature(sh *key.Share, round uint64, msg []byte) ([]byte, error) {
 	var err error
 	signature, ok := h.cache.Get(round, msg)
 	if !ok {
-		signature, err = tbls.Sign(key.Pairing, h.share.Share, msg)
+		signature, err = tbls.Sign(h.scheme.Pairing, sh.Share, msg)
 		if err != nil {
 			return nil, err
 		}
@@ -340,12 +597,445 @@ func (h *Handler) signature(round uint64, msg []byte) ([]byte, error) {
 	return signature, nil
 }
 
+// SetMaxCatchupRounds sets the maximum number of missed rounds the handler
+// catches up on. A node further behind should rather be bootstrapped from a
+// snapshot of the chain of another node. Zero means no limit.
+func (h *Handler) SetMaxCatchupRounds(n uint64) {
+	h.Lock()
+	defer h.Unlock()
+	h.maxCatchup = n
+}
+
+// SetCatchupLimit sets the maximum number of missed rounds fetched from the
+// other nodes in the background when the handler catches up, the most recent
+// first. The older missed rounds are fetched on demand by Lookup. Zero means no
+// limit.
+func (h *Handler) SetCatchupLimit(n int) {
+	h.Lock()
+	defer h.Unlock()
+	h.catchupLimit = n
+}
+
+// SetFanoutDelay makes the handler ask first only the threshold of nodes that
+// replied the fastest in the previous rounds for their partial signature, and
+// the other nodes only if one of these fails or if the threshold is not
+// reached after the given delay. Zero, the default, asks all the nodes at once.
+// All the nodes are asked at once as long as the latency of too few nodes is
+// known.
+func (h *Handler) SetFanoutDelay(d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	h.fanoutDelay = d
+}
+
+// SetBroadcastJitter makes the handler wait a random delay, up to the given
+// one, at the start of each round before asking the other nodes for their
+// partial signature, so that the nodes of a large group do not all send their
+// requests at the same time. The delay is capped to a quarter of the round
+// timeout, or of the period if there is no round timeout, to leave the round
+// the time to reach the threshold. Zero, the default, asks right away.
+func (h *Handler) SetBroadcastJitter(max time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	h.jitter = max
+}
+
+// broadcastDelay returns the random delay to wait before asking the other
+// nodes for their partial signature, see SetBroadcastJitter.
+func (h *Handler) broadcastDelay() time.Duration {
+	h.Lock()
+	max, deadline := h.jitter, h.roundTimeout
+	if deadline == 0 {
+		deadline = h.period
+	}
+	h.Unlock()
+	if deadline > 0 && max > deadline/4 {
+		max = deadline / 4
+	}
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// RoundPolicy tells what the beacon loop does when a round does not reach the
+// threshold of partial signatures before the round timeout.
+type RoundPolicy int
+
+const (
+	// SkipRound gives up on the failed round: the next round starts at its
+	// usual time, built on top of the randomness of the last round that
+	// succeeded, so the chain has a gap but stays linked.
+	SkipRound RoundPolicy = iota
+	// RetryRound asks the nodes again for the failed round, with the same
+	// previous randomness, until it reaches the threshold. The next round
+	// starts once it does, right away if its time has passed already.
+	RetryRound
+)
+
+// SetRoundTimeout sets the time after which a round that has not reached the
+// threshold of partial signatures fails. A failed round is logged as such and
+// is then skipped or retried, according to the policy set with SetRoundPolicy.
+// Zero, the default, lets a round run until the next one is due, after which
+// the node waits for a request from another node to catch up. All the nodes of
+// the group should use the same timeout and policy.
+func (h *Handler) SetRoundTimeout(d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	h.roundTimeout = d
+}
+
+// SetRoundPolicy sets what the loop does when a round fails after the round
+// timeout, SkipRound by default.
+func (h *Handler) SetRoundPolicy(p RoundPolicy) {
+	h.Lock()
+	defer h.Unlock()
+	h.roundPolicy = p
+}
+
+// SetLatency sets the estimate of the time the node of the group at the given
+// address takes to reply with its partial signature, for example as measured
+// by another means before the first round. The estimate is then updated with
+// the replies of the node. See SetFanoutDelay.
+func (h *Handler) SetLatency(addr string, d time.Duration) {
+	for _, n := range h.current().group.Nodes {
+		if n.Address() == addr {
+			h.latencies.set(n.Index, d)
+		}
+	}
+}
+
+// fanout returns the other nodes to ask first for their partial signature,
+// the fastest ones, and the nodes to ask only if these do not reach the
+// threshold, see SetFanoutDelay.
+func (h *Handler) fanout(k *groupShare) ([]*key.IndexedPublic, []*key.IndexedPublic) {
+	var others []*key.IndexedPublic
+	for _, n := range k.group.Nodes {
+		if n.Index != k.index {
+			others = append(others, n)
+		}
+	}
+	h.Lock()
+	delay := h.fanoutDelay
+	h.Unlock()
+	// the partial signature of this node counts towards the threshold
+	needed := k.group.Threshold - 1
+	if delay == 0 || needed >= len(others) {
+		return others, nil
+	}
+	sorted, known := h.latencies.order(others)
+	if known < needed {
+		return others, nil
+	}
+	return sorted[:needed], sorted[needed:]
+}
+
+// SetMessage sets the function building the message signed at each round,
+// instead of Message. All the nodes of the group must use the same function,
+// and changing it on a running chain breaks the chain: the beacons already
+// generated do not verify anymore with the new function.
+func (h *Handler) SetMessage(fn MessageFunc) {
+	h.Lock()
+	defer h.Unlock()
+	h.message = fn
+}
+
+// SetAggregator sets the Aggregator combining the partial signatures of each
+// round into the final signature, instead of TBLSAggregator. A nil aggregator
+// restores the default. The final signature is verified against the
+// distributed public key whatever the aggregator, and the round fails if it
+// does not verify.
+func (h *Handler) SetAggregator(a Aggregator) {
+	h.Lock()
+	defer h.Unlock()
+	if a == nil {
+		a = TBLSAggregator{}
+	}
+	h.aggregator = a
+}
+
+// SetLogger sets the logger of the handler, instead of log.DefaultLogger. The
+// address of the node is added to every message.
+func (h *Handler) SetLogger(l log.Logger) {
+	h.Lock()
+	defer h.Unlock()
+	h.logger = l.With("node", h.addr)
+}
+
+// SetClock sets the source of time of the beacon loop, instead of the real
+// clock. It must be called before Loop.
+func (h *Handler) SetClock(c Clock) {
+	h.Lock()
+	defer h.Unlock()
+	h.clock = c
+}
+
+// SetGenesis aligns the rounds on the given genesis time instead of the time
+// the loop starts: round N starts at genesis + N*period, so the round of any
+// time is known by anyone knowing the genesis time and the period. The loop
+// waits for the first round if it starts before, and skips the rounds whose
+// time has passed. It must be called before Loop.
+func (h *Handler) SetGenesis(genesis time.Time) {
+	h.Lock()
+	defer h.Unlock()
+	h.genesis = genesis
+}
+
+// checkCatchupGap logs the number of rounds missed between the last beacon
+// saved and the round the handler catches up on, and warns if it is more than
+// the maximum number of rounds to catch up on. It returns true if there are
+// missed rounds to fetch from the other nodes.
+func (h *Handler) checkCatchupGap(round uint64) bool {
+	var last uint64
+	if b, err := h.store.Last(); err == nil {
+		last = b.Round
+	}
+	if round <= last+1 {
+		return false
+	}
+	missed := round - last - 1
+	h.Lock()
+	max := h.maxCatchup
+	h.Unlock()
+	if max > 0 && missed > max {
+		h.logger.Error("beacon: too many rounds missed to catch up on: bootstrap this node from a snapshot of the chain of another node", "missed", missed, "since", last, "max", max)
+		return false
+	}
+	h.logger.Info("beacon: rounds missed", "missed", missed, "since", last)
+	return true
+}
+
+// syncGap fetches from the other nodes the rounds missed before the given
+// beacon request, on which the handler catches up, and saves them. The rounds
+// are fetched from the most recent one back to the last beacon saved, so the
+// rounds closest to the current round are available first. Each beacon fetched
+// must be valid and chained to the next one, so the chain saved stays
+// contiguous. At most the catch-up limit of rounds are fetched, and it stops at
+// the first round no node can give: the rounds left are fetched on demand by
+// Lookup.
+func (h *Handler) syncGap(current Beacon) {
+	h.Lock()
+	limit := h.catchupLimit
+	h.Unlock()
+	next := &current
+	var fetched int
+	for round := current.Round - 1; round > 0; round-- {
+		if saved, err := h.store.Get(round); err == nil {
+			if !bytes.Equal(saved.Signature, next.PreviousRand) {
+				h.logger.Error("beacon: the chain saved differs from the one of the other nodes", "round", next.Round, "saved", round)
+				return
+			}
+			break
+		}
+		if limit > 0 && fetched >= limit {
+			h.logger.Warn("beacon: catch-up limit reached, older rounds are fetched on demand", "limit", limit, "up_to", round)
+			return
+		}
+		select {
+		case <-h.close:
+			return
+		default:
+		}
+		prevRand := next.PreviousRand
+		b, ok := h.fetchRound(round, func(b *Beacon) bool {
+			return bytes.Equal(b.Signature, prevRand)
+		})
+		if !ok {
+			h.logger.Warn("beacon: no node could give round, older rounds are fetched on demand", "round", round)
+			return
+		}
+		if err := h.store.Put(b); err != nil {
+			h.logger.Error("beacon: could not store fetched round", "round", round, "err", err)
+			return
+		}
+		fetched++
+		if fetched%catchupLogInterval == 0 {
+			h.logger.Info("beacon: fetching missed rounds", "fetched", fetched, "round", round)
+		}
+		next = b
+	}
+	h.logger.Info("beacon: synced missed rounds", "fetched", fetched, "up_to", current.Round-1)
+}
+
+// catchupLogInterval is the number of missed rounds fetched between two logs
+// of the progress of the catch up.
+const catchupLogInterval = 100
+
+// Lookup returns the beacon of the given round from the store. If the round is
+// missing but before the current round, it is fetched from the other nodes and
+// saved, which fills on demand the rounds missed beyond the catch-up limit. The
+// beacon fetched must be valid and chained to the next round if it is saved.
+func (h *Handler) Lookup(round uint64) (*Beacon, error) {
+	b, err := h.store.Get(round)
+	if err != ErrNoBeaconSaved {
+		return b, err
+	}
+	h.Lock()
+	current := h.round
+	h.Unlock()
+	if round >= current {
+		return nil, err
+	}
+	var nextPrev []byte
+	if next, err := h.store.Get(round + 1); err == nil {
+		nextPrev = next.PreviousRand
+	}
+	b, ok := h.fetchRound(round, func(b *Beacon) bool {
+		return nextPrev == nil || bytes.Equal(b.Signature, nextPrev)
+	})
+	if !ok {
+		return nil, ErrNoBeaconSaved
+	}
+	if err := h.store.Put(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// fetchRound asks the other nodes one after the other for the beacon of the
+// given round and returns the first one valid and accepted by the given
+// function, which checks how it is chained to the beacons saved.
+func (h *Handler) fetchRound(round uint64, chained func(*Beacon) bool) (*Beacon, bool) {
+	k := h.current()
+	for _, id := range k.group.Nodes {
+		if k.index == id.Index {
+			continue
+		}
+		resp, err := h.client.SyncRound(id.Identity, &proto.SyncRequest{Round: round})
+		if err != nil {
+			h.logger.Debug("beacon: could not sync round", "round", round, "from", id.Address(), "err", err)
+			continue
+		}
+		b := &Beacon{
+			Round:        resp.GetRound(),
+			PreviousRand: resp.GetPreviousRand(),
+			Signature:    resp.GetSignature(),
+		}
+		if b.Round != round || !chained(b) {
+			h.logger.Debug("beacon: unchained beacon", "round", round, "from", id.Address())
+			continue
+		}
+		msg := h.message(b.PreviousRand, round)
+		if err := bls.Verify(h.scheme.Pairing, k.pub.Commit(), msg, b.Signature); err != nil {
+			h.logger.Debug("beacon: invalid beacon", "round", round, "from", id.Address(), "err", err)
+			continue
+		}
+		return b, true
+	}
+	return nil, false
+}
+
+// SyncRound replies with the beacon of the requested round if it is saved, so
+// a node restarting after some downtime can fetch the rounds it missed.
+func (h *Handler) SyncRound(c context.Context, in *proto.SyncRequest) (*proto.SyncResponse, error) {
+	b, err := h.store.Get(in.GetRound())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.SyncResponse{
+		Round:        b.Round,
+		PreviousRand: b.PreviousRand,
+		Signature:    b.Signature,
+	}, nil
+}
+
+// RoundState returns the state of the aggregation of the partial signatures
+// of the given round, or of the round in progress if zero. Only the state of
+// the rounds started since the last few rounds is kept. It returns false if
+// the state of the round is not known.
+func (h *Handler) RoundState(round uint64) (*RoundState, bool) {
+	if round == 0 {
+		h.Lock()
+		round = h.round
+		h.Unlock()
+	}
+	return h.states.get(round)
+}
+
+// Stats returns how long the rounds produced by this node took to gather a
+// threshold of partial signatures. A round taking routinely longer than the
+// period is a sign the threshold or the period is misconfigured.
+func (h *Handler) Stats() RoundStats {
+	return h.stats.stats()
+}
+
 func (h *Handler) setCatchup(catchup bool) {
 	h.Lock()
 	defer h.Unlock()
 	h.catchup = catchup
 }
 
+// RoundState is the state of the aggregation of the partial signatures of a
+// round produced by this node.
+type RoundState struct {
+	Round     uint64
+	Threshold int
+	// Contributors are the indexes in the group of the nodes whose valid
+	// partial signature has been received, including this node, in the order
+	// they were received.
+	Contributors []int
+	// Done is true once the beacon of the round is reconstructed and saved.
+	Done bool
+}
+
+// roundStates keeps the state of the recent rounds, which are updated by the
+// rounds in progress while being read.
+type roundStates struct {
+	sync.Mutex
+	states map[uint64]*RoundState
+}
+
+func newRoundStates() *roundStates {
+	return &roundStates{states: make(map[uint64]*RoundState)}
+}
+
+func (r *roundStates) start(round uint64, own, threshold int) {
+	r.Lock()
+	defer r.Unlock()
+	r.states[round] = &RoundState{Round: round, Threshold: threshold, Contributors: []int{own}}
+}
+
+func (r *roundStates) add(round uint64, index int) {
+	r.Lock()
+	defer r.Unlock()
+	if s, ok := r.states[round]; ok {
+		s.Contributors = append(s.Contributors, index)
+	}
+}
+
+func (r *roundStates) done(round uint64) {
+	r.Lock()
+	defer r.Unlock()
+	if s, ok := r.states[round]; ok {
+		s.Done = true
+	}
+}
+
+// get returns a copy of the state of the round.
+func (r *roundStates) get(round uint64) (*RoundState, bool) {
+	r.Lock()
+	defer r.Unlock()
+	s, ok := r.states[round]
+	if !ok {
+		return nil, false
+	}
+	c := *s
+	c.Contributors = append([]int(nil), s.Contributors...)
+	return &c, true
+}
+
+// evict removes the states of the rounds more than maxRoundDelta rounds
+// before the current round.
+func (r *roundStates) evict(currRound uint64) {
+	r.Lock()
+	defer r.Unlock()
+	for round := range r.states {
+		if round+maxRoundDelta < currRound {
+			delete(r.states, round)
+		}
+	}
+}
+
 type signatureCache struct {
 	sync.Mutex
 	cache map[uint64]*partialRand
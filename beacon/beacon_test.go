@@ -3,6 +3,7 @@ package beacon
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -11,11 +12,13 @@ import (
 	"time"
 
 	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/log"
 	"github.com/dedis/drand/net"
 	dkg_proto "github.com/dedis/drand/protobuf/dkg"
 	"github.com/dedis/drand/protobuf/drand"
 	"github.com/dedis/drand/test"
 	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/pairing"
 	"github.com/dedis/kyber/share"
 	"github.com/dedis/kyber/sign/bls"
 	"github.com/dedis/kyber/sign/tbls"
@@ -35,14 +38,48 @@ func (t *testService) Public(context.Context, *drand.PublicRandRequest) (*drand.
 func (t *testService) Private(context.Context, *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
 	return &drand.PrivateRandResponse{}, nil
 }
+func (t *testService) DistKey(context.Context, *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	return &drand.DistKeyResponse{}, nil
+}
+func (t *testService) PublicStream(*drand.PublicRandRequest, drand.Randomness_PublicStreamServer) error {
+	return nil
+}
+func (t *testService) Home(context.Context, *drand.HomeRequest) (*drand.HomeResponse, error) {
+	return &drand.HomeResponse{}, nil
+}
+func (t *testService) Group(context.Context, *drand.GroupRequest) (*drand.GroupResponse, error) {
+	return &drand.GroupResponse{}, nil
+}
+func (t *testService) ChainInfo(context.Context, *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	return &drand.ChainInfoResponse{}, nil
+}
+func (t *testService) LocateRandomness(context.Context, *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	return &drand.PublicRandResponse{}, nil
+}
+func (t *testService) Sign(context.Context, *drand.SignRequest) (*drand.SignResponse, error) {
+	return &drand.SignResponse{}, nil
+}
 func (t *testService) Setup(c context.Context, in *dkg_proto.DKGPacket) (*dkg_proto.DKGResponse, error) {
 	return &dkg_proto.DKGResponse{}, nil
 }
+func (t *testService) DKGStatus(context.Context, *dkg_proto.DKGStatusRequest) (*dkg_proto.DKGStatusResponse, error) {
+	return &dkg_proto.DKGStatusResponse{}, nil
+}
 
 func (t *testService) NewBeacon(c context.Context, in *drand.BeaconRequest) (*drand.BeaconResponse, error) {
 	return t.Handler.ProcessBeacon(c, in)
 }
 
+func (t *testService) SyncRound(c context.Context, in *drand.SyncRequest) (*drand.SyncResponse, error) {
+	return t.Handler.SyncRound(c, in)
+}
+func (t *testService) BeaconState(c context.Context, in *drand.BeaconStateRequest) (*drand.BeaconStateResponse, error) {
+	return &drand.BeaconStateResponse{}, nil
+}
+func (t *testService) PartialSign(c context.Context, in *drand.PartialSignRequest) (*drand.PartialSignResponse, error) {
+	return &drand.PartialSignResponse{}, nil
+}
+
 func dkgShares(n, t int) ([]*key.Share, kyber.Point) {
 	var priPoly *share.PriPoly
 	var pubPoly *share.PubPoly
@@ -97,6 +134,21 @@ func dkgShares(n, t int) ([]*key.Share, kyber.Point) {
 	return dkgShares, pubPoly.Commit()
 }
 
+func TestNewHandlerErrors(t *testing.T) {
+	n, thr := 3, 2
+	shares, _ := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	client := net.NewMemoryNetwork().Client()
+
+	outsider := key.NewKeyPair("127.0.0.1:1")
+	_, err := NewHandler(client, outsider, shares[0], group, NewMemStore())
+	require.Error(t, err)
+
+	group.Scheme = "unknown"
+	_, err = NewHandler(client, privs[0], shares[0], group, NewMemStore())
+	require.Error(t, err)
+}
+
 func TestBeacon(t *testing.T) {
 	slog.Level = slog.LevelDebug
 	n := 5
@@ -140,7 +192,7 @@ func TestBeacon(t *testing.T) {
 	// into the map
 	launchBeacon := func(i int, catchup bool) {
 		myCb := func(b *Beacon) {
-			err := bls.Verify(key.Pairing, public, Message(b.PreviousRand, b.Round), b.Randomness)
+			err := bls.Verify(key.Pairing, public, Message(b.PreviousRand, b.Round), b.Signature)
 			require.NoError(t, err)
 			l.Lock()
 			genBeacons[b.Round] = append(genBeacons[b.Round], b)
@@ -152,7 +204,9 @@ func TestBeacon(t *testing.T) {
 		store = NewCallbackStore(store, myCb)
 		//opts := []grpc.DialOption{grpc.WithTimeout(dialTimeout), grpc.WithBlock()}
 		//opts := []grpc.DialOption{grpc.FailOnNonTempDialError(true)}
-		handlers[i] = NewHandler(net.NewGrpcClientWithTimeout(dialTimeout), privs[i], shares[i], group, store)
+		h, err := NewHandler(net.NewGrpcClientWithTimeout(dialTimeout), privs[i], shares[i], group, store)
+		require.NoError(t, err)
+		handlers[i] = h
 		listeners[i] = net.NewTCPGrpcListener(privs[i].Public.Addr, &testService{handlers[i]})
 		go listeners[i].Start()
 		go handlers[i].Loop(seed, period, catchup)
@@ -212,10 +266,10 @@ func TestBeacon(t *testing.T) {
 			for round, beacons := range genBeacons {
 				original := beacons[0]
 				for i, beacon := range beacons[1:] {
-					if !bytes.Equal(beacon.Randomness, original.Randomness) {
+					if !bytes.Equal(beacon.Signature, original.Signature) {
 						// randomness is not equal we return false
 						l.Unlock()
-						fmt.Printf("round %d: original %x vs (%d) %x\n", round, original.Randomness, i+1, beacon.Randomness)
+						fmt.Printf("round %d: original %x vs (%d) %x\n", round, original.Signature, i+1, beacon.Signature)
 						doneCh <- false
 						return
 					}
@@ -255,3 +309,558 @@ func TestBeacon(t *testing.T) {
 	go countGenBeacons(nbRound, n, done)
 	checkSuccess()
 }
+
+func TestBeaconCatchupGap(t *testing.T) {
+	var buff bytes.Buffer
+	oldOut, oldLvl := slog.Output, slog.Level
+	slog.Output, slog.Level = &buff, slog.LevelInfo
+	defer func() { slog.Output, slog.Level = oldOut, oldLvl }()
+
+	h := &Handler{store: NewMemStore()}
+	h.SetLogger(log.DefaultLogger())
+	h.SetMaxCatchupRounds(10)
+	require.NoError(t, h.store.Put(&Beacon{Round: 5}))
+
+	h.checkCatchupGap(6)
+	require.Empty(t, buff.String())
+
+	h.checkCatchupGap(10)
+	require.Contains(t, buff.String(), "rounds missed node= missed=4 since=5")
+	require.NotContains(t, buff.String(), "snapshot")
+
+	h.checkCatchupGap(100)
+	require.Contains(t, buff.String(), "missed=94 since=5 max=10")
+	require.Contains(t, buff.String(), "snapshot")
+}
+
+func TestBeaconShutdown(t *testing.T) {
+	newHandler := func() *Handler {
+		return &Handler{store: NewMemStore(), close: make(chan bool), abort: make(chan bool)}
+	}
+	// the round in progress finishes before the deadline
+	h := newHandler()
+	h.rounds.Add(1)
+	done := make(chan error)
+	go func() { done <- h.Shutdown(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("shutdown returned during the round")
+	case <-time.After(50 * time.Millisecond):
+	}
+	h.rounds.Done()
+	require.NoError(t, <-done)
+
+	// the round in progress is aborted at the deadline
+	h = newHandler()
+	h.rounds.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, h.Shutdown(ctx))
+	select {
+	case <-h.abort:
+	default:
+		t.Fatal("round not aborted")
+	}
+	// stopping after a shutdown is fine
+	h.Stop()
+}
+
+func TestBeaconSyncGap(t *testing.T) {
+	var buff bytes.Buffer
+	oldOut, oldLvl := slog.Output, slog.Level
+	slog.Output, slog.Level = &buff, slog.LevelInfo
+	defer func() { slog.Output, slog.Level = oldOut, oldLvl }()
+
+	n, thr := 3, 2
+	shares, _ := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	pub := share.NewPubPoly(key.G2, key.G2.Point().Base(), shares[0].Commits)
+
+	// chain of 5 rounds generated while the first node was down after round 2
+	var chain []*Beacon
+	prev := []byte("Sunshine in a bottle")
+	for round := uint64(1); round <= 5; round++ {
+		msg := Message(prev, round)
+		var sigs [][]byte
+		for _, s := range shares[:thr] {
+			sig, err := tbls.Sign(key.Pairing, s.Share, msg)
+			require.NoError(t, err)
+			sigs = append(sigs, sig)
+		}
+		final, err := tbls.Recover(key.Pairing, pub, msg, sigs, thr, n)
+		require.NoError(t, err)
+		chain = append(chain, &Beacon{Round: round, PreviousRand: prev, Signature: final})
+		prev = final
+	}
+
+	handlers := make([]*Handler, n)
+	for i := 0; i < n; i++ {
+		store := NewMemStore()
+		saved := chain
+		if i == 0 {
+			saved = chain[:2]
+		}
+		for _, b := range saved {
+			require.NoError(t, store.Put(b))
+		}
+		h, err := NewHandler(net.NewGrpcClientWithTimeout(200*time.Millisecond), privs[i], shares[i], group, store)
+		require.NoError(t, err)
+		handlers[i] = h
+		if i == 0 {
+			continue
+		}
+		l := net.NewTCPGrpcListener(privs[i].Public.Addr, &testService{handlers[i]})
+		go l.Start()
+		defer l.Stop()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	h := handlers[0]
+	require.True(t, h.checkCatchupGap(6))
+	h.syncGap(Beacon{Round: 6, PreviousRand: chain[4].Signature})
+	for _, b := range chain {
+		saved, err := h.store.Get(b.Round)
+		require.NoError(t, err)
+		require.Equal(t, b.Signature, saved.Signature)
+	}
+	require.Contains(t, buff.String(), "fetched=3 up_to=5")
+
+	// no node has rounds 6 to 8
+	h.syncGap(Beacon{Round: 9})
+	require.Contains(t, buff.String(), "no node could give round, older rounds are fetched on demand node="+h.addr+" round=8")
+	last, err := h.store.Last()
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), last.Round)
+
+	// only the most recent rounds are fetched up to the limit, the older ones
+	// on demand
+	h.store = NewMemStore()
+	for _, b := range chain[:2] {
+		require.NoError(t, h.store.Put(b))
+	}
+	h.SetCatchupLimit(2)
+	h.syncGap(Beacon{Round: 6, PreviousRand: chain[4].Signature})
+	require.Contains(t, buff.String(), "limit=2 up_to=3")
+	_, err = h.store.Get(3)
+	require.Equal(t, ErrNoBeaconSaved, err)
+	for _, b := range chain[3:] {
+		saved, err := h.store.Get(b.Round)
+		require.NoError(t, err)
+		require.Equal(t, b.Signature, saved.Signature)
+	}
+	// rounds after the current round are not fetched
+	h.setRound(6)
+	_, err = h.Lookup(7)
+	require.Equal(t, ErrNoBeaconSaved, err)
+	b, err := h.Lookup(3)
+	require.NoError(t, err)
+	require.Equal(t, chain[2].Signature, b.Signature)
+	saved, err := h.store.Get(3)
+	require.NoError(t, err)
+	require.Equal(t, chain[2].Signature, saved.Signature)
+}
+
+func TestRoundStates(t *testing.T) {
+	r := newRoundStates()
+	_, ok := r.get(1)
+	require.False(t, ok)
+
+	r.start(1, 2, 3)
+	r.add(1, 0)
+	r.add(4, 1)
+	s, ok := r.get(1)
+	require.True(t, ok)
+	require.Equal(t, &RoundState{Round: 1, Threshold: 3, Contributors: []int{2, 0}}, s)
+	_, ok = r.get(4)
+	require.False(t, ok)
+
+	// the state returned is a copy
+	s.Contributors[0] = 5
+	r.add(1, 1)
+	r.done(1)
+	s, _ = r.get(1)
+	require.Equal(t, []int{2, 0, 1}, s.Contributors)
+	require.True(t, s.Done)
+
+	r.start(2, 2, 3)
+	r.evict(1 + maxRoundDelta)
+	_, ok = r.get(1)
+	require.True(t, ok)
+	r.evict(2 + maxRoundDelta)
+	_, ok = r.get(1)
+	require.False(t, ok)
+	_, ok = r.get(2)
+	require.True(t, ok)
+}
+
+// countingService counts the partial signatures requested from a node.
+type countingService struct {
+	*testService
+	sync.Mutex
+	calls int
+}
+
+func (c *countingService) NewBeacon(ctx context.Context, in *drand.BeaconRequest) (*drand.BeaconResponse, error) {
+	c.Lock()
+	c.calls++
+	c.Unlock()
+	return c.testService.NewBeacon(ctx, in)
+}
+
+func (c *countingService) count() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.calls
+}
+
+func TestBeaconFanout(t *testing.T) {
+	n, thr := 4, 3
+	shares, _ := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	network := net.NewMemoryNetwork()
+	handlers := make([]*Handler, n)
+	services := make([]*countingService, n)
+	for i := 0; i < n; i++ {
+		h, err := NewHandler(network.Client(), privs[i], shares[i], group, NewMemStore())
+		require.NoError(t, err)
+		handlers[i] = h
+		services[i] = &countingService{testService: &testService{handlers[i]}}
+	}
+	h := handlers[0]
+	idx := func(i int) int {
+		j, _ := group.Index(privs[i].Public)
+		return j
+	}
+	runRound := func(round uint64) {
+		winCh := make(chan roundInfo, 1)
+		h.rounds.Add(1)
+		go h.run(round, []byte("prev"), winCh, make(chan bool))
+		select {
+		case info := <-winCh:
+			require.Equal(t, round, info.round)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("round %d not finished", round)
+		}
+	}
+
+	// without latency estimates, all the nodes are asked
+	first, rest := h.fanout(h.current())
+	require.Len(t, first, n-1)
+	require.Empty(t, rest)
+
+	h.SetFanoutDelay(time.Hour)
+	h.SetLatency(privs[1].Public.Address(), time.Millisecond)
+	h.SetLatency(privs[2].Public.Address(), 2*time.Millisecond)
+	h.SetLatency(privs[3].Public.Address(), time.Second)
+	first, rest = h.fanout(h.current())
+	require.Equal(t, []int{idx(1), idx(2)}, []int{first[0].Index, first[1].Index})
+	require.Equal(t, idx(3), rest[0].Index)
+
+	// the fastest nodes reach the threshold, the slowest one is not asked
+	for i := 1; i < n; i++ {
+		network.Gateway(privs[i].Public.Address(), services[i])
+	}
+	runRound(1)
+	require.Equal(t, 1, services[1].count())
+	require.Equal(t, 1, services[2].count())
+	require.Equal(t, 0, services[3].count())
+
+	// a fast node is down: the slowest one is asked right away
+	network.Gateway(privs[2].Public.Address(), services[2]).Stop()
+	runRound(2)
+	require.Equal(t, 1, services[3].count())
+
+	stats := h.Stats()
+	require.Equal(t, uint64(2), stats.Rounds)
+	require.True(t, stats.EMA > 0)
+	require.True(t, stats.Max >= stats.EMA)
+}
+
+// countingAggregator counts the recoveries it makes with the default
+// aggregator, or returns the given signature instead if set.
+type countingAggregator struct {
+	sync.Mutex
+	calls int
+	sig   []byte
+}
+
+func (c *countingAggregator) Recover(suite pairing.Suite, pub *share.PubPoly, msg []byte, partials [][]byte, threshold, n int) ([]byte, error) {
+	c.Lock()
+	c.calls++
+	c.Unlock()
+	if c.sig != nil {
+		return c.sig, nil
+	}
+	return TBLSAggregator{}.Recover(suite, pub, msg, partials, threshold, n)
+}
+
+func TestBeaconAggregator(t *testing.T) {
+	n, thr := 4, 3
+	shares, public := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	network := net.NewMemoryNetwork()
+	handlers := make([]*Handler, n)
+	for i := 0; i < n; i++ {
+		h, err := NewHandler(network.Client(), privs[i], shares[i], group, NewMemStore())
+		require.NoError(t, err)
+		handlers[i] = h
+		if i > 0 {
+			network.Gateway(privs[i].Public.Address(), &testService{handlers[i]})
+		}
+	}
+	h := handlers[0]
+	agg := new(countingAggregator)
+	h.SetAggregator(agg)
+	runRound := func(round uint64) {
+		h.rounds.Add(1)
+		go h.run(round, []byte("prev"), make(chan roundInfo, 1), make(chan bool))
+		h.rounds.Wait()
+	}
+
+	// the final signature comes from the aggregator
+	runRound(1)
+	require.Equal(t, 1, agg.calls)
+	b, err := h.store.Get(1)
+	require.NoError(t, err)
+	require.NoError(t, bls.Verify(key.Pairing, public, Message(b.PreviousRand, b.Round), b.Signature))
+
+	// a signature not verifying is not stored
+	agg.sig = b.Signature
+	runRound(2)
+	require.Equal(t, 2, agg.calls)
+	_, err = h.store.Get(2)
+	require.Error(t, err)
+
+	// nil restores the default aggregator
+	h.SetAggregator(nil)
+	runRound(2)
+	require.Equal(t, 2, agg.calls)
+	_, err = h.store.Get(2)
+	require.NoError(t, err)
+}
+
+func TestBeaconBroadcastJitter(t *testing.T) {
+	n, thr := 4, 3
+	shares, public := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	network := net.NewMemoryNetwork()
+	handlers := make([]*Handler, n)
+	for i := 0; i < n; i++ {
+		h, err := NewHandler(network.Client(), privs[i], shares[i], group, NewMemStore())
+		require.NoError(t, err)
+		handlers[i] = h
+		if i > 0 {
+			network.Gateway(privs[i].Public.Address(), &testService{handlers[i]})
+		}
+	}
+	h := handlers[0]
+	require.Equal(t, time.Duration(0), h.broadcastDelay())
+
+	// the delay stays within the jitter, and a quarter of the deadline
+	h.SetBroadcastJitter(time.Hour)
+	h.SetRoundTimeout(4 * time.Second)
+	for i := 0; i < 100; i++ {
+		require.True(t, h.broadcastDelay() < time.Second)
+	}
+	h.SetRoundTimeout(0)
+	h.period = 400 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		require.True(t, h.broadcastDelay() < 100*time.Millisecond)
+	}
+
+	// the round waits for the delay and then reaches the threshold
+	h.SetBroadcastJitter(50 * time.Millisecond)
+	h.rounds.Add(1)
+	go h.run(1, []byte("prev"), make(chan roundInfo, 1), make(chan bool))
+	h.rounds.Wait()
+	b, err := h.store.Get(1)
+	require.NoError(t, err)
+	require.NoError(t, bls.Verify(key.Pairing, public, Message(b.PreviousRand, b.Round), b.Signature))
+}
+
+func TestBeaconSetShare(t *testing.T) {
+	n, thr := 4, 3
+	shares, public := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	// the nodes only talk over the memory network, fixed addresses can not
+	// collide as free ports can
+	for i, p := range privs {
+		p.Public.Addr = fmt.Sprintf("node%d:1234", i)
+	}
+	network := net.NewMemoryNetwork()
+	handlers := make([]*Handler, n)
+	for i := 0; i < n; i++ {
+		idx, _ := group.Index(privs[i].Public)
+		h, err := NewHandler(network.Client(), privs[i], shares[idx], group, NewMemStore())
+		require.NoError(t, err)
+		handlers[i] = h
+		if i > 0 {
+			network.Gateway(privs[i].Public.Address(), &testService{handlers[i]})
+		}
+	}
+
+	// reshare the same distributed key
+	priShares := make([]*share.PriShare, n)
+	for i, s := range shares {
+		priShares[i] = s.Share
+	}
+	secret, err := share.RecoverSecret(key.G2, priShares, thr, n)
+	require.NoError(t, err)
+	pri := share.NewPriPoly(key.G2, thr, secret, random.New())
+	_, commits := pri.Commit(key.G2.Point().Base()).Info()
+	reshared := make([]*key.Share, n)
+	for i, s := range pri.Shares(n) {
+		reshared[i] = &key.Share{Share: s, Commits: commits}
+	}
+
+	h := handlers[0]
+	idx := h.current().index
+	// the share must be the share of the node, of the same distributed key
+	require.Error(t, h.SetShare(reshared[(idx+1)%n], group))
+	other, _ := dkgShares(n, thr)
+	require.Error(t, h.SetShare(other[idx], group))
+	require.Equal(t, shares[idx], h.current().share)
+
+	for _, h := range handlers {
+		require.NoError(t, h.SetShare(reshared[h.current().index], group))
+	}
+	h.rounds.Add(1)
+	go h.run(1, []byte("prev"), make(chan roundInfo, 1), make(chan bool))
+	h.rounds.Wait()
+	b, err := h.store.Get(1)
+	require.NoError(t, err)
+	require.NoError(t, bls.Verify(key.Pairing, public, Message(b.PreviousRand, b.Round), b.Signature))
+	require.Equal(t, reshared[idx], h.current().share)
+}
+
+func TestBeaconDuplicatePartials(t *testing.T) {
+	n, thr := 4, 3
+	shares, public := dkgShares(n, thr)
+	privs, group := test.BatchIdentities(n)
+	for i, p := range privs {
+		p.Public.Addr = fmt.Sprintf("node%d:1234", i)
+	}
+	network := net.NewMemoryNetwork()
+	handlers := make([]*Handler, n)
+	for i := 0; i < n; i++ {
+		idx, _ := group.Index(privs[i].Public)
+		h, err := NewHandler(network.Client(), privs[i], shares[idx], group, NewMemStore())
+		require.NoError(t, err)
+		handlers[i] = h
+	}
+	// two nodes reply with the partial signature of the same share
+	network.Gateway(privs[1].Public.Address(), &testService{handlers[1]})
+	network.Gateway(privs[2].Public.Address(), &testService{handlers[1]})
+	network.Gateway(privs[3].Public.Address(), &testService{handlers[3]})
+
+	h := handlers[0]
+	h.rounds.Add(1)
+	go h.run(1, []byte("prev"), make(chan roundInfo, 1), make(chan bool))
+	h.rounds.Wait()
+	b, err := h.store.Get(1)
+	require.NoError(t, err)
+	require.NoError(t, bls.Verify(key.Pairing, public, Message(b.PreviousRand, b.Round), b.Signature))
+}
+
+func TestRoundStats(t *testing.T) {
+	s := new(roundStats)
+	require.Equal(t, RoundStats{}, s.stats())
+
+	s.observe(4 * time.Second)
+	require.Equal(t, RoundStats{Rounds: 1, EMA: 4 * time.Second, Max: 4 * time.Second}, s.stats())
+	s.observe(8 * time.Second)
+	require.Equal(t, RoundStats{Rounds: 2, EMA: 5 * time.Second, Max: 8 * time.Second}, s.stats())
+
+	// the slow round leaves the window
+	for i := 0; i < StatsWindow; i++ {
+		s.observe(time.Second)
+	}
+	stats := s.stats()
+	require.Equal(t, uint64(StatsWindow+2), stats.Rounds)
+	require.Equal(t, time.Second, stats.Max)
+	require.True(t, stats.EMA >= time.Second && stats.EMA < 2*time.Second)
+}
+
+// switchService fails the requests for partial signatures while it is down
+type switchService struct {
+	*testService
+	sync.Mutex
+	down bool
+}
+
+func (s *switchService) NewBeacon(ctx context.Context, in *drand.BeaconRequest) (*drand.BeaconResponse, error) {
+	s.Lock()
+	down := s.down
+	s.Unlock()
+	if down {
+		return nil, errors.New("node down")
+	}
+	return s.testService.NewBeacon(ctx, in)
+}
+
+func (s *switchService) setDown(down bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.down = down
+}
+
+func TestBeaconRoundTimeout(t *testing.T) {
+	var buff bytes.Buffer
+	oldOut, oldLvl := slog.Output, slog.Level
+	slog.Output, slog.Level = &buff, slog.LevelInfo
+	defer func() { slog.Output, slog.Level = oldOut, oldLvl }()
+
+	n, thr := 4, 3
+	seed := []byte("seed")
+	// runs the loop of a node while too few nodes are up to reach the
+	// threshold, until one more node is up after the given time, and
+	// returns the first beacons produced. The other nodes do not run their
+	// loop, so they only sign the first rounds.
+	run := func(policy RoundPolicy, up time.Duration, count int) []*Beacon {
+		shares, _ := dkgShares(n, thr)
+		privs, group := test.BatchIdentities(n)
+		network := net.NewMemoryNetwork()
+		produced := make(chan *Beacon, 10)
+		handlers := make([]*Handler, n)
+		for i := 0; i < n; i++ {
+			h, err := NewHandler(network.Client(), privs[i], shares[i], group, NewMemStore())
+			require.NoError(t, err)
+			handlers[i] = h
+		}
+		handlers[0].store = NewCallbackStore(handlers[0].store, func(b *Beacon) { produced <- b })
+		network.Gateway(privs[1].Public.Address(), &testService{handlers[1]})
+		slow := &switchService{testService: &testService{handlers[2]}, down: true}
+		network.Gateway(privs[2].Public.Address(), slow)
+		time.AfterFunc(up, func() { slow.setDown(false) })
+
+		h := handlers[0]
+		h.SetRoundTimeout(50 * time.Millisecond)
+		h.SetRoundPolicy(policy)
+		go h.Loop(seed, 300*time.Millisecond, false)
+		defer h.Stop()
+		var beacons []*Beacon
+		for len(beacons) < count {
+			select {
+			case b := <-produced:
+				beacons = append(beacons, b)
+			case <-time.After(5 * time.Second):
+				t.Fatal("no beacon produced")
+			}
+		}
+		return beacons
+	}
+
+	// the first round fails and is skipped, the second one links to the seed
+	beacons := run(SkipRound, 150*time.Millisecond, 1)
+	require.Equal(t, uint64(2), beacons[0].Round)
+	require.Equal(t, seed, beacons[0].PreviousRand)
+	require.Contains(t, buff.String(), "round 1 failed: only got 2/3 signatures")
+
+	// the first round is retried past the time of the second one, which
+	// starts as soon as the first round succeeds
+	beacons = run(RetryRound, 400*time.Millisecond, 2)
+	require.Equal(t, uint64(1), beacons[0].Round)
+	require.Equal(t, seed, beacons[0].PreviousRand)
+	require.Equal(t, uint64(2), beacons[1].Round)
+	require.Equal(t, beacons[0].Signature, beacons[1].PreviousRand)
+}
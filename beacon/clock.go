@@ -0,0 +1,45 @@
+package beacon
+
+import "time"
+
+// Clock is the source of time of the beacon loop. The handler uses the real
+// clock by default; tests can set a clock they advance themselves, so rounds
+// are produced without waiting for the beacon period.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel on which the current time is sent once the
+	// duration has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the Clock following the wall clock.
+type RealClock struct{}
+
+// Now returns time.Now().
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d).
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// RoundAt returns the round in progress at the given time when round N starts
+// at genesis + N*period, i.e. zero before the first round or when the period
+// is not positive. It is the inverse of TimeOfRound, so anyone knowing the
+// genesis time and the period of a network can tell which round a time falls
+// in.
+func RoundAt(t, genesis time.Time, period time.Duration) uint64 {
+	if t.Before(genesis) || period <= 0 {
+		return 0
+	}
+	return uint64(t.Sub(genesis) / period)
+}
+
+// TimeOfRound returns the time at which the given round starts when round N
+// starts at genesis + N*period.
+func TimeOfRound(round uint64, genesis time.Time, period time.Duration) time.Time {
+	return genesis.Add(time.Duration(round) * period)
+}
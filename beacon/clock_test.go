@@ -0,0 +1,34 @@
+package beacon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundAt(t *testing.T) {
+	genesis := time.Unix(1500000000, 0)
+	period := 30 * time.Second
+	require.Equal(t, uint64(0), RoundAt(genesis.Add(-time.Hour), genesis, period))
+	require.Equal(t, uint64(0), RoundAt(genesis, genesis, period))
+	require.Equal(t, uint64(0), RoundAt(genesis.Add(period-1), genesis, period))
+	require.Equal(t, uint64(1), RoundAt(genesis.Add(period), genesis, period))
+	require.Equal(t, uint64(10), RoundAt(genesis.Add(10*period+period/2), genesis, period))
+	for _, r := range []uint64{1, 2, 1000} {
+		require.Equal(t, r, RoundAt(TimeOfRound(r, genesis, period), genesis, period))
+		require.Equal(t, r-1, RoundAt(TimeOfRound(r, genesis, period).Add(-1), genesis, period))
+	}
+	require.Equal(t, uint64(0), RoundAt(genesis.Add(time.Hour), genesis, 0))
+}
+
+func TestTimeOfRound(t *testing.T) {
+	genesis := time.Unix(1500000000, 0)
+	period := 30 * time.Second
+	require.True(t, genesis.Equal(TimeOfRound(0, genesis, period)))
+	require.True(t, genesis.Add(period).Equal(TimeOfRound(1, genesis, period)))
+	require.True(t, genesis.Add(1000*period).Equal(TimeOfRound(1000, genesis, period)))
+	// a time before genesis maps to round zero, which starts at genesis
+	before := genesis.Add(-time.Minute)
+	require.True(t, genesis.Equal(TimeOfRound(RoundAt(before, genesis, period), genesis, period)))
+}
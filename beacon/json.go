@@ -0,0 +1,78 @@
+package beacon
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JSONVersion is the version of the JSON representation of a beacon. It is
+// increased whenever the representation changes in a way that existing
+// decoders can not handle, so clients can detect it. Version 1 had the
+// signature as randomness; since version 2, the randomness is the hash of the
+// signature.
+const JSONVersion = 2
+
+// beaconJSON is the JSON representation of a beacon, version JSONVersion. The
+// keys are stable and the byte fields are hex encoded. The signature is the
+// BLS signature of the round and the previous signature, and the randomness
+// its hash.
+type beaconJSON struct {
+	Version    int    `json:"version"`
+	Round      uint64 `json:"round"`
+	Previous   string `json:"previous"`
+	Randomness string `json:"randomness"`
+	Signature  string `json:"signature"`
+}
+
+// MarshalJSON returns the versioned JSON representation of the beacon:
+//
+//	{"version":2,"round":..,"previous":"<hex>","randomness":"<hex>","signature":"<hex>"}
+func (b *Beacon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&beaconJSON{
+		Version:    JSONVersion,
+		Round:      b.Round,
+		Previous:   hex.EncodeToString(b.PreviousRand),
+		Randomness: hex.EncodeToString(b.Randomness()),
+		Signature:  hex.EncodeToString(b.Signature),
+	})
+}
+
+// UnmarshalJSON decodes the versioned JSON representation of a beacon. It
+// returns an error if the version is missing or not supported, or if the
+// randomness is not the hash of the signature. The signature of a beacon of
+// version 1 is read from its randomness if missing.
+func (b *Beacon) UnmarshalJSON(data []byte) error {
+	var bj beaconJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return err
+	}
+	if bj.Version < 1 || bj.Version > JSONVersion {
+		return fmt.Errorf("beacon: unsupported JSON version %d", bj.Version)
+	}
+	previous, err := hex.DecodeString(bj.Previous)
+	if err != nil {
+		return fmt.Errorf("beacon: invalid previous randomness: %s", err)
+	}
+	randomness, err := hex.DecodeString(bj.Randomness)
+	if err != nil {
+		return fmt.Errorf("beacon: invalid randomness: %s", err)
+	}
+	signature, err := hex.DecodeString(bj.Signature)
+	if err != nil {
+		return fmt.Errorf("beacon: invalid signature: %s", err)
+	}
+	if bj.Version == 1 {
+		if len(signature) == 0 {
+			signature = randomness
+		}
+	} else if !bytes.Equal(randomness, RandomnessFromSignature(signature)) {
+		return errors.New("beacon: randomness is not the hash of the signature")
+	}
+	b.Round = bj.Round
+	b.PreviousRand = previous
+	b.Signature = signature
+	return nil
+}
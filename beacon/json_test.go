@@ -0,0 +1,41 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeaconJSON(t *testing.T) {
+	b := &Beacon{PreviousRand: []byte{0x01, 0x02}, Round: 3, Signature: []byte{0xab}}
+	randomness := hex.EncodeToString(b.Randomness())
+	require.Len(t, b.Randomness(), 32)
+	buff, err := json.Marshal(b)
+	require.NoError(t, err)
+	require.Equal(t, `{"version":2,"round":3,"previous":"0102","randomness":"`+randomness+`","signature":"ab"}`, string(buff))
+
+	decoded := new(Beacon)
+	require.NoError(t, json.Unmarshal(buff, decoded))
+	require.Equal(t, b, decoded)
+
+	// version 1 had the signature as randomness
+	decoded = new(Beacon)
+	require.NoError(t, json.Unmarshal([]byte(`{"version":1,"round":3,"previous":"0102","randomness":"ab","signature":"ab"}`), decoded))
+	require.Equal(t, b, decoded)
+	decoded = new(Beacon)
+	require.NoError(t, json.Unmarshal([]byte(`{"version":1,"round":3,"previous":"0102","randomness":"ab"}`), decoded))
+	require.Equal(t, b, decoded)
+
+	require.Error(t, json.Unmarshal([]byte(`{"round":3,"previous":"0102","randomness":"ab"}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"version":3,"round":3,"previous":"0102","randomness":"ab"}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"version":1,"round":3,"previous":"0102","randomness":"zz"}`), decoded))
+	// the randomness must be the hash of the signature
+	require.Error(t, json.Unmarshal([]byte(`{"version":2,"round":3,"previous":"0102","randomness":"ab","signature":"ab"}`), decoded))
+
+	// the database keeps the key of the beacons saved by earlier versions
+	buff, err = json.Marshal((*storedBeacon)(b))
+	require.NoError(t, err)
+	require.Equal(t, `{"PreviousRand":"AQI=","Round":3,"Randomness":"qw=="}`, string(buff))
+}
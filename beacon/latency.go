@@ -0,0 +1,123 @@
+package beacon
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dedis/drand/key"
+)
+
+// latencies keeps an estimate of the time each node of the group takes to
+// reply with its partial signature, to ask the fastest nodes first.
+type latencies struct {
+	sync.Mutex
+	// estimates per index of the node in the group
+	estimates map[int]time.Duration
+}
+
+func newLatencies() *latencies {
+	return &latencies{estimates: make(map[int]time.Duration)}
+}
+
+// reset forgets the estimates of all the nodes.
+func (l *latencies) reset() {
+	l.Lock()
+	defer l.Unlock()
+	l.estimates = make(map[int]time.Duration)
+}
+
+// set replaces the estimate of the node.
+func (l *latencies) set(index int, d time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.estimates[index] = d
+}
+
+// observe updates the estimate of the node with a new measure, smoothed with
+// the previous estimates so a single slow reply does not demote a fast node.
+func (l *latencies) observe(index int, d time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	old, ok := l.estimates[index]
+	if !ok {
+		l.estimates[index] = d
+		return
+	}
+	l.estimates[index] = (3*old + d) / 4
+}
+
+// order returns the given nodes sorted from the fastest to the slowest, the
+// nodes without estimate last in the given order, and the number of nodes
+// with an estimate.
+func (l *latencies) order(nodes []*key.IndexedPublic) ([]*key.IndexedPublic, int) {
+	l.Lock()
+	defer l.Unlock()
+	sorted := make([]*key.IndexedPublic, len(nodes))
+	copy(sorted, nodes)
+	var known int
+	for _, n := range nodes {
+		if _, ok := l.estimates[n.Index]; ok {
+			known++
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di, iok := l.estimates[sorted[i].Index]
+		dj, jok := l.estimates[sorted[j].Index]
+		if iok != jok {
+			return iok
+		}
+		return di < dj
+	})
+	return sorted, known
+}
+
+// StatsWindow is the number of recent rounds over which the maximum time to
+// reach the threshold is computed.
+const StatsWindow = 100
+
+// RoundStats describes how long the recent rounds took to gather a threshold
+// of partial signatures, from the start of the round.
+type RoundStats struct {
+	// number of rounds that reached the threshold since the handler started
+	Rounds uint64
+	// exponential moving average of the time to reach the threshold
+	EMA time.Duration
+	// maximum time to reach the threshold over the last StatsWindow rounds
+	Max time.Duration
+}
+
+// roundStats records the time each round takes to reach the threshold.
+type roundStats struct {
+	sync.Mutex
+	rounds uint64
+	ema    time.Duration
+	// ring buffer of the last StatsWindow measures
+	window [StatsWindow]time.Duration
+}
+
+// observe records the time a round took to reach the threshold, smoothed
+// like the latencies of the nodes.
+func (s *roundStats) observe(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	if s.rounds == 0 {
+		s.ema = d
+	} else {
+		s.ema = (3*s.ema + d) / 4
+	}
+	s.window[s.rounds%StatsWindow] = d
+	s.rounds++
+}
+
+func (s *roundStats) stats() RoundStats {
+	s.Lock()
+	defer s.Unlock()
+	st := RoundStats{Rounds: s.rounds, EMA: s.ema}
+	for _, d := range s.window {
+		if d > st.Max {
+			st.Max = d
+		}
+	}
+	return st
+}
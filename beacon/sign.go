@@ -0,0 +1,122 @@
+package beacon
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/dedis/drand/key"
+	proto "github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/sign/tbls"
+)
+
+// signDomain prefixes the messages signed on request, see SignMessage.
+var signDomain = []byte("drand-sign-v1:")
+
+// SignMessage returns what the group signs for a message given to Sign: the
+// SHA-256 hash of the message prefixed with a domain tag. The signature of a
+// caller-chosen message is thus never the signature of a beacon, whose message
+// is the round followed by the previous signature.
+func SignMessage(msg []byte) []byte {
+	h := sha256.New()
+	h.Write(signDomain)
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// Sign runs a signing round over the given message, apart from the beacon
+// chain: it sends the partial signature of this node to the other nodes of the
+// group, gathers a threshold of valid partial signatures and returns the
+// signature recovered from them, verified against the distributed key over
+// SignMessage(msg). Nothing is saved in the store. It fails if the threshold
+// is out of reach or the context is done first.
+func (h *Handler) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	k := h.current()
+	digest := SignMessage(msg)
+	partial, err := tbls.Sign(h.scheme.Pairing, k.share.Share, digest)
+	if err != nil {
+		return nil, err
+	}
+	request := &proto.PartialSignRequest{
+		Message:    msg,
+		PartialSig: partial,
+	}
+	var others int
+	respCh := make(chan []byte, k.group.Len())
+	failCh := make(chan bool, k.group.Len())
+	for _, n := range k.group.Nodes {
+		if n.Index == k.index {
+			continue
+		}
+		others++
+		go func(i *key.Identity) {
+			resp, err := h.client.PartialSign(i, request)
+			if err != nil {
+				h.logger.Debug("beacon: no partial signature for signing request", "from", i.Address(), "err", err)
+				failCh <- true
+				return
+			}
+			if err := tbls.Verify(h.scheme.Pairing, k.pub, digest, resp.GetPartialSig()); err != nil {
+				h.logger.Debug("beacon: invalid partial signature for signing request", "from", i.Address(), "err", err)
+				failCh <- true
+				return
+			}
+			respCh <- resp.GetPartialSig()
+		}(n.Identity)
+	}
+	sigs := [][]byte{partial}
+	// partial signatures by share index, a duplicate making the recovery fail
+	seen := map[int]bool{k.share.Share.I: true}
+	var failed int
+	for len(sigs) < k.group.Threshold {
+		select {
+		case sig := <-respCh:
+			idx, err := tbls.SigShare(sig).Index()
+			if err != nil || seen[idx] {
+				h.logger.Debug("beacon: duplicate partial signature for signing request", "index", idx)
+				failCh <- true
+				continue
+			}
+			seen[idx] = true
+			sigs = append(sigs, sig)
+		case <-failCh:
+			failed++
+			if others-failed+len(sigs) < k.group.Threshold {
+				return nil, fmt.Errorf("beacon: signing failed: only got %d/%d signatures", len(sigs), k.group.Threshold)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-h.abort:
+			return nil, errors.New("beacon: stopped during signing")
+		}
+	}
+	sig, err := h.aggregator.Recover(h.scheme.Pairing, k.pub, digest, sigs, k.group.Threshold, k.group.Len())
+	if err != nil {
+		return nil, err
+	}
+	if err := bls.Verify(h.scheme.Pairing, k.pub.Commit(), digest, sig); err != nil {
+		return nil, fmt.Errorf("beacon: invalid recovered signature: %s", err)
+	}
+	return sig, nil
+}
+
+// ProcessPartialSign replies to the signing request of another node with the
+// partial signature of this node over SignMessage of the requested message.
+// The partial signature of the request must be valid, proving that the request
+// comes from a node of the group which has accepted to sign the message. The
+// caller checks that this node accepts to sign it as well.
+func (h *Handler) ProcessPartialSign(c context.Context, in *proto.PartialSignRequest) (*proto.PartialSignResponse, error) {
+	k := h.current()
+	digest := SignMessage(in.GetMessage())
+	if err := tbls.Verify(h.scheme.Pairing, k.pub, digest, in.GetPartialSig()); err != nil {
+		h.logger.Debug("beacon: received invalid signing request", "err", err)
+		return nil, err
+	}
+	partial, err := tbls.Sign(h.scheme.Pairing, k.share.Share, digest)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PartialSignResponse{PartialSig: partial}, nil
+}
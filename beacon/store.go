@@ -2,10 +2,12 @@ package beacon
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"path"
+	"sort"
 	"sync"
 
 	bolt "github.com/coreos/bbolt"
@@ -16,18 +18,40 @@ import (
 // stores and loads beacon signatures. At the moment of writing, it consists of
 // a boltdb key/value database store.
 
-// Beacon holds the randomness as well as the info to verify it.
+// Beacon holds the signature of a round, from which its randomness derives, as
+// well as the info to verify it.
 type Beacon struct {
-	// PreviousRand is the previous randomness generated
+	// PreviousRand is the signature of the previous round, on top of which
+	// this round is signed
 	PreviousRand []byte
 	// Round is the round number this beacon is tied to
 	Round uint64
-	// Randomness is the tbls signature of Round || PreviousRand
-	Randomness []byte
+	// Signature is the tbls signature of Round || PreviousRand. It is saved
+	// in the database under its former name, from when the signature was
+	// the randomness itself.
+	Signature []byte `json:"Randomness"`
 }
 
+// Randomness returns the randomness of the beacon, the hash of its signature.
+func (b *Beacon) Randomness() []byte {
+	return RandomnessFromSignature(b.Signature)
+}
+
+// RandomnessFromSignature returns the randomness derived from the signature of
+// a round: its SHA-256 hash. The signature is the value verified against the
+// distributed key and chained to the next round; the randomness is the value
+// applications consume.
+func RandomnessFromSignature(sig []byte) []byte {
+	h := sha256.Sum256(sig)
+	return h[:]
+}
+
+// MessageFunc returns the message signed at the given round on top of the
+// previous randomness.
+type MessageFunc func(prevRand []byte, round uint64) []byte
+
 // Message returns a slice of bytes as the message to sign or to verify
-// alongside a beacon signature.
+// alongside a beacon signature. It is the default MessageFunc.
 func Message(prevRand []byte, round uint64) []byte {
 	var buff bytes.Buffer
 	buff.Write(roundToBytes(round))
@@ -36,25 +60,91 @@ func Message(prevRand []byte, round uint64) []byte {
 }
 
 // Store is an interface to store Beacons packets where they can also be
-// retrieved to be delivered to end clients.
+// retrieved to be delivered to end clients. Implementations must be safe for
+// concurrent use. See NewBoltStore and NewMemStore.
 type Store interface {
+	// Len returns the number of beacons saved.
 	Len() int
+	// Put saves the beacon. Saving again the beacon of a round is a no-op,
+	// but saving a beacon with a different randomness for a round already
+	// saved fails with ErrBeaconConflict. Beacons are not necessarily saved in
+	// increasing order of rounds, as missing rounds are fetched from other
+	// nodes.
 	Put(*Beacon) error
+	// Last returns the beacon with the highest round, or ErrNoBeaconSaved if
+	// the store is empty.
 	Last() (*Beacon, error)
+	// Get returns the beacon of the given round, or ErrNoBeaconSaved if this
+	// round is not saved.
 	Get(round uint64) (*Beacon, error)
-	//Cursor() (*Cursor,error)
+	// Cursor calls fn with a cursor over the saved beacons, ordered by round.
+	// The cursor reads a consistent snapshot of the store and is only valid
+	// during the call. Cursor returns the error returned by fn.
+	Cursor(fn func(Cursor) error) error
 	// XXX Misses a delete function
 	Close()
 }
 
-// XXX To be implemented
+// Cursor iterates over the beacons of a store in increasing order of rounds.
+// Each method moves the cursor and returns the beacon under it, or
+// ErrNoBeaconSaved if there is no beacon there.
 type Cursor interface {
-	Next() (*Beacon, error)
-	Prev() (*Beacon, error)
+	// First moves to the beacon with the lowest round.
 	First() (*Beacon, error)
+	// Last moves to the beacon with the highest round.
 	Last() (*Beacon, error)
+	// Next moves to the beacon following the current one.
+	Next() (*Beacon, error)
+	// Prev moves to the beacon preceding the current one.
+	Prev() (*Beacon, error)
+	// Seek moves to the beacon of the given round, or of the next round saved
+	// if that one is not.
+	Seek(round uint64) (*Beacon, error)
+}
+
+// Locator is implemented by the stores finding the beacon of a randomness
+// without going through all the beacons saved, see Locate.
+type Locator interface {
+	// Locate returns the beacon whose randomness is the given one, or
+	// ErrNoBeaconSaved if there is none.
+	Locate(randomness []byte) (*Beacon, error)
+}
+
+// Locate returns the beacon saved in the store whose randomness, the hash of
+// its signature, is the given one, or ErrNoBeaconSaved if there is none. It
+// uses the index of the store if it implements Locator, such as the bolt store,
+// and goes through all the beacons otherwise.
+func Locate(s Store, randomness []byte) (*Beacon, error) {
+	if l, ok := s.(Locator); ok {
+		return l.Locate(randomness)
+	}
+	var beacon *Beacon
+	err := s.Cursor(func(c Cursor) error {
+		var err error
+		beacon, err = scan(c, randomness)
+		return err
+	})
+	return beacon, err
+}
+
+// scan returns the first beacon under the cursor with the given randomness.
+func scan(c Cursor, randomness []byte) (*Beacon, error) {
+	for b, err := c.First(); err != ErrNoBeaconSaved; b, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(b.Randomness(), randomness) {
+			return b, nil
+		}
+	}
+	return nil, ErrNoBeaconSaved
 }
 
+// ErrBeaconConflict is returned when saving a beacon for a round already saved
+// with a different randomness. Valid beacons are unique per round, so it
+// reveals a bug or a corrupted chain.
+var ErrBeaconConflict = errors.New("beacon: a different beacon is already saved for this round")
+
 // boldStore implements the Store interface using the kv storage boltdb (native
 // golang implementation). Internally, Beacons are stored as JSON-encoded in the
 // db file.
@@ -66,28 +156,70 @@ type boltStore struct {
 
 var bucketName = []byte("beacons")
 
+// indexBucketName is the bucket mapping the randomness of each beacon saved to
+// its round, see Locate.
+var indexBucketName = []byte("randomness")
+
 const BoltFileName = "drand.db"
 
 // NewBoltStore returns a Store implementation using the boltdb storage engine.
+// With the ReadOnly option, the database must already exist and Put fails.
+// Each beacon is saved in its own transaction, synced to disk before Put
+// returns, so a crash cannot lose or tear a beacon already saved.
 func NewBoltStore(folder string, opts *bolt.Options) (Store, error) {
-	dbPath := path.Join(folder, BoltFileName)
+	return NewBoltStoreFile(path.Join(folder, BoltFileName), opts)
+}
+
+// NewBoltStoreFile returns a Store as NewBoltStore, saving the beacons in the
+// database at the given path.
+func NewBoltStoreFile(dbPath string, opts *bolt.Options) (Store, error) {
 	db, err := bolt.Open(dbPath, 0660, opts)
 	if err != nil {
 		return nil, err
 	}
-
-	// create the bucket already
-	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucketName)
-		return err
+	// commits must be fsynced, whatever the defaults of bolt
+	db.NoSync = false
+	store := &boltStore{db: db}
+	if opts == nil || !opts.ReadOnly {
+		// create the buckets already, and index the beacons saved by
+		// the versions without the index
+		err = db.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists(bucketName)
+			if err != nil {
+				return err
+			}
+			index, err := tx.CreateBucketIfNotExists(indexBucketName)
+			if err != nil {
+				return err
+			}
+			if index.Stats().KeyN == bucket.Stats().KeyN {
+				return nil
+			}
+			return bucket.ForEach(func(k, v []byte) error {
+				b, err := decodeBeacon(k, v)
+				if err != nil {
+					return err
+				}
+				return index.Put(b.Randomness(), k)
+			})
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(bucketName); bucket != nil {
+			store.len = bucket.Stats().KeyN
+		}
+		return nil
 	})
-
-	return &boltStore{
-		db: db,
-	}, err
+	return store, err
 }
 
 func (b *boltStore) Len() int {
+	b.Lock()
+	defer b.Unlock()
 	return b.len
 }
 
@@ -98,24 +230,51 @@ func (b *boltStore) Close() {
 	slog.Debugf("boltdb store: closing ...")
 }
 
-// Put implements the Store interface. WARNING: It does NOT verify that this
-// beacon is not already saved in the database or not.
+// storedBeacon encodes a beacon in the database with the default JSON encoding
+// of its fields, independently of the representation given to clients.
+type storedBeacon Beacon
+
+// Put implements the Store interface. The check of the beacon already saved
+// and the write happen in the same transaction.
 func (b *boltStore) Put(beacon *Beacon) error {
+	b.Lock()
+	defer b.Unlock()
+	var added bool
 	err := b.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(bucketName)
 		key := roundToBytes(beacon.Round)
-		buff, err := json.Marshal(beacon)
+		if v := bucket.Get(key); v != nil {
+			saved := new(Beacon)
+			if err := json.Unmarshal(v, (*storedBeacon)(saved)); err != nil {
+				return err
+			}
+			return checkConflict(saved, beacon)
+		}
+		buff, err := json.Marshal((*storedBeacon)(beacon))
 		if err != nil {
 			return err
 		}
-		return bucket.Put(key, buff)
+		added = true
+		if err := bucket.Put(key, buff); err != nil {
+			return err
+		}
+		return tx.Bucket(indexBucketName).Put(beacon.Randomness(), key)
 	})
 	if err != nil {
 		return err
 	}
-	b.Lock()
-	b.len++
-	b.Unlock()
+	if added {
+		b.len++
+	}
+	return nil
+}
+
+// checkConflict returns ErrBeaconConflict if the beacon differs from the one
+// saved for the same round.
+func checkConflict(saved, beacon *Beacon) error {
+	if !bytes.Equal(saved.Signature, beacon.Signature) {
+		return ErrBeaconConflict
+	}
 	return nil
 }
 
@@ -124,15 +283,18 @@ var ErrNoBeaconSaved = errors.New("no beacon saved in db")
 // Last returns the last beacon signature saved into the db
 func (b *boltStore) Last() (*Beacon, error) {
 	var beacon *Beacon
-	err := b.db.Update(func(tx *bolt.Tx) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return ErrNoBeaconSaved
+		}
 		cursor := bucket.Cursor()
 		_, v := cursor.Last()
 		if v == nil {
 			return ErrNoBeaconSaved
 		}
 		b := &Beacon{}
-		if err := json.Unmarshal(v, b); err != nil {
+		if err := json.Unmarshal(v, (*storedBeacon)(b)); err != nil {
 			return err
 		}
 		beacon = b
@@ -144,14 +306,17 @@ func (b *boltStore) Last() (*Beacon, error) {
 // Get returns the beacon saved at this round
 func (b *boltStore) Get(round uint64) (*Beacon, error) {
 	var beacon *Beacon
-	err := b.db.Update(func(tx *bolt.Tx) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return ErrNoBeaconSaved
+		}
 		v := bucket.Get(roundToBytes(round))
 		if v == nil {
 			return ErrNoBeaconSaved
 		}
 		b := &Beacon{}
-		if err := json.Unmarshal(v, b); err != nil {
+		if err := json.Unmarshal(v, (*storedBeacon)(b)); err != nil {
 			return err
 		}
 		beacon = b
@@ -160,6 +325,186 @@ func (b *boltStore) Get(round uint64) (*Beacon, error) {
 	return beacon, err
 }
 
+// Cursor implements the Store interface, in a read-only transaction.
+func (b *boltStore) Cursor(fn func(Cursor) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return fn(&sliceCursor{pos: -1})
+		}
+		return fn(&boltCursor{bucket.Cursor()})
+	})
+}
+
+// Locate implements the Locator interface with the index of the randomness
+// of the beacons. A database opened read-only that has never been opened by a
+// version maintaining the index is scanned instead.
+func (b *boltStore) Locate(randomness []byte) (*Beacon, error) {
+	var beacon *Beacon
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket, index := tx.Bucket(bucketName), tx.Bucket(indexBucketName)
+		if bucket == nil {
+			return ErrNoBeaconSaved
+		}
+		if index == nil {
+			var err error
+			beacon, err = scan(&boltCursor{bucket.Cursor()}, randomness)
+			return err
+		}
+		k := index.Get(randomness)
+		if k == nil {
+			return ErrNoBeaconSaved
+		}
+		var err error
+		beacon, err = decodeBeacon(k, bucket.Get(k))
+		return err
+	})
+	return beacon, err
+}
+
+// boltCursor decodes the beacons under a bolt cursor.
+type boltCursor struct {
+	c *bolt.Cursor
+}
+
+func (c *boltCursor) First() (*Beacon, error) {
+	return decodeBeacon(c.c.First())
+}
+
+func (c *boltCursor) Last() (*Beacon, error) {
+	return decodeBeacon(c.c.Last())
+}
+
+func (c *boltCursor) Next() (*Beacon, error) {
+	return decodeBeacon(c.c.Next())
+}
+
+func (c *boltCursor) Prev() (*Beacon, error) {
+	return decodeBeacon(c.c.Prev())
+}
+
+func (c *boltCursor) Seek(round uint64) (*Beacon, error) {
+	return decodeBeacon(c.c.Seek(roundToBytes(round)))
+}
+
+func decodeBeacon(k, v []byte) (*Beacon, error) {
+	if k == nil {
+		return nil, ErrNoBeaconSaved
+	}
+	b := new(Beacon)
+	return b, json.Unmarshal(v, (*storedBeacon)(b))
+}
+
+// sliceCursor is a Cursor over beacons sorted by round.
+type sliceCursor struct {
+	beacons []*Beacon
+	pos     int
+}
+
+func (c *sliceCursor) at(pos int) (*Beacon, error) {
+	// the position stays within one step of the bounds, as bolt cursors do
+	if pos < -1 {
+		pos = -1
+	} else if pos > len(c.beacons) {
+		pos = len(c.beacons)
+	}
+	c.pos = pos
+	if pos < 0 || pos >= len(c.beacons) {
+		return nil, ErrNoBeaconSaved
+	}
+	return c.beacons[pos], nil
+}
+
+func (c *sliceCursor) First() (*Beacon, error) {
+	return c.at(0)
+}
+
+func (c *sliceCursor) Last() (*Beacon, error) {
+	return c.at(len(c.beacons) - 1)
+}
+
+func (c *sliceCursor) Next() (*Beacon, error) {
+	return c.at(c.pos + 1)
+}
+
+func (c *sliceCursor) Prev() (*Beacon, error) {
+	return c.at(c.pos - 1)
+}
+
+func (c *sliceCursor) Seek(round uint64) (*Beacon, error) {
+	return c.at(sort.Search(len(c.beacons), func(i int) bool {
+		return c.beacons[i].Round >= round
+	}))
+}
+
+// memStore implements the Store interface by keeping all beacons in memory.
+type memStore struct {
+	sync.Mutex
+	beacons map[uint64]*Beacon
+	last    *Beacon
+}
+
+// NewMemStore returns a Store keeping all beacons in memory. Nothing is
+// written to disk so all beacons are lost when the process exits.
+func NewMemStore() Store {
+	return &memStore{beacons: make(map[uint64]*Beacon)}
+}
+
+func (m *memStore) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.beacons)
+}
+
+func (m *memStore) Put(beacon *Beacon) error {
+	m.Lock()
+	defer m.Unlock()
+	if saved, ok := m.beacons[beacon.Round]; ok {
+		return checkConflict(saved, beacon)
+	}
+	m.beacons[beacon.Round] = beacon
+	if m.last == nil || beacon.Round >= m.last.Round {
+		m.last = beacon
+	}
+	return nil
+}
+
+func (m *memStore) Last() (*Beacon, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.last == nil {
+		return nil, ErrNoBeaconSaved
+	}
+	return m.last, nil
+}
+
+func (m *memStore) Get(round uint64) (*Beacon, error) {
+	m.Lock()
+	defer m.Unlock()
+	b, ok := m.beacons[round]
+	if !ok {
+		return nil, ErrNoBeaconSaved
+	}
+	return b, nil
+}
+
+// Cursor implements the Store interface, over a copy of the beacons saved when
+// it is called.
+func (m *memStore) Cursor(fn func(Cursor) error) error {
+	m.Lock()
+	beacons := make([]*Beacon, 0, len(m.beacons))
+	for _, b := range m.beacons {
+		beacons = append(beacons, b)
+	}
+	m.Unlock()
+	sort.Slice(beacons, func(i, j int) bool {
+		return beacons[i].Round < beacons[j].Round
+	})
+	return fn(&sliceCursor{beacons: beacons, pos: -1})
+}
+
+func (m *memStore) Close() {}
+
 type cbStore struct {
 	Store
 	cb func(*Beacon)
@@ -180,6 +525,12 @@ func (c *cbStore) Put(b *Beacon) error {
 	return nil
 }
 
+// Locate implements the Locator interface with the index of the wrapped
+// store, if any.
+func (c *cbStore) Locate(randomness []byte) (*Beacon, error) {
+	return Locate(c.Store, randomness)
+}
+
 func roundToBytes(r uint64) []byte {
 	var buff bytes.Buffer
 	binary.Write(&buff, binary.BigEndian, r)
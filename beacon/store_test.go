@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	bolt "github.com/coreos/bbolt"
 	"github.com/stretchr/testify/require"
 )
 
@@ -24,13 +25,13 @@ func TestBoltStore(t *testing.T) {
 	b1 := &Beacon{
 		PreviousRand: sig1,
 		Round:        145,
-		Randomness:   sig2,
+		Signature:    sig2,
 	}
 
 	b2 := &Beacon{
 		PreviousRand: sig2,
 		Round:        146,
-		Randomness:   sig1,
+		Signature:    sig1,
 	}
 
 	require.NoError(t, store.Put(b1))
@@ -61,3 +62,156 @@ func TestBoltStore(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+	_, err := store.Last()
+	require.Equal(t, ErrNoBeaconSaved, err)
+
+	b1 := &Beacon{Round: 145, Signature: []byte{0x01}}
+	b2 := &Beacon{Round: 146, Signature: []byte{0x02}}
+	require.NoError(t, store.Put(b2))
+	require.NoError(t, store.Put(b1))
+	require.Equal(t, 2, store.Len())
+
+	last, err := store.Last()
+	require.NoError(t, err)
+	require.Equal(t, b2, last)
+	got, err := store.Get(145)
+	require.NoError(t, err)
+	require.Equal(t, b1, got)
+	_, err = store.Get(147)
+	require.Equal(t, ErrNoBeaconSaved, err)
+}
+
+func TestBoltStoreReadOnly(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drandtest-ro")
+	require.NoError(t, os.MkdirAll(tmp, 0755))
+	defer os.RemoveAll(tmp)
+
+	store, err := NewBoltStore(tmp, nil)
+	require.NoError(t, err)
+	b := &Beacon{Round: 12, Signature: []byte{0x01}}
+	require.NoError(t, store.Put(b))
+	store.Close()
+
+	store, err = NewBoltStore(tmp, &bolt.Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer store.Close()
+	last, err := store.Last()
+	require.NoError(t, err)
+	require.Equal(t, b, last)
+	require.Error(t, store.Put(&Beacon{Round: 13}))
+}
+
+func TestStoreUniqueRounds(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drandtest-unique")
+	require.NoError(t, os.MkdirAll(tmp, 0755))
+	defer os.RemoveAll(tmp)
+	db, err := NewBoltStore(tmp, nil)
+	require.NoError(t, err)
+
+	for _, store := range []Store{NewMemStore(), db} {
+		b := &Beacon{Round: 3, PreviousRand: []byte{0x01}, Signature: []byte{0x02}}
+		require.NoError(t, store.Put(b))
+		// saving the same beacon again is a no-op
+		require.NoError(t, store.Put(&Beacon{Round: 3, PreviousRand: []byte{0x01}, Signature: []byte{0x02}}))
+		require.Equal(t, 1, store.Len())
+		require.Equal(t, ErrBeaconConflict, store.Put(&Beacon{Round: 3, PreviousRand: []byte{0x01}, Signature: []byte{0x03}}))
+		got, err := store.Get(3)
+		require.NoError(t, err)
+		require.Equal(t, b, got)
+	}
+
+	// the number of beacons is kept across restarts
+	db.Close()
+	db, err = NewBoltStore(tmp, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Equal(t, 1, db.Len())
+}
+
+func TestStoreCursor(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drandtest-cursor")
+	require.NoError(t, os.MkdirAll(tmp, 0755))
+	defer os.RemoveAll(tmp)
+	db, err := NewBoltStore(tmp, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, store := range []Store{NewMemStore(), db} {
+		require.NoError(t, store.Cursor(func(c Cursor) error {
+			_, err := c.First()
+			require.Equal(t, ErrNoBeaconSaved, err)
+			return nil
+		}))
+		for _, round := range []uint64{5, 2, 300, 4} {
+			require.NoError(t, store.Put(&Beacon{Round: round, Signature: []byte{byte(round)}}))
+		}
+		var rounds []uint64
+		require.NoError(t, store.Cursor(func(c Cursor) error {
+			for b, err := c.First(); err != ErrNoBeaconSaved; b, err = c.Next() {
+				require.NoError(t, err)
+				rounds = append(rounds, b.Round)
+			}
+			b, err := c.Seek(3)
+			require.NoError(t, err)
+			require.Equal(t, uint64(4), b.Round)
+			b, err = c.Prev()
+			require.NoError(t, err)
+			require.Equal(t, uint64(2), b.Round)
+			_, err = c.Prev()
+			require.Equal(t, ErrNoBeaconSaved, err)
+			b, err = c.Last()
+			require.NoError(t, err)
+			require.Equal(t, uint64(300), b.Round)
+			_, err = c.Seek(301)
+			require.Equal(t, ErrNoBeaconSaved, err)
+			return nil
+		}))
+		require.Equal(t, []uint64{2, 4, 5, 300}, rounds)
+	}
+}
+
+func TestStoreLocate(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drandtest-locate")
+	require.NoError(t, os.MkdirAll(tmp, 0755))
+	defer os.RemoveAll(tmp)
+	db, err := NewBoltStore(tmp, nil)
+	require.NoError(t, err)
+
+	b := &Beacon{Round: 7, PreviousRand: []byte{0x06}, Signature: []byte{0x07}}
+	for _, store := range []Store{NewMemStore(), db, NewCallbackStore(NewMemStore(), func(*Beacon) {})} {
+		require.NoError(t, store.Put(&Beacon{Round: 6, Signature: []byte{0x06}}))
+		require.NoError(t, store.Put(b))
+		got, err := Locate(store, b.Randomness())
+		require.NoError(t, err)
+		require.Equal(t, b, got)
+		// the signature is not the randomness
+		_, err = Locate(store, b.Signature)
+		require.Equal(t, ErrNoBeaconSaved, err)
+	}
+
+	// a database saved without the index is scanned when opened read-only,
+	// and indexed when opened for writing
+	require.NoError(t, db.(*boltStore).db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(indexBucketName)
+	}))
+	db.Close()
+	db, err = NewBoltStore(tmp, &bolt.Options{ReadOnly: true})
+	require.NoError(t, err)
+	got, err := Locate(db, b.Randomness())
+	require.NoError(t, err)
+	require.Equal(t, b, got)
+	db.Close()
+	db, err = NewBoltStore(tmp, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.(*boltStore).db.View(func(tx *bolt.Tx) error {
+		require.Equal(t, 2, tx.Bucket(indexBucketName).Stats().KeyN)
+		return nil
+	}))
+	got, err = Locate(db, b.Randomness())
+	require.NoError(t, err)
+	require.Equal(t, b, got)
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"time"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+)
+
+// Round is a beacon saved by the node together with the context it was saved
+// in, given to the callbacks added with WithRoundCallback.
+type Round struct {
+	*beacon.Beacon
+	// Time is the time the round is scheduled at if the rounds are aligned on
+	// a genesis time, see WithGenesis, and the time the beacon was saved
+	// otherwise.
+	Time time.Time
+	// Group is the group whose distributed key signed the beacon. It changes
+	// when the distributed key is reshared.
+	Group *key.Group
+	// Public is the distributed public key the beacon has been verified
+	// against.
+	Public *key.DistPublic
+	// CatchUp is true if the beacon has been fetched from the other nodes,
+	// while catching up or on demand, rather than produced by a round of this
+	// node.
+	CatchUp bool
+}
+
+// roundCallbacks verifies the beacon saved against the distributed public key
+// and calls each round callback with it in its own goroutine. A beacon that
+// does not verify is not given to the callbacks, and a callback that panics
+// does not stop the node.
+func (d *Drand) roundCallbacks(b *beacon.Beacon) {
+	d.state.Lock()
+	group, pub, h := d.group, d.pub, d.beacon
+	d.state.Unlock()
+	scheme, err := key.SchemeByName(group.Scheme)
+	if err == nil {
+		err = verifyBeacon(scheme, d.opts.beaconMessage(), pub.Key, beaconResponse(b))
+	}
+	if err != nil {
+		d.opts.logger.Error("drand: beacon saved does not verify, round callbacks not called", "round", b.Round, "err", err)
+		return
+	}
+	r := &Round{
+		Beacon:  b,
+		Time:    d.opts.clock.Now(),
+		Group:   group,
+		Public:  pub,
+		CatchUp: true,
+	}
+	if !d.opts.genesis.IsZero() {
+		r.Time = beacon.TimeOfRound(b.Round, d.opts.genesis, d.opts.beaconPeriod)
+	}
+	if h != nil {
+		// only the rounds this node runs have a state
+		_, running := h.RoundState(b.Round)
+		r.CatchUp = !running
+	}
+	for _, fn := range d.opts.roundCbs {
+		go d.runRoundCallback(fn, r)
+	}
+}
+
+func (d *Drand) runRoundCallback(fn func(*Round), r *Round) {
+	defer func() {
+		if err := recover(); err != nil {
+			d.opts.logger.Error("drand: round callback panicked", "round", r.Round, "err", err)
+		}
+	}()
+	fn(r)
+}
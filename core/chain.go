@@ -0,0 +1,87 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+)
+
+// ExportChain writes all the beacons of the store, in increasing order of
+// rounds, as a stream of JSON objects in the versioned format of beacon.Beacon,
+// one per line. The stream can be verified with VerifyChain after ReadBeacons,
+// and saved in another store with ImportChain. It returns the number of
+// beacons written.
+func ExportChain(store beacon.Store, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	var n int
+	err := store.Cursor(func(c beacon.Cursor) error {
+		for b, err := c.First(); err != beacon.ErrNoBeaconSaved; b, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(b); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// ImportChain reads the beacons written by ExportChain, or in any format read
+// by ReadBeacons, verifies them as VerifyChain does and saves them in the
+// store. It only extends the chain saved: the beacons of the rounds up to the
+// last one saved must be saved already and identical, and the first beacon
+// after it must link to it. Nothing is saved if a beacon does not verify or
+// conflicts with the store, in which case a *ChainError reports its round. It
+// returns the number of beacons saved.
+func ImportChain(store beacon.Store, pub *key.DistPublic, r io.Reader) (int, error) {
+	resps, err := ReadBeacons(r)
+	if err != nil {
+		return 0, err
+	}
+	if err := VerifyChain(pub, resps); err != nil {
+		return 0, err
+	}
+	last, err := store.Last()
+	if err == beacon.ErrNoBeaconSaved {
+		last = nil
+	} else if err != nil {
+		return 0, err
+	}
+	var beacons []*beacon.Beacon
+	for _, resp := range resps {
+		sig, err := responseSignature(resp)
+		if err != nil {
+			return 0, &ChainError{Round: resp.GetRound(), Err: err}
+		}
+		b := &beacon.Beacon{PreviousRand: resp.GetPrevious(), Round: resp.GetRound(), Signature: sig}
+		switch {
+		case last != nil && b.Round <= last.Round:
+			saved, err := store.Get(b.Round)
+			if err == beacon.ErrNoBeaconSaved {
+				return 0, &ChainError{Round: b.Round, Err: errors.New("not saved while later rounds are, only appending to the chain is supported")}
+			} else if err != nil {
+				return 0, err
+			}
+			if !bytes.Equal(saved.Signature, b.Signature) {
+				return 0, &ChainError{Round: b.Round, Err: beacon.ErrBeaconConflict}
+			}
+			continue
+		case last != nil && len(beacons) == 0 && !bytes.Equal(b.PreviousRand, last.Signature):
+			return 0, &ChainError{Round: b.Round, Err: errors.New("does not link to the last round saved")}
+		}
+		beacons = append(beacons, b)
+	}
+	for i, b := range beacons {
+		if err := store.Put(b); err != nil {
+			return i, &ChainError{Round: b.Round, Err: err}
+		}
+	}
+	return len(beacons), nil
+}
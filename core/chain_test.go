@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainExportImport(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	public := &key.DistPublic{Key: pub}
+	// signed chain of the given rounds on top of the given signature
+	chain := func(prev []byte, from, to uint64) []*beacon.Beacon {
+		var beacons []*beacon.Beacon
+		for round := from; round <= to; round++ {
+			resp := signedResponse(t, priv, round, prev)
+			beacons = append(beacons, &beacon.Beacon{PreviousRand: prev, Round: round, Signature: resp.Signature})
+			prev = resp.Signature
+		}
+		return beacons
+	}
+	export := func(beacons []*beacon.Beacon) *bytes.Buffer {
+		store := beacon.NewMemStore()
+		for _, b := range beacons {
+			require.NoError(t, store.Put(b))
+		}
+		var buff bytes.Buffer
+		n, err := ExportChain(store, &buff)
+		require.NoError(t, err)
+		require.Equal(t, len(beacons), n)
+		return &buff
+	}
+
+	beacons := chain([]byte("seed"), 1, 7)
+	store := beacon.NewMemStore()
+	n, err := ImportChain(store, public, export(beacons[:5]))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	for _, b := range beacons[:5] {
+		saved, err := store.Get(b.Round)
+		require.NoError(t, err)
+		require.Equal(t, b, saved)
+	}
+
+	// the rounds already saved are skipped and the next ones appended
+	n, err = ImportChain(store, public, export(beacons))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 7, store.Len())
+	n, err = ImportChain(store, public, export(beacons[6:]))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// a fork conflicts with the rounds saved
+	_, err = ImportChain(store, public, export(chain([]byte("fork"), 1, 8)))
+	require.Error(t, err)
+	require.Equal(t, uint64(1), err.(*ChainError).Round)
+	// the rounds appended must link to the last one saved
+	_, err = ImportChain(store, public, export(chain([]byte("fork"), 8, 9)))
+	require.Error(t, err)
+	require.Equal(t, uint64(8), err.(*ChainError).Round)
+	// nothing is saved from a chain that does not verify
+	next := chain(beacons[6].Signature, 8, 9)
+	next[1].Signature = next[0].Signature
+	_, err = ImportChain(store, public, export(next))
+	require.Error(t, err)
+	require.Equal(t, 7, store.Len())
+	// the rounds before the last one saved must be saved
+	gap := beacon.NewMemStore()
+	require.NoError(t, gap.Put(beacons[3]))
+	_, err = ImportChain(gap, public, export(beacons))
+	require.Error(t, err)
+	require.Equal(t, uint64(1), err.(*ChainError).Round)
+}
@@ -1,20 +1,37 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/dedis/drand/beacon"
 	"github.com/dedis/drand/ecies"
 	"github.com/dedis/drand/key"
 	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/crypto"
 	"github.com/dedis/drand/protobuf/drand"
 	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/share"
 	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
+	"github.com/nikkolasg/slog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Client is the endpoint logic, communicating with drand servers
 type Client struct {
 	client net.ExternalClient
 	public *key.DistPublic
+	// builds the message signed at each round, beacon.Message if nil
+	message beacon.MessageFunc
+	// scheme of the beacons, key.DefaultScheme if nil
+	scheme *key.Scheme
 }
 
 // NewGrpcClient returns a Client able to talk to drand instances using gRPC
@@ -25,11 +42,38 @@ func NewGrpcClient(opts ...grpc.DialOption) *Client {
 	}
 }
 
+// NewGrpcClientWithRetry returns a gRPC client retrying the requests that fail
+// because of a transient network error, as described in
+// net.NewGrpcClientWithRetry. Invalid requests and beacons that do not verify
+// are never retried.
+func NewGrpcClientWithRetry(maxAttempts int, backoff time.Duration, opts ...grpc.DialOption) *Client {
+	return &Client{
+		client: net.NewGrpcClientWithRetry(maxAttempts, backoff, opts...),
+	}
+}
+
+// NewClient returns a client using the given network client, for example a
+// net.NewGrpcClientWithRetry client with a custom deadline for each attempt.
+func NewClient(c net.ExternalClient) *Client {
+	return &Client{client: c}
+}
+
 // NewGrpcClientFromCert returns a client that contact its peer over TLS
 func NewGrpcClientFromCert(c *net.CertManager, opts ...grpc.DialOption) *Client {
 	return &Client{client: net.NewGrpcClientFromCertManager(c, opts...)}
 }
 
+// NewClientFromConfig returns a gRPC client using the trusted certificates, the
+// dial options, the maximum message size and the beacon message function of
+// the given config, so that it verifies the beacons of nodes running with the
+// same config.
+func NewClientFromConfig(c *Config) *Client {
+	return &Client{
+		client:  net.NewGrpcClientFromCertManager(c.certmanager, c.dialOptions()...),
+		message: c.beaconMessage(),
+	}
+}
+
 // NewRestClient returns a client that uses the HTTP Rest API delivered by drand
 // nodes
 func NewRESTClient() *Client {
@@ -48,11 +92,278 @@ func NewRESTClientFromCert(c *net.CertManager) *Client {
 // returns it if the randomness is valid. Secure indicates that the request
 // must be made over a TLS protected channel.
 func (c *Client) LastPublic(addr string, pub *key.DistPublic, secure bool) (*drand.PublicRandResponse, error) {
-	resp, err := c.client.Public(&peerAddr{addr, secure}, &drand.PublicRandRequest{})
+	return c.LastPublicCtx(context.Background(), addr, pub, secure)
+}
+
+// LastPublicCtx is LastPublic with a context: the request is canceled when the
+// context is done, for example at its deadline.
+func (c *Client) LastPublicCtx(ctx context.Context, addr string, pub *key.DistPublic, secure bool) (*drand.PublicRandResponse, error) {
+	resp, err := c.fetchPublic(ctx, &peerAddr{addr, secure}, &drand.PublicRandRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp, c.Verify(pub.Key, resp)
+}
+
+// PublicRound returns the randomness beacon of the given round from the server
+// associated, after having verified it. It is useful to verify a beacon
+// recorded earlier. The server returns an error with the NotFound code if it
+// does not have this round.
+func (c *Client) PublicRound(addr string, pub *key.DistPublic, round uint64, secure bool) (*drand.PublicRandResponse, error) {
+	return c.PublicRoundCtx(context.Background(), addr, pub, round, secure)
+}
+
+// PublicRoundCtx is PublicRound with a context: the request is canceled when
+// the context is done, for example at its deadline.
+func (c *Client) PublicRoundCtx(ctx context.Context, addr string, pub *key.DistPublic, round uint64, secure bool) (*drand.PublicRandResponse, error) {
+	resp, err := c.fetchPublic(ctx, &peerAddr{addr, secure}, &drand.PublicRandRequest{Round: round})
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetRound() != round {
+		return nil, newError(ErrVerification, "drand: asked for round %d but got round %d", round, resp.GetRound())
+	}
+	return resp, c.Verify(pub.Key, resp)
+}
+
+// PublicVerifiedChain returns the beacons of the rounds from to to, included,
+// from the server associated, after having verified the randomness of each
+// beacon and that each beacon links to the one before it, as VerifyChain does.
+// It gives stronger guarantees than PublicRound when auditing a segment of the
+// chain. Rounds the server does not have, such as rounds the group skipped, are
+// left out, the link being verified across them. It returns a *ChainError for
+// the first beacon that does not verify.
+func (c *Client) PublicVerifiedChain(addr string, pub *key.DistPublic, from, to uint64, secure bool) ([]*drand.PublicRandResponse, error) {
+	if from == 0 || from > to {
+		return nil, newError(ErrInvalidRequest, "drand: invalid range of rounds [%d,%d]", from, to)
+	}
+	var beacons []*drand.PublicRandResponse
+	for round := from; ; round++ {
+		resp, err := c.fetchPublic(context.Background(), &peerAddr{addr, secure}, &drand.PublicRandRequest{Round: round})
+		if status.Code(err) == codes.NotFound {
+			slog.Debugf("drand: round %d not found on %s, skipped", round, addr)
+		} else if err != nil {
+			return nil, err
+		} else if resp.GetRound() != round {
+			return nil, newError(ErrVerification, "drand: asked for round %d but got round %d", round, resp.GetRound())
+		} else {
+			beacons = append(beacons, resp)
+		}
+		if round == to {
+			break
+		}
+	}
+	if len(beacons) == 0 {
+		return nil, newError(ErrNoBeacon, "drand: no beacon found in rounds [%d,%d]", from, to)
+	}
+	scheme, message := c.verifier()
+	if err := verifyChainBatch(scheme, message, pub.Key, beacons); err != nil {
+		return nil, err
+	}
+	return beacons, nil
+}
+
+// LocateRandomness returns the beacon of the server associated whose
+// randomness is the given one, after having verified it. It tells whether a
+// value presented as drand randomness comes from the chain, and from which
+// round. The server returns an error with the NotFound code if it has no such
+// beacon.
+func (c *Client) LocateRandomness(addr string, pub *key.DistPublic, randomness []byte, secure bool) (*drand.PublicRandResponse, error) {
+	resp, err := c.client.LocateRandomness(&peerAddr{addr, secure}, &drand.LocateRandomnessRequest{Randomness: randomness})
 	if err != nil {
+		return nil, statusError(err)
+	}
+	if !bytes.Equal(resp.GetRandomness(), randomness) {
+		return nil, newError(ErrVerification, "drand: asked for randomness %x but got round %d with randomness %x", randomness, resp.GetRound(), resp.GetRandomness())
+	}
+	return resp, c.Verify(pub.Key, resp)
+}
+
+// Sign has the group of the server associated sign the given message, see
+// Drand.Sign, and returns the signature once verified against the
+// distributed public key. The signature is over beacon.SignMessage(msg).
+func (c *Client) Sign(addr string, pub *key.DistPublic, msg []byte, secure bool) ([]byte, error) {
+	resp, err := c.client.Sign(&peerAddr{addr, secure}, &drand.SignRequest{Message: msg})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	scheme, _ := c.verifier()
+	if err := bls.Verify(scheme.Pairing, pub.Key, beacon.SignMessage(msg), resp.GetSignature()); err != nil {
+		return nil, newError(ErrVerification, "drand: invalid signature: %w", err)
+	}
+	return resp.GetSignature(), nil
+}
+
+// Follow returns a channel on which each new randomness beacon generated by
+// the server associated is sent, once verified. Beacons that do not verify are
+// dropped. The channel is closed when the connection to the server is lost.
+// Following is only supported by gRPC clients.
+func (c *Client) Follow(addr string, pub *key.DistPublic, secure bool) (<-chan *drand.PublicRandResponse, error) {
+	stream, err := c.client.PublicStream(&peerAddr{addr, secure}, &drand.PublicRandRequest{Compressed: true})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	out := make(chan *drand.PublicRandResponse)
+	go func() {
+		defer close(out)
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				slog.Debugf("drand: stream from %s closed: %s", addr, err)
+				return
+			}
+			if err := decompress(resp); err != nil {
+				slog.Infof("drand: invalid beacon for round %d from %s: %s", resp.GetRound(), addr, err)
+				continue
+			}
+			if err := c.Verify(pub.Key, resp); err != nil {
+				slog.Infof("drand: invalid beacon for round %d from %s: %s", resp.GetRound(), addr, err)
+				continue
+			}
+			out <- resp
+		}
+	}()
+	return out, nil
+}
+
+// LastPublicFresh returns the last randomness beacon from the node with the
+// given identity, after having checked that the response is fresh: the request
+// includes a random nonce that the node must sign alongside the beacon with
+// its long-term key. It protects against a node replaying an old, yet valid,
+// beacon as being the last one. The randomness is verified as in LastPublic.
+func (c *Client) LastPublicFresh(id *key.Identity, pub *key.DistPublic) (*drand.PublicRandResponse, error) {
+	return c.LastPublicFreshCtx(context.Background(), id, pub)
+}
+
+// LastPublicFreshCtx is LastPublicFresh with a context: the request is canceled
+// when the context is done, for example at its deadline.
+func (c *Client) LastPublicFreshCtx(ctx context.Context, id *key.Identity, pub *key.DistPublic) (*drand.PublicRandResponse, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
 		return nil, err
 	}
-	return resp, c.verify(pub.Key, resp)
+	resp, err := c.fetchPublic(ctx, id, &drand.PublicRandRequest{Nonce: nonce})
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyAttestation(id, nonce, resp); err != nil {
+		return nil, err
+	}
+	return resp, c.Verify(pub.Key, resp)
+}
+
+// PublicPoly returns the public polynomial of the group from the server
+// associated, after having checked its free coefficient is the distributed
+// public key. The public key share of the node of index i, verifying its
+// partial signatures, is given by Eval(i).
+func (c *Client) PublicPoly(addr string, pub *key.DistPublic, secure bool) (*share.PubPoly, error) {
+	resp, err := c.client.DistKey(&peerAddr{addr, secure}, &drand.DistKeyRequest{})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if len(resp.GetKey()) == 0 {
+		return nil, newError(ErrVerification, "drand: empty public polynomial")
+	}
+	commits := make([]kyber.Point, len(resp.GetKey()))
+	for i, p := range resp.GetKey() {
+		if commits[i], err = crypto.ProtoToKyberPoint(p); err != nil {
+			return nil, err
+		}
+	}
+	if !commits[0].Equal(pub.Key) {
+		return nil, newError(ErrVerification, "drand: public polynomial does not match the distributed public key")
+	}
+	return share.NewPubPoly(key.G2, key.G2.Point().Base(), commits), nil
+}
+
+// Home returns the status of the server associated. It does not need the
+// distributed public key, so it can be used as a health check before the DKG
+// is done.
+func (c *Client) Home(addr string, secure bool) (*drand.HomeResponse, error) {
+	resp, err := c.client.Home(&peerAddr{addr, secure}, &drand.HomeRequest{})
+	return resp, statusError(err)
+}
+
+// FetchGroup returns the group and the distributed public key served by the
+// node at the given address. It lets a client bootstrap from the address of a
+// single node instead of a copy of the group file and of the distributed key,
+// but it only moves the trust to that first contact: the node could serve any
+// key. Callers should pin the distributed key returned, or cross-check it with
+// other nodes or an out-of-band copy, before relying on it.
+func (c *Client) FetchGroup(addr string, secure bool) (*key.Group, *key.DistPublic, error) {
+	resp, err := c.client.Group(&peerAddr{addr, secure}, &drand.GroupRequest{})
+	if err != nil {
+		return nil, nil, statusError(err)
+	}
+	distKey, err := crypto.ProtoToKyberPoint(resp.GetDistKey())
+	if err != nil {
+		return nil, nil, fmt.Errorf("drand: invalid distributed key: %s", err)
+	}
+	group := &key.Group{Threshold: int(resp.GetThreshold())}
+	for _, n := range resp.GetNodes() {
+		k, err := crypto.ProtoToKyberPoint(n.GetKey())
+		if err != nil {
+			return nil, nil, fmt.Errorf("drand: invalid key for %s: %s", n.GetAddress(), err)
+		}
+		group.Nodes = append(group.Nodes, &key.IndexedPublic{
+			Identity: &key.Identity{Key: k, Addr: n.GetAddress(), TLS: n.GetTls()},
+			Index:    int(n.GetIndex()),
+		})
+	}
+	if group.Threshold < 1 || group.Threshold > group.Len() {
+		return nil, nil, fmt.Errorf("drand: invalid threshold %d for a group of %d nodes", group.Threshold, group.Len())
+	}
+	if err := group.CheckIndexes(); err != nil {
+		return nil, nil, err
+	}
+	return group, &key.DistPublic{Key: distKey}, nil
+}
+
+// ChainInfo holds the parameters of a chain and the last round generated, as
+// served by a node.
+type ChainInfo struct {
+	// Round is the last round generated by the node, zero if none
+	Round uint64
+	// Period is the time between two rounds
+	Period time.Duration
+	// GenesisTime is the time at which the first round is, or was, expected.
+	// It is the zero time if the rounds are not aligned.
+	GenesisTime time.Time
+	// Aligned is true if round N starts at GenesisTime + (N-1)*Period.
+	// Otherwise the rounds only start roughly every period after the first.
+	Aligned bool
+	// Seed is the message signed at the first round
+	Seed []byte
+	// Public is the distributed public key verifying the beacons
+	Public *key.DistPublic
+}
+
+// ChainInfo returns the parameters of the chain served by the node at the given
+// address. As with FetchGroup, the distributed key returned should be pinned
+// or cross-checked before relying on it.
+func (c *Client) ChainInfo(addr string, secure bool) (*ChainInfo, error) {
+	resp, err := c.client.ChainInfo(&peerAddr{addr, secure}, &drand.ChainInfoRequest{})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	distKey, err := crypto.ProtoToKyberPoint(resp.GetDistKey())
+	if err != nil {
+		return nil, fmt.Errorf("drand: invalid distributed key: %s", err)
+	}
+	if resp.GetPeriod() == 0 {
+		return nil, errors.New("drand: invalid period of 0")
+	}
+	info := &ChainInfo{
+		Round:   resp.GetRound(),
+		Period:  time.Duration(resp.GetPeriod()) * time.Millisecond,
+		Aligned: resp.GetAligned(),
+		Seed:    resp.GetSeed(),
+		Public:  &key.DistPublic{Key: distKey},
+	}
+	if resp.GetGenesisTime() != 0 {
+		info.GenesisTime = time.Unix(0, resp.GetGenesisTime()*int64(time.Millisecond))
+	}
+	return info, nil
 }
 
 // Private retrieves a private random value from the server. It does that by
@@ -60,7 +371,71 @@ func (c *Client) LastPublic(addr string, pub *key.DistPublic, secure bool) (*dra
 // and decrypts the response, the randomness. Client will attempt a TLS
 // connection to the address in the identity if id.IsTLS() returns true
 func (c *Client) Private(id *key.Identity) ([]byte, error) {
-	ephScalar := key.G2.Scalar()
+	return c.PrivateCtx(context.Background(), id)
+}
+
+// PrivateCtx is Private with a context: the request is canceled when the
+// context is done, for example at its deadline.
+func (c *Client) PrivateCtx(ctx context.Context, id *key.Identity) ([]byte, error) {
+	return c.privateWithHash(ctx, id, ecies.DefaultHashName, 0)
+}
+
+// PrivateWithHash retrieves a private random value from the server as Private,
+// using the hash function of the given name for ECIES, such as "sha256" or
+// "sha512". The server returns an error with the InvalidArgument code if it does
+// not support this hash function.
+func (c *Client) PrivateWithHash(id *key.Identity, hashName string) ([]byte, error) {
+	return c.privateWithHash(context.Background(), id, hashName, 0)
+}
+
+// PrivateN retrieves n private random bytes from the server, for keys or
+// nonces of any size. n must be between 1 and MaxPrivateLength.
+func (c *Client) PrivateN(id *key.Identity, n int) ([]byte, error) {
+	if n < 1 || n > MaxPrivateLength {
+		return nil, newError(ErrInvalidRequest, "drand: can request between 1 and %d private bytes", MaxPrivateLength)
+	}
+	buff, err := c.privateWithHash(context.Background(), id, ecies.DefaultHashName, uint32(n))
+	if err != nil {
+		return nil, err
+	}
+	if len(buff) != n {
+		return nil, newError(ErrVerification, "drand: expected %d bytes of private randomness, got %d", n, len(buff))
+	}
+	return buff, nil
+}
+
+// privateWithHash requests length private random bytes, or a single value of
+// PrivateRandSize bytes if length is zero.
+func (c *Client) privateWithHash(ctx context.Context, id *key.Identity, hashName string, length uint32) ([]byte, error) {
+	hashFn, err := ecies.HashFunc(hashName)
+	if err != nil {
+		return nil, err
+	}
+	ephScalar := key.G2.Scalar().Pick(random.New())
+	ephPoint := key.G2.Point().Mul(ephScalar, nil)
+	ephBuff, err := ephPoint.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	obj, err := ecies.Encrypt(key.G2, hashFn, id.Key, ephBuff)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Private(ctx, id, &drand.PrivateRandRequest{Request: obj, Hash: hashName, Length: length})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return ecies.Decrypt(key.G2, hashFn, ephScalar, resp.GetResponse())
+}
+
+// PrivateBatch retrieves n private random values of PrivateRandSize bytes from
+// the server in one request: a single ephemeral key pair is generated, and the
+// values are encrypted together towards it. n can be at most MaxPrivateBatch.
+func (c *Client) PrivateBatch(id *key.Identity, n int) ([][]byte, error) {
+	if n < 1 || n > MaxPrivateBatch {
+		return nil, newError(ErrInvalidRequest, "drand: can request between 1 and %d private values", MaxPrivateBatch)
+	}
+	ephScalar := key.G2.Scalar().Pick(random.New())
 	ephPoint := key.G2.Point().Mul(ephScalar, nil)
 	ephBuff, err := ephPoint.MarshalBinary()
 	if err != nil {
@@ -70,16 +445,86 @@ func (c *Client) Private(id *key.Identity) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.client.Private(id, &drand.PrivateRandRequest{obj})
+	resp, err := c.client.Private(context.Background(), id, &drand.PrivateRandRequest{Request: obj, Count: uint32(n)})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	buff, err := ecies.Decrypt(key.G2, ecies.DefaultHash, ephScalar, resp.GetResponse())
 	if err != nil {
 		return nil, err
 	}
-	return ecies.Decrypt(key.G2, ecies.DefaultHash, ephScalar, resp.GetResponse())
+	if len(buff) != n*PrivateRandSize {
+		return nil, newError(ErrVerification, "drand: expected %d bytes of private randomness, got %d", n*PrivateRandSize, len(buff))
+	}
+	values := make([][]byte, n)
+	for i := range values {
+		values[i] = buff[i*PrivateRandSize : (i+1)*PrivateRandSize]
+	}
+	return values, nil
 }
 
-func (c *Client) verify(public kyber.Point, resp *drand.PublicRandResponse) error {
-	msg := beacon.Message(resp.GetPrevious(), resp.GetRound())
-	return bls.Verify(key.Pairing, public, msg, resp.GetRandomness())
+// SetScheme sets the scheme of the group whose beacons the client verifies,
+// key.DefaultScheme by default. The scheme of a group is found with
+// key.SchemeByName(group.Scheme).
+func (c *Client) SetScheme(s *key.Scheme) {
+	c.scheme = s
+}
+
+// Verify checks that the response is a valid beacon of the distributed public
+// key, as VerifyBeacon, with the beacon message function and the scheme of the
+// client.
+func (c *Client) Verify(public kyber.Point, resp *drand.PublicRandResponse) error {
+	scheme, message := c.verifier()
+	if err := verifyBeacon(scheme, message, public, resp); err != nil {
+		return newError(ErrVerification, "drand: invalid beacon: %w", err)
+	}
+	return nil
+}
+
+// fetchPublic fetches a beacon from the node, asking for its signature in the
+// compressed encoding to save bandwidth, and returns it with the uncompressed
+// signature the beacon is verified and chained with, and the randomness
+// derived from it. Nodes ignoring the request answer with the uncompressed
+// signature, returned as is.
+func (c *Client) fetchPublic(ctx context.Context, p net.Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	in.Compressed = true
+	resp, err := c.client.Public(ctx, p, in)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return resp, decompress(resp)
+}
+
+// decompress normalizes the response: its signature is replaced by its
+// uncompressed encoding if it is compressed, and its randomness by the hash of
+// the signature. The nodes that predate the signature field carry the
+// signature as randomness, which is then moved to the signature.
+func decompress(resp *drand.PublicRandResponse) error {
+	sig := resp.GetSignature()
+	if len(sig) == 0 {
+		sig = resp.GetRandomness()
+	}
+	sig, err := key.DecompressSignature(sig)
+	if err != nil {
+		return err
+	}
+	resp.Signature = sig
+	resp.Randomness = beacon.RandomnessFromSignature(sig)
+	return nil
+}
+
+// verifier returns the scheme and the beacon message function of the client,
+// or their default.
+func (c *Client) verifier() (*key.Scheme, beacon.MessageFunc) {
+	message := c.message
+	if message == nil {
+		message = beacon.Message
+	}
+	scheme := c.scheme
+	if scheme == nil {
+		scheme = key.DefaultScheme
+	}
+	return scheme, message
 }
 
 func (c *Client) peer(addr string) {
@@ -91,7 +536,12 @@ type peerAddr struct {
 	t    bool
 }
 
+// Address returns the address of the peer in its canonical form, as the
+// addresses of the identities, if it is valid.
 func (p *peerAddr) Address() string {
+	if a, err := key.NormalizeAddress(p.addr); err == nil {
+		return a
+	}
 	return p.addr
 }
 
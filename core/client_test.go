@@ -1,10 +1,18 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"testing"
 
+	"github.com/dedis/drand/ecies"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestClientPrivate(t *testing.T) {
@@ -25,4 +33,60 @@ func TestClientPrivate(t *testing.T) {
 	require.NotNil(t, buff)
 	require.Len(t, buff, 32)
 
+	values, err := client.PrivateBatch(pub, 10)
+	require.NoError(t, err)
+	require.Len(t, values, 10)
+	for i, v := range values {
+		require.Len(t, v, PrivateRandSize)
+		require.NotEqual(t, values[(i+1)%len(values)], v)
+	}
+	_, err = client.PrivateBatch(pub, MaxPrivateBatch+1)
+	require.Error(t, err)
+
+	buff, err = client.PrivateWithHash(pub, "sha512")
+	require.NoError(t, err)
+	require.Len(t, buff, PrivateRandSize)
+	_, err = client.PrivateWithHash(pub, "md5")
+	require.Error(t, err)
+
+	for _, n := range []int{16, 64, MaxPrivateLength} {
+		buff, err = client.PrivateN(pub, n)
+		require.NoError(t, err)
+		require.Len(t, buff, n)
+	}
+	_, err = client.PrivateN(pub, 0)
+	require.Error(t, err)
+	_, err = client.PrivateN(pub, MaxPrivateLength+1)
+	require.Error(t, err)
+
+	// the request is canceled with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewGrpcClientFromCert(drands[0].opts.certmanager).PrivateCtx(ctx, pub)
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	// the server rejects hash functions it does not support
+	obj, err := ecies.Encrypt(key.G2, ecies.DefaultHash, pub.Key, []byte("client key"))
+	require.NoError(t, err)
+	_, err = drands[0].private(&drand.PrivateRandRequest{Request: obj, Hash: "md5"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// and lengths above the maximum
+	_, err = privateSize(&drand.PrivateRandRequest{Length: MaxPrivateLength + 1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = privateSize(&drand.PrivateRandRequest{Length: 16, Count: 2})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the randomness comes from the configured source
+	fixed := bytes.Repeat([]byte{0x42}, PrivateRandSize)
+	drands[0].opts.privateRand = bytes.NewReader(fixed)
+	buff, err = client.Private(pub)
+	require.NoError(t, err)
+	require.Equal(t, fixed, buff)
+
+	// and a source that does not deliver enough bytes fails the request
+	drands[0].opts.privateRand = bytes.NewReader(fixed[:PrivateRandSize/2])
+	_, err = client.Private(pub)
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.True(t, errors.Is(err, ErrInternal))
 }
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/kyber/share"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/sign/tbls"
+)
+
+// CombinePartials recovers offline the signature of the given round, on top of
+// the given previous signature, from the partial signatures of at least the
+// threshold of the nodes of the group, as the nodes do at the end of each
+// round. Each partial signature is the index of the node in the group followed
+// by its signature of the round, as returned by the node. The group file does
+// not hold the commitments of the shares, so the partial signatures can not be
+// checked one by one: the recovered signature is instead verified against the
+// distributed public key, and an error is returned if it does not verify,
+// meaning at least one partial signature is invalid.
+func CombinePartials(group *key.Group, public *key.DistPublic, round uint64, prev []byte, partials [][]byte) ([]byte, error) {
+	scheme, err := key.SchemeByName(group.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	if len(partials) < group.Threshold {
+		return nil, fmt.Errorf("drand: %d partial signatures but the group threshold is %d", len(partials), group.Threshold)
+	}
+	seen := make(map[int]bool)
+	pubShares := make([]*share.PubShare, 0, len(partials))
+	for _, partial := range partials {
+		s := tbls.SigShare(partial)
+		i, err := s.Index()
+		if err != nil {
+			return nil, err
+		}
+		if i >= group.Len() {
+			return nil, fmt.Errorf("drand: partial signature of node %d but the group has %d nodes", i, group.Len())
+		}
+		if seen[i] {
+			return nil, fmt.Errorf("drand: several partial signatures of node %d", i)
+		}
+		seen[i] = true
+		point := scheme.Pairing.G1().Point()
+		if err := point.UnmarshalBinary(s.Value()); err != nil {
+			return nil, newError(ErrVerification, "drand: invalid partial signature of node %d: %w", i, err)
+		}
+		pubShares = append(pubShares, &share.PubShare{I: i, V: point})
+	}
+	commit, err := share.RecoverCommit(scheme.Pairing.G1(), pubShares, group.Threshold, group.Len())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := commit.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if err := bls.Verify(scheme.Pairing, public.Key, beacon.Message(prev, round), sig); err != nil {
+		return nil, newError(ErrVerification, "drand: recovered signature does not verify against the distributed public key, a partial signature is invalid: %w", err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/test"
+	"github.com/dedis/kyber/share"
+	"github.com/dedis/kyber/sign/tbls"
+	"github.com/dedis/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombinePartials(t *testing.T) {
+	n, thr := 5, 3
+	_, group := test.BatchIdentities(n)
+	group.Threshold = thr
+	pri := share.NewPriPoly(key.G2, thr, key.G2.Scalar().Pick(random.New()), random.New())
+	pubPoly := pri.Commit(key.G2.Point().Base())
+	public := &key.DistPublic{Key: pubPoly.Commit()}
+	round, prev := uint64(7), []byte("previous randomness")
+	msg := beacon.Message(prev, round)
+	var partials [][]byte
+	for _, s := range pri.Shares(n) {
+		partial, err := tbls.Sign(key.Pairing, s, msg)
+		require.NoError(t, err)
+		partials = append(partials, partial)
+	}
+
+	// same signature as the one the nodes recover
+	expected, err := beacon.TBLSAggregator{}.Recover(key.Pairing, pubPoly, msg, partials, thr, n)
+	require.NoError(t, err)
+	sig, err := CombinePartials(group, public, round, prev, partials[1:thr+1])
+	require.NoError(t, err)
+	require.Equal(t, expected, sig)
+
+	// too few partial signatures
+	_, err = CombinePartials(group, public, round, prev, partials[:thr-1])
+	require.Error(t, err)
+	// twice the same node
+	_, err = CombinePartials(group, public, round, prev, [][]byte{partials[0], partials[1], partials[1]})
+	require.Error(t, err)
+	// a partial signature of another round
+	other, err := tbls.Sign(key.Pairing, pri.Shares(n)[2], beacon.Message(prev, round+1))
+	require.NoError(t, err)
+	_, err = CombinePartials(group, public, round, prev, [][]byte{partials[0], partials[1], other})
+	require.Error(t, err)
+	// the wrong round
+	_, err = CombinePartials(group, public, round+1, prev, partials[:thr])
+	require.Error(t, err)
+}
@@ -1,13 +1,21 @@
 package core
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"io"
 	"path"
+	"strings"
 	"time"
 
 	bolt "github.com/coreos/bbolt"
 	"github.com/dedis/drand/beacon"
 	"github.com/dedis/drand/dkg"
 	"github.com/dedis/drand/fs"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/log"
 	"github.com/dedis/drand/net"
 	"google.golang.org/grpc"
 )
@@ -25,6 +33,30 @@ func DefaultConfigFolder() string {
 // default it is relative to the DefaultConfigFolder path.
 const DefaultDbFolder = "db"
 
+// ChainsFolderName is the name of the folder, in the config and database
+// folders, holding a subfolder per chain set with WithChainName.
+const ChainsFolderName = "chains"
+
+// DefaultMaxCatchupRounds is the default maximum number of missed rounds a
+// node catches up on.
+const DefaultMaxCatchupRounds = 1000
+
+// DefaultCatchupLimit is the default maximum number of missed rounds fetched in
+// the background when a node catches up.
+const DefaultCatchupLimit = 100
+
+// DefaultMaxMessageSize is the default maximum size, in bytes, of the gRPC
+// messages a node sends and receives, above the 4MB limit of gRPC so that the
+// responses to large batch and range requests fit.
+const DefaultMaxMessageSize = 16 << 20
+
+// DefaultAutoLeaderTimeout is the time a node waits for the DKG to start before
+// electing a leader, see WithAutoLeader.
+const DefaultAutoLeaderTimeout = 30 * time.Second
+
+// Logger is the logger through which drand logs, see WithLogger.
+type Logger = log.Logger
+
 // DefaultBeaconPeriod is the period in which the beacon logic creates new
 // random beacon.
 const DefaultBeaconPeriod time.Duration = 1 * time.Minute
@@ -34,17 +66,60 @@ type ConfigOption func(*Config)
 type Config struct {
 	configFolder string
 	dbFolder     string
+	dbFile       string
+	chainName    string
 	listenAddr   string
 	grpcOpts     []grpc.DialOption
 	callOpts     []grpc.CallOption
+	maxMsgSize   int
 	dkgTimeout   time.Duration
+	dkgFailFast  bool
+	autoLeader   bool
+	electTimeout time.Duration
 	boltOpts     *bolt.Options
 	beaconPeriod time.Duration
 	beaconCbs    []func(*beacon.Beacon)
+	roundCbs     []func(*Round)
+	signing      bool
+	authorize    func(ctx context.Context, msg []byte) error
+	dkgCbs       []func(*key.DistPublic, *key.Group)
 	insecure     bool
 	certPath     string
 	keyPath      string
+	keyPass      []byte
+	clientCAs    *x509.CertPool
+	tlsVersion   uint16
+	cipherSuites []uint16
+	privateRand  io.Reader
 	certmanager  *net.CertManager
+	logSampling  int
+	logger       log.Logger
+	rateLimit    int
+	rateBurst    int
+	events       io.Writer
+	inMemory     bool
+	maxCatchup   uint64
+	catchupLimit int
+	forceChain   bool
+	fanoutDelay  time.Duration
+	jitter       time.Duration
+	roundTimeout time.Duration
+	roundPolicy  beacon.RoundPolicy
+	network      func(listen string, s net.Service) net.Gateway
+	allowWeak    bool
+	minGroupSize int
+	message      beacon.MessageFunc
+	aggregator   beacon.Aggregator
+	beaconStore  func(*Config) (beacon.Store, error)
+	clock        beacon.Clock
+	genesis      time.Time
+	seed         []byte
+
+	// separate listening addresses for the internal and the public APIs
+	internalListen string
+	publicListen   string
+	publicCert     string
+	publicKey      string
 }
 
 // NewConfig returns the config to pass to drand with the default options set
@@ -55,7 +130,15 @@ func NewConfig(opts ...ConfigOption) *Config {
 		//grpcOpts:     []grpc.DialOption{grpc.WithInsecure()},
 		dkgTimeout:   dkg.DefaultTimeout,
 		beaconPeriod: DefaultBeaconPeriod,
+		maxCatchup:   DefaultMaxCatchupRounds,
+		catchupLimit: DefaultCatchupLimit,
+		minGroupSize: DefaultMinimumGroupSize,
 		certmanager:  net.NewCertManager(),
+		clock:        beacon.RealClock{},
+		logger:       log.DefaultLogger(),
+		privateRand:  rand.Reader,
+		maxMsgSize:   DefaultMaxMessageSize,
+		electTimeout: DefaultAutoLeaderTimeout,
 	}
 	d.dbFolder = path.Join(d.configFolder, DefaultDbFolder)
 	for i := range opts {
@@ -64,18 +147,65 @@ func NewConfig(opts ...ConfigOption) *Config {
 	return d
 }
 
+// ConfigFolder returns the folder of the key material of the node, namespaced
+// by the chain name if set.
 func (d *Config) ConfigFolder() string {
+	if d.chainName != "" {
+		return path.Join(d.configFolder, ChainsFolderName, d.chainName)
+	}
 	return d.configFolder
 }
 
+// DBFolder returns the folder of the beacon database and of the files
+// describing the chain, namespaced by the chain name if set.
 func (d *Config) DBFolder() string {
+	if d.chainName != "" {
+		return path.Join(d.dbFolder, ChainsFolderName, d.chainName)
+	}
 	return d.dbFolder
 }
 
+// DBFile returns the path of the bolt database of the beacons.
+func (d *Config) DBFile() string {
+	if d.dbFile != "" {
+		return path.Join(d.DBFolder(), d.dbFile)
+	}
+	return path.Join(d.DBFolder(), beacon.BoltFileName)
+}
+
+// CheckName returns an error if the name of a chain or of a database file is
+// a path, that could escape its folder.
+func CheckName(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("config: invalid name %q, it must not be a path", name)
+	}
+	return nil
+}
+
+func (d *Config) checkNames() error {
+	if err := CheckName(d.chainName); err != nil {
+		return err
+	}
+	return CheckName(d.dbFile)
+}
+
 func (d *Config) Certs() *net.CertManager {
 	return d.certmanager
 }
 
+// checkListenAddresses returns an error if the given address of the gateway or
+// the address of the public listener, if any, can not be listened on, before
+// any listener is created.
+func (d *Config) checkListenAddresses(gateway string) error {
+	if err := net.CheckListenAddress(gateway); err != nil {
+		return err
+	}
+	if d.publicListen != "" {
+		return net.CheckListenAddress(d.publicListen)
+	}
+	return nil
+}
+
 // ListenAddress returns the given default address or the listen address stored
 // in the config thanks to WithListenAddress
 func (d *Config) ListenAddress(defaultAddr string) string {
@@ -91,12 +221,44 @@ func (d *Config) callbacks(b *beacon.Beacon) {
 	}
 }
 
+func (d *Config) dkgCallbacks(pub *key.DistPublic, qual *key.Group) {
+	for _, fn := range d.dkgCbs {
+		fn(pub, qual)
+	}
+}
+
 func WithGrpcOptions(opts ...grpc.DialOption) ConfigOption {
 	return func(d *Config) {
 		d.grpcOpts = opts
 	}
 }
 
+// WithMaxMessageSize sets the maximum size, in bytes, of the gRPC messages the
+// node sends and receives, both as a server and as a client of the other nodes,
+// DefaultMaxMessageSize by default. A larger size lets large batches of private
+// randomness or long ranges of beacons go through in one message, at the cost
+// of the memory to hold a message of that size for each call in progress. The
+// clients built with NewClientFromConfig use the same size, so they agree with
+// the node.
+func WithMaxMessageSize(bytes int) ConfigOption {
+	return func(d *Config) {
+		d.maxMsgSize = bytes
+	}
+}
+
+// dialOptions returns the gRPC options of the connections to the other nodes:
+// the maximum message size followed by the options of WithGrpcOptions, which
+// take precedence.
+func (d *Config) dialOptions() []grpc.DialOption {
+	size := grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(d.maxMsgSize), grpc.MaxCallSendMsgSize(d.maxMsgSize))
+	return append([]grpc.DialOption{size}, d.grpcOpts...)
+}
+
+// serverOptions returns the gRPC options of the listeners of the node.
+func (d *Config) serverOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.MaxRecvMsgSize(d.maxMsgSize), grpc.MaxSendMsgSize(d.maxMsgSize)}
+}
+
 func WithCallOption(opts ...grpc.CallOption) ConfigOption {
 	return func(d *Config) {
 		d.callOpts = opts
@@ -109,6 +271,29 @@ func WithDkgTimeout(t time.Duration) ConfigOption {
 	}
 }
 
+// WithAutoLeader lets the nodes waiting for the DKG start it themselves if no
+// leader has started it after DefaultAutoLeaderTimeout: the node with the
+// lowest index in the group among the ones that reply then starts the DKG, as
+// if run with StartDKG. All the nodes of the group should use it. A node only
+// deals once, so two nodes electing themselves, for example on either side of
+// a network partition, still run the same DKG. The DKG needs all the nodes of
+// the group anyway: this covers a leader started late or restarted without
+// --leader, not a leader down for good.
+func WithAutoLeader() ConfigOption {
+	return func(d *Config) {
+		d.autoLeader = true
+	}
+}
+
+// WithDkgFailFast makes the DKG abort as soon as too many deals have been
+// disqualified for the protocol to ever finish, instead of waiting for the DKG
+// timeout.
+func WithDkgFailFast() ConfigOption {
+	return func(d *Config) {
+		d.dkgFailFast = true
+	}
+}
+
 func WithBoltOptions(opts *bolt.Options) ConfigOption {
 	return func(d *Config) {
 		d.boltOpts = opts
@@ -123,24 +308,202 @@ func WithDbFolder(folder string) ConfigOption {
 	}
 }
 
+// WithDbFile sets the name of the bolt database file of the beacons in the
+// database folder, beacon.BoltFileName by default.
+func WithDbFile(name string) ConfigOption {
+	return func(d *Config) {
+		d.dbFile = name
+	}
+}
+
+// WithChainName namespaces everything the node saves under the given name:
+// the key material is kept in the chains/<name> subfolder of the config folder
+// and the beacons in the chains/<name> subfolder of the database folder. It
+// lets several nodes of independent groups share the same folders without
+// mixing their keys or their chains.
+func WithChainName(name string) ConfigOption {
+	return func(d *Config) {
+		d.chainName = name
+	}
+}
+
 func WithConfigFolder(folder string) ConfigOption {
 	return func(d *Config) {
 		d.configFolder = folder
 	}
 }
 
+// WithBeaconPeriod sets the period between two rounds. All the nodes of a group
+// must use the same period. The period is saved in the database folder when the
+// beacon starts, and a node restarting with another period refuses to start.
 func WithBeaconPeriod(period time.Duration) ConfigOption {
 	return func(d *Config) {
 		d.beaconPeriod = period
 	}
 }
 
+// WithGenesisTime aligns the rounds on the given genesis time: round N starts
+// at genesis + N*period, whenever the nodes start, instead of rounds starting
+// when the DKG finishes. The mapping between rounds and time is then absolute,
+// and anyone knowing the genesis time and the period can compute it. All the
+// nodes of a group must use the same genesis time and period.
+func WithGenesisTime(genesis time.Time) ConfigOption {
+	return func(d *Config) {
+		d.genesis = genesis
+	}
+}
+
+// WithSeed sets the message signed at the first round, instead of DefaultSeed.
+// All the nodes of a group must use the same seed. The seed is saved in the
+// database folder when the beacon starts, and a node restarting with another
+// seed refuses to start, see LoadDrand and BeaconLoop.
+func WithSeed(seed []byte) ConfigOption {
+	return func(d *Config) {
+		d.seed = seed
+	}
+}
+
+// WithPrivateRandSource sets the source of the random bytes served to private
+// randomness requests, for example a generator backed by an HSM or a KMS. By
+// default, it is crypto/rand. A request fails if the source does not deliver
+// all the bytes requested.
+func WithPrivateRandSource(r io.Reader) ConfigOption {
+	return func(d *Config) {
+		d.privateRand = r
+	}
+}
+
+// WithFanoutDelay makes the node ask, at each round, the threshold of nodes
+// that replied the fastest in the previous rounds for their partial signature
+// first, and the other nodes only if one of these fails or if the threshold is
+// not reached after the given delay. It lowers the load and the latency of the
+// rounds in groups spread over distant locations. By default, all the nodes are
+// asked at once. See beacon.Handler.SetFanoutDelay.
+func WithFanoutDelay(delay time.Duration) ConfigOption {
+	return func(d *Config) {
+		d.fanoutDelay = delay
+	}
+}
+
+// WithBroadcastJitter makes the node wait a random delay, up to maxDelay, at
+// the start of each round before asking the other nodes for their partial
+// signature. It spreads the requests of the nodes of large groups with a short
+// period instead of sending them all at the round boundary. The delay is capped
+// to a quarter of the round timeout, or of the period, so the round still has
+// the time to reach the threshold. By default, there is no delay. See
+// beacon.Handler.SetBroadcastJitter.
+func WithBroadcastJitter(maxDelay time.Duration) ConfigOption {
+	return func(d *Config) {
+		d.jitter = maxDelay
+	}
+}
+
+// WithRoundTimeout makes a round fail when it has not reached the threshold of
+// partial signatures after the given time, for example because too many nodes
+// are down. The failure is logged as "round N failed: only got k/t
+// signatures", and the round is then skipped or retried according to the
+// policy set with WithRoundPolicy. By default, a round runs until the next one
+// is due, after which the node waits for another node to contact it. See
+// beacon.Handler.SetRoundTimeout.
+func WithRoundTimeout(timeout time.Duration) ConfigOption {
+	return func(d *Config) {
+		d.roundTimeout = timeout
+	}
+}
+
+// WithRoundPolicy sets what the node does with a round failing after the round
+// timeout: beacon.SkipRound, the default, gives up on it and the next round
+// links to the last randomness produced, while beacon.RetryRound asks for it
+// again until it succeeds. All the nodes of the group must use the same policy.
+func WithRoundPolicy(policy beacon.RoundPolicy) ConfigOption {
+	return func(d *Config) {
+		d.roundPolicy = policy
+	}
+}
+
+// WithAggregator sets the beacon.Aggregator combining the partial signatures
+// of each round into the final signature, for example a client of a dedicated
+// service, instead of recovering it in process with beacon.TBLSAggregator. See
+// beacon.Handler.SetAggregator.
+func WithAggregator(a beacon.Aggregator) ConfigOption {
+	return func(d *Config) {
+		d.aggregator = a
+	}
+}
+
+// WithNetwork sets the function creating the gateway of the node, listening on
+// the given address and serving the given service, instead of gRPC over TCP or
+// TLS. It is meant to run several nodes in the same process without sockets,
+// with the Gateway method of a net.MemoryNetwork shared by all the nodes. The
+// TLS certificate and key are then not needed.
+func WithNetwork(fn func(listen string, s net.Service) net.Gateway) ConfigOption {
+	return func(d *Config) {
+		d.network = fn
+	}
+}
+
+// WithForceChain lets a node restart its beacon with a seed or a beacon period
+// that differ from the ones of the chain saved in the database folder, which
+// are then replaced. The beacons produced afterwards do not chain with the
+// beacons saved with the previous parameters.
+func WithForceChain() ConfigOption {
+	return func(d *Config) {
+		d.forceChain = true
+	}
+}
+
+// WithClock sets the source of time of the beacon loop, of the genesis time
+// and of the election of the DKG leader, instead of the real clock. It is meant for tests, which can advance a
+// fake clock to produce rounds without waiting for the beacon period. All the
+// nodes of a group must follow the same clock.
+func WithClock(c beacon.Clock) ConfigOption {
+	return func(d *Config) {
+		d.clock = c
+	}
+}
+
 func WithBeaconCallback(fn func(*beacon.Beacon)) ConfigOption {
 	return func(d *Config) {
 		d.beaconCbs = append(d.beaconCbs, fn)
 	}
 }
 
+// WithRoundCallback adds a function called with each beacon saved by the node,
+// once it is verified against the distributed public key, together with the
+// time of its round, the group that produced it and whether it was produced by
+// this node or fetched from the others. Each call runs in its own goroutine,
+// and a panic in the function is logged and recovered.
+func WithRoundCallback(fn func(*Round)) ConfigOption {
+	return func(d *Config) {
+		d.roundCbs = append(d.roundCbs, fn)
+	}
+}
+
+// WithSigning enables the Sign RPC, through which clients have the group
+// sign messages of their choice, apart from the beacon chain. Each call runs
+// a signing round with the other nodes of the group, so the node only signs
+// the messages authorize accepts: it is given the context of the call, to
+// look at the client address or metadata, and the message, and returns an
+// error to refuse it. A nil authorize accepts every message, which is only
+// sensible if the public API is not reachable by untrusted clients. The calls
+// are rate limited as the other public calls, see WithRateLimit.
+func WithSigning(authorize func(ctx context.Context, msg []byte) error) ConfigOption {
+	return func(d *Config) {
+		d.signing = true
+		d.authorize = authorize
+	}
+}
+
+// WithDKGCallback adds a function called when the DKG finishes successfully,
+// once the share, the distributed public key and the group are saved. It is
+// given the distributed public key and the group of the qualified
+// participants, i.e. the nodes whose deals were accepted by everyone.
+func WithDKGCallback(fn func(*key.DistPublic, *key.Group)) ConfigOption {
+	return func(d *Config) {
+		d.dkgCbs = append(d.dkgCbs, fn)
+	}
+}
+
 func WithInsecure() ConfigOption {
 	return func(d *Config) {
 		d.insecure = true
@@ -154,6 +517,49 @@ func WithTLS(certPath, keyPath string) ConfigOption {
 	}
 }
 
+// WithTLSKeyPassphrase sets the passphrase of the TLS private keys given with
+// WithTLS and WithPublicTLS, when they are PEM blocks encrypted with it. The
+// drand command reads it from the DRAND_TLS_PASSPHRASE environment variable.
+// Without passphrase, an encrypted key makes drand fail to start.
+func WithTLSKeyPassphrase(passphrase []byte) ConfigOption {
+	return func(d *Config) {
+		d.keyPass = passphrase
+	}
+}
+
+// WithMutualTLS makes the nodes of the group authenticate each other with
+// their TLS certificate. The node presents its certificate when contacting the
+// other nodes, and only processes the DKG packets and partial signatures sent
+// with a certificate issued by one of the authorities of the pool and valid
+// for the host of a node of the group, for its TLS name, see key.Identity, or
+// for the name set for its address with CertManager.SetServerName. The
+// certificates must therefore allow both server and client authentication.
+// The public API stays open to clients without certificate. It has no effect
+// on an insecure node.
+func WithMutualTLS(caPool *x509.CertPool) ConfigOption {
+	return func(d *Config) {
+		d.clientCAs = caPool
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version, such as tls.VersionTLS13,
+// the listeners of the node accept. It is TLS 1.2 by default.
+func WithMinTLSVersion(version uint16) ConfigOption {
+	return func(d *Config) {
+		d.tlsVersion = version
+	}
+}
+
+// WithCipherSuites restricts the cipher suites the listeners of the node accept
+// for TLS 1.2 connections to the given ones, such as
+// tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. By default, the cipher suites of
+// crypto/tls are accepted. The cipher suites of TLS 1.3 are not configurable.
+func WithCipherSuites(suites ...uint16) ConfigOption {
+	return func(d *Config) {
+		d.cipherSuites = suites
+	}
+}
+
 func WithTrustedCerts(certPaths ...string) ConfigOption {
 	return func(d *Config) {
 		for _, p := range certPaths {
@@ -166,9 +572,169 @@ func WithTrustedCerts(certPaths ...string) ConfigOption {
 
 // WithListenAddress specifies the address the drand instance should bind to. It
 // is useful if you want to advertise a public proxy address and the drand
-// instance runs behind your network.
+// instance runs behind your network. A malformed address is reported when the
+// node is created, before it listens on anything. With a port zero, e.g.
+// "127.0.0.1:0", the system chooses a free port, returned by
+// Drand.ListenAddress.
 func WithListenAddress(addr string) ConfigOption {
 	return func(d *Config) {
 		d.listenAddr = addr
 	}
 }
+
+// WithInternalListen sets the address on which drand serves the internal API,
+// i.e. the DKG and beacon protocols run between the nodes. It overrides the
+// address given by WithListenAddress.
+func WithInternalListen(addr string) ConfigOption {
+	return func(d *Config) {
+		d.internalListen = addr
+	}
+}
+
+// WithPublicListen serves the public API, i.e. the randomness for clients
+// over gRPC and REST, on a separate listener bound to the given address. The
+// main listener then only serves the internal API.
+func WithPublicListen(addr string) ConfigOption {
+	return func(d *Config) {
+		d.publicListen = addr
+	}
+}
+
+// WithPublicTLS sets the certificate and private key used by the public
+// listener set with WithPublicListen. By default, it uses the same ones as
+// the internal listener.
+func WithPublicTLS(certPath, keyPath string) ConfigOption {
+	return func(d *Config) {
+		d.publicCert = certPath
+		d.publicKey = keyPath
+	}
+}
+
+// newBeaconStore returns the store for the beacons of the node.
+func (d *Config) newBeaconStore() (beacon.Store, error) {
+	if d.beaconStore != nil {
+		return d.beaconStore(d)
+	}
+	if d.inMemory {
+		return beacon.NewMemStore(), nil
+	}
+	fs.CreateSecureFolder(d.DBFolder())
+	return beacon.NewBoltStoreFile(d.DBFile(), d.boltOpts)
+}
+
+// WithRequestLogSampling logs one out of n requests received on the public
+// API. Requests resulting in an error are always logged, except the ones
+// rejected by the rate limit, of which one out of 100 is logged. By default, no
+// successful requests are logged.
+func WithRequestLogSampling(n int) ConfigOption {
+	return func(d *Config) {
+		d.logSampling = n
+	}
+}
+
+// WithRateLimit limits the rate of the requests to the public API of each
+// remote IP to rps requests per second, allowing bursts of burst requests. A
+// PublicStream call counts as one request when it starts.
+// Requests above the limit fail with the ResourceExhausted code, or the 429
+// status over the REST API. The DKG and beacon requests between the nodes are
+// not limited. By default, requests are not limited.
+func WithRateLimit(rps, burst int) ConfigOption {
+	return func(d *Config) {
+		d.rateLimit = rps
+		d.rateBurst = burst
+	}
+}
+
+// WithEventWriter writes the lifecycle events of the node (DKG started and
+// completed, rounds produced or skipped, etc) to w as newline-delimited JSON.
+func WithEventWriter(w io.Writer) ConfigOption {
+	return func(d *Config) {
+		d.events = w
+	}
+}
+
+// WithInMemory keeps the beacons in memory instead of in a bolt database, so
+// that nothing is written to disk when drand is also given an in-memory
+// key.Store (see key.NewMemStore). It is useful for ephemeral nodes and tests.
+func WithInMemory() ConfigOption {
+	return func(d *Config) {
+		d.inMemory = true
+	}
+}
+
+// WithBeaconStore sets the function creating the store in which the node saves
+// its beacons, for example to keep them in an external database shared by
+// several nodes serving the same chain. It is called each time the beacon
+// starts, with the config of the node. By default, beacons are saved in a bolt
+// database in the DBFolder, or in memory with WithInMemory.
+func WithBeaconStore(fn func(*Config) (beacon.Store, error)) ConfigOption {
+	return func(d *Config) {
+		d.beaconStore = fn
+	}
+}
+
+// WithMaxCatchupRounds sets the maximum number of missed rounds a node catches
+// up on when it rejoins the group. A node further behind logs that it should
+// rather be bootstrapped from a snapshot of the chain of another node. Zero
+// means no limit.
+func WithMaxCatchupRounds(n uint64) ConfigOption {
+	return func(d *Config) {
+		d.maxCatchup = n
+	}
+}
+
+// WithCatchupLimit sets the maximum number of missed rounds a node rejoining the
+// group fetches from the other nodes, the most recent first, in the background
+// while it produces the current round again. The older missed rounds are
+// fetched when they are requested on the public API. Zero means no limit.
+func WithCatchupLimit(n int) ConfigOption {
+	return func(d *Config) {
+		d.catchupLimit = n
+	}
+}
+
+// WithAllowWeak lets drand start with a group whose threshold is not above
+// half of its members or which has less members than the minimum group size.
+// Such groups are always allowed in insecure mode.
+func WithAllowWeak() ConfigOption {
+	return func(d *Config) {
+		d.allowWeak = true
+	}
+}
+
+// WithMinimumGroupSize sets the minimum number of members a group must have
+// for drand to start. It defaults to DefaultMinimumGroupSize.
+func WithMinimumGroupSize(n int) ConfigOption {
+	return func(d *Config) {
+		d.minGroupSize = n
+	}
+}
+
+// WithBeaconMessage sets the function building the message signed at each
+// round, for example to include a domain separation tag. By default, the
+// message is built by beacon.Message. Changing it is incompatible with any
+// existing chain: all the nodes of the group must use the same function from
+// the first round on, and clients must verify with it too, see
+// NewClientFromConfig.
+func WithBeaconMessage(fn func(prev []byte, round uint64) []byte) ConfigOption {
+	return func(d *Config) {
+		d.message = fn
+	}
+}
+
+// beaconMessage returns the function building the message signed at each
+// round.
+func (d *Config) beaconMessage() beacon.MessageFunc {
+	if d.message != nil {
+		return d.message
+	}
+	return beacon.Message
+}
+
+// WithLogger sets the logger through which the node and its beacon loop log.
+// It defaults to log.DefaultLogger, printing through slog.
+func WithLogger(l Logger) ConfigOption {
+	return func(d *Config) {
+		d.logger = l
+	}
+}
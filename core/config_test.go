@@ -0,0 +1,56 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigChainName(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "drand-chains")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+	db := path.Join(tmp, "db")
+
+	// the default is a single chain per folder
+	conf := NewConfig(WithConfigFolder(tmp), WithDbFolder(db))
+	require.Equal(t, tmp, conf.ConfigFolder())
+	require.Equal(t, path.Join(db, beacon.BoltFileName), conf.DBFile())
+
+	privs, _ := test.BatchIdentities(2)
+	confs := []*Config{
+		NewConfig(WithConfigFolder(tmp), WithDbFolder(db), WithChainName("alpha")),
+		NewConfig(WithConfigFolder(tmp), WithDbFolder(db), WithChainName("beta"), WithDbFile("beta.db")),
+	}
+	require.Equal(t, path.Join(tmp, ChainsFolderName, "alpha"), confs[0].ConfigFolder())
+	require.Equal(t, path.Join(db, ChainsFolderName, "beta", "beta.db"), confs[1].DBFile())
+	for i, c := range confs {
+		require.NoError(t, key.NewFileStore(c.ConfigFolder()).SaveKeyPair(privs[i]))
+		store, err := c.newBeaconStore()
+		require.NoError(t, err)
+		require.NoError(t, store.Put(&beacon.Beacon{Round: 1, Signature: []byte{byte(i)}}))
+		store.Close()
+		_, err = os.Stat(c.DBFile())
+		require.NoError(t, err)
+	}
+	// each chain keeps its own key pair
+	for i, c := range confs {
+		pair, err := key.NewFileStore(c.ConfigFolder()).LoadKeyPair()
+		require.NoError(t, err)
+		require.Equal(t, privs[i].Public.Address(), pair.Public.Address())
+	}
+	_, err = key.NewFileStore(tmp).LoadKeyPair()
+	require.Error(t, err)
+
+	for _, name := range []string{"..", "a/b", `a\b`} {
+		require.Error(t, CheckName(name))
+		require.Error(t, NewConfig(WithChainName(name)).checkNames())
+	}
+	require.NoError(t, NewConfig(WithChainName("mainnet"), WithDbFile("drand-main.db")).checkNames())
+}
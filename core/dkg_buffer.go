@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/dedis/drand/dkg"
+	"github.com/dedis/drand/log"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+)
+
+// DKGBufferSize is the maximum number of DKG packets buffered for each dealer,
+// and the maximum number of dealers, when the packets arrive before the DKG
+// handler of the node is set up. Packets above these limits are dropped.
+const DKGBufferSize = 128
+
+type bufferedPacket struct {
+	ctx    context.Context
+	packet *dkg_proto.DKGPacket
+}
+
+// packetBuffer keeps the DKG packets received before the DKG handler is ready
+// to consume them, so the nodes of a group can be started in any order. The
+// packets are indexed by the dealer they relate to: the dealer of a deal, the
+// dealer whose deal a response is about, or the node confirming the polynomial
+// it reshared.
+type packetBuffer struct {
+	sync.Mutex
+	packets map[uint32][]bufferedPacket
+}
+
+func newPacketBuffer() *packetBuffer {
+	return &packetBuffer{packets: make(map[uint32][]bufferedPacket)}
+}
+
+// push buffers the packet. It returns false if the packet is dropped because
+// the buffer is full.
+func (b *packetBuffer) push(c context.Context, p *dkg_proto.DKGPacket) bool {
+	var dealer uint32
+	switch {
+	case p.GetDeal() != nil:
+		dealer = p.GetDeal().GetIndex()
+	case p.GetResponse() != nil:
+		dealer = p.GetResponse().GetIndex()
+	case p.GetReshared() != nil:
+		dealer = p.GetOrigin()
+	default:
+		return false
+	}
+	b.Lock()
+	defer b.Unlock()
+	pending, ok := b.packets[dealer]
+	if (!ok && len(b.packets) >= DKGBufferSize) || len(pending) >= DKGBufferSize {
+		return false
+	}
+	b.packets[dealer] = append(pending, bufferedPacket{c, p})
+	return true
+}
+
+// flush empties the buffer and processes the packets with the given handler,
+// dealer after dealer, in the order they were received. Packets that do not
+// come from a node of the group of the handler are dropped.
+func (b *packetBuffer) flush(h *dkg.Handler, l log.Logger) {
+	b.Lock()
+	packets := b.packets
+	b.packets = make(map[uint32][]bufferedPacket)
+	b.Unlock()
+	if len(packets) == 0 {
+		return
+	}
+	dealers := make([]int, 0, len(packets))
+	for dealer := range packets {
+		dealers = append(dealers, int(dealer))
+	}
+	sort.Ints(dealers)
+	l.Debug("drand: processing buffered dkg packets", "dealers", len(dealers))
+	for _, dealer := range dealers {
+		for _, p := range packets[uint32(dealer)] {
+			if err := h.Verify(p.packet); err != nil {
+				l.Info("drand: dropping buffered dkg packet", "err", err)
+				continue
+			}
+			h.Process(p.ctx, p.packet)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dedis/drand/key"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/drand/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacketBuffer(t *testing.T) {
+	b := newPacketBuffer()
+	deal := func(i uint32) *dkg_proto.DKGPacket {
+		return &dkg_proto.DKGPacket{Deal: &dkg_proto.Deal{Index: i}}
+	}
+	resp := func(i uint32) *dkg_proto.DKGPacket {
+		return &dkg_proto.DKGPacket{Response: &dkg_proto.Response{Index: i}}
+	}
+	require.False(t, b.push(context.Background(), &dkg_proto.DKGPacket{}))
+
+	for i := 0; i < DKGBufferSize; i++ {
+		require.True(t, b.push(context.Background(), resp(0)))
+	}
+	require.False(t, b.push(context.Background(), deal(0)))
+	require.Len(t, b.packets[0], DKGBufferSize)
+
+	for i := 1; i < DKGBufferSize; i++ {
+		require.True(t, b.push(context.Background(), deal(uint32(i))))
+	}
+	require.False(t, b.push(context.Background(), deal(DKGBufferSize)))
+	require.True(t, b.push(context.Background(), resp(1)))
+	require.Equal(t, []*dkg_proto.DKGPacket{deal(1), resp(1)}, []*dkg_proto.DKGPacket{b.packets[1][0].packet, b.packets[1][1].packet})
+}
+
+func TestDrandReshareLateJoiner(t *testing.T) {
+	n := 4
+	opts := []ConfigOption{WithInMemory()}
+	drands, dir := BatchNewDrand(n, true, opts...)
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+	public := drands[0].share.Public()
+
+	joining := test.GenerateIDs(1)[0]
+	store := test.NewKeyStore()
+	store.SaveKeyPair(joining)
+	joiner, err := NewReshareDrand(store, drands[0].group, public, NewConfig(append(opts, WithInsecure())...))
+	require.NoError(t, err)
+	defer joiner.Stop()
+	nodes := append(append([]*Drand{}, drands...), joiner)
+	var ids []*key.Identity
+	for _, d := range nodes {
+		ids = append(ids, d.priv.Public)
+	}
+	newGroup := key.NewGroup(ids, 3)
+
+	wg.Add(len(nodes) - 1)
+	for _, d := range nodes[1:] {
+		go func(d *Drand) {
+			if d == joiner {
+				// the joiner only gets ready once the other nodes have sent
+				// their deals
+				time.Sleep(500 * time.Millisecond)
+			}
+			require.NoError(t, d.WaitReshare(newGroup))
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, nodes[0].StartReshare(newGroup))
+	wg.Wait()
+	require.True(t, public.Key.Equal(joiner.share.Public().Key))
+}
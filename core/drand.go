@@ -2,22 +2,27 @@ package core
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/dedis/drand/beacon"
 	"github.com/dedis/drand/dkg"
 	"github.com/dedis/drand/ecies"
-	"github.com/dedis/drand/fs"
 	"github.com/dedis/drand/key"
 	"github.com/dedis/drand/net"
 	"github.com/dedis/drand/protobuf/crypto"
 	dkg_proto "github.com/dedis/drand/protobuf/dkg"
 	"github.com/dedis/drand/protobuf/drand"
 	"github.com/dedis/kyber"
-	"github.com/nikkolasg/slog"
+	"github.com/dedis/kyber/share"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Drand is the main logic of the program. It reads the keys / group file, it
@@ -29,15 +34,39 @@ type Drand struct {
 	group   *key.Group
 	store   key.Store
 	gateway net.Gateway
+	// address the gateway is bound to, as configured
+	listen string
+	// serves the public API if it is separate from the gateway
+	publicListener net.Listener
 
 	dkg         *dkg.Handler
 	beacon      *beacon.Handler
 	beaconStore beacon.Store
+	// dkg packets received before the dkg handler is set up
+	dkgBuffer *packetBuffer
 	// dkg private share. can be nil if dkg not finished yet.
 	share *key.Share
 	// dkg public key. Can be nil if dkg not finished yet.
 	pub     *key.DistPublic
 	dkgDone bool
+	// true while the distributed key is reshared to a new group
+	resharing bool
+	// group the distributed key is reshared to, nil if not resharing
+	reshareGroup *key.Group
+	// last beacon saved, served directly to the public API
+	lastBeacon *beacon.Beacon
+	// logs a sample of the requests received on the public API
+	reqLogger *requestLogger
+	limiter   *rateLimiter
+	// time at which the first round is expected
+	genesis time.Time
+	// lifecycle events for supervisors
+	events *eventLog
+	// new beacons sent to the clients following them
+	feed *publicFeed
+	// node the beacons are synced from, only set on a replica
+	upstream    string
+	stopReplica chan bool
 
 	state sync.Mutex
 }
@@ -46,26 +75,45 @@ type Drand struct {
 // the given group and then to serve randomness. It assumes the private key pair
 // has been generated already.
 func NewDrand(s key.Store, g *key.Group, c *Config) (*Drand, error) {
+	if err := checkGroupSafety(g, c); err != nil {
+		return nil, err
+	}
+	scheme, err := key.SchemeByName(g.Scheme)
+	if err != nil {
+		return nil, err
+	}
 	d, err := initDrand(s, c)
 	if err != nil {
 		return nil, err
 	}
 	dkgConf := &dkg.Config{
-		Suite:   key.G2.(dkg.Suite),
-		Group:   g,
-		Timeout: d.opts.dkgTimeout,
+		Suite:    scheme.DKGSuite(),
+		Group:    g,
+		Timeout:  d.opts.dkgTimeout,
+		FailFast: d.opts.dkgFailFast,
 	}
-	d.dkg, err = dkg.NewHandler(d.priv, dkgConf, d.dkgNetwork())
+	h, err := dkg.NewHandler(d.priv, dkgConf, d.dkgNetwork())
+	if err != nil {
+		d.Stop()
+		return nil, err
+	}
+	d.state.Lock()
+	d.dkg = h
 	d.group = g
-	return d, err
+	d.state.Unlock()
+	d.dkgBuffer.flush(h, d.opts.logger)
+	return d, nil
 }
 
 // initDrand inits the drand struct by loading the private key, and by creating the
 // gateway with the correct options.
 func initDrand(s key.Store, c *Config) (*Drand, error) {
-	if c.insecure == false && (c.certPath == "" || c.keyPath == "") {
+	if c.network == nil && c.insecure == false && (c.certPath == "" || c.keyPath == "") {
 		return nil, errors.New("config: need to set WithInsecure if no certificate and private key path given")
 	}
+	if err := c.checkNames(); err != nil {
+		return nil, err
+	}
 	priv, err := s.LoadKeyPair()
 	if err != nil {
 		return nil, err
@@ -74,43 +122,189 @@ func initDrand(s key.Store, c *Config) (*Drand, error) {
 	// identity. If there is an option to set the address, it will override the
 	// default set here..
 	d := &Drand{
-		store: s,
-		priv:  priv,
-		opts:  c,
+		store:     s,
+		priv:      priv,
+		opts:      c,
+		reqLogger: newRequestLogger(c.logSampling, c.logger),
+		limiter:   newRateLimiter(c.rateLimit, c.rateBurst, c.clock),
+		events:    newEventLog(c.events, c.logger),
+		feed:      newPublicFeed(c.logger),
+		dkgBuffer: newPacketBuffer(),
 	}
 
 	a := c.ListenAddress(priv.Public.Address())
+	if c.internalListen != "" {
+		a = c.internalListen
+	}
+	d.listen = a
+	if c.network != nil {
+		d.gateway = c.network(a, d)
+		go d.gateway.Start()
+		return d, nil
+	}
+	if err := c.checkListenAddresses(a); err != nil {
+		return nil, err
+	}
+	if c.publicListen != "" {
+		return d, d.initSeparateGateways(a)
+	}
 	if c.insecure {
-		d.gateway = net.NewGrpcGatewayInsecure(a, d, d.opts.grpcOpts...)
+		d.gateway = net.Gateway{
+			Listener:       net.NewTCPGrpcListener(a, d, c.serverOptions()...),
+			InternalClient: net.NewGrpcClient(c.dialOptions()...),
+		}
+	} else if net.IsUnixAddress(a) {
+		// the other nodes are still contacted over TLS
+		client, err := d.tlsClient()
+		if err != nil {
+			return nil, err
+		}
+		d.gateway = net.Gateway{
+			Listener:       net.NewTCPGrpcListener(a, d, c.serverOptions()...),
+			InternalClient: client,
+		}
 	} else {
-		d.gateway = net.NewGrpcGatewayFromCertManager(a, c.certPath, c.keyPath, c.certmanager, d, d.opts.grpcOpts...)
+		client, err := d.tlsClient()
+		if err != nil {
+			return nil, err
+		}
+		l, err := d.listenerFor(a, c.certPath, c.keyPath, net.AllAPIs)
+		if err != nil {
+			return nil, err
+		}
+		d.gateway = net.Gateway{
+			Listener:       l,
+			InternalClient: client,
+		}
 	}
 	go d.gateway.Start()
 	return d, nil
 }
 
-// LoadDrand restores a drand instance as it was running after a DKG instance
-func LoadDrand(s key.Store, c *Config) (*Drand, error) {
+// initSeparateGateways starts a gateway serving only the internal API on the
+// given address, and a listener serving only the public API on the address set
+// with WithPublicListen.
+func (d *Drand) initSeparateGateways(internal string) error {
+	c := d.opts
+	var client net.InternalClient = net.NewGrpcClient(c.dialOptions()...)
+	if !c.insecure {
+		var err error
+		if client, err = d.tlsClient(); err != nil {
+			return err
+		}
+	}
+	certPath, keyPath := c.certPath, c.keyPath
+	if c.publicCert != "" {
+		certPath, keyPath = c.publicCert, c.publicKey
+	}
+	internalL, err := d.listenerFor(internal, c.certPath, c.keyPath, net.InternalAPI)
+	if err != nil {
+		return err
+	}
+	if d.publicListener, err = d.listenerFor(c.publicListen, certPath, keyPath, net.PublicAPI); err != nil {
+		internalL.Stop()
+		return err
+	}
+	d.gateway = net.Gateway{Listener: internalL, InternalClient: client}
+	go d.gateway.Start()
+	go d.publicListener.Start()
+	return nil
+}
+
+// listenerFor returns a listener serving the given APIs on the address, over
+// TLS with the given certificate unless the node is insecure or the address is
+// the one of a Unix socket.
+func (d *Drand) listenerFor(addr, certPath, keyPath string, apis net.API) (net.Listener, error) {
+	opts := d.opts.serverOptions()
+	if d.opts.insecure || net.IsUnixAddress(addr) {
+		return net.NewTCPGrpcListenerFor(addr, d, apis, opts...), nil
+	}
+	conf := &net.TLSConfig{
+		Passphrase:   d.opts.keyPass,
+		MinVersion:   d.opts.tlsVersion,
+		CipherSuites: d.opts.cipherSuites,
+	}
+	if d.opts.clientCAs != nil && apis&net.InternalAPI != 0 {
+		conf.ClientCAs = d.opts.clientCAs
+		opts = append(opts, grpc.UnaryInterceptor(net.NewMemberInterceptor(d.checkMember)))
+	}
+	return net.NewTLSGrpcListenerWithConfig(addr, certPath, keyPath, conf, d, apis, opts...)
+}
+
+// tlsClient returns the client contacting the other nodes over TLS, which
+// presents the certificate of the node with WithMutualTLS.
+func (d *Drand) tlsClient() (net.InternalClient, error) {
+	c := d.opts
+	client := net.NewGrpcClientFromCertManager(c.certmanager, c.dialOptions()...)
+	if c.clientCAs != nil {
+		if err := client.SetClientCertificate(c.certPath, c.keyPath, c.keyPass); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// checkMember returns an error unless the certificate is valid for a node of
+// the group, or of the group the distributed key is reshared to, using TLS.
+func (d *Drand) checkMember(cert *x509.Certificate) error {
+	d.state.Lock()
+	groups := []*key.Group{d.group, d.reshareGroup}
+	d.state.Unlock()
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, id := range g.Identities() {
+			if id.IsTLS() && cert.VerifyHostname(d.opts.certmanager.PeerServerName(id)) == nil {
+				return nil
+			}
+		}
+	}
+	return errors.New("drand: client certificate is not valid for any node of the group")
+}
+
+// LoadDrand restores a drand instance as it was running after a DKG instance.
+// It returns an error if the seed or the beacon period of the config differ
+// from the ones of the chain saved, unless WithForceChain is given.
+func LoadDrand(s key.Store, c *Config) (_ *Drand, err error) {
 	d, err := initDrand(s, c)
 	if err != nil {
 		return nil, err
 	}
-	d.group, err = s.LoadGroup()
-	if err != nil {
+	// the node does not serve anything if it fails to load
+	defer func() {
+		if err != nil {
+			d.gateway.Stop()
+			if d.publicListener != nil {
+				d.publicListener.Stop()
+			}
+		}
+	}()
+	if d.group, err = s.LoadGroup(); err != nil {
 		return nil, err
 	}
-	d.share, err = s.LoadShare()
-	if err != nil {
+	if err = checkGroupSafety(d.group, c); err != nil {
 		return nil, err
 	}
-	d.pub, err = s.LoadDistPublic()
-	if err != nil {
+	if d.share, err = s.LoadShare(); err != nil {
+		return nil, err
+	}
+	if d.pub, err = s.LoadDistPublic(); err != nil {
+		return nil, err
+	}
+	if err = d.checkShare(); err != nil {
+		return nil, err
+	}
+	if err = d.checkDistPublic(); err != nil {
 		return nil, err
 	}
-	if err := d.initBeacon(); err != nil {
+	if _, err = d.checkChain(); err != nil {
 		return nil, err
 	}
-	slog.Debugf("drand: loaded and serving at %s", d.priv.Public.Address())
+	if err = d.initBeacon(); err != nil {
+		return nil, err
+	}
+	d.opts.logger.Debug("drand: loaded and serving", "addr", d.priv.Public.Address())
 	return d, nil
 }
 
@@ -118,13 +312,34 @@ func LoadDrand(s key.Store, c *Config) (*Drand, error) {
 // protocol to every other node in the group. It returns nil if the DKG protocol
 // finished successfully or an error otherwise.
 func (d *Drand) StartDKG() error {
+	d.events.emit(&Event{Type: EventDKGStarted, Leader: true, Nodes: d.group.Len(), Threshold: d.group.Threshold})
 	d.dkg.Start()
-	return d.WaitDKG()
+	return d.waitDKG()
 }
 
 // WaitDKG waits messages from the DKG protocol started by a leader or some
-// nodes, and then wait until completion.
+// nodes, and then wait until completion. With WithAutoLeader, the node starts
+// the DKG itself if it is elected leader after a timeout.
 func (d *Drand) WaitDKG() error {
+	d.events.emit(&Event{Type: EventDKGStarted, Nodes: d.group.Len(), Threshold: d.group.Threshold})
+	if d.opts.autoLeader {
+		stop := make(chan bool)
+		defer close(stop)
+		go d.autoLead(stop)
+	}
+	return d.waitDKG()
+}
+
+func (d *Drand) waitDKG() error {
+	if err := d.runDKG(); err != nil {
+		d.events.emit(&Event{Type: EventDKGFailed, Error: err.Error()})
+		return err
+	}
+	d.events.emit(&Event{Type: EventDKGCompleted, Nodes: d.group.Len(), Threshold: d.group.Threshold})
+	return nil
+}
+
+func (d *Drand) runDKG() error {
 	var err error
 	select {
 	case share := <-d.dkg.WaitShare():
@@ -135,10 +350,25 @@ func (d *Drand) WaitDKG() error {
 	if err != nil {
 		return err
 	}
-	d.store.SaveShare(d.share)
-	d.store.SaveDistPublic(d.share.Public())
-	// XXX See if needed to change to qualified group
-	d.store.SaveGroup(d.group)
+	if err := d.checkShare(); err != nil {
+		return err
+	}
+	d.pub = d.share.Public()
+	if err := d.store.SaveShare(d.share); err != nil {
+		return err
+	}
+	if err := d.store.SaveDistPublic(d.pub); err != nil {
+		return err
+	}
+	// the whole group is saved since the indexes of the shares are the indexes
+	// in the whole group
+	if err := d.store.SaveGroup(d.group); err != nil {
+		return err
+	}
+	if err := d.saveTranscript(); err != nil {
+		return err
+	}
+	d.opts.dkgCallbacks(d.pub, d.dkg.QualifiedGroup())
 	return d.initBeacon()
 }
 
@@ -149,8 +379,18 @@ var DefaultSeed = []byte("Truth is like the sun. You can shut it out for a time,
 // signatures are chained:
 // s_i+1 = SIG(s_i || timestamp)
 // For the moment, each resulting signature is stored in a file named
-// beacons/<timestamp>.sig.
-func (d *Drand) BeaconLoop() {
+// beacons/<timestamp>.sig. The loop does not start if the seed, given with
+// WithSeed, or the beacon period differ from the ones saved when the beacon
+// first started, unless WithForceChain is given.
+// It runs until the node is stopped, when it returns nil, or until the context
+// is done, when no new round starts and it returns the context error. The
+// round in progress, if any, is left to finish: Shutdown waits for it. It
+// returns an error right away if the loop can not start.
+func (d *Drand) BeaconLoop(ctx context.Context) error {
+	seed, err := d.beaconSeed()
+	if err != nil {
+		return fmt.Errorf("drand: could not start beacon loop: %s", err)
+	}
 	// heuristic: we catchup when we can retrieve a beacon from the db
 	// if there is an error we quit, if there is no beacon saved yet, we
 	// run the loop as usual.
@@ -162,75 +402,390 @@ func (d *Drand) BeaconLoop() {
 			catchup = false
 		} else {
 			// there's a serious error
-			slog.Printf("drand: could not determine beacon state: %s", err)
-			return
+			return fmt.Errorf("drand: could not determine beacon state: %s", err)
 		}
 	}
 	if catchup {
-		slog.Infof("drand: starting beacon loop in catch-up mode", err, b)
+		d.opts.logger.Info("drand: starting beacon loop in catch-up mode", "last_round", b.Round)
 	} else {
-		slog.Infof("drand: starting beacon loop")
+		d.opts.logger.Info("drand: starting beacon loop")
+	}
+	d.events.emit(&Event{Type: EventBeaconStarted, CatchUp: catchup})
+	done := make(chan bool)
+	go func() {
+		d.beacon.Loop(seed, d.opts.beaconPeriod, catchup)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.beacon.StopLoop()
+		<-done
+		return ctx.Err()
 	}
-	d.beacon.Loop(DefaultSeed, d.opts.beaconPeriod, catchup)
 }
 
-func (d *Drand) Public(context.Context, *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
-	beacon, err := d.beaconStore.Last()
+// Public returns the last beacon generated, or the beacon of the requested
+// round if it is not zero.
+func (d *Drand) Public(c context.Context, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	var b *beacon.Beacon
+	var cached bool
+	var err error
+	if in.GetRound() == 0 {
+		b, cached, err = d.lastPublic()
+		if err != nil {
+			err = d.publicError(err)
+		}
+	} else {
+		b, err = d.publicRound(in.GetRound())
+	}
+	d.reqLogger.log(c, "public", cached, err)
 	if err != nil {
-		return nil, fmt.Errorf("can't retrieve beacon: %s", err)
+		return nil, err
+	}
+	resp := beaconResponse(b)
+	if len(in.GetNonce()) > 0 {
+		if err := attest(d.priv, in.GetNonce(), resp); err != nil {
+			return nil, err
+		}
+	}
+	if in.GetCompressed() {
+		return compressResponse(resp)
+	}
+	return resp, nil
+}
+
+// compressResponse returns a copy of the response with its signature in the
+// compressed encoding. The randomness and the attestation, if any, derive from
+// the uncompressed signature the client recovers.
+func compressResponse(resp *drand.PublicRandResponse) (*drand.PublicRandResponse, error) {
+	compressed := *resp
+	var err error
+	compressed.Signature, err = key.CompressSignature(resp.Signature)
+	return &compressed, err
+}
+
+// PublicStream sends each new beacon to the client as soon as it is generated,
+// until the client disconnects or the node stops, with their randomness
+// compressed if the request asks for it. The other fields of the request are
+// ignored. Beacons are dropped for a client that does not consume them fast
+// enough.
+func (d *Drand) PublicStream(in *drand.PublicRandRequest, stream drand.Randomness_PublicStreamServer) error {
+	ch, ok := d.feed.subscribe()
+	if !ok {
+		return status.Error(codes.Unavailable, "drand: node stopped")
+	}
+	defer d.feed.unsubscribe(ch)
+	for {
+		select {
+		case resp, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if in.GetCompressed() {
+				var err error
+				if resp, err = compressResponse(resp); err != nil {
+					return err
+				}
+			}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
 	}
-	return &drand.PublicRandResponse{
-		Previous:   beacon.PreviousRand,
-		Round:      beacon.Round,
-		Randomness: beacon.Randomness,
-	}, nil
 }
 
+// PrivateRandSize is the size in bytes of a private random value.
+const PrivateRandSize = 32
+
+// MaxPrivateBatch is the maximum number of private random values a client can
+// request at once.
+const MaxPrivateBatch = 1024
+
+// MaxPrivateLength is the maximum number of private random bytes a client can
+// request with PrivateN.
+const MaxPrivateLength = 1024
+
 func (d *Drand) Private(c context.Context, priv *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
+	if d.isReplica() {
+		return nil, errReplica
+	}
+	resp, err := d.private(priv)
+	d.reqLogger.log(c, "private", false, err)
+	return resp, err
+}
+
+// errGatheringRandomness is returned when the source of private randomness
+// fails, the cause being logged but not sent to the client.
+var errGatheringRandomness = newError(ErrInternal, "drand: error gathering randomness")
+
+func (d *Drand) private(priv *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
 	protoPoint := priv.GetRequest().GetEphemeral()
 	point, err := crypto.ProtoToKyberPoint(protoPoint)
 	if err != nil {
-		return nil, err
+		return nil, newError(ErrInvalidRequest, "drand: invalid ephemeral key: %w", err)
 	}
 	groupable, ok := point.(kyber.Groupable)
 	if !ok {
-		return nil, errors.New("point is not on a registered curve")
+		return nil, newError(ErrInvalidRequest, "drand: point is not on a registered curve")
 	}
 	if groupable.Group().String() != key.G2.String() {
-		return nil, errors.New("point is not on the supported curve")
+		return nil, newError(ErrInvalidRequest, "drand: point is not on the supported curve")
+	}
+	hashFn, err := ecies.HashFunc(priv.GetHash())
+	if err != nil {
+		return nil, newError(ErrInvalidRequest, "%w", err)
 	}
-	msg, err := ecies.Decrypt(key.G2, ecies.DefaultHash, d.priv.Key, priv.GetRequest())
+	msg, err := ecies.Decrypt(key.G2, hashFn, d.priv.Key, priv.GetRequest())
 	if err != nil {
-		slog.Debugf("drand: received invalid ECIES private request:", err)
-		return nil, errors.New("invalid ECIES request")
+		d.opts.logger.Debug("drand: received invalid ECIES private request", "err", err)
+		return nil, newError(ErrInvalidRequest, "drand: invalid ECIES request")
 	}
 
 	clientKey := key.G2.Point()
 	if err := clientKey.UnmarshalBinary(msg); err != nil {
-		return nil, errors.New("invalid client key")
+		return nil, newError(ErrInvalidRequest, "drand: invalid client key")
 	}
-	var randomness [32]byte
-	if n, err := rand.Read(randomness[:]); err != nil {
-		return nil, errors.New("error gathering randomness")
-	} else if n != 32 {
-		return nil, errors.New("error gathering randomness")
+	size, err := privateSize(priv)
+	if err != nil {
+		return nil, err
+	}
+	randomness := make([]byte, size)
+	if n, err := io.ReadFull(d.opts.privateRand, randomness); err != nil {
+		d.opts.logger.Error("drand: could not read private randomness", "read", n, "wanted", size, "err", err)
+		return nil, errGatheringRandomness
+	} else if n != len(randomness) {
+		return nil, errGatheringRandomness
 	}
 
-	obj, err := ecies.Encrypt(key.G2, ecies.DefaultHash, clientKey, randomness[:])
+	obj, err := ecies.Encrypt(key.G2, hashFn, clientKey, randomness)
 	return &drand.PrivateRandResponse{obj}, err
 }
 
+// privateSize returns the number of random bytes requested: the length of the
+// request, or count values of PrivateRandSize bytes.
+func privateSize(priv *drand.PrivateRandRequest) (int, error) {
+	length, count := int(priv.GetLength()), int(priv.GetCount())
+	switch {
+	case length > 0 && count > 0:
+		return 0, newError(ErrInvalidRequest, "drand: a private request can not set both a count and a length")
+	case length > MaxPrivateLength:
+		return 0, newError(ErrInvalidRequest, "drand: too many private bytes requested, maximum is %d", MaxPrivateLength)
+	case length > 0:
+		return length, nil
+	case count > MaxPrivateBatch:
+		return 0, newError(ErrInvalidRequest, "drand: too many private values requested, maximum is %d", MaxPrivateBatch)
+	case count == 0:
+		count = 1
+	}
+	return count * PrivateRandSize, nil
+}
+
+// DistKey returns the coefficients of the public polynomial of the group.
+func (d *Drand) DistKey(c context.Context, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	d.state.Lock()
+	defer d.state.Unlock()
+	if d.share == nil {
+		return nil, errDKGNotFinished
+	}
+	resp := &drand.DistKeyResponse{}
+	for _, c := range d.share.Commits {
+		point, err := crypto.KyberToProtoPoint(c)
+		if err != nil {
+			return nil, err
+		}
+		resp.Key = append(resp.Key, point)
+	}
+	return resp, nil
+}
+
+// Home returns the status of the node. It does not need the DKG to be done, so
+// it can be used as a liveness check, and the node reports itself ready once
+// the beacon is set up and it can serve randomness.
+func (d *Drand) Home(c context.Context, in *drand.HomeRequest) (*drand.HomeResponse, error) {
+	d.state.Lock()
+	defer d.state.Unlock()
+	resp := &drand.HomeResponse{
+		Address: d.priv.Public.Address(),
+		DkgDone: d.dkgDone,
+		Ready:   d.beacon != nil || d.isReplica(),
+	}
+	if d.lastBeacon != nil {
+		resp.Round = d.lastBeacon.Round
+	}
+	if d.beacon != nil {
+		stats := d.beacon.Stats()
+		resp.RoundsMeasured = stats.Rounds
+		resp.ThresholdLatencyEma = uint64(stats.EMA / time.Millisecond)
+		resp.ThresholdLatencyMax = uint64(stats.Max / time.Millisecond)
+	}
+	return resp, nil
+}
+
+// Group returns the public identities of the members of the group and the
+// distributed public key, so clients can bootstrap from a single node. It
+// returns an error while the DKG is not finished.
+func (d *Drand) Group(c context.Context, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	d.state.Lock()
+	defer d.state.Unlock()
+	if d.pub == nil {
+		return nil, errDKGNotFinished
+	}
+	distKey, err := crypto.KyberToProtoPoint(d.pub.Key)
+	if err != nil {
+		return nil, err
+	}
+	resp := &drand.GroupResponse{
+		Threshold: uint32(d.group.Threshold),
+		DistKey:   distKey,
+	}
+	for _, n := range d.group.Nodes {
+		k, err := crypto.KyberToProtoPoint(n.Key)
+		if err != nil {
+			return nil, err
+		}
+		resp.Nodes = append(resp.Nodes, &drand.GroupNode{
+			Address: n.Addr,
+			Key:     k,
+			Tls:     n.TLS,
+			Index:   uint32(n.Index),
+		})
+	}
+	return resp, nil
+}
+
+// ChainInfo returns the parameters of the chain a verifier needs, with the last
+// round generated, so clients need a single call to start following the chain.
+// It returns an error while the DKG is not finished.
+func (d *Drand) ChainInfo(c context.Context, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	seed, err := d.checkChain()
+	if err != nil {
+		return nil, err
+	}
+	d.state.Lock()
+	defer d.state.Unlock()
+	if d.pub == nil {
+		return nil, errDKGNotFinished
+	}
+	distKey, err := crypto.KyberToProtoPoint(d.pub.Key)
+	if err != nil {
+		return nil, err
+	}
+	resp := &drand.ChainInfoResponse{
+		Period:  uint64(d.opts.beaconPeriod / time.Millisecond),
+		Aligned: !d.opts.genesis.IsZero(),
+		Seed:    seed,
+		DistKey: distKey,
+	}
+	// without a genesis time, the first round started whenever the leader
+	// started the beacon loop, which is not a parameter of the chain
+	if resp.Aligned {
+		resp.GenesisTime = d.genesis.UnixNano() / int64(time.Millisecond)
+	}
+	if d.lastBeacon != nil {
+		resp.Round = d.lastBeacon.Round
+	}
+	return resp, nil
+}
+
+// LocateRandomness returns the beacon whose randomness is the given one, to
+// find out whether a value presented as drand randomness comes from the chain
+// and from which round. It fails with the NotFound code if the node has no such
+// beacon, for example because it missed the round. The beacons are indexed by
+// their randomness, so the lookup does not go through the whole chain.
+func (d *Drand) LocateRandomness(c context.Context, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	b, err := d.locateRandomness(in.GetRandomness())
+	d.reqLogger.log(c, "locate", false, err)
+	if err != nil {
+		return nil, err
+	}
+	return beaconResponse(b), nil
+}
+
+func (d *Drand) locateRandomness(randomness []byte) (*beacon.Beacon, error) {
+	if len(randomness) != sha256.Size {
+		return nil, newError(ErrInvalidRequest, "drand: randomness must be %d bytes long", sha256.Size)
+	}
+	d.state.Lock()
+	store := d.beaconStore
+	d.state.Unlock()
+	if store == nil {
+		return nil, errDKGNotFinished
+	}
+	b, err := beacon.Locate(store, randomness)
+	switch err {
+	case nil:
+		return b, nil
+	case beacon.ErrNoBeaconSaved:
+		return nil, newError(ErrNoBeacon, "drand: randomness %x not found", randomness)
+	default:
+		return nil, fmt.Errorf("drand: can't retrieve beacon: %w", err)
+	}
+}
+
+// Setup processes a packet of the DKG, or of the resharing, after checking it
+// is signed by a node of the group running the protocol.
 func (d *Drand) Setup(c context.Context, in *dkg_proto.DKGPacket) (*dkg_proto.DKGResponse, error) {
-	if d.isDKGDone() {
+	if d.isReplica() {
+		return nil, errReplica
+	}
+	d.state.Lock()
+	done, resharing, h := d.dkgDone, d.resharing, d.dkg
+	// the packet is buffered under the lock so it can not be missed by the
+	// flush following the set up of the dkg handler
+	buffered := h == nil && d.dkgBuffer.push(c, in)
+	d.state.Unlock()
+	if done && !resharing {
 		return nil, errors.New("drand: dkg finished already")
 	}
-	d.dkg.Process(c, in)
+	if buffered {
+		return &dkg_proto.DKGResponse{}, nil
+	}
+	if h == nil {
+		return nil, errors.New("drand: no dkg running and too many dkg packets buffered")
+	}
+	if err := h.Verify(in); err != nil {
+		return nil, err
+	}
+	h.Process(c, in)
 	return &dkg_proto.DKGResponse{}, nil
 }
 
+// DKGStatus returns the progress of the DKG, or of the resharing, run by the
+// node. A node that loaded its share from disk reports a finished DKG.
+func (d *Drand) DKGStatus(c context.Context, in *dkg_proto.DKGStatusRequest) (*dkg_proto.DKGStatusResponse, error) {
+	d.state.Lock()
+	h, done := d.dkg, d.dkgDone
+	d.state.Unlock()
+	if h == nil {
+		if done {
+			return &dkg_proto.DKGStatusResponse{Phase: string(dkg.PhaseFinished), Progress: 100}, nil
+		}
+		return nil, errors.New("drand: no dkg running")
+	}
+	st := h.Status()
+	resp := &dkg_proto.DKGStatusResponse{
+		Phase:        string(st.Phase),
+		Progress:     uint32(st.Progress),
+		Deals:        uint32(st.Deals),
+		ValidDeals:   uint32(st.ValidDeals),
+		Needed:       uint32(st.Needed),
+		Responses:    uint32(st.Responses),
+		Disqualified: uint32(st.Disqualified),
+		Missing:      st.Missing,
+	}
+	if st.Err != nil {
+		resp.Error = st.Err.Error()
+	}
+	return resp, nil
+}
+
 func (d *Drand) NewBeacon(c context.Context, in *drand.BeaconRequest) (*drand.BeaconResponse, error) {
 	if !d.isDKGDone() {
-		return nil, errors.New("drand: dkg not finished")
+		return nil, newError(ErrDKGNotDone, "drand: dkg not finished")
 	}
 	if d.beacon == nil {
 		panic("that's not ever should happen so I'm panicking right now")
@@ -238,13 +793,250 @@ func (d *Drand) NewBeacon(c context.Context, in *drand.BeaconRequest) (*drand.Be
 	return d.beacon.ProcessBeacon(c, in)
 }
 
+// SyncRound returns the beacon of the requested past round to a node catching
+// up on the rounds it missed.
+func (d *Drand) SyncRound(c context.Context, in *drand.SyncRequest) (*drand.SyncResponse, error) {
+	d.state.Lock()
+	h := d.beacon
+	d.state.Unlock()
+	if h == nil {
+		return nil, errBeaconNotStarted
+	}
+	return h.SyncRound(c, in)
+}
+
+// BeaconState returns the indexes of the nodes whose valid partial signature
+// has been received for a recent round, or for the round in progress if no
+// round is given. It helps finding out which node is silent when rounds do
+// not reach the threshold.
+func (d *Drand) BeaconState(c context.Context, in *drand.BeaconStateRequest) (*drand.BeaconStateResponse, error) {
+	d.state.Lock()
+	h := d.beacon
+	d.state.Unlock()
+	if h == nil {
+		return nil, errBeaconNotStarted
+	}
+	s, ok := h.RoundState(in.GetRound())
+	if !ok {
+		return nil, newError(ErrNoBeacon, "drand: no state for round %d", in.GetRound())
+	}
+	resp := &drand.BeaconStateResponse{
+		Round:     s.Round,
+		Threshold: uint32(s.Threshold),
+		Done:      s.Done,
+	}
+	for _, i := range s.Contributors {
+		resp.Contributors = append(resp.Contributors, uint32(i))
+	}
+	return resp, nil
+}
+
+// MaxSignLength is the maximum size of a message clients can have the group
+// sign.
+const MaxSignLength = 4096
+
+// Sign runs a signing round with the other nodes of the group over the
+// message of the request and returns the recovered signature of the
+// distributed key over beacon.SignMessage of the message. It is disabled
+// unless the node is configured with WithSigning, whose function decides which
+// messages are signed.
+func (d *Drand) Sign(c context.Context, in *drand.SignRequest) (*drand.SignResponse, error) {
+	if d.isReplica() {
+		return nil, errReplica
+	}
+	sig, err := d.sign(c, in.GetMessage())
+	d.reqLogger.log(c, "sign", false, err)
+	if err != nil {
+		return nil, err
+	}
+	return &drand.SignResponse{Signature: sig}, nil
+}
+
+func (d *Drand) sign(c context.Context, msg []byte) ([]byte, error) {
+	if err := d.canSign(c, msg); err != nil {
+		return nil, err
+	}
+	d.state.Lock()
+	h := d.beacon
+	d.state.Unlock()
+	if h == nil {
+		return nil, newError(ErrDKGNotDone, "drand: dkg not finished, nothing to sign with")
+	}
+	sig, err := h.Sign(c, msg)
+	if err != nil {
+		return nil, newError(ErrThreshold, "%s", err)
+	}
+	return sig, nil
+}
+
+// canSign returns an error unless signing is enabled on this node and the
+// message is accepted by the authorization function given to WithSigning.
+func (d *Drand) canSign(c context.Context, msg []byte) error {
+	if !d.opts.signing {
+		return newError(ErrNotSupported, "drand: signing is not enabled on this node")
+	}
+	if len(msg) > MaxSignLength {
+		return newError(ErrInvalidRequest, "drand: message to sign is longer than %d bytes", MaxSignLength)
+	}
+	if d.opts.authorize != nil {
+		if err := d.opts.authorize(c, msg); err != nil {
+			return newError(ErrPermissionDenied, "drand: message not signed: %w", err)
+		}
+	}
+	return nil
+}
+
+// PartialSign replies to the signing round of another node of the group with
+// the partial signature of this node, see Sign. The node must accept to sign
+// the message as for Sign: its partial signature is never given for a message
+// it would refuse to sign itself.
+func (d *Drand) PartialSign(c context.Context, in *drand.PartialSignRequest) (*drand.PartialSignResponse, error) {
+	if err := d.canSign(c, in.GetMessage()); err != nil {
+		return nil, err
+	}
+	d.state.Lock()
+	h := d.beacon
+	d.state.Unlock()
+	if h == nil {
+		return nil, errBeaconNotStarted
+	}
+	return h.ProcessPartialSign(c, in)
+}
+
 func (d *Drand) Stop() {
 	d.state.Lock()
 	defer d.state.Unlock()
+	d.feed.close()
+	if d.stopReplica != nil {
+		// a replica has no beacon handler closing its store
+		close(d.stopReplica)
+		d.stopReplica = nil
+		d.beaconStore.Close()
+	}
 	d.gateway.Stop()
+	if d.publicListener != nil {
+		d.publicListener.Stop()
+	}
 	if d.beacon != nil {
 		d.beacon.Stop()
 	}
+	d.gateway.CloseIdle(0)
+	d.events.emit(&Event{Type: EventStopped})
+}
+
+// CloseIdleConnections closes the connections to the other nodes that have
+// not been used for the given duration, and returns how many have been closed.
+// Otherwise, the connection to a node is kept open and reused by all the calls
+// to this node, and replaced if it fails.
+func (d *Drand) CloseIdleConnections(idle time.Duration) int {
+	return d.gateway.CloseIdle(idle)
+}
+
+// ListenAddress returns the address the node listens on for the internal API,
+// and for the public API as well unless it is served on a separate listener.
+// It is the address actually bound, so with a port zero, e.g. ":0", it tells
+// the port the system chose.
+func (d *Drand) ListenAddress() string {
+	if a, ok := d.gateway.Listener.(net.Addresser); ok {
+		return a.Addr()
+	}
+	return d.listen
+}
+
+// ReloadTLS reloads the certificates of the node from the files given in the
+// configuration, typically after they have been renewed. New connections use
+// the new certificates while the established ones are left untouched. It
+// returns an error if the node does not use TLS.
+func (d *Drand) ReloadTLS() error {
+	if d.opts.insecure {
+		return net.ErrNotTLS
+	}
+	if err := d.gateway.ReloadTLS(d.opts.certPath, d.opts.keyPath); err != nil {
+		return err
+	}
+	if d.publicListener == nil {
+		return nil
+	}
+	certPath, keyPath := d.opts.certPath, d.opts.keyPath
+	if d.opts.publicCert != "" {
+		certPath, keyPath = d.opts.publicCert, d.opts.publicKey
+	}
+	return net.Gateway{Listener: d.publicListener}.ReloadTLS(certPath, keyPath)
+}
+
+// Reload loads the group, the share and the distributed public key from the
+// store again, e.g. once they have been replaced by a resharing run by another
+// process or restored from a backup, and swaps them into the running beacon:
+// the rounds starting from then on use them, without any gap in the chain.
+// They are checked as when the node is loaded, and the distributed key must
+// remain the same for the chain to keep verifying. Nothing changes if any
+// check fails.
+func (d *Drand) Reload() error {
+	if !d.canReload() {
+		return errNothingToReload
+	}
+	group, err := d.store.LoadGroup()
+	if err != nil {
+		return err
+	}
+	if err := checkGroupSafety(group, d.opts); err != nil {
+		return err
+	}
+	share, err := d.store.LoadShare()
+	if err != nil {
+		return err
+	}
+	pub, err := d.store.LoadDistPublic()
+	if err != nil {
+		return err
+	}
+	if err := VerifyShare(d.priv.Public, group, share, pub); err != nil {
+		return err
+	}
+	d.state.Lock()
+	defer d.state.Unlock()
+	if d.beacon == nil || d.resharing {
+		return errNothingToReload
+	}
+	if !pub.Key.Equal(d.pub.Key) {
+		return errors.New("drand: the distributed public key differs from the one of the chain")
+	}
+	if err := d.beacon.SetShare(share, group); err != nil {
+		return err
+	}
+	d.group, d.share, d.pub = group, share, pub
+	d.opts.logger.Info("drand: share reloaded", "nodes", group.Len(), "threshold", group.Threshold)
+	d.events.emit(&Event{Type: EventReloaded, Nodes: group.Len(), Threshold: group.Threshold})
+	return nil
+}
+
+var errNothingToReload = newError(ErrDKGNotDone, "drand: nothing to reload before the dkg or the resharing finishes")
+
+// errBeaconNotStarted is returned by the calls of the other nodes before this
+// node runs the beacon.
+var errBeaconNotStarted = newError(ErrDKGNotDone, "drand: beacon not started")
+
+// canReload returns true if the beacon runs with a share Reload can replace.
+func (d *Drand) canReload() bool {
+	d.state.Lock()
+	defer d.state.Unlock()
+	return d.beacon != nil && !d.resharing
+}
+
+// Shutdown stops the node gracefully: the beacon stops starting new rounds and
+// the round in progress, if any, has until the context is done to finish and be
+// saved. The node is then stopped as with Stop. It returns the context error if
+// the round in progress had to be aborted.
+func (d *Drand) Shutdown(ctx context.Context) error {
+	d.state.Lock()
+	b := d.beacon
+	d.state.Unlock()
+	var err error
+	if b != nil {
+		err = b.Shutdown(ctx)
+	}
+	d.Stop()
+	return err
 }
 
 // isDKGDone returns true if the DKG protocol has already been executed. That
@@ -255,22 +1047,171 @@ func (d *Drand) isDKGDone() bool {
 	return d.dkgDone
 }
 
+// VerifyShare checks that the share is the share of the node of the given
+// identity in the group and that it is consistent with the group and the
+// distributed public key, as done when a node loads its share. It is meant to
+// check a share before restoring it from a backup.
+func VerifyShare(id *key.Identity, group *key.Group, s *key.Share, pub *key.DistPublic) error {
+	if err := checkShareIndex(id, group, s); err != nil {
+		return err
+	}
+	return checkShareCommits(group, s, pub)
+}
+
+// checkShare verifies that the indexes of the group are distinct and
+// contiguous and that the index of the share is the index of this node in the
+// group.
+func (d *Drand) checkShare() error {
+	return checkShareIndex(d.priv.Public, d.group, d.share)
+}
+
+func checkShareIndex(id *key.Identity, group *key.Group, s *key.Share) error {
+	if err := group.CheckIndexes(); err != nil {
+		return err
+	}
+	idx, ok := group.Index(id)
+	if !ok {
+		return errors.New("drand: own public key not found in the group")
+	}
+	if s.Share.I != idx {
+		return fmt.Errorf("drand: share index %d differs from own index %d in the group", s.Share.I, idx)
+	}
+	return nil
+}
+
+// checkDistPublic checks that the share is consistent with the group and the
+// distributed public key: it must have as many commitments as the group
+// threshold, its first commitment must be the distributed public key, and its
+// private value must match the commitments at its index. A share copied from
+// another group fails these checks instead of producing invalid partial
+// signatures.
+func (d *Drand) checkDistPublic() error {
+	return checkShareCommits(d.group, d.share, d.pub)
+}
+
+func checkShareCommits(group *key.Group, s *key.Share, pub *key.DistPublic) error {
+	derived, err := DeriveDistPublic(group, s)
+	if err != nil {
+		return err
+	}
+	if !derived.Key.Equal(pub.Key) {
+		return errors.New("drand: distributed public key differs from the one of the share: the share or the distributed public key belongs to another group")
+	}
+	return nil
+}
+
+// DeriveDistPublic recomputes the distributed public key from the public
+// coefficients stored with the share, after checking that there are as many
+// as the group threshold and that the private value of the share matches them.
+// It lets an operator check the stored distributed public key offline.
+func DeriveDistPublic(group *key.Group, s *key.Share) (*key.DistPublic, error) {
+	if len(s.Commits) != group.Threshold {
+		return nil, fmt.Errorf("drand: share has %d commitments but the group threshold is %d: the share belongs to another group", len(s.Commits), group.Threshold)
+	}
+	pubPoly := share.NewPubPoly(key.G2, key.G2.Point().Base(), s.Commits)
+	expected := pubPoly.Eval(s.Share.I).V
+	if !expected.Equal(key.G2.Point().Mul(s.Share.V, nil)) {
+		return nil, errors.New("drand: private share does not match its commitments")
+	}
+	return &key.DistPublic{Key: pubPoly.Commit()}, nil
+}
+
 func (d *Drand) initBeacon() error {
 	d.state.Lock()
 	defer d.state.Unlock()
 	d.dkgDone = true
-	fs.CreateSecureFolder(d.opts.DBFolder())
-	store, err := beacon.NewBoltStore(d.opts.dbFolder, d.opts.boltOpts)
+	// the leader starts the beacon loop, hence the first round, as soon as
+	// the DKG is finished, unless rounds are aligned on a genesis time
+	if !d.opts.genesis.IsZero() {
+		d.genesis = d.opts.genesis.Add(d.opts.beaconPeriod)
+	} else if d.genesis.IsZero() {
+		d.genesis = d.opts.clock.Now()
+	}
+	store, err := d.opts.newBeaconStore()
 	if err != nil {
 		return err
 	}
 	d.beaconStore = beacon.NewCallbackStore(store, d.beaconCallback)
-	d.beacon = beacon.NewHandler(d.gateway.InternalClient, d.priv, d.share, d.group, d.beaconStore)
+	if d.beacon, err = beacon.NewHandler(d.gateway.InternalClient, d.priv, d.share, d.group, d.beaconStore); err != nil {
+		store.Close()
+		return err
+	}
+	d.beacon.SetLogger(d.opts.logger)
+	d.beacon.SetMaxCatchupRounds(d.opts.maxCatchup)
+	d.beacon.SetCatchupLimit(d.opts.catchupLimit)
+	d.beacon.SetFanoutDelay(d.opts.fanoutDelay)
+	d.beacon.SetBroadcastJitter(d.opts.jitter)
+	d.beacon.SetRoundTimeout(d.opts.roundTimeout)
+	d.beacon.SetRoundPolicy(d.opts.roundPolicy)
+	d.beacon.SetMessage(d.opts.beaconMessage())
+	d.beacon.SetAggregator(d.opts.aggregator)
+	d.beacon.SetClock(d.opts.clock)
+	if !d.opts.genesis.IsZero() {
+		d.beacon.SetGenesis(d.opts.genesis)
+	}
 	return nil
 }
 
 func (d *Drand) beaconCallback(b *beacon.Beacon) {
+	d.state.Lock()
+	var skipped *Event
+	if d.lastBeacon != nil && b.Round > d.lastBeacon.Round+1 {
+		skipped = &Event{Type: EventRoundSkipped, Round: d.lastBeacon.Round + 1, Skipped: b.Round - d.lastBeacon.Round - 1}
+	}
+	if d.lastBeacon == nil || b.Round > d.lastBeacon.Round {
+		d.lastBeacon = b
+	}
+	d.state.Unlock()
+	if skipped != nil {
+		d.events.emit(skipped)
+	}
+	d.events.emit(roundProduced(b.Round, b.Randomness()))
+	d.feed.publish(beaconResponse(b))
 	d.opts.callbacks(b)
+	if len(d.opts.roundCbs) > 0 {
+		d.roundCallbacks(b)
+	}
+}
+
+// lastPublic returns the last beacon generated. It is served from memory if a
+// beacon has been saved since this node started, or from the beacon store
+// otherwise. The boolean indicates whether the beacon comes from memory.
+func (d *Drand) lastPublic() (*beacon.Beacon, bool, error) {
+	d.state.Lock()
+	defer d.state.Unlock()
+	if d.lastBeacon != nil {
+		return d.lastBeacon, true, nil
+	}
+	if d.beaconStore == nil {
+		return nil, false, errDKGNotFinished
+	}
+	b, err := d.beaconStore.Last()
+	return b, false, err
+}
+
+// publicRound returns the beacon of the given round from the beacon store. A
+// round missed by this node is fetched from the other nodes if the beacon is
+// running.
+func (d *Drand) publicRound(round uint64) (*beacon.Beacon, error) {
+	d.state.Lock()
+	store, handler := d.beaconStore, d.beacon
+	d.state.Unlock()
+	if store == nil {
+		return nil, errDKGNotFinished
+	}
+	get := store.Get
+	if handler != nil {
+		get = handler.Lookup
+	}
+	b, err := get(round)
+	switch err {
+	case nil:
+		return b, nil
+	case beacon.ErrNoBeaconSaved:
+		return nil, newError(ErrNoBeacon, "drand: round %d not found", round)
+	default:
+		return nil, fmt.Errorf("drand: can't retrieve beacon: %w", err)
+	}
 }
 
 // little trick to be able to capture when drand is using the DKG methods,
@@ -2,8 +2,12 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	gonet "net"
 	"os"
 	"path"
 	"sync"
@@ -11,13 +15,20 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/dkg"
 	"github.com/dedis/drand/key"
 	"github.com/dedis/drand/net"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
 	"github.com/dedis/drand/protobuf/drand"
 	"github.com/dedis/drand/test"
+	"github.com/dedis/kyber/share"
 	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/sign/tbls"
+	"github.com/dedis/kyber/util/random"
 	"github.com/kabukky/httpscerts"
 	"github.com/nikkolasg/slog"
 	"github.com/stretchr/testify/require"
@@ -62,6 +73,12 @@ func TestDrandDKG(t *testing.T) {
 	require.NotNil(t, public)
 	_, err = root.store.LoadShare()
 	require.Nil(t, err)
+	// and the transcript of the DKG, which verifies
+	transcript, err := LoadTranscript(path.Join(root.opts.DBFolder(), TranscriptFileName))
+	require.NoError(t, err)
+	audited, err := dkg.VerifyTranscript(root.group, transcript)
+	require.NoError(t, err)
+	require.True(t, public.Key.Equal(audited.Key))
 
 	// make the last node fail
 	drands[n-1].Stop()
@@ -81,9 +98,9 @@ func TestDrandDKG(t *testing.T) {
 	launchDrand := func(i int) {
 		myCb := func(b *beacon.Beacon) {
 			msg := beacon.Message(b.PreviousRand, b.Round)
-			err := bls.Verify(key.Pairing, public.Key, msg, b.Randomness)
+			err := bls.Verify(key.Pairing, public.Key, msg, b.Signature)
 			if err != nil {
-				fmt.Printf("Beacon error callback: %s\n", b.Randomness)
+				fmt.Printf("Beacon error callback: %s\n", b.Signature)
 			}
 			require.NoError(t, err)
 			l.Lock()
@@ -93,7 +110,7 @@ func TestDrandDKG(t *testing.T) {
 		}
 		drands[i].opts.beaconCbs = append(drands[i].opts.beaconCbs, myCb)
 		//fmt.Printf(" --- Launch drand %s\n", drands[i].priv.Public.Address())
-		go drands[i].BeaconLoop()
+		go drands[i].BeaconLoop(context.Background())
 	}
 
 	for i := 0; i < n-1; i++ {
@@ -142,7 +159,7 @@ func TestDrandDKG(t *testing.T) {
 			for _, beacons := range genBeacons {
 				original := beacons[0]
 				for _, beacon := range beacons[1:] {
-					if !bytes.Equal(beacon.Randomness, original.Randomness) {
+					if !bytes.Equal(beacon.Signature, original.Signature) {
 						// randomness is not equal we return false
 						l.Unlock()
 						doneCh <- false
@@ -185,7 +202,7 @@ func TestDrandDKG(t *testing.T) {
 	require.NoError(t, drands[n-1].beaconStore.Put(&beacon.Beacon{
 		Round:        56,
 		PreviousRand: []byte{0x01, 0x02, 0x03},
-		Randomness:   []byte("best randomness ever"),
+		Signature:    []byte("best randomness ever"),
 	}))
 	// ugly trick to not get the callback triggered because it gets triggered in
 	// a goroutine, and the callback are set before by launchDrand.
@@ -196,9 +213,544 @@ func TestDrandDKG(t *testing.T) {
 	checkSuccess()
 
 	client := net.NewGrpcClientFromCertManager(root.opts.certmanager, root.opts.grpcOpts...)
-	resp, err := client.Public(test.NewTLSPeer(root.priv.Public.Addr), &drand.PublicRandRequest{})
+	resp, err := client.Public(context.Background(), test.NewTLSPeer(root.priv.Public.Addr), &drand.PublicRandRequest{})
 	require.NoError(t, err)
 	require.NotNil(t, resp)
+
+	fresh, err := NewGrpcClientFromCert(root.opts.certmanager).LastPublicFresh(root.priv.Public, public)
+	require.NoError(t, err)
+	require.True(t, fresh.GetRound() >= resp.GetRound())
+
+	past, err := NewGrpcClientFromCert(root.opts.certmanager).PublicRound(root.priv.Public.Addr, public, resp.GetRound(), true)
+	require.NoError(t, err)
+	require.Equal(t, resp.GetRandomness(), past.GetRandomness())
+	require.Equal(t, beacon.RandomnessFromSignature(past.GetSignature()), past.GetRandomness())
+	past, err = NewRESTClientFromCert(root.opts.certmanager).PublicRound(root.priv.Public.Addr, public, resp.GetRound(), true)
+	require.NoError(t, err)
+	require.Equal(t, resp.GetRandomness(), past.GetRandomness())
+	require.Equal(t, resp.GetSignature(), past.GetSignature())
+
+	// the signature can be asked in its compressed encoding, which verifies
+	compressed, err := client.Public(context.Background(), test.NewTLSPeer(root.priv.Public.Addr), &drand.PublicRandRequest{Round: resp.GetRound(), Compressed: true})
+	require.NoError(t, err)
+	require.Len(t, compressed.GetSignature(), key.CompressedSignatureSize)
+	require.NoError(t, VerifyBeacon(public.Key, compressed))
+	_, err = NewGrpcClientFromCert(root.opts.certmanager).PublicRound(root.priv.Public.Addr, public, resp.GetRound()+1000, true)
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// the round of a randomness is found by the randomness alone
+	for _, c := range []*Client{NewGrpcClientFromCert(root.opts.certmanager), NewRESTClientFromCert(root.opts.certmanager)} {
+		located, err := c.LocateRandomness(root.priv.Public.Addr, public, resp.GetRandomness(), true)
+		require.NoError(t, err)
+		require.Equal(t, resp.GetRound(), located.GetRound())
+		require.Equal(t, resp.GetSignature(), located.GetSignature())
+	}
+	c := NewGrpcClientFromCert(root.opts.certmanager)
+	_, err = c.LocateRandomness(root.priv.Public.Addr, public, resp.GetSignature()[:32], true)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = c.LocateRandomness(root.priv.Public.Addr, public, resp.GetSignature(), true)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	for _, c := range []*Client{NewGrpcClientFromCert(root.opts.certmanager), NewRESTClientFromCert(root.opts.certmanager)} {
+		poly, err := c.PublicPoly(root.priv.Public.Addr, public, true)
+		require.NoError(t, err)
+		for _, d := range drands[:n-1] {
+			pubShare := key.G2.Point().Mul(d.share.Share.V, nil)
+			require.True(t, pubShare.Equal(poly.Eval(d.share.Share.I).V))
+		}
+	}
+}
+
+func TestDrandMaxMessageSize(t *testing.T) {
+	drands, dir := BatchNewDrand(2, true, WithInMemory(), WithMaxMessageSize(8))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	addr := drands[0].priv.Public.Address()
+
+	// the node does not send a response larger than its maximum size
+	_, err := NewGrpcClient().Home(addr, false)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	// nor do its clients receive it
+	big, dir2 := BatchNewDrand(2, true, WithInMemory())
+	defer CloseAllDrands(big)
+	defer os.RemoveAll(dir2)
+	addr = big[0].priv.Public.Address()
+	_, err = NewGrpcClient().Home(addr, false)
+	require.NoError(t, err)
+	_, err = NewClientFromConfig(drands[0].opts).Home(addr, false)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestDrandAutoLeader(t *testing.T) {
+	n := 4
+	clock := test.NewFakeClock(time.Unix(1500000000, 0))
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithAutoLeader(), WithClock(clock))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	// no node is the leader: the first one starts the dkg once elected
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for _, d := range drands {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	clock.BlockUntil(n)
+	clock.Advance(drands[0].opts.electTimeout)
+	wg.Wait()
+	for _, d := range drands[1:] {
+		require.True(t, d.pub.Key.Equal(drands[0].pub.Key))
+	}
+
+	// the nodes that do not reply are not elected
+	group := drands[0].group
+	byIndex := make([]*Drand, n)
+	for _, d := range drands {
+		i, ok := group.Index(d.priv.Public)
+		require.True(t, ok)
+		byIndex[i] = d
+	}
+	require.Equal(t, group.Public(0), byIndex[2].electLeader(group))
+	byIndex[0].gateway.Stop()
+	require.Equal(t, group.Public(1), byIndex[2].electLeader(group))
+	require.Equal(t, group.Public(1), byIndex[1].electLeader(group))
+}
+
+func TestDrandBeaconLoopContext(t *testing.T) {
+	n := 3
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(200*time.Millisecond))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+
+	produced := make(chan uint64, 1)
+	drands[0].opts.beaconCbs = append(drands[0].opts.beaconCbs, func(b *beacon.Beacon) {
+		select {
+		case produced <- b.Round:
+		default:
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, n)
+	for _, d := range drands {
+		go func(d *Drand) { errs <- d.BeaconLoop(ctx) }(d)
+	}
+	select {
+	case <-produced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no beacon produced")
+	}
+
+	// the loops return the context error once it is cancelled
+	cancel()
+	for range drands {
+		select {
+		case err := <-errs:
+			require.Equal(t, context.Canceled, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("beacon loop not stopped")
+		}
+	}
+	// and the beacons are still served
+	_, err := drands[0].Public(context.Background(), &drand.PublicRandRequest{})
+	require.NoError(t, err)
+}
+
+func TestDrandRoundCallback(t *testing.T) {
+	n := 3
+	rounds := make(chan *Round, 10*n)
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(200*time.Millisecond),
+		WithRoundCallback(func(*Round) { panic("misbehaving callback") }),
+		WithRoundCallback(func(r *Round) { rounds <- r }))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+	for _, d := range drands {
+		go d.BeaconLoop(context.Background())
+	}
+
+	// the panic of the first callback does not prevent the second one
+	for i := 0; i < n; i++ {
+		select {
+		case r := <-rounds:
+			require.Equal(t, uint64(1), r.Round)
+			require.False(t, r.CatchUp)
+			require.False(t, r.Time.IsZero())
+			require.True(t, r.Public.Key.Equal(drands[0].pub.Key))
+			require.Equal(t, n, r.Group.Len())
+		case <-time.After(5 * time.Second):
+			t.Fatal("round callback not called")
+		}
+	}
+}
+
+func TestDrandReload(t *testing.T) {
+	n := 3
+	drands, dir := BatchNewDrand(n, true, WithInMemory())
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	d := drands[0]
+	// nothing to reload before the dkg
+	require.Error(t, d.Reload())
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, d.StartDKG())
+	wg.Wait()
+	require.NoError(t, d.Reload())
+
+	// a share not matching its commitments is refused
+	pub, current := d.pub, d.share
+	sh := *d.share
+	sh.Share = &share.PriShare{I: sh.Share.I, V: key.G2.Scalar().Pick(random.New())}
+	require.NoError(t, d.store.SaveShare(&sh))
+	require.Error(t, d.Reload())
+	require.Equal(t, pub, d.pub)
+	require.Equal(t, current, d.share)
+}
+
+// noShareStore is a key store failing to load the share.
+type noShareStore struct {
+	key.Store
+}
+
+func (s *noShareStore) LoadShare() (*key.Share, error) {
+	return nil, errors.New("no share")
+}
+
+func TestLoadDrandFailure(t *testing.T) {
+	privs, group := test.BatchIdentities(3)
+	s := test.NewKeyStore()
+	require.NoError(t, s.SaveKeyPair(privs[0]))
+	require.NoError(t, s.SaveGroup(group))
+	dir, err := ioutil.TempDir("", "drand")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = LoadDrand(&noShareStore{s}, NewConfig(WithInsecure(), WithDbFolder(dir)))
+	require.Error(t, err)
+	// the listener of the node is stopped
+	l, err := gonet.Listen("tcp", privs[0].Public.Address())
+	require.NoError(t, err)
+	l.Close()
+}
+
+func TestDrandSign(t *testing.T) {
+	n := 3
+	authorize := func(ctx context.Context, msg []byte) error {
+		if string(msg) == "forbidden" {
+			return errors.New("not signing that")
+		}
+		return nil
+	}
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithSigning(authorize))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	root := drands[0]
+	_, err := root.Sign(context.Background(), &drand.SignRequest{Message: []byte("hello")})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, root.StartDKG())
+	wg.Wait()
+
+	msg := []byte("hello")
+	for _, c := range []*Client{NewGrpcClientFromCert(root.opts.certmanager), NewRESTClientFromCert(root.opts.certmanager)} {
+		sig, err := c.Sign(root.priv.Public.Addr, root.pub, msg, false)
+		require.NoError(t, err)
+		require.NoError(t, bls.Verify(key.Pairing, root.pub.Key, beacon.SignMessage(msg), sig))
+	}
+	_, err = root.Sign(context.Background(), &drand.SignRequest{Message: []byte("forbidden")})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// without WithSigning the node refuses to sign
+	root.opts.signing = false
+	_, err = root.Sign(context.Background(), &drand.SignRequest{Message: msg})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	// the other nodes only sign for a member of the group
+	_, err = drands[1].PartialSign(context.Background(), &drand.PartialSignRequest{Message: msg, PartialSig: []byte("invalid")})
+	require.Error(t, err)
+
+	// and only the messages they would sign themselves
+	partial := func(msg []byte) *drand.PartialSignRequest {
+		sig, err := tbls.Sign(key.Pairing, root.share.Share, beacon.SignMessage(msg))
+		require.NoError(t, err)
+		return &drand.PartialSignRequest{Message: msg, PartialSig: sig}
+	}
+	_, err = drands[1].PartialSign(context.Background(), partial(msg))
+	require.NoError(t, err)
+	_, err = drands[1].PartialSign(context.Background(), partial([]byte("forbidden")))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	drands[1].opts.signing = false
+	_, err = drands[1].PartialSign(context.Background(), partial(msg))
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestDrandInMemory(t *testing.T) {
+	n := 4
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(500*time.Millisecond))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	addr := drands[0].priv.Public.Address()
+	home, err := NewGrpcClient().Home(addr, false)
+	require.NoError(t, err)
+	require.Equal(t, addr, home.GetAddress())
+	require.False(t, home.GetDkgDone())
+	require.False(t, home.GetReady())
+	_, err = NewGrpcClient().ChainInfo(addr, false)
+	require.Error(t, err)
+	dkgStatus, err := net.NewGrpcClient().DKGStatus(drands[0].priv.Public, &dkg_proto.DKGStatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, string(dkg.PhaseDeal), dkgStatus.GetPhase())
+	require.Len(t, dkgStatus.GetMissing(), n-1)
+
+	var dkgPub *key.DistPublic
+	var qual *key.Group
+	WithDKGCallback(func(pub *key.DistPublic, group *key.Group) {
+		dkgPub, qual = pub, group
+	})(drands[0].opts)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+	require.NotNil(t, dkgPub)
+	require.True(t, dkgPub.Key.Equal(drands[0].pub.Key))
+	require.Equal(t, n, qual.Len())
+	dkgStatus, err = drands[0].DKGStatus(context.Background(), &dkg_proto.DKGStatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, string(dkg.PhaseFinished), dkgStatus.GetPhase())
+	require.Equal(t, uint32(100), dkgStatus.GetProgress())
+	require.Empty(t, dkgStatus.GetError())
+
+	produced := make(chan uint64, 1)
+	drands[0].opts.beaconCbs = append(drands[0].opts.beaconCbs, func(b *beacon.Beacon) {
+		select {
+		case produced <- b.Round:
+		default:
+		}
+	})
+	public, err := drands[0].store.LoadDistPublic()
+	require.NoError(t, err)
+	follow, err := NewGrpcClient().Follow(drands[0].priv.Public.Address(), public, false)
+	require.NoError(t, err)
+	go drands[0].BeaconLoop(context.Background())
+	select {
+	case round := <-produced:
+		state, err := drands[0].BeaconState(context.Background(), &drand.BeaconStateRequest{Round: round})
+		require.NoError(t, err)
+		require.Equal(t, round, state.GetRound())
+		require.True(t, len(state.GetContributors()) >= drands[0].group.Threshold)
+		own, _ := drands[0].group.Index(drands[0].priv.Public)
+		require.Equal(t, uint32(own), state.GetContributors()[0])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no beacon produced")
+	}
+	select {
+	case resp := <-follow:
+		require.NotNil(t, resp)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no beacon followed")
+	}
+
+	home, err = NewRESTClient().Home(addr, false)
+	require.NoError(t, err)
+	require.True(t, home.GetDkgDone())
+	require.True(t, home.GetReady())
+	require.NotZero(t, home.GetRound())
+	require.True(t, home.GetThresholdLatencyMax() >= home.GetThresholdLatencyEma())
+
+	for _, c := range []*Client{NewGrpcClient(), NewRESTClient()} {
+		group, dist, err := c.FetchGroup(addr, false)
+		require.NoError(t, err)
+		require.True(t, dist.Key.Equal(public.Key))
+		require.Equal(t, drands[0].group.Threshold, group.Threshold)
+		require.Equal(t, drands[0].group.Len(), group.Len())
+		for _, n := range drands[0].group.Nodes {
+			idx, ok := group.Index(n.Identity)
+			require.True(t, ok)
+			require.Equal(t, n.Index, idx)
+		}
+		info, err := c.ChainInfo(addr, false)
+		require.NoError(t, err)
+		require.True(t, info.Public.Key.Equal(public.Key))
+		require.Equal(t, 500*time.Millisecond, info.Period)
+		require.Equal(t, DefaultSeed, info.Seed)
+		require.False(t, info.Aligned)
+		require.NotZero(t, info.Round)
+		require.True(t, info.GenesisTime.IsZero())
+	}
+
+	for _, d := range drands {
+		_, err := os.Stat(d.opts.DBFolder())
+		require.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestDrandFakeClock(t *testing.T) {
+	n := 3
+	period := time.Minute
+	clock := test.NewFakeClock(time.Unix(1500000000, 0))
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(period), WithClock(clock))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+	require.Equal(t, clock.Now(), drands[0].genesis)
+
+	produced := make(chan *beacon.Beacon, n)
+	for _, d := range drands {
+		d.opts.beaconCbs = append(d.opts.beaconCbs, func(b *beacon.Beacon) {
+			produced <- b
+		})
+		go d.BeaconLoop(context.Background())
+	}
+	for round := uint64(1); round <= 5; round++ {
+		for i := 0; i < n; i++ {
+			select {
+			case b := <-produced:
+				require.Equal(t, round, b.Round)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("round %d not produced", round)
+			}
+		}
+		// every node waits for the next tick before the clock moves
+		clock.BlockUntil(n)
+		clock.Advance(period)
+	}
+
+	addr := drands[0].priv.Public.Address()
+	chain, err := NewGrpcClient().PublicVerifiedChain(addr, drands[0].pub, 2, 5, false)
+	require.NoError(t, err)
+	require.Len(t, chain, 4)
+	for i, b := range chain {
+		require.Equal(t, uint64(i+2), b.GetRound())
+	}
+	_, err = NewGrpcClient().PublicVerifiedChain(addr, drands[0].pub, 5, 2, false)
+	require.Error(t, err)
+	// a node hiding a round can not hide that the next one does not link
+	hiding := NewClient(&hidingClient{ExternalClient: net.NewGrpcClient(), round: 3})
+	_, err = hiding.PublicVerifiedChain(addr, drands[0].pub, 2, 5, false)
+	chainErr, ok := err.(*ChainError)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, uint64(4), chainErr.Round)
+}
+
+// hidingClient answers that it does not have the given round.
+type hidingClient struct {
+	net.ExternalClient
+	round uint64
+}
+
+func (h *hidingClient) Public(ctx context.Context, p net.Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	if in.GetRound() == h.round {
+		return nil, status.Error(codes.NotFound, "round not found")
+	}
+	return h.ExternalClient.Public(ctx, p, in)
+}
+
+func TestDrandGenesisTime(t *testing.T) {
+	n := 3
+	period := time.Minute
+	genesis := time.Unix(1500000000, 0)
+	// the nodes start in the middle of round 3
+	clock := test.NewFakeClock(genesis.Add(3*period + period/2))
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(period), WithClock(clock), WithGenesisTime(genesis))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+	require.Equal(t, genesis.Add(period), drands[0].genesis)
+	info, err := drands[0].ChainInfo(context.Background(), &drand.ChainInfoRequest{})
+	require.NoError(t, err)
+	require.True(t, info.GetAligned())
+	require.Equal(t, genesis.Add(period).UnixNano()/int64(time.Millisecond), info.GetGenesisTime())
+
+	produced := make(chan *beacon.Beacon, n)
+	for _, d := range drands {
+		d.opts.beaconCbs = append(d.opts.beaconCbs, func(b *beacon.Beacon) {
+			produced <- b
+		})
+		go d.BeaconLoop(context.Background())
+	}
+	expect := func(round uint64) {
+		for i := 0; i < n; i++ {
+			select {
+			case b := <-produced:
+				require.Equal(t, round, b.Round)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("round %d not produced", round)
+			}
+		}
+	}
+	expect(3)
+	clock.BlockUntil(n)
+	clock.Advance(period / 2)
+	expect(4)
+	// rounds whose time has passed are skipped
+	clock.BlockUntil(n)
+	clock.Advance(2 * period)
+	expect(6)
 }
 
 func BatchNewDrand(n int, insecure bool, opts ...ConfigOption) ([]*Drand, string) {
@@ -225,7 +777,7 @@ func BatchNewDrand(n int, insecure bool, opts ...ConfigOption) ([]*Drand, string
 			keyPath := path.Join(dir, fmt.Sprintf("server-%d.key", i))
 			if httpscerts.Check(certPath, keyPath) != nil {
 				fmt.Println("generating on the fly")
-				if httpscerts.Generate(certPath, keyPath, privs[i].Public.Address()) != nil {
+				if httpscerts.Generate(certPath, keyPath, test.Host(privs[i].Public.Address())) != nil {
 					panic(err)
 				}
 			}
@@ -260,3 +812,220 @@ func CloseAllDrands(drands []*Drand) {
 		os.RemoveAll(drands[i].opts.dbFolder)
 	}
 }
+
+func TestDrandSeparateListeners(t *testing.T) {
+	privs, group := test.BatchIdentities(4)
+	s := test.NewKeyStore()
+	require.NoError(t, s.SaveKeyPair(privs[0]))
+	public := test.Addresses(1)[0]
+	d, err := NewDrand(s, group, NewConfig(WithInsecure(), WithInMemory(), WithPublicListen(public)))
+	require.NoError(t, err)
+	defer d.Stop()
+
+	client := NewGrpcClient()
+	home, err := client.Home(public, false)
+	require.NoError(t, err)
+	require.Equal(t, privs[0].Public.Address(), home.GetAddress())
+
+	// the internal listener does not serve the public API
+	_, err = client.Home(privs[0].Public.Address(), false)
+	require.Error(t, err)
+}
+
+func TestDrandListenAddress(t *testing.T) {
+	privs, group := test.BatchIdentities(4)
+	s := test.NewKeyStore()
+	require.NoError(t, s.SaveKeyPair(privs[0]))
+	_, err := NewDrand(s, group, NewConfig(WithInsecure(), WithInMemory(), WithListenAddress("127.0.0.1")))
+	require.Error(t, err)
+	_, err = NewDrand(s, group, NewConfig(WithInsecure(), WithInMemory(), WithPublicListen("127.0.0.1:port")))
+	require.Error(t, err)
+
+	d, err := NewDrand(s, group, NewConfig(WithInsecure(), WithInMemory(), WithListenAddress("127.0.0.1:0")))
+	require.NoError(t, err)
+	defer d.Stop()
+	addr := d.ListenAddress()
+	require.NotEqual(t, "127.0.0.1:0", addr)
+	home, err := NewGrpcClient().Home(addr, false)
+	require.NoError(t, err)
+	require.Equal(t, privs[0].Public.Address(), home.GetAddress())
+}
+
+func TestDrandUnixSocket(t *testing.T) {
+	n := 4
+	privs, group := test.BatchIdentities(n)
+	dir, err := ioutil.TempDir("", "drand-unix")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for i, p := range privs {
+		p.Public.Addr = net.UnixScheme + path.Join(dir, fmt.Sprintf("node-%d.sock", i))
+	}
+	public := test.Addresses(1)[0]
+	drands := make([]*Drand, n)
+	for i := range drands {
+		s := test.NewKeyStore()
+		require.NoError(t, s.SaveKeyPair(privs[i]))
+		opts := []ConfigOption{WithInsecure(), WithInMemory()}
+		if i == 0 {
+			// the public API stays on TCP
+			opts = append(opts, WithPublicListen(public))
+		}
+		drands[i], err = NewDrand(s, group, NewConfig(opts...))
+		require.NoError(t, err)
+	}
+	defer CloseAllDrands(drands)
+
+	// the nodes run the DKG over their Unix sockets
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+
+	home, err := NewGrpcClient().Home(public, false)
+	require.NoError(t, err)
+	require.True(t, home.GetDkgDone())
+	_, err = NewGrpcClient().Home(privs[1].Public.Address(), false)
+	require.NoError(t, err)
+}
+
+func TestConfigBeaconStore(t *testing.T) {
+	store := beacon.NewMemStore()
+	var given *Config
+	conf := NewConfig(WithBeaconStore(func(c *Config) (beacon.Store, error) {
+		given = c
+		return store, nil
+	}))
+	s, err := conf.newBeaconStore()
+	require.NoError(t, err)
+	require.Equal(t, store, s)
+	require.Equal(t, conf, given)
+
+	conf = NewConfig(WithBeaconStore(func(*Config) (beacon.Store, error) {
+		return nil, errors.New("no database")
+	}))
+	_, err = conf.newBeaconStore()
+	require.Error(t, err)
+}
+
+func TestCheckDistPublic(t *testing.T) {
+	n, thr := 5, 3
+	_, group := test.BatchIdentities(n)
+	group.Threshold = thr
+	newShare := func(i int) (*key.Share, *key.DistPublic) {
+		pri := share.NewPriPoly(key.G2, thr, key.G2.Scalar().Pick(random.New()), random.New())
+		_, commits := pri.Commit(key.G2.Point().Base()).Info()
+		s := &key.Share{Share: pri.Shares(n)[i], Commits: commits}
+		return s, s.Public()
+	}
+	sh, pub := newShare(1)
+	d := &Drand{group: group, share: sh, pub: pub}
+	require.NoError(t, d.checkDistPublic())
+
+	_, other := newShare(1)
+	d.pub = other
+	require.Error(t, d.checkDistPublic())
+
+	// share of another group with the same threshold and index
+	d.share, d.pub = newShare(1)
+	d.share.Share.V = sh.Share.V
+	require.Error(t, d.checkDistPublic())
+
+	d.share, d.pub = newShare(1)
+	d.group.Threshold = thr + 1
+	require.Error(t, d.checkDistPublic())
+
+	d.group.Threshold = thr
+	derived, err := DeriveDistPublic(group, sh)
+	require.NoError(t, err)
+	require.True(t, derived.Key.Equal(pub.Key))
+}
+
+func TestCheckMember(t *testing.T) {
+	_, group := test.BatchTLSIdentities(3)
+	d := &Drand{group: group, opts: NewConfig()}
+	member := &x509.Certificate{IPAddresses: []gonet.IP{gonet.ParseIP("127.0.0.1")}}
+	other := &x509.Certificate{DNSNames: []string{"drand.example.org"}}
+	require.NoError(t, d.checkMember(member))
+	require.Error(t, d.checkMember(other))
+
+	// nodes of the group the key is reshared to are members as well
+	joining := &key.Identity{Key: key.NewKeyPair("a").Public.Key, Addr: "drand.example.org:443", TLS: true}
+	d.reshareGroup = key.NewGroup(append(group.Identities(), joining), 3)
+	require.NoError(t, d.checkMember(other))
+
+	// as well as the names set for the address of a node
+	d.reshareGroup = nil
+	d.opts.certmanager.SetServerName(group.Public(0).Address(), "drand.example.org")
+	require.NoError(t, d.checkMember(other))
+}
+
+func TestVerifyShare(t *testing.T) {
+	n, thr := 4, 3
+	_, group := test.BatchIdentities(n)
+	group.Threshold = thr
+	pri := share.NewPriPoly(key.G2, thr, key.G2.Scalar().Pick(random.New()), random.New())
+	_, commits := pri.Commit(key.G2.Point().Base()).Info()
+	id := group.Public(2)
+	idx, ok := group.Index(id)
+	require.True(t, ok)
+	s := &key.Share{Share: pri.Shares(n)[idx], Commits: commits}
+	require.NoError(t, VerifyShare(id, group, s, s.Public()))
+
+	// share of another node of the group
+	require.Error(t, VerifyShare(group.Public((idx+1)%n), group, s, s.Public()))
+	// distributed key of another group
+	other := &key.DistPublic{Key: key.G2.Point().Pick(random.New())}
+	require.Error(t, VerifyShare(id, group, s, other))
+}
+
+func TestDrandMemoryNetwork(t *testing.T) {
+	n := 5
+	period := time.Minute
+	clock := test.NewFakeClock(time.Unix(1500000000, 0))
+	network := net.NewMemoryNetwork()
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(period), WithClock(clock), WithNetwork(network.Gateway))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+
+	produced := make(chan *beacon.Beacon, n)
+	for _, d := range drands {
+		d.opts.beaconCbs = append(d.opts.beaconCbs, func(b *beacon.Beacon) {
+			produced <- b
+		})
+		go d.BeaconLoop(context.Background())
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case b := <-produced:
+			require.Equal(t, uint64(1), b.Round)
+		case <-time.After(5 * time.Second):
+			t.Fatal("round not produced")
+		}
+	}
+
+	client := NewClient(network.Client())
+	resp, err := client.LastPublic(drands[1].priv.Public.Address(), drands[0].pub, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.GetRound())
+
+	drands[1].Stop()
+	_, err = client.LastPublic(drands[1].priv.Public.Address(), drands[0].pub, false)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
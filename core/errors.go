@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The kinds of the errors returned by the node and by the client, to test with
+// errors.Is. The errors are *Error values of one of these kinds, wrapping the
+// cause of the failure.
+var (
+	// ErrDKGNotDone is returned when the node has no share and no distributed
+	// key yet, before the DKG finishes.
+	ErrDKGNotDone = errors.New("drand: dkg not finished")
+	// ErrNoBeacon is returned when the node does not have the beacon
+	// requested: it is not generated yet, it was missed or there is no beacon
+	// with the randomness requested.
+	ErrNoBeacon = errors.New("drand: no such beacon")
+	// ErrVerification is returned when a beacon, a signature or a key does not
+	// verify against the distributed key, or a reply does not match the
+	// request.
+	ErrVerification = errors.New("drand: verification failed")
+	// ErrInvalidRequest is returned for a request with invalid arguments.
+	ErrInvalidRequest = errors.New("drand: invalid request")
+	// ErrRateLimited is returned when the client sends too many requests.
+	ErrRateLimited = errors.New("drand: rate limited")
+	// ErrNotSupported is returned for a call the node does not serve, such as
+	// the private randomness on a replica or Sign without WithSigning.
+	ErrNotSupported = errors.New("drand: not supported")
+	// ErrPermissionDenied is returned when the node refuses the request of
+	// the client.
+	ErrPermissionDenied = errors.New("drand: permission denied")
+	// ErrThreshold is returned when a signing round does not gather a
+	// threshold of valid partial signatures.
+	ErrThreshold = errors.New("drand: threshold not reached")
+	// ErrInternal is returned when the node fails to serve a valid request,
+	// for example because its source of randomness fails.
+	ErrInternal = errors.New("drand: internal error")
+)
+
+// errorKinds gives for each kind of error its name, sent to the clients as a
+// detail of the gRPC status, and the gRPC code it is served with.
+var errorKinds = []struct {
+	kind error
+	name string
+	code codes.Code
+}{
+	{ErrDKGNotDone, "dkg_not_done", codes.Unavailable},
+	{ErrNoBeacon, "no_beacon", codes.NotFound},
+	{ErrVerification, "verification", codes.InvalidArgument},
+	{ErrInvalidRequest, "invalid_request", codes.InvalidArgument},
+	{ErrRateLimited, "rate_limited", codes.ResourceExhausted},
+	{ErrNotSupported, "not_supported", codes.Unimplemented},
+	{ErrPermissionDenied, "permission_denied", codes.PermissionDenied},
+	{ErrThreshold, "threshold", codes.Unavailable},
+	{ErrInternal, "internal", codes.Internal},
+}
+
+// Error is an error of one of the kinds above, so that errors.Is(err, kind)
+// holds, wrapping the cause of the failure. The RPC handlers serve it with
+// its gRPC code and its kind as a detail of the status, from which the client
+// returns an *Error of the same kind.
+type Error struct {
+	// Kind is one of the ErrXXX errors of the package.
+	Kind error
+	// Code is the gRPC code the error is served with.
+	Code codes.Code
+	// Err is the cause of the failure, whose message is the one of the
+	// error.
+	Err error
+	// details are added to the gRPC status, after the kind
+	details []proto.Message
+	// status of the reply, for an error returned by the client
+	st *status.Status
+}
+
+// newError returns an error of the given kind, served with the code of the
+// kind, whose cause is formatted as fmt.Errorf does.
+func newError(kind error, format string, args ...interface{}) *Error {
+	e := &Error{Kind: kind, Code: codes.Unknown, Err: fmt.Errorf(format, args...)}
+	for _, k := range errorKinds {
+		if k.kind == kind {
+			e.Code = k.code
+		}
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Is returns true if the target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the status the error is served with, carrying its kind,
+// or the status of the reply for an error returned by the client.
+func (e *Error) GRPCStatus() *status.Status {
+	if e.st != nil {
+		return e.st
+	}
+	st := status.New(e.Code, e.Error())
+	var details []proto.Message
+	for _, k := range errorKinds {
+		if k.kind == e.Kind {
+			details = append(details, &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: k.name}})
+		}
+	}
+	detailed, err := st.WithDetails(append(details, e.details...)...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// statusError returns the error a node replied with as an *Error of the kind
+// carried by its status, keeping the status and its details. Other errors,
+// such as network failures or replies of nodes predating the kinds, are
+// returned as is.
+func statusError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+	for _, d := range st.Details() {
+		v, ok := d.(*structpb.Value)
+		if !ok {
+			continue
+		}
+		for _, k := range errorKinds {
+			if k.name == v.GetStringValue() {
+				return &Error{Kind: k.kind, Code: st.Code(), Err: err, st: st}
+			}
+		}
+	}
+	return err
+}
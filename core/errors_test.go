@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dedis/drand/key"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorStatus(t *testing.T) {
+	genesis := time.Unix(1530000000, 0)
+	err := genesisError(genesis)
+	require.True(t, errors.Is(err, ErrNoBeacon))
+	require.False(t, errors.Is(err, ErrDKGNotDone))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	// the kind and the details survive the round trip through the status
+	replied := statusError(status.ErrorProto(status.Convert(err).Proto()))
+	require.True(t, errors.Is(replied, ErrNoBeacon))
+	require.Equal(t, codes.Unavailable, status.Code(replied))
+	got, ok := GenesisTime(replied)
+	require.True(t, ok)
+	require.True(t, genesis.Equal(got))
+
+	require.Equal(t, codes.Unimplemented, status.Code(errReplica))
+	require.Equal(t, codes.ResourceExhausted, status.Code(errRateLimited))
+
+	// errors without a kind are returned as is
+	plain := status.Error(codes.NotFound, "not found")
+	require.Equal(t, plain, statusError(plain))
+	require.NoError(t, statusError(nil))
+}
+
+func TestErrorClient(t *testing.T) {
+	drands, dir := BatchNewDrand(3, true, WithInMemory())
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	addr := drands[0].priv.Public.Address()
+	pub := &key.DistPublic{Key: key.G2.Point().Base()}
+	for _, c := range []*Client{NewGrpcClient(), NewRESTClient()} {
+		_, err := c.LastPublic(addr, pub, false)
+		require.True(t, errors.Is(err, ErrDKGNotDone))
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		_, err = c.PublicPoly(addr, pub, false)
+		require.True(t, errors.Is(err, ErrDKGNotDone))
+	}
+}
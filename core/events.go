@@ -0,0 +1,85 @@
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dedis/drand/log"
+)
+
+// Types of the events emitted by a drand node.
+const (
+	EventDKGStarted       = "dkg_started"
+	EventDKGCompleted     = "dkg_completed"
+	EventDKGFailed        = "dkg_failed"
+	EventReshareStarted   = "reshare_started"
+	EventReshareCompleted = "reshare_completed"
+	EventReshareFailed    = "reshare_failed"
+	EventBeaconStarted    = "beacon_started"
+	EventRoundProduced    = "round_produced"
+	EventRoundSkipped     = "round_skipped"
+	EventReloaded         = "reloaded"
+	EventStopped          = "stopped"
+)
+
+// Event describes a lifecycle transition of a drand node. Events are written
+// as newline-delimited JSON so supervisors can react to the state of the node
+// without parsing the human readable logs. Only the fields relevant to the
+// type of the event are set.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Leader is true if the node started the DKG or the resharing
+	Leader bool `json:"leader,omitempty"`
+	// Nodes and Threshold describe the group running the DKG or the
+	// resharing, or the group reloaded
+	Nodes     int `json:"nodes,omitempty"`
+	Threshold int `json:"threshold,omitempty"`
+	// CatchUp is true if the beacon loop started by catching up
+	CatchUp bool `json:"catchup,omitempty"`
+	// Round is the round produced or the first round skipped
+	Round uint64 `json:"round,omitempty"`
+	// Skipped is the number of consecutive rounds skipped
+	Skipped uint64 `json:"skipped,omitempty"`
+	// Randomness is the hex encoded randomness of the round produced
+	Randomness string `json:"randomness,omitempty"`
+	// Error is the reason of the failure, if any
+	Error string `json:"error,omitempty"`
+}
+
+// eventLog writes the events to a writer, one JSON object per line. A nil
+// writer disables the events.
+type eventLog struct {
+	sync.Mutex
+	w      io.Writer
+	logger log.Logger
+}
+
+func newEventLog(w io.Writer, l log.Logger) *eventLog {
+	return &eventLog{w: w, logger: l}
+}
+
+func (e *eventLog) emit(ev *Event) {
+	if e.w == nil {
+		return
+	}
+	ev.Time = time.Now()
+	buff, err := json.Marshal(ev)
+	if err != nil {
+		e.logger.Info("drand: can't marshal event", "type", ev.Type, "err", err)
+		return
+	}
+	buff = append(buff, '\n')
+	e.Lock()
+	defer e.Unlock()
+	if _, err := e.w.Write(buff); err != nil {
+		e.logger.Info("drand: can't write event", "type", ev.Type, "err", err)
+	}
+}
+
+func roundProduced(round uint64, randomness []byte) *Event {
+	return &Event{Type: EventRoundProduced, Round: round, Randomness: hex.EncodeToString(randomness)}
+}
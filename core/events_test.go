@@ -0,0 +1,41 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsRounds(t *testing.T) {
+	var buff bytes.Buffer
+	d := &Drand{opts: NewConfig(), events: newEventLog(&buff, log.DefaultLogger()), feed: newPublicFeed(log.DefaultLogger())}
+	d.beaconCallback(&beacon.Beacon{Round: 1, Signature: []byte{0x01}})
+	d.beaconCallback(&beacon.Beacon{Round: 4, Signature: []byte{0x04}})
+
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	require.Len(t, lines, 3)
+	var events []Event
+	for _, l := range lines {
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(l), &e))
+		events = append(events, e)
+	}
+	require.Equal(t, EventRoundProduced, events[0].Type)
+	require.Equal(t, uint64(1), events[0].Round)
+	require.Equal(t, hex.EncodeToString(beacon.RandomnessFromSignature([]byte{0x01})), events[0].Randomness)
+	require.Equal(t, EventRoundSkipped, events[1].Type)
+	require.Equal(t, uint64(2), events[1].Round)
+	require.Equal(t, uint64(2), events[1].Skipped)
+	require.Equal(t, EventRoundProduced, events[2].Type)
+	require.Equal(t, uint64(4), events[2].Round)
+
+	// no writer, no events
+	d = &Drand{opts: NewConfig(), events: newEventLog(nil, log.DefaultLogger()), feed: newPublicFeed(log.DefaultLogger())}
+	d.beaconCallback(&beacon.Beacon{Round: 1})
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/kyber/sign/bls"
+)
+
+// MaxNonceSize is the maximum size of the nonce a client can send along a
+// public randomness request.
+const MaxNonceSize = 64
+
+// NonceSize is the size of the nonces generated by the client.
+const NonceSize = 32
+
+// freshnessMessage returns the message signed by a node to attest the response
+// has been created after having received the nonce:
+// sha256(nonce) || timestamp || round || randomness
+func freshnessMessage(nonce []byte, timestamp, round uint64, randomness []byte) []byte {
+	h := sha256.Sum256(nonce)
+	msg := make([]byte, 0, len(h)+16+len(randomness))
+	msg = append(msg, h[:]...)
+	var buff [8]byte
+	binary.BigEndian.PutUint64(buff[:], timestamp)
+	msg = append(msg, buff[:]...)
+	binary.BigEndian.PutUint64(buff[:], round)
+	msg = append(msg, buff[:]...)
+	return append(msg, randomness...)
+}
+
+// attest echoes the nonce in the response and signs it with the long-term key
+// of the node, alongside the current time and the beacon, proving the response
+// is fresh. The signature is made with the scheme of the key of the node.
+func attest(priv *key.Pair, nonce []byte, resp *drand.PublicRandResponse) error {
+	if len(nonce) > MaxNonceSize {
+		return fmt.Errorf("drand: nonce too long (%d > %d bytes)", len(nonce), MaxNonceSize)
+	}
+	scheme, err := key.SchemeByName(priv.Public.Scheme)
+	if err != nil {
+		return err
+	}
+	resp.Nonce = nonce
+	resp.Timestamp = uint64(time.Now().Unix())
+	msg := freshnessMessage(nonce, resp.Timestamp, resp.Round, resp.Randomness)
+	sig, err := bls.Sign(scheme.Pairing, priv.Key, msg)
+	if err != nil {
+		return err
+	}
+	resp.Attestation = sig
+	return nil
+}
+
+// verifyAttestation checks that the response echoes the nonce and that the
+// attestation is a valid signature from the long-term key of the given
+// identity, with the scheme of the identity.
+func verifyAttestation(id *key.Identity, nonce []byte, resp *drand.PublicRandResponse) error {
+	scheme, err := key.SchemeByName(id.Scheme)
+	if err != nil {
+		return newError(ErrVerification, "drand: cannot verify attestation: %w", err)
+	}
+	if len(resp.GetAttestation()) == 0 {
+		return newError(ErrVerification, "drand: response without attestation")
+	}
+	if string(resp.GetNonce()) != string(nonce) {
+		return newError(ErrVerification, "drand: response does not echo the nonce")
+	}
+	msg := freshnessMessage(nonce, resp.GetTimestamp(), resp.GetRound(), resp.GetRandomness())
+	if err := bls.Verify(scheme.Pairing, id.Key, msg, resp.GetAttestation()); err != nil {
+		return newError(ErrVerification, "drand: invalid attestation: %w", err)
+	}
+	return nil
+}
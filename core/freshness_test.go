@@ -0,0 +1,34 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestation(t *testing.T) {
+	priv := key.NewKeyPair("127.0.0.1:80")
+	nonce := []byte("fresh nonce")
+	resp := &drand.PublicRandResponse{Round: 10, Randomness: []byte("randomness")}
+	require.NoError(t, attest(priv, nonce, resp))
+	require.NoError(t, verifyAttestation(priv.Public, nonce, resp))
+
+	// replayed response for another nonce
+	require.Error(t, verifyAttestation(priv.Public, []byte("other nonce"), resp))
+	// signed by another node
+	other := key.NewKeyPair("127.0.0.1:81")
+	require.Error(t, verifyAttestation(other.Public, nonce, resp))
+	// tampered round
+	resp.Round = 11
+	require.Error(t, verifyAttestation(priv.Public, nonce, resp))
+
+	require.Error(t, attest(priv, make([]byte, MaxNonceSize+1), resp))
+
+	// the scheme of the identity is the one of the attestation
+	unknown := *priv.Public
+	unknown.Scheme = "unknown"
+	require.Error(t, attest(&key.Pair{Key: priv.Key, Public: &unknown}, nonce, resp))
+	require.Error(t, verifyAttestation(&unknown, nonce, resp))
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errDKGNotFinished is returned when the public randomness is requested before
+// the DKG finished, in which case the genesis time is not known yet.
+var errDKGNotFinished = newError(ErrDKGNotDone, "drand: dkg not finished, genesis time unknown")
+
+// genesisError returns the error served to clients requesting the public
+// randomness before the first round has been generated. It is of kind
+// ErrNoBeacon, has the Unavailable code and carries the genesis time, i.e. the
+// time at which the first round is expected, as a detail.
+func genesisError(genesis time.Time) error {
+	e := newError(ErrNoBeacon, "drand: no beacon generated yet, first round expected at genesis time %s", genesis.UTC().Format(time.RFC3339))
+	e.Code = codes.Unavailable
+	if ts, err := ptypes.TimestampProto(genesis); err == nil {
+		e.details = append(e.details, ts)
+	}
+	return e
+}
+
+// GenesisTime returns the genesis time carried by an error returned by a drand
+// node when no beacon has been generated yet. Clients can use it to know how
+// long to wait before the first round. It returns false if the error does not
+// carry any genesis time.
+func GenesisTime(err error) (time.Time, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable {
+		return time.Time{}, false
+	}
+	for _, detail := range st.Details() {
+		ts, ok := detail.(*timestamp.Timestamp)
+		if !ok {
+			continue
+		}
+		genesis, err := ptypes.Timestamp(ts)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return genesis, true
+	}
+	return time.Time{}, false
+}
+
+// publicError converts an error encountered while retrieving the last beacon
+// into the error served to clients.
+func (d *Drand) publicError(err error) error {
+	switch err {
+	case errDKGNotFinished:
+		return err
+	case beacon.ErrNoBeaconSaved:
+		d.state.Lock()
+		genesis := d.genesis
+		d.state.Unlock()
+		return genesisError(genesis)
+	default:
+		return fmt.Errorf("drand: can't retrieve beacon: %w", err)
+	}
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGenesisError(t *testing.T) {
+	genesis := time.Unix(1530000000, 0)
+	err := genesisError(genesis)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Contains(t, err.Error(), "2018-06-26T08:00:00Z")
+
+	got, ok := GenesisTime(err)
+	require.True(t, ok)
+	require.True(t, genesis.Equal(got))
+
+	_, ok = GenesisTime(errDKGNotFinished)
+	require.False(t, ok)
+	_, ok = GenesisTime(errors.New("can't retrieve beacon"))
+	require.False(t, ok)
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"github.com/dedis/drand/key"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+)
+
+// autoLead starts the DKG if it has not started after the election timeout
+// and this node is the elected leader, see WithAutoLeader. The election is run
+// again after each timeout, in case the elected leader goes down before
+// starting, until the DKG starts or the stop channel is closed.
+func (d *Drand) autoLead(stop chan bool) {
+	d.state.Lock()
+	h, group := d.dkg, d.group
+	d.state.Unlock()
+	for {
+		select {
+		case <-d.opts.clock.After(d.opts.electTimeout):
+		case <-stop:
+			return
+		}
+		if h.Started() {
+			return
+		}
+		leader := d.electLeader(group)
+		if !leader.Key.Equal(d.priv.Public.Key) {
+			d.opts.logger.Info("drand: no dkg leader yet, waiting for the elected one", "leader", leader.Address())
+			continue
+		}
+		d.opts.logger.Info("drand: no dkg leader yet, starting the dkg as the elected one")
+		h.Start()
+		return
+	}
+}
+
+// electLeader returns the node of the group with the lowest index that replies
+// to a DKG status request, or this node if none of the nodes before it does.
+// All the nodes running the election reach the same result as long as they
+// reach the same nodes.
+func (d *Drand) electLeader(group *key.Group) *key.Identity {
+	for i := 0; i < group.Len(); i++ {
+		id := group.Public(i)
+		if id.Key.Equal(d.priv.Public.Key) {
+			return id
+		}
+		if _, err := d.gateway.InternalClient.DKGStatus(id, &dkg_proto.DKGStatusRequest{}); err == nil {
+			return id
+		}
+	}
+	return d.priv.Public
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/nikkolasg/slog"
+)
+
+// Monitor periodically fetches the latest beacon from a trusted drand node and
+// verifies it against the distributed public key. It keeps track of the
+// results so they can be exposed over HTTP, as a JSON status page and as
+// metrics in the Prometheus text format. It turns any machine into a
+// lightweight external watchdog of a drand deployment.
+type Monitor struct {
+	sync.Mutex
+	client *Client
+	peer   *peerAddr
+	public *key.DistPublic
+	period time.Duration
+	status MonitorStatus
+	close  chan bool
+}
+
+// MonitorStatus holds the results of the monitoring of a drand node.
+type MonitorStatus struct {
+	// Address of the monitored node
+	Address string `json:"address"`
+	// LastRound is the last round successfully verified
+	LastRound uint64 `json:"last_round"`
+	// LastVerified is the time at which the last round has been verified
+	LastVerified time.Time `json:"last_verified"`
+	// Latency is the time it took to fetch the last beacon
+	Latency time.Duration `json:"latency"`
+	// FetchFailures is the number of times the node could not be contacted
+	FetchFailures uint64 `json:"fetch_failures"`
+	// VerifyFailures is the number of beacons that did not verify or that
+	// went back in time
+	VerifyFailures uint64 `json:"verify_failures"`
+	// LastError is the last error encountered, if any
+	LastError string `json:"last_error,omitempty"`
+	// ThresholdLatencyEMA is the moving average of the time the rounds of
+	// the node take to reach the threshold of partial signatures, as
+	// reported by the node
+	ThresholdLatencyEMA time.Duration `json:"threshold_latency_ema"`
+	// ThresholdLatencyMax is the maximum time the recent rounds of the node
+	// took to reach the threshold, as reported by the node
+	ThresholdLatencyMax time.Duration `json:"threshold_latency_max"`
+}
+
+// NewMonitor returns a monitor fetching the beacons from the node at the given
+// address every period, using the given client.
+func NewMonitor(c *Client, addr string, pub *key.DistPublic, secure bool, period time.Duration) *Monitor {
+	return &Monitor{
+		client: c,
+		peer:   &peerAddr{addr, secure},
+		public: pub,
+		period: period,
+		status: MonitorStatus{Address: addr},
+		close:  make(chan bool),
+	}
+}
+
+// Start polls the trusted node every period until Stop is called. It blocks
+// until then.
+func (m *Monitor) Start() {
+	ticker := time.NewTicker(m.period)
+	defer ticker.Stop()
+	for {
+		m.Check()
+		select {
+		case <-ticker.C:
+		case <-m.close:
+			return
+		}
+	}
+}
+
+// Stop stops the polling of the trusted node.
+func (m *Monitor) Stop() {
+	close(m.close)
+}
+
+// Check fetches the latest beacon from the trusted node, verifies it and
+// records the result.
+func (m *Monitor) Check() {
+	start := time.Now()
+	resp, err := m.client.fetchPublic(context.Background(), m.peer, &drand.PublicRandRequest{})
+	latency := time.Since(start)
+	// the round statistics are informative only, nodes may not report them
+	home, homeErr := m.client.client.Home(m.peer, &drand.HomeRequest{})
+	m.Lock()
+	defer m.Unlock()
+	if homeErr == nil && home.GetRoundsMeasured() > 0 {
+		m.status.ThresholdLatencyEMA = time.Duration(home.GetThresholdLatencyEma()) * time.Millisecond
+		m.status.ThresholdLatencyMax = time.Duration(home.GetThresholdLatencyMax()) * time.Millisecond
+	}
+	if err != nil {
+		m.status.FetchFailures++
+		m.status.LastError = fmt.Sprintf("fetching beacon: %s", err)
+		slog.Infof("monitor: could not fetch beacon from %s: %s", m.peer.Address(), err)
+		return
+	}
+	m.status.Latency = latency
+	if err := m.client.Verify(m.public.Key, resp); err != nil {
+		m.status.VerifyFailures++
+		m.status.LastError = fmt.Sprintf("round %d: invalid beacon: %s", resp.GetRound(), err)
+		slog.Printf("monitor: INVALID beacon for round %d from %s: %s", resp.GetRound(), m.peer.Address(), err)
+		return
+	}
+	if resp.GetRound() < m.status.LastRound {
+		m.status.VerifyFailures++
+		m.status.LastError = fmt.Sprintf("round %d: went back from round %d", resp.GetRound(), m.status.LastRound)
+		slog.Printf("monitor: %s served round %d after round %d", m.peer.Address(), resp.GetRound(), m.status.LastRound)
+		return
+	}
+	m.status.LastRound = resp.GetRound()
+	m.status.LastVerified = time.Now()
+	slog.Debugf("monitor: round %d from %s verified in %s", resp.GetRound(), m.peer.Address(), latency)
+}
+
+// Status returns the current monitoring results.
+func (m *Monitor) Status() MonitorStatus {
+	m.Lock()
+	defer m.Unlock()
+	return m.status
+}
+
+// ServeHTTP serves the JSON status page on "/" and the metrics on "/metrics".
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := m.Status()
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "application/json")
+		buff, err := json.MarshalIndent(status, "", "    ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(buff)
+	case "/metrics":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metric := func(name, kind, help string, value interface{}) {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+		}
+		var verified int64
+		if !status.LastVerified.IsZero() {
+			verified = status.LastVerified.Unix()
+		}
+		metric("drand_monitor_last_round", "gauge", "Last round verified from the monitored node.", status.LastRound)
+		metric("drand_monitor_last_verified_timestamp_seconds", "gauge", "Time at which the last round has been verified.", verified)
+		metric("drand_monitor_latency_seconds", "gauge", "Time it took to fetch the last beacon.", status.Latency.Seconds())
+		metric("drand_monitor_fetch_failures_total", "counter", "Number of failed attempts to contact the monitored node.", status.FetchFailures)
+		metric("drand_monitor_verify_failures_total", "counter", "Number of invalid beacons served by the monitored node.", status.VerifyFailures)
+		metric("drand_monitor_threshold_latency_ema_seconds", "gauge", "Moving average of the time the rounds of the node take to reach the threshold.", status.ThresholdLatencyEMA.Seconds())
+		metric("drand_monitor_threshold_latency_max_seconds", "gauge", "Maximum time the recent rounds of the node took to reach the threshold.", status.ThresholdLatencyMax.Seconds())
+	default:
+		http.NotFound(w, r)
+	}
+}
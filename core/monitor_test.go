@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient always serves the same response or error
+type fakeClient struct {
+	resp *drand.PublicRandResponse
+	home *drand.HomeResponse
+	err  error
+}
+
+func (f *fakeClient) Public(ctx context.Context, p net.Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	return f.resp, f.err
+}
+
+func (f *fakeClient) Private(ctx context.Context, p net.Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) DistKey(p net.Peer, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) PublicStream(p net.Peer, in *drand.PublicRandRequest) (drand.Randomness_PublicStreamClient, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) Home(p net.Peer, in *drand.HomeRequest) (*drand.HomeResponse, error) {
+	if f.home == nil {
+		return nil, errors.New("not implemented")
+	}
+	return f.home, f.err
+}
+
+func (f *fakeClient) Group(p net.Peer, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) ChainInfo(p net.Peer, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) LocateRandomness(p net.Peer, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) Sign(p net.Peer, in *drand.SignRequest) (*drand.SignResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func signedResponse(t *testing.T, priv kyber.Scalar, round uint64, prev []byte) *drand.PublicRandResponse {
+	sig, err := bls.Sign(key.Pairing, priv, beacon.Message(prev, round))
+	require.NoError(t, err)
+	return &drand.PublicRandResponse{Round: round, Previous: prev, Randomness: beacon.RandomnessFromSignature(sig), Signature: sig}
+}
+
+func TestMonitor(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	fake := &fakeClient{resp: signedResponse(t, priv, 10, []byte("prev"))}
+	client := &Client{client: fake}
+	m := NewMonitor(client, "127.0.0.1:4444", &key.DistPublic{Key: pub}, false, time.Second)
+
+	m.Check()
+	status := m.Status()
+	require.Equal(t, uint64(10), status.LastRound)
+	require.Equal(t, uint64(0), status.VerifyFailures)
+	require.False(t, status.LastVerified.IsZero())
+	require.Equal(t, time.Duration(0), status.ThresholdLatencyEMA)
+
+	// the node reports how long its rounds take
+	fake.home = &drand.HomeResponse{RoundsMeasured: 3, ThresholdLatencyEma: 250, ThresholdLatencyMax: 1500}
+	m.Check()
+	status = m.Status()
+	require.Equal(t, 250*time.Millisecond, status.ThresholdLatencyEMA)
+	require.Equal(t, 1500*time.Millisecond, status.ThresholdLatencyMax)
+
+	// invalid signature
+	fake.resp = signedResponse(t, priv, 11, []byte("prev"))
+	fake.resp.Signature[0] ^= 0x01
+	m.Check()
+	status = m.Status()
+	require.Equal(t, uint64(10), status.LastRound)
+	require.Equal(t, uint64(1), status.VerifyFailures)
+
+	// valid but older round
+	fake.resp = signedResponse(t, priv, 9, []byte("prev"))
+	m.Check()
+	require.Equal(t, uint64(2), m.Status().VerifyFailures)
+
+	// node unreachable
+	fake.err = errors.New("unreachable")
+	m.Check()
+	status = m.Status()
+	require.Equal(t, uint64(1), status.FetchFailures)
+	require.Contains(t, status.LastError, "unreachable")
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "drand_monitor_last_round 10")
+	require.Contains(t, string(body), "drand_monitor_verify_failures_total 2")
+	require.Contains(t, string(body), "drand_monitor_threshold_latency_max_seconds 1.5")
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	require.Contains(t, rec.Body.String(), `"last_round": 10`)
+}
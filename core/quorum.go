@@ -0,0 +1,259 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/nikkolasg/slog"
+)
+
+// QuorumError is returned by LastPublicQuorum when no round is agreed upon by
+// the quorum of servers.
+type QuorumError struct {
+	Quorum int
+	// Valid is the number of servers that answered with a valid beacon.
+	Valid int
+}
+
+func (q *QuorumError) Error() string {
+	if q.Valid < q.Quorum {
+		return fmt.Sprintf("drand: no quorum, only %d servers out of the %d needed answered with a valid beacon", q.Valid, q.Quorum)
+	}
+	return fmt.Sprintf("drand: %d servers answered with a valid beacon but no %d of them agree on a round", q.Valid, q.Quorum)
+}
+
+// LastPublicQuorum asks the last randomness beacon to each of the servers at
+// the given addresses, verifies them, and returns the beacon of the highest
+// round on which at least q servers agree. Servers ahead of others are asked
+// for the beacon of the rounds of the others, so servers agree on a round if
+// they serve the same beacon for it. It protects against a single server
+// serving a stale, yet valid, beacon as the last one. It returns a
+// *QuorumError if no round is agreed upon by q servers.
+func (c *Client) LastPublicQuorum(addrs []string, pub *key.DistPublic, q int, secure bool) (*drand.PublicRandResponse, error) {
+	if q < 1 || q > len(addrs) {
+		return nil, fmt.Errorf("drand: quorum of %d out of %d servers", q, len(addrs))
+	}
+	lasts := make([]*drand.PublicRandResponse, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			resp, err := c.LastPublicCtx(context.Background(), addr, pub, secure)
+			if err != nil {
+				slog.Debugf("drand: no valid beacon from %s: %s", addr, err)
+				return
+			}
+			lasts[i] = resp
+		}(i, addr)
+	}
+	wg.Wait()
+
+	var valid int
+	var rounds []uint64
+	seen := make(map[uint64]bool)
+	for _, resp := range lasts {
+		if resp == nil {
+			continue
+		}
+		valid++
+		if !seen[resp.GetRound()] {
+			seen[resp.GetRound()] = true
+			rounds = append(rounds, resp.GetRound())
+		}
+	}
+	if valid < q {
+		return nil, &QuorumError{Quorum: q, Valid: valid}
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i] > rounds[j] })
+	for _, round := range rounds {
+		if resp, ok := c.agreeOn(addrs, lasts, round, pub, q, secure); ok {
+			return resp, nil
+		}
+	}
+	return nil, &QuorumError{Quorum: q, Valid: valid}
+}
+
+// agreeOn returns the beacon of the given round served by at least q servers,
+// if any. The servers whose last beacon is after the round are asked for the
+// beacon of the round.
+func (c *Client) agreeOn(addrs []string, lasts []*drand.PublicRandResponse, round uint64, pub *key.DistPublic, q int, secure bool) (*drand.PublicRandResponse, bool) {
+	var beacons []*drand.PublicRandResponse
+	for i, last := range lasts {
+		switch {
+		case last == nil || last.GetRound() < round:
+			continue
+		case last.GetRound() == round:
+			beacons = append(beacons, last)
+		default:
+			resp, err := c.PublicRound(addrs[i], pub, round, secure)
+			if err != nil {
+				slog.Debugf("drand: no valid beacon for round %d from %s: %s", round, addrs[i], err)
+				continue
+			}
+			beacons = append(beacons, resp)
+		}
+	}
+	for _, b := range beacons {
+		agree := 0
+		for _, other := range beacons {
+			if bytes.Equal(b.GetRandomness(), other.GetRandomness()) {
+				agree++
+			}
+		}
+		if agree >= q {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// GroupQuorumError is returned by FetchGroupQuorum when no group is served
+// identically by the threshold of servers.
+type GroupQuorumError struct {
+	Threshold int
+	// Valid is the number of servers that served a valid group
+	Valid int
+	// Diffs lists how the groups served differ from the most common one
+	Diffs []string
+}
+
+func (g *GroupQuorumError) Error() string {
+	msg := fmt.Sprintf("drand: no group served by %d servers", g.Threshold)
+	if g.Valid < g.Threshold {
+		msg = fmt.Sprintf("drand: no quorum, only %d servers out of the %d needed served a valid group", g.Valid, g.Threshold)
+	}
+	if len(g.Diffs) > 0 {
+		msg += ":\n" + strings.Join(g.Diffs, "\n")
+	}
+	return msg
+}
+
+// fetchedGroup is a group and its distributed key as served by a server.
+type fetchedGroup struct {
+	addr   string
+	group  *key.Group
+	public *key.DistPublic
+}
+
+// FetchGroupQuorum fetches the group and the distributed public key from each
+// of the servers at the given addresses, as FetchGroup, and returns the ones
+// served identically by at least threshold of them: same threshold, same
+// distributed key and same identities, including their TLS flag. It protects
+// the bootstrap against a single lying server. The servers serving another
+// group are logged with the differences, and a *GroupQuorumError listing them
+// is returned if no group is served by threshold servers.
+func (c *Client) FetchGroupQuorum(addrs []string, threshold int, secure bool) (*key.Group, *key.DistPublic, error) {
+	if threshold < 1 || threshold > len(addrs) {
+		return nil, nil, fmt.Errorf("drand: quorum of %d out of %d servers", threshold, len(addrs))
+	}
+	fetched := make([]*fetchedGroup, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			group, public, err := c.FetchGroup(addr, secure)
+			if err != nil {
+				slog.Debugf("drand: no valid group from %s: %s", addr, err)
+				return
+			}
+			fetched[i] = &fetchedGroup{addr, group, public}
+		}(i, addr)
+	}
+	wg.Wait()
+
+	// count the servers serving each group, in the order of the addresses
+	var valid []*fetchedGroup
+	var best *fetchedGroup
+	counts := make(map[string]int)
+	for _, f := range fetched {
+		if f == nil {
+			continue
+		}
+		valid = append(valid, f)
+		id := groupID(f)
+		counts[id]++
+		if best == nil || counts[id] > counts[groupID(best)] {
+			best = f
+		}
+	}
+	if best == nil {
+		return nil, nil, &GroupQuorumError{Threshold: threshold}
+	}
+	var diffs []string
+	for _, f := range valid {
+		if d := groupDiff(best, f); len(d) > 0 {
+			diffs = append(diffs, fmt.Sprintf("%s serves a group differing from %s: %s", f.addr, best.addr, strings.Join(d, ", ")))
+		}
+	}
+	if counts[groupID(best)] < threshold {
+		return nil, nil, &GroupQuorumError{Threshold: threshold, Valid: len(valid), Diffs: diffs}
+	}
+	for _, d := range diffs {
+		slog.Infof("drand: %s", d)
+	}
+	return best.group, best.public, nil
+}
+
+// groupID returns a string identifying the group and the distributed key.
+func groupID(f *fetchedGroup) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d|%s", f.group.Threshold, f.public.Key)
+	for _, n := range sortedNodes(f.group) {
+		fmt.Fprintf(&b, "|%s", nodeID(n))
+	}
+	return b.String()
+}
+
+func nodeID(n *key.IndexedPublic) string {
+	return fmt.Sprintf("%d %s %v %s", n.Index, n.Addr, n.TLS, n.Key)
+}
+
+func sortedNodes(g *key.Group) []*key.IndexedPublic {
+	nodes := make([]*key.IndexedPublic, len(g.Nodes))
+	copy(nodes, g.Nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Index < nodes[j].Index })
+	return nodes
+}
+
+// groupDiff describes how the group b differs from the group a.
+func groupDiff(a, b *fetchedGroup) []string {
+	var diffs []string
+	if a.group.Threshold != b.group.Threshold {
+		diffs = append(diffs, fmt.Sprintf("threshold %d instead of %d", b.group.Threshold, a.group.Threshold))
+	}
+	if !a.public.Key.Equal(b.public.Key) {
+		diffs = append(diffs, "different distributed key")
+	}
+	nodes := make(map[int]*key.IndexedPublic)
+	for _, n := range a.group.Nodes {
+		nodes[n.Index] = n
+	}
+	for _, n := range sortedNodes(b.group) {
+		other, ok := nodes[n.Index]
+		delete(nodes, n.Index)
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("extra node %d (%s)", n.Index, n.Addr))
+		case other.Addr != n.Addr:
+			diffs = append(diffs, fmt.Sprintf("node %d at %s instead of %s", n.Index, n.Addr, other.Addr))
+		case !other.Key.Equal(n.Key):
+			diffs = append(diffs, fmt.Sprintf("node %d (%s) with a different key", n.Index, n.Addr))
+		case other.TLS != n.TLS:
+			diffs = append(diffs, fmt.Sprintf("node %d (%s) with TLS %v instead of %v", n.Index, n.Addr, n.TLS, other.TLS))
+		}
+	}
+	for _, n := range sortedNodes(a.group) {
+		if _, ok := nodes[n.Index]; ok {
+			diffs = append(diffs, fmt.Sprintf("missing node %d (%s)", n.Index, n.Addr))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/crypto"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/drand/test"
+	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+// chainClient serves for each address the beacons of a chain, the last one
+// being the last beacon of the chain.
+type chainClient struct {
+	fakeClient
+	chains map[string][]*drand.PublicRandResponse
+}
+
+func (c *chainClient) Public(ctx context.Context, p net.Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	chain, ok := c.chains[p.Address()]
+	if !ok || len(chain) == 0 {
+		return nil, errors.New("unreachable")
+	}
+	if in.GetRound() == 0 {
+		return chain[len(chain)-1], nil
+	}
+	for _, b := range chain {
+		if b.GetRound() == in.GetRound() {
+			return b, nil
+		}
+	}
+	return nil, errors.New("round not found")
+}
+
+func TestLastPublicQuorum(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	dist := &key.DistPublic{Key: pub}
+	var chain []*drand.PublicRandResponse
+	prev := []byte("seed")
+	for round := uint64(1); round <= 5; round++ {
+		b := signedResponse(t, priv, round, prev)
+		chain = append(chain, b)
+		prev = b.Signature
+	}
+	forked := signedResponse(t, priv, 5, []byte("fork"))
+
+	fake := &chainClient{chains: map[string][]*drand.PublicRandResponse{
+		"a": chain,
+		"b": chain[:4],
+		"c": chain[:4],
+		"d": append(append([]*drand.PublicRandResponse{}, chain[:4]...), forked),
+	}}
+	client := &Client{client: fake}
+	addrs := []string{"a", "b", "c", "d", "e"}
+
+	// only a and d are at round 5 and they disagree, but all are at round 4
+	resp, err := client.LastPublicQuorum(addrs, dist, 2, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), resp.GetRound())
+	resp, err = client.LastPublicQuorum(addrs, dist, 4, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), resp.GetRound())
+	resp, err = client.LastPublicQuorum(addrs, dist, 1, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), resp.GetRound())
+
+	fake.chains["b"] = chain
+	resp, err = client.LastPublicQuorum(addrs, dist, 2, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), resp.GetRound())
+	require.Equal(t, chain[4].Randomness, resp.GetRandomness())
+
+	// e is unreachable
+	_, err = client.LastPublicQuorum(addrs, dist, 5, false)
+	require.Equal(t, &QuorumError{Quorum: 5, Valid: 4}, err)
+
+	// invalid beacons do not count
+	invalid := signedResponse(t, priv, 4, []byte("prev"))
+	invalid.Signature[0] ^= 0x01
+	fake.chains["c"] = []*drand.PublicRandResponse{invalid}
+	_, err = client.LastPublicQuorum(addrs, dist, 4, false)
+	require.Equal(t, &QuorumError{Quorum: 4, Valid: 3}, err)
+
+	// enough valid beacons but no agreement
+	fake.chains = map[string][]*drand.PublicRandResponse{"a": chain, "d": []*drand.PublicRandResponse{forked}}
+	_, err = client.LastPublicQuorum([]string{"a", "d"}, dist, 2, false)
+	require.Equal(t, &QuorumError{Quorum: 2, Valid: 2}, err)
+	require.Contains(t, err.Error(), "agree")
+
+	_, err = client.LastPublicQuorum(addrs, dist, 6, false)
+	require.Error(t, err)
+}
+
+// groupClient serves for each address a group
+type groupClient struct {
+	fakeClient
+	groups map[string]*drand.GroupResponse
+}
+
+func (c *groupClient) Group(p net.Peer, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	resp, ok := c.groups[p.Address()]
+	if !ok {
+		return nil, errors.New("unreachable")
+	}
+	return resp, nil
+}
+
+func groupResponse(t *testing.T, group *key.Group, public kyber.Point) *drand.GroupResponse {
+	distKey, err := crypto.KyberToProtoPoint(public)
+	require.NoError(t, err)
+	resp := &drand.GroupResponse{Threshold: uint32(group.Threshold), DistKey: distKey}
+	for _, n := range group.Nodes {
+		k, err := crypto.KyberToProtoPoint(n.Key)
+		require.NoError(t, err)
+		resp.Nodes = append(resp.Nodes, &drand.GroupNode{Address: n.Addr, Key: k, Tls: n.TLS, Index: uint32(n.Index)})
+	}
+	return resp
+}
+
+func TestFetchGroupQuorum(t *testing.T) {
+	_, group := test.BatchTLSIdentities(4)
+	_, public := bls.NewKeyPair(key.Pairing, random.New())
+	honest := groupResponse(t, group, public)
+	// a lying server downgrades a node to plain text
+	lying := groupResponse(t, group, public)
+	lying.Nodes[1].Tls = false
+	client := &Client{client: &groupClient{groups: map[string]*drand.GroupResponse{
+		"a": honest,
+		"b": lying,
+		"c": honest,
+	}}}
+
+	fetched, dist, err := client.FetchGroupQuorum([]string{"a", "b", "c"}, 2, false)
+	require.NoError(t, err)
+	require.True(t, public.Equal(dist.Key))
+	require.Equal(t, group.Len(), fetched.Len())
+	for _, n := range fetched.Nodes {
+		require.True(t, n.IsTLS())
+	}
+
+	_, _, err = client.FetchGroupQuorum([]string{"a", "b", "c"}, 3, false)
+	qerr, ok := err.(*GroupQuorumError)
+	require.True(t, ok)
+	require.Equal(t, 3, qerr.Valid)
+	require.Len(t, qerr.Diffs, 1)
+	require.Contains(t, qerr.Diffs[0], "b serves a group differing from a")
+	require.Contains(t, qerr.Diffs[0], "with TLS false instead of true")
+
+	// unreachable servers do not count
+	_, _, err = client.FetchGroupQuorum([]string{"a", "d", "e"}, 2, false)
+	require.Equal(t, &GroupQuorumError{Threshold: 2, Valid: 1}, err)
+	_, _, err = client.FetchGroupQuorum([]string{"a"}, 2, false)
+	require.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dedis/drand/beacon"
+	"google.golang.org/grpc"
+)
+
+// maxRateBuckets is the number of remote addresses tracked by a rateLimiter
+// above which the addresses that did not send any request for a while are
+// forgotten.
+const maxRateBuckets = 10000
+
+var errRateLimited = newError(ErrRateLimited, "drand: too many requests, slow down")
+
+// rateLimiter limits the rate of the requests received on the public facing
+// API with a token bucket per remote IP: each IP can send burst requests at
+// once, and then rps requests per second.
+type rateLimiter struct {
+	sync.Mutex
+	rps     float64
+	burst   float64
+	clock   beacon.Clock
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing rps requests per second with the
+// given burst per remote IP, or nil if rps is not positive, in which case every
+// request is allowed.
+func newRateLimiter(rps, burst int, clock beacon.Clock) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rps:     float64(rps),
+		burst:   float64(burst),
+		clock:   clock,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow returns errRateLimited if the remote IP of the request has no token
+// left, and nil otherwise.
+func (r *rateLimiter) allow(c context.Context) error {
+	if r == nil {
+		return nil
+	}
+	ip := peerAddress(c)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	r.Lock()
+	defer r.Unlock()
+	now := r.clock.Now()
+	b, ok := r.buckets[ip]
+	if !ok {
+		if len(r.buckets) >= maxRateBuckets {
+			r.forget(now)
+		}
+		b = &tokenBucket{tokens: r.burst, last: now}
+		r.buckets[ip] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * r.rps
+	if b.tokens > r.burst {
+		b.tokens = r.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return errRateLimited
+	}
+	b.tokens--
+	return nil
+}
+
+// forget removes the buckets that are full again, i.e. of the addresses that
+// could send a full burst anyway.
+func (r *rateLimiter) forget(now time.Time) {
+	for ip, b := range r.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*r.rps >= r.burst {
+			delete(r.buckets, ip)
+		}
+	}
+}
+
+// PublicInterceptors returns the interceptors the listeners run on the calls
+// to the public API, see net.PublicInterceptor: they reject the calls above the
+// rate limit of the remote IP, whatever the method, see WithRateLimit. The
+// rejected calls are logged by sample, see requestLogger.rateLimited.
+func (d *Drand) PublicInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(c context.Context, in interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := d.limiter.allow(c); err != nil {
+			d.reqLogger.rateLimited(c, info.FullMethod)
+			return nil, err
+		}
+		return handler(c, in)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := d.limiter.allow(ss.Context()); err != nil {
+			d.reqLogger.rateLimited(ss.Context(), info.FullMethod)
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
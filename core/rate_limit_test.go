@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/drand/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := test.NewFakeClock(time.Unix(1500000000, 0))
+	r := newRateLimiter(2, 3, clock)
+	from := func(addr string) context.Context {
+		tcp, err := net.ResolveTCPAddr("tcp", addr)
+		require.NoError(t, err)
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: tcp})
+	}
+	a1, a2, b := from("10.0.0.1:4000"), from("10.0.0.1:5000"), from("10.0.0.2:4000")
+
+	// the burst is shared by all the ports of an IP
+	require.NoError(t, r.allow(a1))
+	require.NoError(t, r.allow(a2))
+	require.NoError(t, r.allow(a1))
+	require.Equal(t, codes.ResourceExhausted, status.Code(r.allow(a2)))
+	require.NoError(t, r.allow(b))
+
+	clock.Advance(500 * time.Millisecond)
+	require.NoError(t, r.allow(a1))
+	require.Error(t, r.allow(a1))
+	// tokens do not accumulate above the burst
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.allow(a1))
+	}
+	require.Error(t, r.allow(a1))
+
+	// no limit by default
+	r = newRateLimiter(0, 0, clock)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, r.allow(a1))
+	}
+}
+
+func TestDrandRateLimit(t *testing.T) {
+	drands, dir := BatchNewDrand(4, true, WithInMemory(), WithRateLimit(1, 2))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+	addr := drands[0].priv.Public.Address()
+
+	// the DKG is not done, so the requests below the limit fail but are served
+	for i := 0; i < 2; i++ {
+		_, err := NewGrpcClient().LastPublic(addr, nil, false)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	_, err := NewGrpcClient().LastPublic(addr, nil, false)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	resp, err := http.Get("http://" + addr + "/api/public")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// the streams are limited as the other calls of the public API: the
+	// accepted ones wait for a beacon until the context is done
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := drand.NewRandomnessClient(conn)
+	var limited int
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		stream, err := client.PublicStream(ctx, &drand.PublicRandRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		cancel()
+		if status.Code(err) == codes.ResourceExhausted {
+			limited++
+		}
+	}
+	require.True(t, limited >= 8, "only %d streams limited", limited)
+}
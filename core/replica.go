@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/drand"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errReplica is returned by the calls a replica does not serve.
+var errReplica = newError(ErrNotSupported, "drand: not supported on replica")
+
+// LoadReplica returns a drand node serving the public randomness of the group
+// without holding a share: it never takes part in the DKG nor in the beacon
+// rounds, but syncs the beacons generated by the upstream node into its own
+// beacon store, once every beacon period. Only beacons verifying against the
+// distributed public key are stored. The store must hold the key pair of the
+// replica, the group and its distributed public key. The replica only serves
+// the public API, on which Private is not supported. The upstream node is
+// contacted over TLS unless the replica is insecure.
+func LoadReplica(s key.Store, c *Config, upstream string) (*Drand, error) {
+	if c.insecure == false && (c.certPath == "" || c.keyPath == "") {
+		return nil, errors.New("config: need to set WithInsecure if no certificate and private key path given")
+	}
+	priv, err := s.LoadKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.LoadGroup()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := s.LoadDistPublic()
+	if err != nil {
+		return nil, err
+	}
+	d := &Drand{
+		store:       s,
+		priv:        priv,
+		group:       group,
+		pub:         pub,
+		opts:        c,
+		reqLogger:   newRequestLogger(c.logSampling, c.logger),
+		limiter:     newRateLimiter(c.rateLimit, c.rateBurst, c.clock),
+		events:      newEventLog(c.events, c.logger),
+		feed:        newPublicFeed(c.logger),
+		dkgBuffer:   newPacketBuffer(),
+		upstream:    upstream,
+		stopReplica: make(chan bool),
+	}
+	a := c.ListenAddress(priv.Public.Address())
+	if c.publicListen != "" {
+		a = c.publicListen
+	}
+	if err := net.CheckListenAddress(a); err != nil {
+		return nil, err
+	}
+	d.listen = a
+	store, err := c.newBeaconStore()
+	if err != nil {
+		return nil, err
+	}
+	d.beaconStore = beacon.NewCallbackStore(store, d.beaconCallback)
+
+	l, err := d.listenerFor(a, c.certPath, c.keyPath, net.PublicAPI)
+	if err != nil {
+		d.beaconStore.Close()
+		return nil, err
+	}
+	d.gateway = net.Gateway{Listener: l}
+	go d.gateway.Start()
+	go d.replicate(NewClientFromConfig(c), d.stopReplica)
+	c.logger.Debug("drand: replica serving", "upstream", upstream, "addr", a)
+	return d, nil
+}
+
+// replicate syncs the beacons from the upstream node until the stop channel is
+// closed.
+func (d *Drand) replicate(client *Client, stop chan bool) {
+	for {
+		d.syncReplica(client)
+		select {
+		case <-d.opts.clock.After(d.opts.beaconPeriod):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// syncReplica fetches the last beacon of the upstream node and stores it, as
+// well as the rounds missed since the last beacon stored, or since the first
+// round, up to the maximum number of rounds caught up.
+func (d *Drand) syncReplica(client *Client) {
+	secure := !d.opts.insecure
+	last, err := client.LastPublic(d.upstream, d.pub, secure)
+	if err != nil {
+		d.opts.logger.Info("drand: replica could not sync", "upstream", d.upstream, "err", err)
+		return
+	}
+	from := uint64(1)
+	stored, err := d.beaconStore.Last()
+	switch {
+	case err == nil && stored.Round >= last.GetRound():
+		return
+	case err == nil:
+		from = stored.Round + 1
+	case err != beacon.ErrNoBeaconSaved:
+		d.opts.logger.Info("drand: replica could not read its beacon store", "err", err)
+		return
+	}
+	if d.opts.maxCatchup > 0 && last.GetRound()-from > d.opts.maxCatchup {
+		from = last.GetRound() - d.opts.maxCatchup
+	}
+	for round := from; round < last.GetRound(); round++ {
+		resp, err := client.PublicRound(d.upstream, d.pub, round, secure)
+		if status.Code(err) == codes.NotFound {
+			// the round has been skipped by the group
+			continue
+		} else if err != nil {
+			d.opts.logger.Info("drand: replica could not sync round", "round", round, "upstream", d.upstream, "err", err)
+			return
+		}
+		if err := d.putResponse(resp); err != nil {
+			d.opts.logger.Info("drand: replica could not save round", "round", round, "err", err)
+			return
+		}
+	}
+	if err := d.putResponse(last); err != nil {
+		d.opts.logger.Info("drand: replica could not save round", "round", last.GetRound(), "err", err)
+	}
+}
+
+// putResponse saves the beacon of the verified response in the beacon store.
+func (d *Drand) putResponse(resp *drand.PublicRandResponse) error {
+	sig, err := responseSignature(resp)
+	if err != nil {
+		return err
+	}
+	return d.beaconStore.Put(&beacon.Beacon{PreviousRand: resp.GetPrevious(), Round: resp.GetRound(), Signature: sig})
+}
+
+// isReplica returns true if the node is a replica loaded with LoadReplica.
+func (d *Drand) isReplica() bool {
+	return d.upstream != ""
+}
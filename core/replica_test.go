@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/drand/test"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReplica(t *testing.T) {
+	n := 3
+	period := time.Minute
+	clock := test.NewFakeClock(time.Unix(1500000000, 0))
+	drands, dir := BatchNewDrand(n, true, WithInMemory(), WithBeaconPeriod(period), WithClock(clock))
+	defer CloseAllDrands(drands)
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+
+	produced := make(chan *beacon.Beacon, n)
+	for _, d := range drands {
+		d.opts.beaconCbs = append(d.opts.beaconCbs, func(b *beacon.Beacon) {
+			produced <- b
+		})
+		go d.BeaconLoop(context.Background())
+	}
+	nextRound := func() {
+		for i := 0; i < n; i++ {
+			select {
+			case <-produced:
+			case <-time.After(5 * time.Second):
+				t.Fatal("round not produced")
+			}
+		}
+	}
+	nextRound()
+	clock.BlockUntil(n)
+	clock.Advance(period)
+	nextRound()
+
+	s := test.NewKeyStore()
+	priv := test.GenerateIDs(1)[0]
+	require.NoError(t, s.SaveKeyPair(priv))
+	require.NoError(t, s.SaveGroup(drands[0].group))
+	require.NoError(t, s.SaveDistPublic(drands[0].pub))
+	synced := make(chan uint64, 10)
+	replicaClock := test.NewFakeClock(clock.Now())
+	conf := NewConfig(WithInsecure(), WithInMemory(), WithBeaconPeriod(period), WithClock(replicaClock), WithBeaconCallback(func(b *beacon.Beacon) {
+		synced <- b.Round
+	}))
+	replica, err := LoadReplica(s, conf, drands[0].priv.Public.Address())
+	require.NoError(t, err)
+	defer replica.Stop()
+
+	// callbacks run concurrently so the rounds may come in any order
+	expect := func(rounds ...uint64) {
+		var got []uint64
+		for range rounds {
+			select {
+			case r := <-synced:
+				got = append(got, r)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("rounds %v not synced, got %v", rounds, got)
+			}
+		}
+		require.ElementsMatch(t, rounds, got)
+	}
+	// the missed rounds are synced first
+	expect(1, 2)
+	client := NewGrpcClient()
+	resp, err := client.LastPublic(priv.Public.Address(), drands[0].pub, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.GetRound())
+
+	clock.BlockUntil(n)
+	clock.Advance(period)
+	nextRound()
+	replicaClock.BlockUntil(1)
+	replicaClock.Advance(period)
+	expect(3)
+
+	_, err = client.Private(priv.Public)
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = replica.Setup(context.Background(), &dkg.DKGPacket{})
+	require.Equal(t, errReplica, err)
+	home, err := client.Home(priv.Public.Address(), false)
+	require.NoError(t, err)
+	require.True(t, home.GetReady())
+	_, err = replica.Public(context.Background(), &drand.PublicRandRequest{Round: 1})
+	require.NoError(t, err)
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/dedis/drand/log"
+	"google.golang.org/grpc/peer"
+)
+
+// limitedLogSample is the number of requests rejected by the rate limit for
+// which a single line is logged.
+const limitedLogSample = 100
+
+// requestLogger logs a sample of the requests received on the public facing
+// API: one request out of n is logged, so operators get a representative view
+// of the traffic without flooding the logs. Requests that resulted in an error
+// are always logged, except the ones rejected by the rate limit, which are
+// logged one out of limitedLogSample.
+type requestLogger struct {
+	n       uint64
+	counter uint64
+	// number of requests rejected by the rate limit
+	limited uint64
+	logger  log.Logger
+}
+
+func newRequestLogger(n int, l log.Logger) *requestLogger {
+	if n < 0 {
+		n = 0
+	}
+	return &requestLogger{n: uint64(n), logger: l}
+}
+
+// log logs the request if it has been sampled or if err is not nil. The cached
+// flag indicates whether the response has been served from the in-memory cache
+// instead of the beacon store.
+func (r *requestLogger) log(c context.Context, method string, cached bool, err error) {
+	if err != nil {
+		r.logger.Info("drand: request failed", "method", method, "from", peerAddress(c), "cached", cached, "err", err)
+		return
+	}
+	if r.n == 0 {
+		return
+	}
+	if atomic.AddUint64(&r.counter, 1)%r.n != 0 {
+		return
+	}
+	r.logger.Info("drand: request", "method", method, "from", peerAddress(c), "cached", cached, "sampled", r.n)
+}
+
+// rateLimited logs the first request rejected by the rate limit and then one
+// out of limitedLogSample, with the number of requests rejected so far, so a
+// client flooding the node does not flood the logs as well.
+func (r *requestLogger) rateLimited(c context.Context, method string) {
+	n := atomic.AddUint64(&r.limited, 1)
+	if n%limitedLogSample != 1 {
+		return
+	}
+	r.logger.Info("drand: request rate limited", "method", method, "from", peerAddress(c), "rejected", n)
+}
+
+// peerAddress returns the address of the remote peer issuing the request if
+// known.
+func peerAddress(c context.Context) string {
+	p, ok := peer.FromContext(c)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	return p.Addr.String()
+}
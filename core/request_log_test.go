@@ -0,0 +1,59 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dedis/drand/log"
+	"github.com/nikkolasg/slog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLoggerSampling(t *testing.T) {
+	var buff bytes.Buffer
+	oldOut, oldLvl := slog.Output, slog.Level
+	slog.Output, slog.Level = &buff, slog.LevelInfo
+	defer func() { slog.Output, slog.Level = oldOut, oldLvl }()
+
+	lines := func() int {
+		return strings.Count(buff.String(), "\n")
+	}
+
+	r := newRequestLogger(5, log.DefaultLogger())
+	for i := 0; i < 10; i++ {
+		r.log(context.Background(), "public", true, nil)
+	}
+	require.Equal(t, 2, lines())
+	require.Contains(t, buff.String(), "method=public from=unknown cached=true sampled=5")
+
+	// errors are always logged
+	r.log(context.Background(), "private", false, errors.New("invalid"))
+	require.Equal(t, 3, lines())
+
+	// no sampling by default
+	buff.Reset()
+	r = newRequestLogger(0, log.DefaultLogger())
+	for i := 0; i < 10; i++ {
+		r.log(context.Background(), "public", false, nil)
+	}
+	require.Equal(t, 0, lines())
+}
+
+func TestRequestLoggerRateLimited(t *testing.T) {
+	var buff bytes.Buffer
+	oldOut, oldLvl := slog.Output, slog.Level
+	slog.Output, slog.Level = &buff, slog.LevelInfo
+	defer func() { slog.Output, slog.Level = oldOut, oldLvl }()
+
+	r := newRequestLogger(0, log.DefaultLogger())
+	for i := 0; i < 2*limitedLogSample; i++ {
+		r.rateLimited(context.Background(), "/drand.Randomness/PublicStream")
+	}
+	require.Equal(t, 2, strings.Count(buff.String(), "\n"))
+	require.Contains(t, buff.String(), "method=/drand.Randomness/PublicStream from=unknown rejected=1")
+	require.Contains(t, buff.String(), fmt.Sprintf("rejected=%d", limitedLogSample+1))
+}
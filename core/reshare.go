@@ -0,0 +1,225 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/dedis/drand/dkg"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/kyber"
+)
+
+// PreviousFolderName is the name of the folder, relative to the configuration
+// folder, in which the share and the group of a node are saved before being
+// replaced by a resharing.
+const PreviousFolderName = "previous"
+
+// NewReshareDrand returns a drand struct for a node joining an existing group
+// through a resharing, ready to run StartReshare or WaitReshare. It needs the
+// current group and its distributed public key, which does not change. The
+// public polynomial of the current group is fetched from its nodes when the
+// resharing starts.
+func NewReshareDrand(s key.Store, current *key.Group, pub *key.DistPublic, c *Config) (*Drand, error) {
+	d, err := initDrand(s, c)
+	if err != nil {
+		return nil, err
+	}
+	d.group = current
+	d.pub = pub
+	return d, nil
+}
+
+// StartReshare starts the redistribution of the distributed key of the current
+// group to the nodes of the new group, by sending the first packet of the
+// protocol to every node of the new group. The distributed public key stays
+// the same, so the beacons remain verifiable with it. At least a threshold of
+// the nodes of the current group must be in the new group and take part in the
+// protocol, as well as the nodes joining the group. Nodes leaving the group do
+// not take part. If some nodes of the current group do not deal, the resharing
+// goes on at the DKG timeout without them, as long as a threshold of them did,
+// and the nodes missing are logged. The nodes then confirm to each other the
+// distributed polynomial they reshared, within the DKG timeout again, before
+// using their new share. It returns nil if the resharing finished
+// successfully or an error otherwise, in which case the current share is kept.
+func (d *Drand) StartReshare(newGroup *key.Group) error {
+	if err := d.setupReshare(newGroup); err != nil {
+		return err
+	}
+	d.events.emit(&Event{Type: EventReshareStarted, Leader: true, Nodes: newGroup.Len(), Threshold: newGroup.Threshold})
+	d.dkg.Start()
+	return d.waitReshare(newGroup)
+}
+
+// WaitReshare waits messages from the resharing protocol started by a leader,
+// and then waits until completion. See StartReshare.
+func (d *Drand) WaitReshare(newGroup *key.Group) error {
+	if err := d.setupReshare(newGroup); err != nil {
+		return err
+	}
+	d.events.emit(&Event{Type: EventReshareStarted, Nodes: newGroup.Len(), Threshold: newGroup.Threshold})
+	return d.waitReshare(newGroup)
+}
+
+// setupReshare replaces the DKG handler by one resharing the distributed key of
+// the current group to the new group.
+func (d *Drand) setupReshare(newGroup *key.Group) error {
+	if err := checkGroupSafety(newGroup, d.opts); err != nil {
+		return err
+	}
+	scheme, err := key.SchemeByName(newGroup.Scheme)
+	if err != nil {
+		return err
+	}
+	d.state.Lock()
+	current, pub, share := d.group, d.pub, d.share
+	d.state.Unlock()
+	var commits []kyber.Point
+	if share == nil && pub != nil {
+		if commits, err = d.fetchCommits(current, pub); err != nil {
+			return err
+		}
+	}
+	d.state.Lock()
+	if d.pub == nil {
+		d.state.Unlock()
+		return errors.New("drand: resharing needs the distributed public key of the current group")
+	}
+	if !newGroup.Contains(d.priv.Public) {
+		d.state.Unlock()
+		return errors.New("drand: own public key not found in the new group")
+	}
+	if current, err := key.SchemeByName(d.group.Scheme); err == nil && current != scheme {
+		d.state.Unlock()
+		return fmt.Errorf("drand: the new group uses scheme %s, not the scheme %s of the current group", scheme.Name, current.Name)
+	}
+	conf := &dkg.Config{
+		Suite:    scheme.DKGSuite(),
+		Group:    newGroup,
+		Timeout:  d.opts.dkgTimeout,
+		FailFast: d.opts.dkgFailFast,
+		OldGroup: d.group,
+		Share:    d.share,
+		Public:   d.pub,
+		Commits:  commits,
+	}
+	h, err := dkg.NewHandler(d.priv, conf, d.dkgNetwork())
+	if err != nil {
+		d.state.Unlock()
+		return err
+	}
+	d.dkg = h
+	d.resharing = true
+	d.reshareGroup = newGroup
+	d.state.Unlock()
+	d.dkgBuffer.flush(h, d.opts.logger)
+	return nil
+}
+
+// fetchCommits returns the distributed public polynomial of the current group,
+// which a node joining the group needs to check the deals of the nodes of the
+// current group. At least one of them must serve it, and all the nodes serving
+// it must serve the same one.
+func (d *Drand) fetchCommits(current *key.Group, pub *key.DistPublic) ([]kyber.Point, error) {
+	client := NewClientFromConfig(d.opts)
+	var commits []kyber.Point
+	for _, n := range current.Nodes {
+		poly, err := client.PublicPoly(n.Address(), pub, n.IsTLS())
+		if err != nil {
+			d.opts.logger.Info("drand: could not fetch the public polynomial", "addr", n.Address(), "err", err)
+			continue
+		}
+		_, c := poly.Info()
+		if commits == nil {
+			commits = c
+			continue
+		}
+		if len(c) != len(commits) {
+			return nil, fmt.Errorf("drand: %s serves another public polynomial than the current group", n.Address())
+		}
+		for i := range c {
+			if !c[i].Equal(commits[i]) {
+				return nil, fmt.Errorf("drand: %s serves another public polynomial than the current group", n.Address())
+			}
+		}
+	}
+	if commits == nil {
+		return nil, errors.New("drand: no node of the current group serves its public polynomial")
+	}
+	return commits, nil
+}
+
+func (d *Drand) waitReshare(newGroup *key.Group) error {
+	err := d.runReshare(newGroup)
+	d.state.Lock()
+	d.resharing = false
+	d.reshareGroup = nil
+	d.state.Unlock()
+	if err != nil {
+		d.events.emit(&Event{Type: EventReshareFailed, Error: err.Error()})
+		return err
+	}
+	d.events.emit(&Event{Type: EventReshareCompleted, Nodes: newGroup.Len(), Threshold: newGroup.Threshold})
+	return nil
+}
+
+// runReshare waits for the new share and, once received, saves the current
+// share and group in the PreviousFolderName folder before replacing them by
+// the new ones. A running beacon is stopped and must be restarted with
+// BeaconLoop.
+func (d *Drand) runReshare(newGroup *key.Group) error {
+	var share *key.Share
+	select {
+	case s := <-d.dkg.WaitShare():
+		ks := key.Share(s)
+		share = &ks
+	case err := <-d.dkg.WaitError():
+		return err
+	}
+	if idx, _ := newGroup.Index(d.priv.Public); share.Share.I != idx {
+		return errors.New("drand: new share index differs from own index in the new group")
+	}
+	if err := d.savePrevious(); err != nil {
+		return err
+	}
+	if err := d.store.SaveShare(share); err != nil {
+		return err
+	}
+	if err := d.store.SaveDistPublic(d.pub); err != nil {
+		return err
+	}
+	if err := d.store.SaveGroup(newGroup); err != nil {
+		return err
+	}
+	if err := d.saveTranscript(); err != nil {
+		return err
+	}
+	d.state.Lock()
+	d.share = share
+	d.group = newGroup
+	if d.beacon != nil {
+		// stopping the beacon closes its store, it is reopened by initBeacon
+		d.beacon.Stop()
+		d.beacon = nil
+		d.beaconStore = nil
+	}
+	d.state.Unlock()
+	d.opts.logger.Info("drand: resharing finished", "nodes", newGroup.Len(), "threshold", newGroup.Threshold)
+	return d.initBeacon()
+}
+
+// savePrevious saves the current share and group, if any, in the
+// PreviousFolderName folder. Nothing is saved by in-memory nodes.
+func (d *Drand) savePrevious() error {
+	if d.share == nil || d.opts.inMemory {
+		return nil
+	}
+	prev := key.NewFileStore(path.Join(d.opts.ConfigFolder(), PreviousFolderName))
+	if err := prev.SaveShare(d.share); err != nil {
+		return err
+	}
+	if err := prev.SaveDistPublic(d.pub); err != nil {
+		return err
+	}
+	return prev.SaveGroup(d.group)
+}
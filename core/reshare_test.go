@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/test"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrandReshare(t *testing.T) {
+	n := 5
+	opts := []ConfigOption{WithInMemory(), WithBeaconPeriod(500 * time.Millisecond)}
+	drands, dir := BatchNewDrand(n, true, opts...)
+	defer CloseAllDrands(drands[:n-1])
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for _, d := range drands[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitDKG())
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, drands[0].StartDKG())
+	wg.Wait()
+	public := drands[0].share.Public()
+
+	// the last node leaves and a new one joins
+	drands[n-1].Stop()
+	joining := test.GenerateIDs(1)[0]
+	store := test.NewKeyStore()
+	store.SaveKeyPair(joining)
+	joiner, err := NewReshareDrand(store, drands[0].group, public, NewConfig(append(opts, WithInsecure())...))
+	require.NoError(t, err)
+	defer joiner.Stop()
+	nodes := append(append([]*Drand{}, drands[:n-1]...), joiner)
+	var ids []*key.Identity
+	for _, d := range nodes {
+		ids = append(ids, d.priv.Public)
+	}
+	newGroup := key.NewGroup(ids, 3)
+
+	wg.Add(len(nodes) - 1)
+	for _, d := range nodes[1:] {
+		go func(d *Drand) {
+			require.NoError(t, d.WaitReshare(newGroup))
+			wg.Done()
+		}(d)
+	}
+	require.NoError(t, nodes[0].StartReshare(newGroup))
+	wg.Wait()
+
+	for _, d := range nodes {
+		require.True(t, public.Key.Equal(d.share.Public().Key))
+		require.Equal(t, newGroup.Len(), d.group.Len())
+		g, err := d.store.LoadGroup()
+		require.NoError(t, err)
+		require.Equal(t, 3, g.Threshold)
+	}
+
+	// the new group produces beacons verifiable with the same key
+	produced := make(chan *beacon.Beacon, 1)
+	nodes[0].opts.beaconCbs = append(nodes[0].opts.beaconCbs, func(b *beacon.Beacon) {
+		select {
+		case produced <- b:
+		default:
+		}
+	})
+	go nodes[0].BeaconLoop(context.Background())
+	select {
+	case b := <-produced:
+		require.NoError(t, bls.Verify(key.Pairing, public.Key, beacon.Message(b.PreviousRand, b.Round), b.Signature))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no beacon produced")
+	}
+}
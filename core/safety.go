@@ -0,0 +1,35 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/dedis/drand/key"
+)
+
+// DefaultMinimumGroupSize is the minimum number of members a group must have
+// for drand to start when not running in insecure mode, unless weak groups
+// are explicitly allowed.
+const DefaultMinimumGroupSize = 4
+
+// checkGroupSafety returns an error if the group is too weak for a production
+// beacon: the threshold must be above half of the nodes, so that no minority
+// of nodes can produce the randomness on its own, and the group must have at
+// least the configured minimum number of members. The check is skipped in
+// insecure mode or when weak groups are allowed.
+func checkGroupSafety(g *key.Group, c *Config) error {
+	if c.insecure || c.allowWeak {
+		return nil
+	}
+	var reason string
+	n := g.Len()
+	switch {
+	case n < c.minGroupSize:
+		reason = fmt.Sprintf("the group has %d members, less than the minimum of %d", n, c.minGroupSize)
+	case 2*g.Threshold <= n:
+		reason = fmt.Sprintf("the threshold %d is not above half of the %d members: a minority of nodes can produce the randomness on its own", g.Threshold, n)
+	default:
+		return nil
+	}
+	c.logger.Error("drand: refusing to start with an insecure group, use a higher threshold or pass --allow-weak to start anyway", "reason", reason, "min_threshold", n/2+1)
+	return fmt.Errorf("drand: insecure group: %s", reason)
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/dedis/drand/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckGroupSafety(t *testing.T) {
+	_, group := test.BatchTLSIdentities(5)
+	conf := NewConfig()
+	require.NoError(t, checkGroupSafety(group, conf))
+
+	group.Threshold = 2
+	err := checkGroupSafety(group, conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "threshold 2")
+	require.NoError(t, checkGroupSafety(group, NewConfig(WithAllowWeak())))
+	require.NoError(t, checkGroupSafety(group, NewConfig(WithInsecure())))
+
+	_, small := test.BatchTLSIdentities(3)
+	err = checkGroupSafety(small, conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "minimum of 4")
+	require.NoError(t, checkGroupSafety(small, NewConfig(WithMinimumGroupSize(3))))
+
+	// the check happens before anything is started
+	_, err = NewDrand(test.NewKeyStore(), small, conf)
+	require.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dedis/drand/fs"
+)
+
+// SeedFileName is the name of the file, in the database folder, in which the
+// seed of the chain is saved, hex encoded, next to the beacons.
+const SeedFileName = "seed"
+
+// PeriodFileName is the name of the file, in the database folder, in which the
+// beacon period of the chain is saved next to the beacons.
+const PeriodFileName = "period"
+
+// MaxSeedSize is the maximum size in bytes of the seed of the chain.
+const MaxSeedSize = 1024
+
+// CheckSeed returns an error if the seed is empty or longer than MaxSeedSize.
+func CheckSeed(seed []byte) error {
+	if len(seed) == 0 {
+		return errors.New("drand: empty seed")
+	}
+	if len(seed) > MaxSeedSize {
+		return fmt.Errorf("drand: seed of %d bytes is too long, at most %d bytes", len(seed), MaxSeedSize)
+	}
+	return nil
+}
+
+// beaconSeed returns the seed of the chain, see checkChain, and saves the seed
+// and the beacon period of the chain in the database folder. Nothing is saved
+// by in-memory nodes.
+func (d *Drand) beaconSeed() ([]byte, error) {
+	seed, err := d.checkChain()
+	if err != nil {
+		return nil, err
+	}
+	if d.opts.inMemory {
+		return seed, nil
+	}
+	if err := os.MkdirAll(d.opts.DBFolder(), fs.SecureFolderMode); err != nil {
+		return nil, err
+	}
+	seedPath := path.Join(d.opts.DBFolder(), SeedFileName)
+	if err := ioutil.WriteFile(seedPath, []byte(hex.EncodeToString(seed)+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	periodPath := path.Join(d.opts.DBFolder(), PeriodFileName)
+	return seed, ioutil.WriteFile(periodPath, []byte(d.opts.beaconPeriod.String()+"\n"), 0644)
+}
+
+// checkChain returns the seed of the chain: the seed saved in the database
+// folder if any, or the seed given with WithSeed or DefaultSeed otherwise. It
+// returns an error if the seed given with WithSeed or the beacon period differ
+// from the ones saved when the beacon first started, so a restarting node never
+// produces beacons incompatible with the chain saved. With WithForceChain, the
+// given seed and period are used instead.
+func (d *Drand) checkChain() ([]byte, error) {
+	seed := d.opts.seed
+	if seed == nil {
+		seed = DefaultSeed
+	}
+	if err := CheckSeed(seed); err != nil {
+		return nil, err
+	}
+	if d.opts.inMemory {
+		return seed, nil
+	}
+	seedPath := path.Join(d.opts.DBFolder(), SeedFileName)
+	saved, err := readChainFile(seedPath)
+	if err != nil {
+		return nil, err
+	} else if saved != "" {
+		savedSeed, err := hex.DecodeString(saved)
+		if err != nil {
+			return nil, fmt.Errorf("drand: invalid seed file %s: %s", seedPath, err)
+		}
+		if d.opts.seed == nil {
+			seed = savedSeed
+		} else if !bytes.Equal(savedSeed, d.opts.seed) {
+			if err := d.chainMismatch(fmt.Errorf("drand: seed differs from the seed of the chain saved in %s", seedPath)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	periodPath := path.Join(d.opts.DBFolder(), PeriodFileName)
+	saved, err = readChainFile(periodPath)
+	if err != nil {
+		return nil, err
+	} else if saved != "" {
+		period, err := time.ParseDuration(saved)
+		if err != nil {
+			return nil, fmt.Errorf("drand: invalid period file %s: %s", periodPath, err)
+		}
+		if period != d.opts.beaconPeriod {
+			if err := d.chainMismatch(fmt.Errorf("drand: period %s differs from the period %s of the chain saved in %s", d.opts.beaconPeriod, period, periodPath)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return seed, nil
+}
+
+// chainMismatch returns the error unless WithForceChain is given, in which
+// case it is only logged.
+func (d *Drand) chainMismatch(err error) error {
+	if !d.opts.forceChain {
+		return err
+	}
+	d.opts.logger.Warn("drand: chain mismatch overridden", "err", err)
+	return nil
+}
+
+// readChainFile returns the content of the file without surrounding
+// whitespace, or an empty string if the file does not exist.
+func readChainFile(name string) (string, error) {
+	buff, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(buff)), nil
+}
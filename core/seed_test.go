@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeaconSeed(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "drand-seed")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+	seedOf := func(opts ...ConfigOption) ([]byte, error) {
+		d := &Drand{opts: NewConfig(append([]ConfigOption{WithDbFolder(tmp)}, opts...)...)}
+		return d.beaconSeed()
+	}
+
+	seed := []byte{0x00, 0xff, 0x42}
+	s, err := seedOf(WithSeed(seed))
+	require.NoError(t, err)
+	require.Equal(t, seed, s)
+	// the saved seed is used when none is given
+	s, err = seedOf()
+	require.NoError(t, err)
+	require.Equal(t, seed, s)
+	_, err = seedOf(WithSeed(DefaultSeed))
+	require.Error(t, err)
+	// nor does the beacon loop start
+	mismatch := &Drand{opts: NewConfig(WithDbFolder(tmp), WithSeed(DefaultSeed))}
+	require.Error(t, mismatch.BeaconLoop(context.Background()))
+
+	// the period of the chain is saved too
+	_, err = seedOf(WithBeaconPeriod(time.Second))
+	require.Error(t, err)
+	// unless forced, which replaces the saved seed and period
+	s, err = seedOf(WithBeaconPeriod(time.Second), WithSeed(DefaultSeed), WithForceChain())
+	require.NoError(t, err)
+	require.Equal(t, DefaultSeed, s)
+	s, err = seedOf(WithBeaconPeriod(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, DefaultSeed, s)
+	d := &Drand{opts: NewConfig(WithDbFolder(tmp))}
+	_, err = d.checkChain()
+	require.Error(t, err)
+
+	// nothing is saved in memory
+	s, err = seedOf(WithInMemory(), WithSeed(DefaultSeed))
+	require.NoError(t, err)
+	require.Equal(t, DefaultSeed, s)
+
+	_, err = seedOf(WithInMemory(), WithSeed([]byte{}))
+	require.Error(t, err)
+	_, err = seedOf(WithInMemory(), WithSeed([]byte(strings.Repeat("a", MaxSeedSize+1))))
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/dedis/drand/log"
+	"github.com/dedis/drand/protobuf/drand"
+)
+
+// FeedBufferSize is the number of beacons buffered for each client following
+// the beacons with PublicStream. Beacons are dropped for clients too slow to
+// consume them, so they never block the beacon loop.
+const FeedBufferSize = 16
+
+// publicFeed dispatches each new beacon to the clients following them.
+type publicFeed struct {
+	sync.Mutex
+	subs   map[chan *drand.PublicRandResponse]bool
+	closed bool
+	logger log.Logger
+}
+
+func newPublicFeed(l log.Logger) *publicFeed {
+	return &publicFeed{subs: make(map[chan *drand.PublicRandResponse]bool), logger: l}
+}
+
+// subscribe returns a channel on which the new beacons are sent. It returns
+// false if the feed is closed.
+func (f *publicFeed) subscribe() (chan *drand.PublicRandResponse, bool) {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return nil, false
+	}
+	ch := make(chan *drand.PublicRandResponse, FeedBufferSize)
+	f.subs[ch] = true
+	return ch, true
+}
+
+// unsubscribe removes and closes the given channel if it is still in the feed.
+func (f *publicFeed) unsubscribe(ch chan *drand.PublicRandResponse) {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.subs[ch]; ok {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends the beacon to every subscriber without blocking: the beacon
+// is dropped for subscribers whose buffer is full.
+func (f *publicFeed) publish(resp *drand.PublicRandResponse) {
+	f.Lock()
+	defer f.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- resp:
+		default:
+			f.logger.Debug("drand: slow stream consumer, dropping round", "round", resp.GetRound())
+		}
+	}
+}
+
+// close closes the channels of all subscribers and rejects new ones.
+func (f *publicFeed) close() {
+	f.Lock()
+	defer f.Unlock()
+	f.closed = true
+	for ch := range f.subs {
+		close(ch)
+	}
+	f.subs = make(map[chan *drand.PublicRandResponse]bool)
+}
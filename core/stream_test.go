@@ -0,0 +1,34 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/dedis/drand/log"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicFeed(t *testing.T) {
+	feed := newPublicFeed(log.DefaultLogger())
+	slow, ok := feed.subscribe()
+	require.True(t, ok)
+	gone, ok := feed.subscribe()
+	require.True(t, ok)
+	feed.unsubscribe(gone)
+	_, ok = <-gone
+	require.False(t, ok)
+
+	// a slow subscriber never blocks the publisher
+	for i := 0; i < 2*FeedBufferSize; i++ {
+		feed.publish(&drand.PublicRandResponse{Round: uint64(i + 1)})
+	}
+	require.Len(t, slow, FeedBufferSize)
+	require.Equal(t, uint64(1), (<-slow).GetRound())
+
+	feed.close()
+	for range slow {
+	}
+	feed.unsubscribe(slow)
+	_, ok = feed.subscribe()
+	require.False(t, ok)
+}
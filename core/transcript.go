@@ -0,0 +1,46 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/dedis/drand/dkg"
+	"github.com/dedis/drand/fs"
+)
+
+// TranscriptFileName is the name of the file, in the database folder, in which
+// the transcript of the last DKG or resharing of the node is saved as JSON, to
+// be audited with dkg.VerifyTranscript.
+const TranscriptFileName = "dkg_transcript.json"
+
+// saveTranscript saves the transcript of the DKG that just finished in the
+// database folder. Nothing is saved by in-memory nodes.
+func (d *Drand) saveTranscript() error {
+	if d.opts.inMemory {
+		return nil
+	}
+	t, err := d.dkg.Transcript()
+	if err != nil {
+		return err
+	}
+	buff, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.opts.DBFolder(), fs.SecureFolderMode); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(d.opts.DBFolder(), TranscriptFileName), append(buff, '\n'), 0644)
+}
+
+// LoadTranscript reads a transcript saved by a node after a DKG.
+func LoadTranscript(name string) (*dkg.Transcript, error) {
+	buff, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t := new(dkg.Transcript)
+	return t, json.Unmarshal(buff, t)
+}
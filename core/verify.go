@@ -0,0 +1,269 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/pairing"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
+)
+
+// ChainError is returned by VerifyChain and indicates the first round of the
+// chain that does not verify.
+type ChainError struct {
+	Round uint64
+	Err   error
+}
+
+func (c *ChainError) Error() string {
+	return fmt.Sprintf("round %d: %s", c.Round, c.Err)
+}
+
+// Unwrap returns the error of the round, of kind ErrVerification when the
+// beacon does not verify.
+func (c *ChainError) Unwrap() error {
+	return c.Err
+}
+
+// VerifyChain verifies offline a sequence of beacons ordered by round: the
+// randomness of each beacon must be a valid signature of the distributed key
+// and the previous randomness of each beacon must be the randomness of the
+// beacon before it. It returns a *ChainError for the first beacon that does
+// not verify.
+func VerifyChain(pub *key.DistPublic, beacons []*drand.PublicRandResponse) error {
+	return verifyChainBatch(key.DefaultScheme, beacon.Message, pub.Key, beacons)
+}
+
+// verifyChainBatch verifies the sequence of beacons as VerifyChain, checking
+// the randomness of all the beacons at once with a batch verification. Only if
+// the batch does not verify is each beacon checked, to find the first invalid
+// one.
+func verifyChainBatch(scheme *key.Scheme, message beacon.MessageFunc, public kyber.Point, beacons []*drand.PublicRandResponse) error {
+	verify := func(*drand.PublicRandResponse) error { return nil }
+	if err := batchVerify(scheme, message, public, beacons); err != nil {
+		verify = func(b *drand.PublicRandResponse) error {
+			return verifyBeacon(scheme, message, public, b)
+		}
+	}
+	return verifyChain(verify, beacons)
+}
+
+// verifyChain verifies the sequence of beacons as VerifyChain, with the given
+// function verifying the randomness of each beacon.
+func verifyChain(verify func(*drand.PublicRandResponse) error, beacons []*drand.PublicRandResponse) error {
+	for i, b := range beacons {
+		if err := verify(b); err != nil {
+			return &ChainError{Round: b.GetRound(), Err: newError(ErrVerification, "invalid randomness: %w", err)}
+		}
+		if i == 0 {
+			continue
+		}
+		prev := beacons[i-1]
+		if b.GetRound() <= prev.GetRound() {
+			return &ChainError{Round: b.GetRound(), Err: newError(ErrVerification, "comes after round %d", prev.GetRound())}
+		}
+		prevSig, err := responseSignature(prev)
+		if err != nil {
+			return &ChainError{Round: prev.GetRound(), Err: newError(ErrVerification, "%w", err)}
+		}
+		if !bytes.Equal(b.GetPrevious(), prevSig) {
+			return &ChainError{Round: b.GetRound(), Err: newError(ErrVerification, "previous randomness differs from the randomness of round %d", prev.GetRound())}
+		}
+	}
+	return nil
+}
+
+// ReadBeacons reads beacons, either as a JSON array or as a sequence of JSON
+// objects. Each beacon is either in the versioned JSON format of beacon.Beacon,
+// as printed by "drand fetch public", or in the JSON format of
+// PublicRandResponse, as printed by earlier versions.
+func ReadBeacons(r io.Reader) ([]*drand.PublicRandResponse, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil, errors.New("no beacon found")
+	} else if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(br)
+	var raws []json.RawMessage
+	if first == '[' {
+		if err := dec.Decode(&raws); err != nil {
+			return nil, err
+		}
+	} else {
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			raws = append(raws, raw)
+		}
+	}
+	beacons := make([]*drand.PublicRandResponse, 0, len(raws))
+	for _, raw := range raws {
+		b, err := decodeBeacon(raw)
+		if err != nil {
+			return nil, err
+		}
+		beacons = append(beacons, b)
+	}
+	return beacons, nil
+}
+
+// decodeBeacon decodes a beacon in the versioned JSON format of beacon.Beacon
+// if it has a version, or in the JSON format of PublicRandResponse otherwise.
+func decodeBeacon(raw json.RawMessage) (*drand.PublicRandResponse, error) {
+	var versioned struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &versioned); err != nil {
+		return nil, err
+	}
+	if versioned.Version == nil {
+		resp := new(drand.PublicRandResponse)
+		return resp, json.Unmarshal(raw, resp)
+	}
+	b := new(beacon.Beacon)
+	if err := json.Unmarshal(raw, b); err != nil {
+		return nil, err
+	}
+	return beaconResponse(b), nil
+}
+
+// beaconResponse returns the response carrying the given beacon: its
+// signature and the randomness derived from it.
+func beaconResponse(b *beacon.Beacon) *drand.PublicRandResponse {
+	return &drand.PublicRandResponse{
+		Round:      b.Round,
+		Previous:   b.PreviousRand,
+		Randomness: b.Randomness(),
+		Signature:  b.Signature,
+	}
+}
+
+// responseSignature returns the uncompressed signature of the response, after
+// checking that the randomness is its hash. The responses of the nodes that
+// predate the signature field carry the signature as randomness, which is then
+// returned.
+func responseSignature(resp *drand.PublicRandResponse) ([]byte, error) {
+	if len(resp.GetSignature()) == 0 {
+		return key.DecompressSignature(resp.GetRandomness())
+	}
+	sig, err := key.DecompressSignature(resp.GetSignature())
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(resp.GetRandomness(), beacon.RandomnessFromSignature(sig)) {
+		return nil, errors.New("randomness is not the hash of the signature")
+	}
+	return sig, nil
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c, r.UnreadByte()
+	}
+}
+
+// VerifyBeacon checks that the randomness of the response is a valid BLS
+// signature of the distributed public key over the message of beacon.Message,
+// built from its round and previous randomness. It lets callers verify a
+// response obtained by other means than a Client, exactly as a Client does.
+// The error returned is of kind ErrVerification.
+func VerifyBeacon(pub kyber.Point, resp *drand.PublicRandResponse) error {
+	if err := verifyBeacon(key.DefaultScheme, beacon.Message, pub, resp); err != nil {
+		return newError(ErrVerification, "drand: invalid beacon: %w", err)
+	}
+	return nil
+}
+
+// VerifyBeaconBatch checks the randomness of many beacons, as VerifyBeacon
+// does for each of them, but with a single batch verification of all of them,
+// which is much faster for a large range of rounds. If the batch does not
+// verify, the beacons are checked one by one and a *ChainError is returned for
+// the first beacon that does not verify. Unlike VerifyChain, it does not check
+// that the beacons link to each other.
+func VerifyBeaconBatch(pub kyber.Point, responses []*drand.PublicRandResponse) error {
+	if batchVerify(key.DefaultScheme, beacon.Message, pub, responses) == nil {
+		return nil
+	}
+	for _, resp := range responses {
+		if err := VerifyBeacon(pub, resp); err != nil {
+			return &ChainError{Round: resp.GetRound(), Err: newError(ErrVerification, "invalid randomness: %w", err)}
+		}
+	}
+	return nil
+}
+
+// batchVerify checks the randomness of all the responses at once. Each
+// signature S_i of a message m_i is weighted by a random scalar r_i, and the
+// check e(sum r_i*S_i, B2) == e(sum r_i*H(m_i), X) costs two pairings instead of
+// two per response. It holds if all the signatures are valid, and with a
+// negligible probability otherwise, the random weights preventing invalid
+// signatures from cancelling out.
+func batchVerify(scheme *key.Scheme, message beacon.MessageFunc, public kyber.Point, resps []*drand.PublicRandResponse) error {
+	suite := scheme.Pairing
+	sigs := suite.G1().Point().Null()
+	hashes := suite.G1().Point().Null()
+	for _, resp := range resps {
+		signature, err := responseSignature(resp)
+		if err != nil {
+			return err
+		}
+		sig := suite.G1().Point()
+		if err := sig.UnmarshalBinary(signature); err != nil {
+			return err
+		}
+		r := suite.G1().Scalar().Pick(random.New())
+		hm := hashToG1(suite, message(resp.GetPrevious(), resp.GetRound()))
+		sigs = sigs.Add(sigs, suite.G1().Point().Mul(r, sig))
+		hashes = hashes.Add(hashes, suite.G1().Point().Mul(r, hm))
+	}
+	left := suite.Pair(hashes, public)
+	right := suite.Pair(sigs, suite.G2().Point().Base())
+	if !left.Equal(right) {
+		return errors.New("bls: invalid batch of signatures")
+	}
+	return nil
+}
+
+// hashToG1 hashes the message to a point of G1 as bls.Sign and bls.Verify do.
+func hashToG1(suite pairing.Suite, msg []byte) kyber.Point {
+	h := suite.Hash()
+	h.Write(msg)
+	x := suite.G1().Scalar().SetBytes(h.Sum(nil))
+	return suite.G1().Point().Mul(x, nil)
+}
+
+// verifyBeacon checks that the signature of the response, compressed or not,
+// is a valid BLS signature of the distributed key of the scheme over the
+// message built from its round and previous signature, and that the randomness
+// is its hash.
+func verifyBeacon(scheme *key.Scheme, message beacon.MessageFunc, public kyber.Point, resp *drand.PublicRandResponse) error {
+	sig, err := responseSignature(resp)
+	if err != nil {
+		return err
+	}
+	msg := message(resp.GetPrevious(), resp.GetRound())
+	return bls.Verify(scheme.Pairing, public, msg, sig)
+}
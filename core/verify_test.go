@@ -0,0 +1,173 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dedis/drand/beacon"
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyChain(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	public := &key.DistPublic{Key: pub}
+	var chain []*drand.PublicRandResponse
+	prev := []byte("seed")
+	for round := uint64(1); round <= 4; round++ {
+		b := signedResponse(t, priv, round, prev)
+		chain = append(chain, b)
+		prev = b.Signature
+	}
+	require.NoError(t, VerifyChain(public, chain))
+	for _, b := range chain {
+		require.NoError(t, VerifyBeacon(pub, b))
+		require.NoError(t, NewGrpcClient().Verify(pub, b))
+	}
+
+	// the signature may be compressed
+	var compressed []*drand.PublicRandResponse
+	for _, b := range chain {
+		c, err := compressResponse(b)
+		require.NoError(t, err)
+		require.Len(t, c.Signature, key.CompressedSignatureSize)
+		require.NoError(t, NewGrpcClient().Verify(pub, c))
+		compressed = append(compressed, c)
+	}
+	require.NoError(t, VerifyChain(public, compressed))
+	require.NoError(t, VerifyBeaconBatch(pub, compressed))
+
+	// nodes predating the signature field send the signature as randomness
+	var legacy []*drand.PublicRandResponse
+	for _, b := range chain {
+		legacy = append(legacy, &drand.PublicRandResponse{Round: b.Round, Previous: b.Previous, Randomness: b.Signature})
+	}
+	require.NoError(t, VerifyChain(public, legacy))
+	require.NoError(t, VerifyBeaconBatch(pub, legacy))
+	for _, b := range legacy {
+		require.NoError(t, NewGrpcClient().Verify(pub, b))
+	}
+
+	// the client normalizes all of them to the signature and its randomness
+	for i, b := range chain {
+		for _, resp := range []*drand.PublicRandResponse{compressed[i], legacy[i]} {
+			require.NoError(t, decompress(resp))
+			require.Equal(t, b.Signature, resp.Signature)
+			require.Equal(t, b.Randomness, resp.Randomness)
+		}
+	}
+
+	// read back the chain as printed by fetch public, and as an array
+	var buff bytes.Buffer
+	for _, b := range chain {
+		out, err := json.MarshalIndent(b, "", "    ")
+		require.NoError(t, err)
+		buff.Write(out)
+		buff.WriteString("\n")
+	}
+	read, err := ReadBeacons(&buff)
+	require.NoError(t, err)
+	require.Equal(t, chain, read)
+	array, err := json.Marshal(chain)
+	require.NoError(t, err)
+	read, err = ReadBeacons(bytes.NewReader(array))
+	require.NoError(t, err)
+	require.Len(t, read, len(chain))
+	_, err = ReadBeacons(strings.NewReader("  "))
+	require.Error(t, err)
+
+	// versioned format of beacon.Beacon
+	buff.Reset()
+	for _, b := range chain {
+		out, err := json.Marshal(&beacon.Beacon{PreviousRand: b.Previous, Round: b.Round, Signature: b.Signature})
+		require.NoError(t, err)
+		buff.Write(out)
+	}
+	read, err = ReadBeacons(&buff)
+	require.NoError(t, err)
+	require.Equal(t, chain, read)
+
+	// broken link
+	forged := signedResponse(t, priv, 3, []byte("other"))
+	err = VerifyChain(public, []*drand.PublicRandResponse{chain[0], chain[1], forged, chain[3]})
+	require.Error(t, err)
+	require.Equal(t, uint64(3), err.(*ChainError).Round)
+
+	// randomness not derived from the signature
+	randomness := chain[2].Randomness
+	chain[2].Randomness = chain[1].Randomness
+	err = VerifyChain(public, chain)
+	require.Error(t, err)
+	require.Equal(t, uint64(3), err.(*ChainError).Round)
+	require.Error(t, VerifyBeacon(pub, chain[2]))
+	chain[2].Randomness = randomness
+
+	// invalid signature
+	chain[2].Signature, chain[2].Randomness = chain[1].Signature, chain[1].Randomness
+	err = VerifyChain(public, chain)
+	require.Error(t, err)
+	require.Equal(t, uint64(3), err.(*ChainError).Round)
+	require.Contains(t, err.Error(), "invalid randomness")
+	require.True(t, errors.Is(err, ErrVerification))
+	require.True(t, errors.Is(VerifyBeacon(pub, chain[2]), ErrVerification))
+}
+
+func TestClientBeaconMessage(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	public := &key.DistPublic{Key: pub}
+	tagged := func(prev []byte, round uint64) []byte {
+		return append([]byte("my-chain"), beacon.Message(prev, round)...)
+	}
+	sig, err := bls.Sign(key.Pairing, priv, tagged([]byte("prev"), 3))
+	require.NoError(t, err)
+	fake := &fakeClient{resp: &drand.PublicRandResponse{Round: 3, Previous: []byte("prev"), Randomness: sig}}
+
+	conf := NewConfig(WithBeaconMessage(tagged))
+	client := &Client{client: fake, message: conf.beaconMessage()}
+	_, err = client.LastPublic("127.0.0.1:4444", public, false)
+	require.NoError(t, err)
+
+	// default message
+	_, err = (&Client{client: fake}).LastPublic("127.0.0.1:4444", public, false)
+	require.Error(t, err)
+	fake.resp = signedResponse(t, priv, 3, []byte("prev"))
+	_, err = (&Client{client: fake, message: NewConfig().beaconMessage()}).LastPublic("127.0.0.1:4444", public, false)
+	require.NoError(t, err)
+}
+
+func TestVerifyBeaconBatch(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	var chain []*drand.PublicRandResponse
+	prev := []byte("seed")
+	for round := uint64(1); round <= 16; round++ {
+		b := signedResponse(t, priv, round, prev)
+		chain = append(chain, b)
+		prev = b.Signature
+	}
+	require.NoError(t, VerifyBeaconBatch(pub, chain))
+	require.NoError(t, VerifyBeaconBatch(pub, nil))
+	_, other := bls.NewKeyPair(key.Pairing, random.New())
+	require.Error(t, VerifyBeaconBatch(other, chain))
+
+	// two swapped signatures do not cancel out
+	swap := func() {
+		chain[4].Signature, chain[5].Signature = chain[5].Signature, chain[4].Signature
+		chain[4].Randomness, chain[5].Randomness = chain[5].Randomness, chain[4].Randomness
+	}
+	swap()
+	err := VerifyBeaconBatch(pub, chain)
+	require.Error(t, err)
+	require.Equal(t, uint64(5), err.(*ChainError).Round)
+	swap()
+
+	chain[9].Signature = []byte("not a signature")
+	err = VerifyBeaconBatch(pub, chain)
+	require.Error(t, err)
+	require.Equal(t, uint64(10), err.(*ChainError).Round)
+}
@@ -0,0 +1,97 @@
+package dkg
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/dedis/drand/key"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/golang/protobuf/proto"
+)
+
+// packetMessage returns the message signed by the sender of a packet: the
+// sha256 hash of the packet encoded without its signature.
+func packetMessage(p *dkg_proto.DKGPacket) ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	buff, err := proto.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(buff)
+	return h[:], nil
+}
+
+// SignPacket sets the origin of the packet to the given index of the sender in
+// the group and signs the packet with its long-term key, with the scheme of the
+// key.
+func SignPacket(priv *key.Pair, origin int, p *dkg_proto.DKGPacket) error {
+	scheme, err := key.SchemeByName(priv.Public.Scheme)
+	if err != nil {
+		return err
+	}
+	p.Origin = uint32(origin)
+	msg, err := packetMessage(p)
+	if err != nil {
+		return err
+	}
+	sig, err := bls.Sign(scheme.Pairing, priv.Key, msg)
+	if err != nil {
+		return err
+	}
+	p.Signature = sig
+	return nil
+}
+
+// VerifyPacket checks that the origin of the packet is a node of the group,
+// that the packet is signed by the long-term key of this node, with the scheme
+// of the group, and that the node only sends its own deal, response or
+// justification: a node could otherwise sign the packets of another one.
+func VerifyPacket(group *key.Group, p *dkg_proto.DKGPacket) error {
+	origin := p.GetOrigin()
+	if int(origin) >= group.Len() {
+		return fmt.Errorf("dkg: packet from unknown origin %d", origin)
+	}
+	if len(p.GetSignature()) == 0 {
+		return errors.New("dkg: unsigned packet")
+	}
+	if err := checkOrigin(p); err != nil {
+		return err
+	}
+	scheme, err := key.SchemeByName(group.Scheme)
+	if err != nil {
+		return err
+	}
+	msg, err := packetMessage(p)
+	if err != nil {
+		return err
+	}
+	public := group.Public(int(origin))
+	if err := bls.Verify(scheme.Pairing, public.Key, msg, p.GetSignature()); err != nil {
+		return fmt.Errorf("dkg: invalid signature of packet from %s: %s", public.Address(), err)
+	}
+	return nil
+}
+
+// checkOrigin returns an error if the packet carries a deal or a justification
+// of another dealer than its origin, or a response of another participant.
+func checkOrigin(p *dkg_proto.DKGPacket) error {
+	origin := p.GetOrigin()
+	switch {
+	case p.Deal != nil && p.Deal.GetIndex() != origin:
+		return fmt.Errorf("dkg: packet from %d carries the deal of %d", origin, p.Deal.GetIndex())
+	case p.Response != nil && p.Response.GetResponse().GetIndex() != origin:
+		return fmt.Errorf("dkg: packet from %d carries the response of %d", origin, p.Response.GetResponse().GetIndex())
+	case p.Justification != nil && p.Justification.GetIndex() != origin:
+		return fmt.Errorf("dkg: packet from %d carries the justification of %d", origin, p.Justification.GetIndex())
+	}
+	return nil
+}
+
+// Verify checks that the packet comes from a node of the group of the handler,
+// see VerifyPacket.
+func (h *Handler) Verify(p *dkg_proto.DKGPacket) error {
+	return VerifyPacket(h.conf.Group, p)
+}
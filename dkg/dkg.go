@@ -10,10 +10,15 @@ import (
 
 	"github.com/dedis/drand/key"
 	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/crypto"
+	share_proto "github.com/dedis/drand/protobuf/crypto/share"
 	vss_proto "github.com/dedis/drand/protobuf/crypto/share/vss"
 	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/share"
 	"github.com/dedis/kyber/share/dkg/pedersen"
 	"github.com/dedis/kyber/share/vss/pedersen"
+	"github.com/dedis/kyber/sign/schnorr"
 	"github.com/dedis/kyber/util/random"
 	"github.com/nikkolasg/slog"
 	"google.golang.org/grpc/peer"
@@ -28,29 +33,106 @@ const DefaultTimeout = time.Duration(1) * time.Minute
 type Config struct {
 	Suite dkg.Suite // which crypto group to use for this DKG run
 	Group *key.Group
-	// XXX Currently not in use / tested
-	Timeout time.Duration // after timeout, protocol is finished in any cases.
+	// after timeout, protocol is finished in any cases. The timer starts when
+	// the deals are sent. A zero timeout disables it.
+	Timeout time.Duration
+	// FailFast makes the protocol abort as soon as enough deals have been
+	// disqualified so that it can never finish, instead of waiting for the
+	// timeout.
+	FailFast bool
+	// OldGroup puts the handler in resharing mode: instead of generating a
+	// fresh distributed key, the distributed key of OldGroup is redistributed
+	// to the nodes of Group, and stays the same. At least a threshold of the
+	// nodes of OldGroup must be in Group and deal. When some of them do not,
+	// the protocol finishes at the timeout with the deals received.
+	OldGroup *key.Group
+	// Share is the share of this node in OldGroup, nil for a node joining the
+	// group. Only used when resharing.
+	Share *key.Share
+	// Public is the distributed public key of OldGroup. Only used when
+	// resharing.
+	Public *key.DistPublic
+	// Commits is the distributed public polynomial of OldGroup, which a node
+	// joining the group needs to check the deals of the nodes of OldGroup. A
+	// node of OldGroup uses the one of its share. Only used when resharing.
+	Commits []kyber.Point
 }
 
 // Share represents the private information that a node holds after a successful
 // DKG. This information MUST stay private !
 type Share = dkg.DistKeyShare
 
+// Phase is the step of the protocol a Handler is at.
+type Phase string
+
+const (
+	// PhaseDeal is the phase during which the deals are exchanged.
+	PhaseDeal Phase = "deal"
+	// PhaseResponse starts once the deals of all the other nodes have been
+	// received, the handler then waits for the responses to the deals.
+	PhaseResponse Phase = "response"
+	// PhaseJustification starts when a complaint about a deal requires a
+	// justification from its dealer.
+	PhaseJustification Phase = "justification"
+	// PhaseConfirmation starts once a resharing node computed its new share,
+	// it then waits for the other nodes to confirm they reshared the same
+	// distributed polynomial.
+	PhaseConfirmation Phase = "confirmation"
+	// PhaseFinished is reached once the protocol is certified or aborted.
+	PhaseFinished Phase = "finished"
+)
+
+// Status is a snapshot of the progress of a Handler.
+type Status struct {
+	Phase Phase
+	// Progress is the percentage of the packets expected from the other nodes
+	// that have been processed.
+	Progress     int
+	Deals        int // deals processed, valid or not
+	ValidDeals   int // valid deals, including our own
+	Needed       int // valid deals needed to finish the protocol
+	Responses    int // responses processed
+	Disqualified int // dealers disqualified
+	// Missing holds the addresses of the nodes whose deal has not been
+	// received yet.
+	Missing []string
+	// Err is the error that aborted the protocol, if any.
+	Err error
+}
+
 // Handler is the stateful struct that runs a DKG with the peers
 type Handler struct {
 	net           Network                    // network to send data out
 	conf          *Config                    // configuration given at init time
 	private       *key.Pair                  // private key
 	idx           int                        // the index of the private/public key pair in the list
-	state         *dkg.DistKeyGenerator      // dkg stateful struct
+	state         *generator                 // dkg stateful struct
 	n             int                        // number of participants
 	tmpResponses  map[uint32][]*dkg.Response // temporary buffer of responses
 	sentDeals     bool                       // true if the deals have been sent already
 	dealProcessed int                        // how many deals have we processed so far
 	respProcessed int                        // how many responses have we processed so far
+	validDeals    int                        // how many valid deals have we received so far
+	disqualified  map[uint32]bool            // dealers whose deal or justification is invalid, or not justified in time
+	received      map[uint32]bool            // dealers whose deal has been received, valid or not
+	complaints    map[uint32]map[uint32]bool // complaints not justified yet, by dealer and then by complainer
+	timer         *time.Timer                // fires when the protocol times out
 	done          bool                       // is the protocol done
+	err           error                      // error that aborted the protocol, if any
 	shareCh       chan Share                 // share gets sent over shareCh when ready
 	errCh         chan error                 // any fatal error for the protocol gets sent over
+	share         *Share                     // share of this node once the protocol is finished
+	transcript    *transcript                // messages processed, see Transcript
+
+	// justifications received before the complaint they answer, by dealer
+	// and complainer
+	tmpJustifications map[[2]uint32]*dkg.Justification
+
+	// share reshared by this node, kept until the other nodes confirm they
+	// reshared the same distributed polynomial
+	pending *Share
+	// distributed polynomials reshared by the other nodes, by index
+	confirmations map[uint32][]kyber.Point
 
 	sync.Mutex
 }
@@ -66,8 +148,14 @@ func NewHandler(priv *key.Pair, conf *Config, n Network) (*Handler, error) {
 	if !ok {
 		return nil, errors.New("dkg: no nublic key corresponding in the given list")
 	}
-	randomSecret := conf.Suite.Scalar().Pick(random.New())
-	state, err := dkg.NewDistKeyGenerator(conf.Suite, priv.Key, points, t, randomSecret)
+	secret := conf.Suite.Scalar().Pick(random.New())
+	if conf.resharing() {
+		var err error
+		if secret, err = reshareSecret(conf.Suite, priv, conf); err != nil {
+			return nil, err
+		}
+	}
+	state, err := newGenerator(conf.Suite, priv.Key, points, t, secret)
 	if err != nil {
 		return nil, fmt.Errorf("dkg: error using dkg library: %s", err)
 	}
@@ -77,10 +165,17 @@ func NewHandler(priv *key.Pair, conf *Config, n Network) (*Handler, error) {
 		state:        state,
 		net:          n,
 		tmpResponses: make(map[uint32][]*dkg.Response),
+		disqualified: make(map[uint32]bool),
+		received:     make(map[uint32]bool),
+		complaints:   make(map[uint32]map[uint32]bool),
 		idx:          myIdx,
 		n:            conf.Group.Len(),
 		shareCh:      make(chan Share, 1),
 		errCh:        make(chan error, 1),
+		transcript:   newTranscript(),
+
+		tmpJustifications: make(map[[2]uint32]*dkg.Justification),
+		confirmations:     make(map[uint32][]kyber.Point),
 	}, nil
 }
 
@@ -93,19 +188,40 @@ func (h *Handler) Process(c context.Context, packet *dkg_proto.DKGPacket) {
 	case packet.Response != nil:
 		h.processResponse(peer, packet.Response)
 	case packet.Justification != nil:
-		panic("not yet implemented")
+		h.processJustification(peer, packet.Justification)
+	case packet.Reshared != nil:
+		h.processReshared(packet.Origin, packet.Reshared)
 	}
 }
 
-// Start sends the first message to run the protocol
+// Start sends the first message to run the protocol. It does nothing if the
+// deals have been sent already, because Start has been called or a deal has
+// been received, so each node deals once whatever the number of nodes starting
+// the protocol.
 func (h *Handler) Start() {
+	h.Lock()
+	if h.sentDeals {
+		h.Unlock()
+		return
+	}
 	h.sentDeals = true
+	h.startTimer()
+	h.Unlock()
 	if err := h.sendDeals(); err != nil {
-		h.errCh <- err
-		h.done = true
+		h.Lock()
+		h.fail(err)
+		h.Unlock()
 	}
 }
 
+// Started returns true once the deals have been sent, because Start has been
+// called or a deal has been received.
+func (h *Handler) Started() bool {
+	h.Lock()
+	defer h.Unlock()
+	return h.sentDeals
+}
+
 // WaitShare returns a channel over which the share will be sent over when
 // ready.
 func (h *Handler) WaitShare() chan Share {
@@ -127,6 +243,44 @@ func (h *Handler) QualifiedGroup() *key.Group {
 	return h.conf.Group.Filter(quals)
 }
 
+// Status returns the progress of the protocol, so a DKG that does not finish
+// can be diagnosed: it tells the phase the handler is stuck at and the nodes
+// whose deal is still awaited.
+func (h *Handler) Status() *Status {
+	h.Lock()
+	defer h.Unlock()
+	st := &Status{
+		Phase:        PhaseDeal,
+		Progress:     100,
+		Deals:        h.dealProcessed,
+		ValidDeals:   h.valid(),
+		Needed:       h.needed(),
+		Responses:    h.respProcessed,
+		Disqualified: len(h.disqualified),
+		Missing:      h.missingDealers(),
+		Err:          h.err,
+	}
+	switch {
+	case h.done:
+		st.Phase = PhaseFinished
+	case h.pending != nil:
+		st.Phase = PhaseConfirmation
+	case len(h.complaints) > 0:
+		st.Phase = PhaseJustification
+	case len(st.Missing) == 0:
+		st.Phase = PhaseResponse
+	}
+	// each other node sends us its deal, and a response to each deal but its
+	// own, i.e. n * (n - 1) packets in total
+	if expected := h.n * (h.n - 1); expected > 0 && !h.done {
+		st.Progress = 100 * (h.dealProcessed + h.respProcessed) / expected
+		if st.Progress > 100 {
+			st.Progress = 100
+		}
+	}
+	return st
+}
+
 func (h *Handler) processDeal(p *peer.Peer, pdeal *dkg_proto.Deal) {
 	h.Lock()
 	h.dealProcessed++
@@ -139,18 +293,60 @@ func (h *Handler) processDeal(p *peer.Peer, pdeal *dkg_proto.Deal) {
 			Cipher:    pdeal.Deal.Cipher,
 		},
 	}
-	defer h.processTmpResponses(deal)
+	defer h.processTmpResponses(deal.Index)
 	defer h.Unlock()
+	// a second deal of a dealer is dropped before reaching the library,
+	// without disqualifying the first one
+	if int(deal.Index) >= h.n || deal.Index == uint32(h.idx) || h.received[deal.Index] {
+		slog.Infof("dkg: dropping deal of index %d: out of the group, own index or already received", deal.Index)
+		return
+	}
+	h.received[deal.Index] = true
 	slog.Debugf("dkg: %s processing deal from %s (%d processed)", h.addr(), h.raddr(deal.Index), h.dealProcessed)
+	points := h.conf.Group.Points()
+	plain, err := decryptDeal(h.conf.Suite, h.private.Key, points[deal.Index], points, deal.Deal)
+	if err == nil {
+		// the library would approve a deal not committing to the secret
+		// to reshare, so such a deal is complained about without it
+		if err := h.checkReshareDeal(deal.Index, plain); err != nil {
+			slog.Infof("%s", err)
+			h.disqualify(deal.Index)
+			h.transcript.addDeal(deal, plain)
+			resp, err := h.complaint(deal.Index, plain)
+			if err != nil {
+				slog.Infof("dkg: error signing complaint: %s", err)
+				return
+			}
+			h.respond(resp)
+			return
+		}
+	}
 	resp, err := h.state.ProcessDeal(deal)
 	if err != nil {
 		slog.Infof("dkg: error processing deal: %s", err)
+		h.disqualify(deal.Index)
 		return
 	}
+	if resp.Response.Status == vss.StatusComplaint {
+		h.addComplaint(deal.Index, resp.Response.Index)
+	}
+	h.validDeals++
+	if plain != nil {
+		h.transcript.addDeal(deal, plain)
+	}
+	h.respond(resp)
+}
+
+// respond records and broadcasts the response of this node about a deal, after
+// having sent the deals of this node if not done yet. It must be called with
+// the lock held.
+func (h *Handler) respond(resp *dkg.Response) {
+	h.transcript.addResponse(resp)
 
 	if !h.sentDeals {
 		go h.sendDeals()
 		h.sentDeals = true
+		h.startTimer()
 		slog.Debugf("dkg: sent all deals")
 	}
 	out := &dkg_proto.DKGPacket{
@@ -164,25 +360,52 @@ func (h *Handler) processDeal(p *peer.Peer, pdeal *dkg_proto.Deal) {
 			},
 		},
 	}
+	if err := SignPacket(h.private, h.idx, out); err != nil {
+		slog.Infof("dkg: error signing response: %s", err)
+		return
+	}
 	go h.broadcast(out)
 	slog.Debugf("dkg: broadcasted response")
 }
 
-func (h *Handler) processTmpResponses(deal *dkg.Deal) {
+// complaint returns the signed complaint of this node about the deal of a
+// dealer, for a deal not given to the library.
+func (h *Handler) complaint(dealer uint32, deal *vss.Deal) (*dkg.Response, error) {
+	suite := h.conf.Suite
+	points := h.conf.Group.Points()
+	resp := &vss.Response{
+		SessionID: sessionID(suite, points[dealer], points, deal.Commitments, int(deal.T)),
+		Index:     uint32(h.idx),
+		Status:    vss.StatusComplaint,
+	}
+	sig, err := schnorr.Sign(suite, h.private.Key, resp.Hash(suite))
+	if err != nil {
+		return nil, err
+	}
+	resp.Signature = sig
+	return &dkg.Response{Index: dealer, Response: resp}, nil
+}
+
+// processTmpResponses processes the responses about the deal of the given
+// dealer received before the deal itself.
+func (h *Handler) processTmpResponses(dealer uint32) {
 	h.Lock()
 	defer h.checkCertified()
 	defer h.Unlock()
-	resps, ok := h.tmpResponses[deal.Index]
+	resps, ok := h.tmpResponses[dealer]
 	if !ok {
 		return
 	}
-	slog.Debug("dkg: processing ", len(resps), " out-of-order responses for dealer", deal.Index)
-	delete(h.tmpResponses, deal.Index)
+	slog.Debug("dkg: processing ", len(resps), " out-of-order responses for dealer", dealer)
+	delete(h.tmpResponses, dealer)
 	for _, r := range resps {
-		_, err := h.state.ProcessResponse(r)
+		signed := *r.Response
+		j, err := h.state.ProcessResponse(r)
 		if err != nil {
 			slog.Debugf("dkg: err process temp response: ", err)
+			continue
 		}
+		h.responseProcessed(&dkg.Response{Index: r.Index, Response: &signed}, j)
 	}
 }
 
@@ -191,7 +414,111 @@ func (h *Handler) processResponse(p *peer.Peer, presp *dkg_proto.Response) {
 	defer h.checkCertified()
 	defer h.Unlock()
 	h.respProcessed++
-	resp := &dkg.Response{
+	resp := responseFromProto(presp)
+	j, err := h.state.ProcessResponse(resp)
+	slog.Debugf("dkg: processing response(%d so far) from %s", h.respProcessed, p.Addr)
+	if err != nil {
+		if strings.Contains(err.Error(), "no deal for it") && !h.received[resp.Index] {
+			h.tmpResponses[resp.Index] = append(h.tmpResponses[resp.Index], resp)
+			slog.Debugf("dkg: %s storing future response for unknown deal (from %s) %d", h.addr(), p.Addr, resp.Index)
+			return
+		}
+		slog.Infof("dkg: error process response: %s", err)
+		return
+	}
+	h.responseProcessed(responseFromProto(presp), j)
+	slog.Debugf("dkg: processResponse(%d/%d) from %s --> Certified() ? %v --> done ? %v", h.respProcessed, h.n*(h.n-1), p.Addr, h.state.Certified(), h.done)
+}
+
+// responseProcessed records a response processed by the library. A complaint
+// about the deal of another dealer awaits its justification, while the library
+// returns the justification of a complaint about our own deal, which is then
+// broadcast. The library turns a complaint about our own deal into an approval
+// once justified, so the response must be a copy of the signed one, not the
+// one given to the library. It must be called with the lock held.
+func (h *Handler) responseProcessed(r *dkg.Response, j *dkg.Justification) {
+	h.transcript.addResponse(r)
+	if r.Response.Status == vss.StatusApproval {
+		return
+	}
+	if r.Index != uint32(h.idx) {
+		h.addComplaint(r.Index, r.Response.Index)
+		return
+	}
+	if j == nil {
+		return
+	}
+	pj, err := justificationToProto(j)
+	if err != nil {
+		slog.Infof("dkg: error encoding justification: %s", err)
+		return
+	}
+	packet := &dkg_proto.DKGPacket{Justification: pj}
+	if err := SignPacket(h.private, h.idx, packet); err != nil {
+		slog.Infof("dkg: error signing justification: %s", err)
+		return
+	}
+	slog.Debugf("dkg: broadcasting justification of the complaint of %s", h.raddr(j.Justification.Index))
+	go h.broadcast(packet)
+}
+
+// addComplaint records the complaint of a node about the deal of a dealer,
+// and processes the justification of the dealer if it came first. It must be
+// called with the lock held.
+func (h *Handler) addComplaint(dealer, complainer uint32) {
+	if h.complaints[dealer] == nil {
+		h.complaints[dealer] = make(map[uint32]bool)
+	}
+	h.complaints[dealer][complainer] = true
+	slog.Debugf("dkg: %s complains about the deal of %s", h.raddr(complainer), h.raddr(dealer))
+	if j, ok := h.tmpJustifications[[2]uint32{dealer, complainer}]; ok {
+		delete(h.tmpJustifications, [2]uint32{dealer, complainer})
+		h.justify(j)
+	}
+}
+
+func (h *Handler) processJustification(p *peer.Peer, pj *dkg_proto.Justification) {
+	h.Lock()
+	defer h.checkCertified()
+	defer h.Unlock()
+	j, err := justificationFromProto(h.conf.Suite, pj)
+	if err != nil {
+		slog.Infof("dkg: invalid justification of index %d: %s", pj.GetIndex(), err)
+		return
+	}
+	if int(j.Index) >= h.n || j.Index == uint32(h.idx) {
+		slog.Infof("dkg: dropping justification of index %d: out of the group or own index", j.Index)
+		return
+	}
+	h.justify(j)
+}
+
+// justify processes the justification of a dealer answering the complaint of a
+// node about its deal: the complaint is resolved if the justification reveals a
+// valid share for the node, and the dealer is disqualified otherwise. A
+// justification received before the complaint it answers is kept until the
+// complaint is received. It must be called with the lock held.
+func (h *Handler) justify(j *dkg.Justification) {
+	dealer, complainer := j.Index, j.Justification.Index
+	if !h.complaints[dealer][complainer] {
+		h.tmpJustifications[[2]uint32{dealer, complainer}] = j
+		return
+	}
+	delete(h.complaints[dealer], complainer)
+	if len(h.complaints[dealer]) == 0 {
+		delete(h.complaints, dealer)
+	}
+	if err := h.state.ProcessJustification(j); err != nil {
+		slog.Infof("dkg: invalid justification from %s: %s", h.raddr(dealer), err)
+		h.disqualify(dealer)
+		return
+	}
+	slog.Debugf("dkg: %s justified the complaint of %s", h.raddr(dealer), h.raddr(complainer))
+}
+
+// responseFromProto returns the response held in a packet.
+func responseFromProto(presp *dkg_proto.Response) *dkg.Response {
+	return &dkg.Response{
 		Index: presp.Index,
 		Response: &vss.Response{
 			SessionID: presp.Response.SessionId,
@@ -200,34 +527,70 @@ func (h *Handler) processResponse(p *peer.Peer, presp *dkg_proto.Response) {
 			Signature: presp.Response.Signature,
 		},
 	}
-	j, err := h.state.ProcessResponse(resp)
-	slog.Debugf("dkg: processing response(%d so far) from %s", h.respProcessed, p.Addr)
+}
+
+// justificationToProto returns the justification to send in a packet.
+func justificationToProto(j *dkg.Justification) (*dkg_proto.Justification, error) {
+	deal := j.Justification.Deal
+	v, err := crypto.KyberToProtoScalar(deal.SecShare.V)
 	if err != nil {
-		if strings.Contains(err.Error(), "no deal for it") {
-			h.tmpResponses[resp.Index] = append(h.tmpResponses[resp.Index], resp)
-			slog.Debugf("dkg: %s storing future response for unknown deal (from %s) %d", h.addr(), p.Addr, resp.Index)
-			return
+		return nil, err
+	}
+	commits := make([]*crypto.Point, len(deal.Commitments))
+	for i, c := range deal.Commitments {
+		if commits[i], err = crypto.KyberToProtoPoint(c); err != nil {
+			return nil, err
 		}
-		slog.Infof("dkg: error process response: %s", err)
-		return
 	}
-	if j != nil {
-		// XXX TODO
-		slog.Debugf("dkg: broadcasting justification")
-		/*packet := &dkg_proto.Packet{*/
-		//Justification: &dkg_proto.Justification{
-		//Index: j.Index,
-		//Justification: &vss.Justification{
-		//SessionID: j.Justification.Index,
-		//Index: j.Justification.Index,
-		//Signature: j.Justification.Signature,
-
-		//}
-		//},
-		//}
-		/*go h.broadcast(packet)*/
+	return &dkg_proto.Justification{
+		Index: j.Index,
+		Justification: &vss_proto.Justification{
+			SessionId: j.Justification.SessionID,
+			Index:     j.Justification.Index,
+			Deal: &vss_proto.Deal{
+				SessionId:   deal.SessionID,
+				Share:       &share_proto.PrivateShare{Index: uint32(deal.SecShare.I), Share: v},
+				Threshold:   deal.T,
+				Commitments: commits,
+			},
+			Signature: j.Justification.Signature,
+		},
+	}, nil
+}
+
+// justificationFromProto returns the justification held in a packet. The
+// points and scalars are decoded in the group of the suite, whatever the group
+// they claim to be from.
+func justificationFromProto(suite Suite, pj *dkg_proto.Justification) (*dkg.Justification, error) {
+	pdeal := pj.GetJustification().GetDeal()
+	if pdeal == nil || pdeal.GetShare() == nil {
+		return nil, errors.New("dkg: justification without deal")
 	}
-	slog.Debugf("dkg: processResponse(%d/%d) from %s --> Certified() ? %v --> done ? %v", h.respProcessed, h.n*(h.n-1), p.Addr, h.state.Certified(), h.done)
+	v := suite.Scalar()
+	if err := v.UnmarshalBinary(pdeal.GetShare().GetShare().GetData()); err != nil {
+		return nil, err
+	}
+	commits := make([]kyber.Point, len(pdeal.GetCommitments()))
+	for i, c := range pdeal.GetCommitments() {
+		commits[i] = suite.Point()
+		if err := commits[i].UnmarshalBinary(c.GetData()); err != nil {
+			return nil, err
+		}
+	}
+	return &dkg.Justification{
+		Index: pj.GetIndex(),
+		Justification: &vss.Justification{
+			SessionID: pj.Justification.GetSessionId(),
+			Index:     pj.Justification.GetIndex(),
+			Deal: &vss.Deal{
+				SessionID:   pdeal.GetSessionId(),
+				SecShare:    &share.PriShare{I: int(pdeal.GetShare().GetIndex()), V: v},
+				T:           pdeal.GetThreshold(),
+				Commitments: commits,
+			},
+			Signature: pj.Justification.GetSignature(),
+		},
+	}, nil
 }
 
 // checkCertified checks if there has been enough responses and if so, creates
@@ -236,28 +599,177 @@ func (h *Handler) processResponse(p *peer.Peer, presp *dkg_proto.Response) {
 func (h *Handler) checkCertified() {
 	h.Lock()
 	defer h.Unlock()
-	if !h.state.Certified() || h.done {
+	// every deal is needed, so every complaint must have been justified
+	certified := h.state.Certified() && len(h.complaints) == 0
+	if h.conf.resharing() {
+		certified = h.reshareCertified()
+	}
+	if !certified || h.done || h.pending != nil {
 		return
 	}
 	//slog.Debugf("%s: processResponse(%d) from %s #3", d.addr, d.respProcessed, pub.Address)
-	h.done = true
 	slog.Infof("dkg: certified!")
+	if h.conf.resharing() {
+		dks, err := h.reshareShare()
+		if err != nil {
+			h.fail(err)
+			return
+		}
+		h.confirm(dks)
+		return
+	}
 	dks, err := h.state.DistKeyShare()
 	if err != nil {
 		return
 	}
-	share := Share(*dks)
-	h.shareCh <- share
+	h.finish(dks)
+}
+
+// finish ends the protocol by sending the share along the channel returned by
+// WaitShare. It must be called with the lock held.
+func (h *Handler) finish(dks *Share) {
+	h.done = true
+	h.share = dks
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.shareCh <- *dks
+}
+
+// needed returns the number of valid deals required to finish the protocol.
+// The DKG can only be certified once the deals of all participants have been
+// certified, which is never less than the threshold. A resharing only needs
+// the deals of a threshold of the nodes of the old group.
+func (h *Handler) needed() int {
+	if h.conf.resharing() {
+		return h.conf.OldGroup.Threshold
+	}
+	return h.n
+}
+
+// dealersLeft returns the number of dealers not disqualified whose deal counts
+// towards needed. It must be called with the lock held.
+func (h *Handler) dealersLeft() int {
+	if !h.conf.resharing() {
+		return h.n - len(h.disqualified)
+	}
+	var left int
+	for _, n := range h.conf.OldGroup.Nodes {
+		if idx, ok := h.conf.Group.Index(n.Identity); ok && !h.disqualified[uint32(idx)] {
+			left++
+		}
+	}
+	return left
+}
+
+// valid returns the number of valid deals received so far, including our own.
+func (h *Handler) valid() int {
+	if h.sentDeals {
+		return h.validDeals + 1
+	}
+	return h.validDeals
+}
+
+// disqualify marks the deal of the given dealer as invalid. If FailFast is set
+// and there are not enough qualified dealers left to ever finish the protocol,
+// the protocol is aborted. It must be called with the lock held.
+func (h *Handler) disqualify(dealer uint32) {
+	if h.disqualified[dealer] {
+		return
+	}
+	h.disqualified[dealer] = true
+	slog.Infof("dkg: deal from %s disqualified (%d so far)", h.raddr(dealer), len(h.disqualified))
+	if !h.conf.FailFast {
+		return
+	}
+	if left := h.dealersLeft(); left < h.needed() {
+		h.fail(fmt.Errorf("dkg: impossible to finish, %d deals disqualified: at most %d valid deals out of %d needed (threshold %d)", len(h.disqualified), left, h.needed(), h.conf.Group.Threshold))
+	}
+}
+
+// startTimer starts the timeout of the protocol if any. It must be called with
+// the lock held.
+func (h *Handler) startTimer() {
+	if h.conf.Timeout <= 0 || h.timer != nil {
+		return
+	}
+	h.timer = time.AfterFunc(h.conf.Timeout, h.timeout)
+}
+
+// timeout aborts the protocol if it is not finished yet. The error lists the
+// nodes whose deal has never been received, which are likely misconfigured or
+// unreachable. A resharing goes on instead without the nodes which have not
+// answered, if enough deals of the old group are qualified, and waits for the
+// confirmations of the other nodes.
+func (h *Handler) timeout() {
+	h.Lock()
+	defer h.Unlock()
+	if h.done || h.pending != nil {
+		return
+	}
+	// the complaints not justified in time disqualify their dealer
+	for dealer := range h.complaints {
+		slog.Infof("dkg: complaints about the deal of %s not justified in time", h.raddr(dealer))
+		h.disqualify(dealer)
+	}
+	h.complaints = make(map[uint32]map[uint32]bool)
+	var reshareErr error
+	if h.conf.resharing() {
+		h.state.SetTimeout()
+		var dks *Share
+		if dks, reshareErr = h.reshareShare(); reshareErr == nil {
+			h.confirm(dks)
+			return
+		}
+	}
+	err := fmt.Sprintf("dkg: timeout after %s: %d valid deals out of %d needed (threshold %d), %d disqualified", h.conf.Timeout, h.valid(), h.needed(), h.conf.Group.Threshold, len(h.disqualified))
+	if missing := h.missingDealers(); len(missing) > 0 {
+		err += fmt.Sprintf(", no deal received from %s", strings.Join(missing, ", "))
+	}
+	if reshareErr != nil {
+		err += ": " + reshareErr.Error()
+	}
+	h.fail(errors.New(err))
+}
+
+// missingDealers returns the addresses of the other nodes whose deal has not
+// been received. It must be called with the lock held.
+func (h *Handler) missingDealers() []string {
+	var missing []string
+	for i := 0; i < h.n; i++ {
+		if i != h.idx && !h.received[uint32(i)] {
+			missing = append(missing, h.raddr(uint32(i)))
+		}
+	}
+	return missing
+}
+
+// fail aborts the protocol and sends the error along the channel returned by
+// WaitError. It must be called with the lock held.
+func (h *Handler) fail(err error) {
+	if h.done {
+		return
+	}
+	h.done = true
+	h.err = err
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.errCh <- err
 }
 
 // sendDeals tries to send the deals to each of the nodes.
 // It returns an error if a number of node superior to the threshold have not
 // received the deal. It is basically a no-go.
 func (h *Handler) sendDeals() error {
+	h.Lock()
 	deals, err := h.state.Deals()
+	h.Unlock()
 	if err != nil {
 		return err
 	}
+	// the responses about our deal received before it was processed
+	h.processTmpResponses(uint32(h.idx))
 	var good = 1
 	for i, deal := range deals {
 		if i == h.idx {
@@ -275,6 +787,9 @@ func (h *Handler) sendDeals() error {
 				},
 			},
 		}
+		if err := SignPacket(h.private, h.idx, packet); err != nil {
+			return err
+		}
 
 		slog.Debugf("dkg: %s sending deal to %s", h.addr(), id.Address())
 		if err := h.net.Send(id, packet); err != nil {
@@ -325,5 +840,8 @@ type Network interface {
 
 func validateConf(conf *Config) error {
 	// XXX TODO
+	if conf.resharing() {
+		return validateReshare(conf)
+	}
 	return nil
 }
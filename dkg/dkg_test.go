@@ -8,10 +8,16 @@ import (
 
 	"github.com/dedis/drand/key"
 	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/crypto"
+	"github.com/dedis/drand/protobuf/crypto/share/vss"
 	"github.com/dedis/drand/protobuf/dkg"
 	"github.com/dedis/drand/protobuf/drand"
 	"github.com/dedis/drand/test"
+	"github.com/dedis/kyber/share"
 	sdkg "github.com/dedis/kyber/share/dkg/pedersen"
+	kvss "github.com/dedis/kyber/share/vss/pedersen"
+	"github.com/dedis/kyber/sign/schnorr"
+	"github.com/dedis/kyber/util/random"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,15 +33,49 @@ func (t *testService) Public(context.Context, *drand.PublicRandRequest) (*drand.
 func (t *testService) Private(context.Context, *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
 	return &drand.PrivateRandResponse{}, nil
 }
+func (t *testService) DistKey(context.Context, *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	return &drand.DistKeyResponse{}, nil
+}
+func (t *testService) PublicStream(*drand.PublicRandRequest, drand.Randomness_PublicStreamServer) error {
+	return nil
+}
+func (t *testService) Home(context.Context, *drand.HomeRequest) (*drand.HomeResponse, error) {
+	return &drand.HomeResponse{}, nil
+}
+func (t *testService) Group(context.Context, *drand.GroupRequest) (*drand.GroupResponse, error) {
+	return &drand.GroupResponse{}, nil
+}
+func (t *testService) ChainInfo(context.Context, *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	return &drand.ChainInfoResponse{}, nil
+}
+func (t *testService) LocateRandomness(context.Context, *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	return &drand.PublicRandResponse{}, nil
+}
+func (t *testService) Sign(context.Context, *drand.SignRequest) (*drand.SignResponse, error) {
+	return &drand.SignResponse{}, nil
+}
 func (t *testService) Setup(c context.Context, in *dkg.DKGPacket) (*dkg.DKGResponse, error) {
 	t.h.Process(c, in)
 	return &dkg.DKGResponse{}, nil
 }
+func (t *testService) DKGStatus(context.Context, *dkg.DKGStatusRequest) (*dkg.DKGStatusResponse, error) {
+	return &dkg.DKGStatusResponse{}, nil
+}
 
 func (t *testService) NewBeacon(c context.Context, in *drand.BeaconRequest) (*drand.BeaconResponse, error) {
 	return &drand.BeaconResponse{}, nil
 }
 
+func (t *testService) SyncRound(c context.Context, in *drand.SyncRequest) (*drand.SyncResponse, error) {
+	return &drand.SyncResponse{}, nil
+}
+func (t *testService) BeaconState(c context.Context, in *drand.BeaconStateRequest) (*drand.BeaconStateResponse, error) {
+	return &drand.BeaconStateResponse{}, nil
+}
+func (t *testService) PartialSign(c context.Context, in *drand.PartialSignRequest) (*drand.PartialSignResponse, error) {
+	return &drand.PartialSignResponse{}, nil
+}
+
 // testNet implements the network interface that the dkg Handler expects
 type testNet struct {
 	net.InternalClient
@@ -84,7 +124,8 @@ func TestDKG(t *testing.T) {
 
 	finished := make(chan int, n)
 	goDkg := func(idx int) {
-		if idx == 0 {
+		// a node starting after receiving a deal does not deal again
+		if idx < 2 {
 			go handlers[idx].Start()
 		}
 		shareCh := handlers[idx].WaitShare()
@@ -106,4 +147,517 @@ func TestDKG(t *testing.T) {
 	for i := 0; i < n; i++ {
 		<-finished
 	}
+
+	for _, h := range handlers {
+		st := h.Status()
+		require.True(t, h.Started())
+		require.Equal(t, PhaseFinished, st.Phase)
+		require.Equal(t, 100, st.Progress)
+		require.Equal(t, n, st.ValidDeals)
+		require.Empty(t, st.Missing)
+		require.NoError(t, st.Err)
+	}
+
+	// the transcripts of all the nodes verify to the same distributed key
+	var public *key.DistPublic
+	for _, h := range handlers {
+		tr, err := h.Transcript()
+		require.NoError(t, err)
+		require.Len(t, tr.Deals, n)
+		require.Len(t, tr.Responses, n*(n-1))
+		pub, err := VerifyTranscript(conf.Group, tr)
+		require.NoError(t, err)
+		if public != nil {
+			require.True(t, public.Key.Equal(pub.Key))
+		}
+		public = pub
+	}
+
+	// tampered transcripts do not verify
+	tr, err := handlers[0].Transcript()
+	require.NoError(t, err)
+	tr.Responses[0].Approved = !tr.Responses[0].Approved
+	_, err = VerifyTranscript(conf.Group, tr)
+	require.Error(t, err)
+	tr, err = handlers[0].Transcript()
+	require.NoError(t, err)
+	tr.Deals[1].Commitments[0], tr.Deals[2].Commitments[0] = tr.Deals[2].Commitments[0], tr.Deals[1].Commitments[0]
+	_, err = VerifyTranscript(conf.Group, tr)
+	require.Error(t, err)
+	tr, err = handlers[0].Transcript()
+	require.NoError(t, err)
+	tr.Qualified = tr.Qualified[1:]
+	_, err = VerifyTranscript(conf.Group, tr)
+	require.Error(t, err)
+}
+
+// dropNet accepts all packets but never delivers them
+type dropNet struct{}
+
+func (d *dropNet) Send(p net.Peer, pack *dkg.DKGPacket) error {
+	return nil
+}
+
+func TestDKGTimeout(t *testing.T) {
+	n := 5
+	privs := test.GenerateIDs(n)
+	conf := &Config{
+		Suite:   key.G2.(sdkg.Suite),
+		Group:   key.NewGroup(test.ListFromPrivates(privs), key.DefaultThreshold(n)),
+		Timeout: 100 * time.Millisecond,
+	}
+	h, err := NewHandler(privs[0], conf, &dropNet{})
+	require.NoError(t, err)
+	st := h.Status()
+	require.Equal(t, PhaseDeal, st.Phase)
+	require.Equal(t, 0, st.Progress)
+	require.Equal(t, 0, st.ValidDeals)
+	require.Equal(t, n, st.Needed)
+	require.Len(t, st.Missing, n-1)
+	h.Start()
+	select {
+	case <-h.WaitShare():
+		t.Fatal("dkg should not finish")
+	case err := <-h.WaitError():
+		st := h.Status()
+		require.Equal(t, PhaseFinished, st.Phase)
+		require.Equal(t, err, st.Err)
+		require.Equal(t, 1, st.ValidDeals)
+		require.Len(t, st.Missing, n-1)
+		require.Contains(t, err.Error(), "timeout")
+		require.Contains(t, err.Error(), "1 valid deals out of 5 needed")
+		for _, p := range privs[1:] {
+			require.Contains(t, err.Error(), p.Public.Address())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dkg did not time out")
+	}
+}
+
+func TestDKGFailFast(t *testing.T) {
+	n := 5
+	privs := test.GenerateIDs(n)
+	group := key.NewGroup(test.ListFromPrivates(privs), key.DefaultThreshold(n))
+	// the deal of another node, since a deal of its own index is dropped
+	own, _ := group.Index(privs[0].Public)
+	invalidDeal := &dkg.DKGPacket{
+		Deal: &dkg.Deal{
+			Index: uint32(own+1) % uint32(n),
+			Deal: &vss.EncryptedDeal{
+				Dhkey:  []byte("dhkey"),
+				Cipher: []byte("cipher"),
+			},
+		},
+	}
+	newHandler := func(failFast bool) *Handler {
+		conf := &Config{
+			Suite:    key.G2.(sdkg.Suite),
+			Group:    group,
+			FailFast: failFast,
+		}
+		h, err := NewHandler(privs[0], conf, &dropNet{})
+		require.NoError(t, err)
+		return h
+	}
+
+	h := newHandler(true)
+	h.Process(context.Background(), invalidDeal)
+	select {
+	case err := <-h.WaitError():
+		require.Contains(t, err.Error(), "1 deals disqualified")
+	default:
+		t.Fatal("dkg should have failed")
+	}
+
+	h = newHandler(false)
+	h.Process(context.Background(), invalidDeal)
+	select {
+	case err := <-h.WaitError():
+		t.Fatal("dkg should wait for the timeout: ", err)
+	default:
+	}
+}
+
+// complainNet sends each deal of its node twice, and turns the approvals of its
+// node about the deal of the given dealer into complaints, as a node falsely
+// complaining would.
+type complainNet struct {
+	*testNet
+	priv   *key.Pair
+	idx    int
+	dealer uint32
+}
+
+func (c *complainNet) Send(p net.Peer, d *dkg.DKGPacket) error {
+	if d.Deal != nil {
+		if err := c.testNet.Send(p, d); err != nil {
+			return err
+		}
+	}
+	if r := d.Response; r != nil && r.Index == c.dealer && r.Response.Status {
+		r.Response.Status = kvss.StatusComplaint
+		resp := &kvss.Response{SessionID: r.Response.SessionId, Index: r.Response.Index, Status: r.Response.Status}
+		suite := key.G2.(sdkg.Suite)
+		sig, err := schnorr.Sign(suite, c.priv.Key, resp.Hash(suite))
+		if err != nil {
+			return err
+		}
+		r.Response.Signature = sig
+		d.Signature = nil
+		if err := SignPacket(c.priv, c.idx, d); err != nil {
+			return err
+		}
+	}
+	return c.testNet.Send(p, d)
+}
+
+func TestDKGJustification(t *testing.T) {
+	n := 5
+	privs := test.GenerateIDs(n)
+	group := key.NewGroup(test.ListFromPrivates(privs), key.DefaultThreshold(n))
+	nets := testNets(n)
+	handlers := make([]*Handler, n)
+	// the last node complains about the deal of the first one, which
+	// justifies it, so the deal is not disqualified
+	liar := n - 1
+	for i := range privs {
+		var network Network = nets[i]
+		if i == liar {
+			network = &complainNet{testNet: nets[i], priv: privs[i], idx: i, dealer: 0}
+		}
+		var err error
+		handlers[i], err = NewHandler(privs[i], &Config{Suite: key.G2.(sdkg.Suite), Group: group}, network)
+		require.NoError(t, err)
+		l := net.NewTCPGrpcListener(privs[i].Public.Addr, &testService{handlers[i]})
+		go l.Start()
+		defer l.Stop()
+	}
+	shares := runHandlers(t, handlers)
+	for i, h := range handlers {
+		st := h.Status()
+		require.Equal(t, PhaseFinished, st.Phase)
+		require.Equal(t, 0, st.Disqualified)
+		require.True(t, shares[0].Public().Equal(shares[i].Public()))
+	}
+}
+
+// runHandlers runs the protocol between the given handlers, the first one
+// starting it, and returns the shares in the same order.
+func runHandlers(t *testing.T, handlers []*Handler) []Share {
+	go handlers[0].Start()
+	shares := make([]Share, len(handlers))
+	for i, h := range handlers {
+		select {
+		case shares[i] = <-h.WaitShare():
+		case err := <-h.WaitError():
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("not finished in time")
+		}
+	}
+	return shares
+}
+
+func TestReshare(t *testing.T) {
+	suite := key.G2.(sdkg.Suite)
+	n := 5
+	// the last node leaves, two nodes join and the threshold changes
+	allPrivs := test.GenerateIDs(n + 2)
+	services := make([]*testService, len(allPrivs))
+	for i, priv := range allPrivs {
+		services[i] = &testService{}
+		l := net.NewTCPGrpcListener(priv.Public.Addr, services[i])
+		go l.Start()
+		defer l.Stop()
+	}
+
+	privs := allPrivs[:n]
+	oldGroup := key.NewGroup(test.ListFromPrivates(privs), key.DefaultThreshold(n))
+	nets := testNets(len(allPrivs))
+	handlers := make([]*Handler, n)
+	for i := range privs {
+		var err error
+		handlers[i], err = NewHandler(privs[i], &Config{Suite: suite, Group: oldGroup}, nets[i])
+		require.NoError(t, err)
+		services[i].h = handlers[i]
+	}
+	oldShares := runHandlers(t, handlers)
+	public := &key.DistPublic{Key: oldShares[0].Public()}
+
+	staying := n - 1
+	newPrivs := append(append([]*key.Pair{}, allPrivs[:staying]...), allPrivs[n:]...)
+	newN, newThr := len(newPrivs), 4
+	newGroup := key.NewGroup(test.ListFromPrivates(newPrivs), newThr)
+	handlers = make([]*Handler, newN)
+	for i, priv := range newPrivs {
+		conf := &Config{Suite: suite, Group: newGroup, OldGroup: oldGroup, Public: public}
+		svc := i
+		if i < staying {
+			s := key.Share(oldShares[i])
+			conf.Share = &s
+		} else {
+			svc = n + i - staying
+			conf.Commits = oldShares[0].Commits
+		}
+		var err error
+		handlers[i], err = NewHandler(priv, conf, nets[svc])
+		require.NoError(t, err)
+		services[svc].h = handlers[i]
+	}
+	newShares := runHandlers(t, handlers)
+
+	var oldPri, newPri []*share.PriShare
+	for i := range oldShares {
+		oldPri = append(oldPri, oldShares[i].PriShare())
+	}
+	for i, s := range newShares {
+		require.True(t, public.Key.Equal(s.Public()))
+		idx, _ := newGroup.Index(newPrivs[i].Public)
+		require.Equal(t, idx, s.Share.I)
+		newPri = append(newPri, s.PriShare())
+	}
+	oldSecret, err := share.RecoverSecret(suite, oldPri, oldGroup.Threshold, n)
+	require.NoError(t, err)
+	newSecret, err := share.RecoverSecret(suite, newPri[:newThr], newThr, newN)
+	require.NoError(t, err)
+	require.True(t, oldSecret.Equal(newSecret))
+
+	// not enough nodes of the current group stay
+	tooFew := key.NewGroup(test.ListFromPrivates(newPrivs[2:]), newThr)
+	s := key.Share(oldShares[2])
+	_, err = NewHandler(newPrivs[2], &Config{Suite: suite, Group: tooFew, OldGroup: oldGroup, Share: &s, Public: public}, &dropNet{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "at least 4")
+
+	// a joining node needs the polynomial of the current group
+	joiner := newPrivs[newN-1]
+	_, err = NewHandler(joiner, &Config{Suite: suite, Group: newGroup, OldGroup: oldGroup, Public: public}, &dropNet{})
+	require.Error(t, err)
+	_, err = NewHandler(joiner, &Config{Suite: suite, Group: newGroup, OldGroup: oldGroup, Public: public, Commits: oldShares[0].Commits[1:]}, &dropNet{})
+	require.Error(t, err)
+}
+
+// reshareGroup runs a DKG between n nodes listening on the returned
+// listeners, and returns their shares in the same order as the private keys.
+func reshareGroup(t *testing.T, n int) ([]*key.Pair, *key.Group, []*testService, []net.Listener, []*testNet, []Share) {
+	suite := key.G2.(sdkg.Suite)
+	privs := test.GenerateIDs(n)
+	group := key.NewGroup(test.ListFromPrivates(privs), key.DefaultThreshold(n))
+	services := make([]*testService, n)
+	listeners := make([]net.Listener, n)
+	for i, priv := range privs {
+		services[i] = &testService{}
+		listeners[i] = net.NewTCPGrpcListener(priv.Public.Addr, services[i])
+		go listeners[i].Start()
+	}
+	nets := testNets(n)
+	handlers := make([]*Handler, n)
+	for i := range privs {
+		var err error
+		handlers[i], err = NewHandler(privs[i], &Config{Suite: suite, Group: group}, nets[i])
+		require.NoError(t, err)
+		services[i].h = handlers[i]
+	}
+	return privs, group, services, listeners, nets, runHandlers(t, handlers)
+}
+
+// checkReshared checks that the new shares share the secret of the old ones,
+// and that the transcripts of the handlers verify, with the deals of a
+// threshold of the nodes of the old group but the given ones.
+func checkReshared(t *testing.T, group *key.Group, oldShares, newShares []Share, handlers []*Handler, without ...*key.Pair) {
+	suite := key.G2.(sdkg.Suite)
+	public := oldShares[0].Public()
+	var oldPri, newPri []*share.PriShare
+	for i := range oldShares {
+		oldPri = append(oldPri, oldShares[i].PriShare())
+	}
+	for i, s := range newShares {
+		require.True(t, public.Equal(s.Public()))
+		newPri = append(newPri, s.PriShare())
+
+		tr, err := handlers[i].Transcript()
+		require.NoError(t, err)
+		require.Len(t, tr.Reshared, group.Threshold)
+		for _, w := range without {
+			idx, _ := group.Index(w.Public)
+			require.NotContains(t, tr.Reshared, uint32(idx))
+		}
+		pub, err := VerifyTranscript(group, tr)
+		require.NoError(t, err)
+		require.True(t, public.Equal(pub.Key))
+	}
+	oldSecret, err := share.RecoverSecret(suite, oldPri, group.Threshold, group.Len())
+	require.NoError(t, err)
+	newSecret, err := share.RecoverSecret(suite, newPri[:group.Threshold], group.Threshold, group.Len())
+	require.NoError(t, err)
+	require.True(t, oldSecret.Equal(newSecret))
+}
+
+func TestReshareOffline(t *testing.T) {
+	n := 5
+	privs, group, services, listeners, nets, oldShares := reshareGroup(t, n)
+	for _, l := range listeners {
+		defer l.Stop()
+	}
+	public := &key.DistPublic{Key: oldShares[0].Public()}
+
+	// the last node is down: the group reshares to itself without it
+	offline := n - 1
+	listeners[offline].Stop()
+	handlers := make([]*Handler, offline)
+	for i := range handlers {
+		s := key.Share(oldShares[i])
+		conf := &Config{Suite: key.G2.(sdkg.Suite), Group: group, Timeout: 500 * time.Millisecond, OldGroup: group, Share: &s, Public: public}
+		var err error
+		handlers[i], err = NewHandler(privs[i], conf, nets[i])
+		require.NoError(t, err)
+		services[i].h = handlers[i]
+	}
+	newShares := runHandlers(t, handlers)
+	checkReshared(t, group, oldShares, newShares, handlers, privs[offline])
+}
+
+func TestReshareInvalidDeal(t *testing.T) {
+	n := 5
+	privs, group, services, listeners, nets, oldShares := reshareGroup(t, n)
+	for _, l := range listeners {
+		defer l.Stop()
+	}
+	public := &key.DistPublic{Key: oldShares[0].Public()}
+
+	// the last node deals another secret than its share
+	cheater := n - 1
+	handlers := make([]*Handler, n)
+	for i := range handlers {
+		s := key.Share(oldShares[i])
+		if i == cheater {
+			s.Share = &share.PriShare{I: s.Share.I, V: key.G2.Scalar().Pick(random.New())}
+		}
+		conf := &Config{Suite: key.G2.(sdkg.Suite), Group: group, OldGroup: group, Share: &s, Public: public}
+		var err error
+		handlers[i], err = NewHandler(privs[i], conf, nets[i])
+		require.NoError(t, err)
+		services[i].h = handlers[i]
+	}
+	// the cheater justifies the complaints about its deal, so only the other
+	// nodes, which check the deal themselves, disqualify it
+	newShares := runHandlers(t, handlers[:cheater])
+	cheaterIdx, _ := group.Index(privs[cheater].Public)
+	for _, h := range handlers[:cheater] {
+		require.Equal(t, 1, h.Status().Disqualified)
+		// the deal is complained about, never approved
+		tr, err := h.Transcript()
+		require.NoError(t, err)
+		for _, r := range tr.Responses {
+			if r.Dealer == uint32(cheaterIdx) {
+				require.False(t, r.Approved)
+			}
+		}
+	}
+	checkReshared(t, group, oldShares, newShares, handlers, privs[cheater])
+}
+
+// reshareNet confirms another distributed polynomial than the one reshared by
+// its node.
+type reshareNet struct {
+	*testNet
+	priv *key.Pair
+	idx  int
+}
+
+func (r *reshareNet) Send(p net.Peer, d *dkg.DKGPacket) error {
+	if d.Reshared == nil {
+		return r.testNet.Send(p, d)
+	}
+	commits := append([]*crypto.Point{}, d.Reshared.Commits...)
+	last, err := crypto.KyberToProtoPoint(key.G2.Point().Pick(random.New()))
+	if err != nil {
+		return err
+	}
+	commits[len(commits)-1] = last
+	forged := &dkg.DKGPacket{Reshared: &dkg.Reshared{Commits: commits}}
+	if err := SignPacket(r.priv, r.idx, forged); err != nil {
+		return err
+	}
+	return r.testNet.Send(p, forged)
+}
+
+func TestReshareMismatch(t *testing.T) {
+	n := 5
+	privs, group, services, listeners, nets, oldShares := reshareGroup(t, n)
+	for _, l := range listeners {
+		defer l.Stop()
+	}
+	public := &key.DistPublic{Key: oldShares[0].Public()}
+
+	// the last node confirms another polynomial than the one reshared: the
+	// other nodes abort instead of using their new share
+	liar := n - 1
+	handlers := make([]*Handler, n)
+	for i := range handlers {
+		s := key.Share(oldShares[i])
+		var network Network = nets[i]
+		if i == liar {
+			idx, _ := group.Index(privs[i].Public)
+			network = &reshareNet{testNet: nets[i], priv: privs[i], idx: idx}
+		}
+		conf := &Config{Suite: key.G2.(sdkg.Suite), Group: group, OldGroup: group, Share: &s, Public: public}
+		var err error
+		handlers[i], err = NewHandler(privs[i], conf, network)
+		require.NoError(t, err)
+		services[i].h = handlers[i]
+	}
+	go handlers[0].Start()
+	for _, h := range handlers[:liar] {
+		select {
+		case <-h.WaitShare():
+			t.Fatal("resharing should have failed")
+		case err := <-h.WaitError():
+			require.Contains(t, err.Error(), "reshared another distributed polynomial")
+		case <-time.After(5 * time.Second):
+			t.Fatal("not finished in time")
+		}
+	}
+}
+
+func TestVerifyPacket(t *testing.T) {
+	privs, group := test.BatchIdentities(3)
+	idx, ok := group.Index(privs[0].Public)
+	require.True(t, ok)
+	packet := func() *dkg.DKGPacket {
+		return &dkg.DKGPacket{Response: &dkg.Response{Index: 1, Response: &vss.Response{Index: uint32(idx), Signature: []byte("sig")}}}
+	}
+
+	p := packet()
+	require.Error(t, VerifyPacket(group, p), "unsigned packet")
+	require.NoError(t, SignPacket(privs[0], idx, p))
+	require.NoError(t, VerifyPacket(group, p))
+
+	// tampered packet
+	p.Response.Index = 2
+	require.Error(t, VerifyPacket(group, p))
+
+	// signed by another node than the claimed origin
+	p = packet()
+	require.NoError(t, SignPacket(privs[1], idx, p))
+	require.Error(t, VerifyPacket(group, p))
+
+	// signed by a node outside of the group
+	p = packet()
+	require.NoError(t, SignPacket(test.GenerateIDs(1)[0], group.Len(), p))
+	require.Error(t, VerifyPacket(group, p))
+
+	// response of another node, signed by the origin
+	p = packet()
+	p.Response.Response.Index = uint32(idx + 1)
+	require.NoError(t, SignPacket(privs[0], idx, p))
+	require.Error(t, VerifyPacket(group, p))
+
+	// deal of another dealer, signed by the origin
+	p = &dkg.DKGPacket{Deal: &dkg.Deal{Index: uint32(idx + 1)}}
+	require.NoError(t, SignPacket(privs[0], idx, p))
+	require.Error(t, VerifyPacket(group, p))
+	p = &dkg.DKGPacket{Deal: &dkg.Deal{Index: uint32(idx)}}
+	require.NoError(t, SignPacket(privs[0], idx, p))
+	require.NoError(t, VerifyPacket(group, p))
 }
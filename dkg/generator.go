@@ -0,0 +1,231 @@
+package dkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/share"
+	"github.com/dedis/kyber/share/dkg/pedersen"
+	"github.com/dedis/kyber/share/vss/pedersen"
+)
+
+// generator runs the Pedersen DKG as the DistKeyGenerator of kyber does, with
+// the same vss dealer and verifiers, but keeps the deal received from each
+// dealer, its own included: a resharing weights the deals of some of the
+// dealers only, and the transcript records the deal of this node. The share of
+// a deal this node complained about is the one revealed by the justification
+// of the dealer.
+type generator struct {
+	suite        Suite
+	index        uint32
+	long         kyber.Scalar
+	participants []kyber.Point
+
+	dealer    *vss.Dealer
+	verifiers map[uint32]*vss.Verifier
+	deals     map[uint32]*vss.Deal
+}
+
+// newGenerator returns a generator dealing the given secret to the
+// participants, this node being the owner of the long-term key.
+func newGenerator(suite Suite, longterm kyber.Scalar, participants []kyber.Point, t int, secret kyber.Scalar) (*generator, error) {
+	pub := suite.Point().Mul(longterm, nil)
+	index := -1
+	for i, p := range participants {
+		if p.Equal(pub) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("dkg: own public key not found in list of participants")
+	}
+	dealer, err := vss.NewDealer(suite, longterm, secret, participants, t)
+	if err != nil {
+		return nil, err
+	}
+	return &generator{
+		suite:        suite,
+		index:        uint32(index),
+		long:         longterm,
+		participants: participants,
+		dealer:       dealer,
+		verifiers:    make(map[uint32]*vss.Verifier),
+		deals:        make(map[uint32]*vss.Deal),
+	}, nil
+}
+
+// Deals returns the deals to send to the other participants, by index, after
+// having processed the deal of this node.
+func (g *generator) Deals() (map[int]*dkg.Deal, error) {
+	deals, err := g.dealer.EncryptedDeals()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := g.verifiers[g.index]; !ok {
+		resp, err := g.ProcessDeal(&dkg.Deal{Index: g.index, Deal: deals[g.index]})
+		if err != nil {
+			return nil, fmt.Errorf("dkg: cannot process own deal: %s", err)
+		}
+		if resp.Response.Status != vss.StatusApproval {
+			return nil, errors.New("dkg: own deal gave a complaint")
+		}
+	}
+	dd := make(map[int]*dkg.Deal)
+	for i, d := range deals {
+		if i != int(g.index) {
+			dd[i] = &dkg.Deal{Index: g.index, Deal: d}
+		}
+	}
+	return dd, nil
+}
+
+// ProcessDeal verifies and keeps the deal of a dealer, and returns the response
+// of this node to broadcast. It returns an error if a deal of the dealer has
+// already been processed or if the deal cannot be decrypted.
+func (g *generator) ProcessDeal(dd *dkg.Deal) (*dkg.Response, error) {
+	if int(dd.Index) >= len(g.participants) {
+		return nil, errors.New("dkg: dist deal out of bounds index")
+	}
+	if _, ok := g.verifiers[dd.Index]; ok {
+		return nil, errors.New("dkg: already received dist deal from same index")
+	}
+	pub := g.participants[dd.Index]
+	ver, err := vss.NewVerifier(g.suite, g.long, pub, g.participants)
+	if err != nil {
+		return nil, err
+	}
+	g.verifiers[dd.Index] = ver
+	resp, err := ver.ProcessEncryptedDeal(dd.Deal)
+	if err != nil {
+		return nil, err
+	}
+	// the dealer approves its own deal
+	ver.UnsafeSetResponseDKG(dd.Index, vss.StatusApproval)
+	if dd.Index == g.index {
+		g.deals[dd.Index], err = g.dealer.PlaintextDeal(int(g.index))
+	} else {
+		g.deals[dd.Index], err = decryptDeal(g.suite, g.long, pub, g.participants, dd.Deal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dkg.Response{Index: dd.Index, Response: resp}, nil
+}
+
+// ProcessResponse verifies the response of a participant about a deal. For a
+// complaint about the deal of this node, it returns the justification to
+// broadcast.
+func (g *generator) ProcessResponse(resp *dkg.Response) (*dkg.Justification, error) {
+	v, ok := g.verifiers[resp.Index]
+	if !ok {
+		return nil, errors.New("dkg: complaint received but no deal for it")
+	}
+	if err := v.ProcessResponse(resp.Response); err != nil {
+		return nil, err
+	}
+	if resp.Index != g.index {
+		return nil, nil
+	}
+	j, err := g.dealer.ProcessResponse(resp.Response)
+	if err != nil || j == nil {
+		return nil, err
+	}
+	if err := v.ProcessJustification(j); err != nil {
+		return nil, err
+	}
+	return &dkg.Justification{Index: g.index, Justification: j}, nil
+}
+
+// ProcessJustification verifies the justification of a dealer answering a
+// complaint about its deal. The share revealed must be the one of the node
+// which complained, and replaces the share of this node if it complained.
+func (g *generator) ProcessJustification(j *dkg.Justification) error {
+	v, ok := g.verifiers[j.Index]
+	if !ok {
+		return errors.New("dkg: justification received but no deal for it")
+	}
+	if j.Justification.Deal == nil || j.Justification.Deal.SecShare == nil {
+		return errors.New("dkg: justification without deal")
+	}
+	if i := j.Justification.Deal.SecShare.I; i != int(j.Justification.Index) {
+		return fmt.Errorf("dkg: justification reveals the share of index %d instead of %d", i, j.Justification.Index)
+	}
+	if err := v.ProcessJustification(j.Justification); err != nil {
+		return err
+	}
+	if j.Justification.Index == g.index {
+		g.deals[j.Index] = j.Justification.Deal
+	}
+	return nil
+}
+
+// SetTimeout turns the missing responses about every deal into complaints.
+func (g *generator) SetTimeout() {
+	for _, v := range g.verifiers {
+		v.SetTimeout()
+	}
+}
+
+// certified returns true if the deal of the dealer is certified, i.e. every
+// participant responded and enough of them approved it.
+func (g *generator) certified(dealer uint32) bool {
+	v, ok := g.verifiers[dealer]
+	return ok && v.DealCertified()
+}
+
+// Deal returns the deal of the dealer if it is certified, nil otherwise.
+func (g *generator) Deal(dealer uint32) *vss.Deal {
+	if !g.certified(dealer) {
+		return nil
+	}
+	return g.deals[dealer]
+}
+
+// QUAL returns the sorted indexes of the dealers whose deal is certified.
+func (g *generator) QUAL() []int {
+	var good []int
+	for i := range g.verifiers {
+		if g.certified(i) {
+			good = append(good, int(i))
+		}
+	}
+	sort.Ints(good)
+	return good
+}
+
+// Certified returns true once the deals of all the participants are
+// certified.
+func (g *generator) Certified() bool {
+	return len(g.QUAL()) >= len(g.participants)
+}
+
+// DistKeyShare returns the share of this node of the distributed key, the sum
+// of the certified deals, once they are all certified.
+func (g *generator) DistKeyShare() (*Share, error) {
+	if !g.Certified() {
+		return nil, errors.New("dkg: distributed key not certified")
+	}
+	sh := g.suite.Scalar().Zero()
+	var pub *share.PubPoly
+	for _, i := range g.QUAL() {
+		deal := g.deals[uint32(i)]
+		sh.Add(sh, deal.SecShare.V)
+		poly := share.NewPubPoly(g.suite, g.suite.Point().Base(), deal.Commitments)
+		if pub == nil {
+			pub = poly
+			continue
+		}
+		var err error
+		if pub, err = pub.Add(poly); err != nil {
+			return nil, err
+		}
+	}
+	_, commits := pub.Info()
+	return &Share{
+		Commits: commits,
+		Share:   &share.PriShare{I: int(g.index), V: sh},
+	}, nil
+}
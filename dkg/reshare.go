@@ -0,0 +1,341 @@
+package dkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/protobuf/crypto"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/kyber"
+	"github.com/dedis/kyber/share"
+	"github.com/dedis/kyber/share/vss/pedersen"
+	"github.com/nikkolasg/slog"
+)
+
+// resharing returns true if the handler redistributes the distributed key of
+// an existing group instead of generating a fresh one.
+func (c *Config) resharing() bool {
+	return c.OldGroup != nil
+}
+
+// validateReshare checks that the configuration allows to reshare the
+// distributed key of the old group to the new group.
+func validateReshare(conf *Config) error {
+	if conf.Public == nil {
+		return errors.New("dkg: resharing needs the distributed public key")
+	}
+	if conf.Share != nil && !conf.Share.Public().Key.Equal(conf.Public.Key) {
+		return errors.New("dkg: share does not match the distributed public key")
+	}
+	commits := conf.oldCommits()
+	if len(commits) == 0 {
+		return errors.New("dkg: resharing needs the distributed public polynomial")
+	}
+	if len(commits) != conf.OldGroup.Threshold || !commits[0].Equal(conf.Public.Key) {
+		return errors.New("dkg: distributed public polynomial does not match the distributed public key and the current threshold")
+	}
+	if staying := stayingIndexes(conf); len(staying) < conf.OldGroup.Threshold {
+		return fmt.Errorf("dkg: only %d nodes of the current group are in the new group, at least %d (the current threshold) are needed to reshare", len(staying), conf.OldGroup.Threshold)
+	}
+	return nil
+}
+
+// oldCommits returns the distributed public polynomial of the old group, the
+// one of the share of this node if it has one.
+func (c *Config) oldCommits() []kyber.Point {
+	if c.Share != nil {
+		return c.Share.Commits
+	}
+	return c.Commits
+}
+
+// stayingIndexes returns the indexes in the old group of the nodes of the old
+// group that are also in the new group. Only these nodes contribute their
+// share during a resharing, so the nodes leaving the group do not need to be
+// online.
+func stayingIndexes(conf *Config) []int {
+	var staying []int
+	for _, n := range conf.OldGroup.Nodes {
+		if conf.Group.Contains(n.Identity) {
+			staying = append(staying, n.Index)
+		}
+	}
+	return staying
+}
+
+// reshareSecret returns the secret dealt by this node during a resharing. A
+// node of the old group deals its share of the distributed secret, weighted
+// once the deals are in by reshareShare. A node joining the group deals zero.
+func reshareSecret(suite Suite, priv *key.Pair, conf *Config) (kyber.Scalar, error) {
+	oldIdx, inOld := conf.OldGroup.Index(priv.Public)
+	if !inOld {
+		if conf.Share != nil {
+			return nil, errors.New("dkg: share given but own public key not found in the current group")
+		}
+		return suite.Scalar().Zero(), nil
+	}
+	if conf.Share == nil {
+		return nil, errors.New("dkg: node of the current group needs its share to reshare")
+	}
+	if conf.Share.Share.I != oldIdx {
+		return nil, fmt.Errorf("dkg: share index %d differs from own index %d in the current group", conf.Share.Share.I, oldIdx)
+	}
+	return conf.Share.Share.V.Clone(), nil
+}
+
+// checkReshareDeal checks the commitment to the secret dealt by a dealer during
+// a resharing. A node of the old group deals its share, committed to by the
+// distributed public polynomial evaluated at its index, and a joining node
+// deals zero. Every node checks the deals itself, a joining node with the
+// polynomial given in its configuration.
+func (h *Handler) checkReshareDeal(dealer uint32, deal *vss.Deal) error {
+	if !h.conf.resharing() {
+		return nil
+	}
+	suite := h.conf.Suite
+	expected := suite.Point().Null()
+	if oldIdx, inOld := h.conf.OldGroup.Index(h.conf.Group.Public(int(dealer))); inOld {
+		expected = share.NewPubPoly(suite, nil, h.conf.oldCommits()).Eval(oldIdx).V
+	}
+	if len(deal.Commitments) == 0 || !deal.Commitments[0].Equal(expected) {
+		return fmt.Errorf("dkg: deal from %s does not commit to the secret it must reshare", h.raddr(dealer))
+	}
+	return nil
+}
+
+// reshareCertified returns true once the deals resharing the distributed key
+// are picked for good during a resharing, see resharers. It must be called
+// with the lock held.
+func (h *Handler) reshareCertified() bool {
+	deals, _, final := h.resharers()
+	return final && len(deals) == h.conf.OldGroup.Threshold
+}
+
+// resharers returns the deals resharing the distributed key, by index of their
+// dealer in the old group: the deals of the first OldGroup.Threshold nodes of
+// the old group staying in the group, in the order of the old group, whose
+// deal is qualified, i.e. certified without complaints left to justify. All
+// the nodes thereby pick the same deals out of the same view of the protocol,
+// whatever the order in which the deals arrived. It also returns the nodes of
+// the old group skipped, and whether the deals picked are final: they are not
+// as long as the deal of a node skipped is neither qualified nor disqualified.
+// It must be called with the lock held.
+func (h *Handler) resharers() (map[int]*vss.Deal, []string, bool) {
+	nodes := append([]*key.IndexedPublic(nil), h.conf.OldGroup.Nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Index < nodes[j].Index })
+	deals := make(map[int]*vss.Deal)
+	var skipped []string
+	final := true
+	for _, n := range nodes {
+		if len(deals) == h.conf.OldGroup.Threshold {
+			break
+		}
+		idx, ok := h.conf.Group.Index(n.Identity)
+		if !ok {
+			continue
+		}
+		dealer := uint32(idx)
+		switch {
+		case h.disqualified[dealer]:
+		case h.state.certified(dealer) && len(h.complaints[dealer]) == 0:
+			deals[n.Index] = h.state.Deal(dealer)
+			continue
+		default:
+			final = false
+		}
+		skipped = append(skipped, fmt.Sprintf("%d (%s)", n.Index, n.Address()))
+	}
+	return deals, skipped, final
+}
+
+// reshareShare returns the share of this node once the deals are in during a
+// resharing. The deals picked by resharers, weighted by their Lagrange
+// coefficients among them, share the distributed secret again. The deals of
+// the joining nodes share zero and are left out. The nodes of the old group
+// skipped are logged. It must be called with the lock held.
+func (h *Handler) reshareShare() (*Share, error) {
+	deals, skipped, _ := h.resharers()
+	if len(deals) < h.conf.OldGroup.Threshold {
+		return nil, fmt.Errorf("dkg: only %d qualified deals from the current group, at least %d (the current threshold) are needed to reshare, missing the nodes %s", len(deals), h.conf.OldGroup.Threshold, strings.Join(skipped, ", "))
+	}
+	if len(skipped) > 0 {
+		slog.Infof("dkg: resharing without the nodes %s of the current group", strings.Join(skipped, ", "))
+	}
+	suite := h.conf.Suite
+	indexes := make([]int, 0, len(deals))
+	for i := range deals {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	secret := suite.Scalar().Zero()
+	var commits []kyber.Point
+	for _, i := range indexes {
+		deal := deals[i]
+		lambda := lagrangeBasis(suite, indexes, i)
+		secret.Add(secret, suite.Scalar().Mul(lambda, deal.SecShare.V))
+		if commits == nil {
+			commits = make([]kyber.Point, len(deal.Commitments))
+			for k := range commits {
+				commits[k] = suite.Point().Null()
+			}
+		}
+		if len(deal.Commitments) != len(commits) {
+			return nil, fmt.Errorf("dkg: deal of node %d of the current group has %d commitments instead of %d", i, len(deal.Commitments), len(commits))
+		}
+		for k, c := range deal.Commitments {
+			commits[k].Add(commits[k], suite.Point().Mul(lambda, c))
+		}
+	}
+	if !commits[0].Equal(h.conf.Public.Key) {
+		return nil, errors.New("dkg: reshared distributed key differs from the current one")
+	}
+	return &Share{
+		Commits: commits,
+		Share:   &share.PriShare{I: h.idx, V: secret},
+	}, nil
+}
+
+// confirm keeps the share reshared by this node until the other nodes confirm
+// they reshared the same distributed polynomial, and broadcasts the polynomial
+// for them to check it. The confirmations must arrive before the timeout, if
+// any, which starts again. It must be called with the lock held.
+func (h *Handler) confirm(dks *Share) {
+	h.pending = dks
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	if h.conf.Timeout > 0 {
+		h.timer = time.AfterFunc(h.conf.Timeout, h.confirmTimeout)
+	}
+	commits := make([]*crypto.Point, len(dks.Commits))
+	for i, c := range dks.Commits {
+		var err error
+		if commits[i], err = crypto.KyberToProtoPoint(c); err != nil {
+			h.fail(fmt.Errorf("dkg: error encoding reshared polynomial: %s", err))
+			return
+		}
+	}
+	packet := &dkg_proto.DKGPacket{Reshared: &dkg_proto.Reshared{Commits: commits}}
+	if err := SignPacket(h.private, h.idx, packet); err != nil {
+		h.fail(fmt.Errorf("dkg: error signing reshared polynomial: %s", err))
+		return
+	}
+	slog.Debugf("dkg: broadcasting reshared polynomial")
+	go h.broadcast(packet)
+	h.checkConfirmed()
+}
+
+// processReshared records the distributed polynomial reshared by another node,
+// which may come before this node reshared its own.
+func (h *Handler) processReshared(origin uint32, pr *dkg_proto.Reshared) {
+	h.Lock()
+	defer h.Unlock()
+	if !h.conf.resharing() || int(origin) >= h.n || origin == uint32(h.idx) {
+		slog.Infof("dkg: dropping reshared polynomial of index %d: not resharing, out of the group or own index", origin)
+		return
+	}
+	if _, ok := h.confirmations[origin]; ok {
+		slog.Infof("dkg: dropping reshared polynomial of %s: already received", h.raddr(origin))
+		return
+	}
+	commits := make([]kyber.Point, len(pr.GetCommits()))
+	for i, c := range pr.GetCommits() {
+		commits[i] = h.conf.Suite.Point()
+		if err := commits[i].UnmarshalBinary(c.GetData()); err != nil {
+			slog.Infof("dkg: invalid reshared polynomial from %s: %s", h.raddr(origin), err)
+			return
+		}
+	}
+	h.confirmations[origin] = commits
+	h.checkConfirmed()
+}
+
+// checkConfirmed finishes the resharing once the other nodes whose deal has
+// been received and not disqualified have all reshared the same distributed
+// polynomial as this node, and aborts it as soon as one of them reshared
+// another one. It must be called with the lock held.
+func (h *Handler) checkConfirmed() {
+	if h.pending == nil || h.done {
+		return
+	}
+	for i := 0; i < h.n; i++ {
+		commits, ok := h.confirmations[uint32(i)]
+		if !ok || !h.confirming(uint32(i)) {
+			continue
+		}
+		if !equalPoints(commits, h.pending.Commits) {
+			h.fail(fmt.Errorf("dkg: %s reshared another distributed polynomial", h.raddr(uint32(i))))
+			return
+		}
+	}
+	if len(h.unconfirmed()) == 0 {
+		h.finish(h.pending)
+	}
+}
+
+// confirming returns true if the node of the given index must confirm the
+// distributed polynomial reshared by this node: its deal has been received and
+// not disqualified. It must be called with the lock held.
+func (h *Handler) confirming(i uint32) bool {
+	return i != uint32(h.idx) && h.received[i] && !h.disqualified[i]
+}
+
+// unconfirmed returns the addresses of the nodes which have not confirmed the
+// distributed polynomial reshared by this node yet. It must be called with the
+// lock held.
+func (h *Handler) unconfirmed() []string {
+	var missing []string
+	for i := 0; i < h.n; i++ {
+		if _, ok := h.confirmations[uint32(i)]; !ok && h.confirming(uint32(i)) {
+			missing = append(missing, h.raddr(uint32(i)))
+		}
+	}
+	return missing
+}
+
+// confirmTimeout aborts the resharing if the other nodes have not all
+// confirmed the distributed polynomial reshared by this node in time.
+func (h *Handler) confirmTimeout() {
+	h.Lock()
+	defer h.Unlock()
+	if h.done {
+		return
+	}
+	h.fail(fmt.Errorf("dkg: timeout after %s waiting for %s to confirm the reshared distributed polynomial", h.conf.Timeout, strings.Join(h.unconfirmed(), ", ")))
+}
+
+// equalPoints returns true if both lists hold the same points in the same
+// order.
+func equalPoints(a, b []kyber.Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// lagrangeBasis returns the Lagrange coefficient at zero of the share of
+// index i among the shares of the given indexes. As for kyber private shares,
+// the share of index i is the evaluation of the polynomial at i+1.
+func lagrangeBasis(suite Suite, indexes []int, i int) kyber.Scalar {
+	xi := suite.Scalar().SetInt64(int64(i + 1))
+	num := suite.Scalar().One()
+	den := suite.Scalar().One()
+	for _, j := range indexes {
+		if j == i {
+			continue
+		}
+		xj := suite.Scalar().SetInt64(int64(j + 1))
+		num.Mul(num, xj)
+		den.Mul(den, suite.Scalar().Sub(xj, xi))
+	}
+	return num.Div(num, den)
+}
@@ -0,0 +1,407 @@
+package dkg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dedis/drand/key"
+	"github.com/dedis/kyber"
+	dkg "github.com/dedis/kyber/share/dkg/pedersen"
+	vss "github.com/dedis/kyber/share/vss/pedersen"
+	"github.com/dedis/kyber/sign/schnorr"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Transcript records the messages a node received and sent during a DKG, so
+// the DKG can be audited offline with VerifyTranscript: the commitments of
+// each dealer, the signed responses of the nodes approving or complaining
+// about each deal, and the qualified dealers whose commitments sum up to the
+// distributed key. The shares themselves are never recorded, nor are the
+// justifications since they reveal the share of the complaining node.
+type Transcript struct {
+	// Node is the index in the group of the node that recorded the transcript
+	Node uint32 `json:"node"`
+	// Deals are the deals received by the node, sorted by dealer, with the
+	// deal of the node itself
+	Deals []*TranscriptDeal `json:"deals"`
+	// Responses are the valid responses received and sent by the node,
+	// sorted by dealer and then by verifier
+	Responses []*TranscriptResponse `json:"responses"`
+	// Qualified are the indexes of the dealers whose deal is certified
+	Qualified []int `json:"qualified"`
+	// DistKey are the hex encoded coefficients of the distributed public
+	// polynomial, the first one being the distributed public key
+	DistKey []string `json:"dist_key"`
+	// Reshared gives for a resharing the index in the old group of each
+	// dealer of the old group whose deal reshares the distributed key, the
+	// first threshold of them qualified: the distributed polynomial is then
+	// the sum of their polynomials weighted by their Lagrange coefficients
+	// among them, the deals of the joining nodes being left out.
+	Reshared map[uint32]int `json:"reshared,omitempty"`
+}
+
+// TranscriptDeal is a deal received during the DKG, without the encrypted
+// share. The deal of the node recording the transcript has no DH key nor
+// signature.
+type TranscriptDeal struct {
+	Dealer uint32 `json:"dealer"`
+	// DHKey is the hex encoded ephemeral key with which the share is
+	// encrypted, and Signature its signature by the long-term key of the
+	// dealer
+	DHKey     string `json:"dh_key,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	// Threshold is the threshold of the deal and Commitments the hex encoded
+	// coefficients of the public polynomial of the dealer
+	Threshold   uint32   `json:"threshold"`
+	Commitments []string `json:"commitments"`
+}
+
+// TranscriptResponse is the response of a verifier about the deal of a dealer,
+// signed by the long-term key of the verifier.
+type TranscriptResponse struct {
+	Dealer    uint32 `json:"dealer"`
+	Verifier  uint32 `json:"verifier"`
+	SessionID string `json:"session_id"`
+	Approved  bool   `json:"approved"`
+	Signature string `json:"signature"`
+}
+
+// transcript accumulates the messages of a DKG as they are processed.
+type transcript struct {
+	deals     map[uint32]*TranscriptDeal
+	responses map[[2]uint32]*TranscriptResponse
+}
+
+func newTranscript() *transcript {
+	return &transcript{
+		deals:     make(map[uint32]*TranscriptDeal),
+		responses: make(map[[2]uint32]*TranscriptResponse),
+	}
+}
+
+// addDeal records a deal whose commitments have been decrypted.
+func (t *transcript) addDeal(deal *dkg.Deal, plain *vss.Deal) {
+	t.deals[deal.Index] = &TranscriptDeal{
+		Dealer:      deal.Index,
+		DHKey:       hex.EncodeToString(deal.Deal.DHKey),
+		Signature:   hex.EncodeToString(deal.Deal.Signature),
+		Threshold:   plain.T,
+		Commitments: encodePoints(plain.Commitments),
+	}
+}
+
+// addResponse records a response verified by the DKG library.
+func (t *transcript) addResponse(r *dkg.Response) {
+	t.responses[[2]uint32{r.Index, r.Response.Index}] = &TranscriptResponse{
+		Dealer:    r.Index,
+		Verifier:  r.Response.Index,
+		SessionID: hex.EncodeToString(r.Response.SessionID),
+		Approved:  r.Response.Status == vss.StatusApproval,
+		Signature: hex.EncodeToString(r.Response.Signature),
+	}
+}
+
+// Transcript returns the transcript of the DKG once the share of the node has
+// been sent on the channel returned by WaitShare.
+func (h *Handler) Transcript() (*Transcript, error) {
+	h.Lock()
+	defer h.Unlock()
+	if h.share == nil {
+		return nil, errors.New("dkg: distributed key not certified")
+	}
+	own := h.state.Deal(uint32(h.idx))
+	if own == nil {
+		return nil, errors.New("dkg: own deal not certified")
+	}
+	var qual []int
+	for _, q := range h.state.QUAL() {
+		// the deals disqualified are left out of a resharing
+		if !h.conf.resharing() || !h.disqualified[uint32(q)] {
+			qual = append(qual, q)
+		}
+	}
+	sort.Ints(qual)
+	t := &Transcript{
+		Node:      uint32(h.idx),
+		Qualified: qual,
+		DistKey:   encodePoints(h.share.Commits),
+	}
+	if h.conf.resharing() {
+		t.Reshared = make(map[uint32]int)
+		deals, _, _ := h.resharers()
+		for i := range deals {
+			idx, _ := h.conf.Group.Index(h.conf.OldGroup.Public(i))
+			t.Reshared[uint32(idx)] = i
+		}
+	}
+	t.Deals = append(t.Deals, &TranscriptDeal{
+		Dealer:      uint32(h.idx),
+		Threshold:   own.T,
+		Commitments: encodePoints(own.Commitments),
+	})
+	for i, d := range h.transcript.deals {
+		if i != uint32(h.idx) {
+			t.Deals = append(t.Deals, d)
+		}
+	}
+	sort.Slice(t.Deals, func(i, j int) bool { return t.Deals[i].Dealer < t.Deals[j].Dealer })
+	for _, r := range h.transcript.responses {
+		t.Responses = append(t.Responses, r)
+	}
+	sort.Slice(t.Responses, func(i, j int) bool {
+		ri, rj := t.Responses[i], t.Responses[j]
+		if ri.Dealer != rj.Dealer {
+			return ri.Dealer < rj.Dealer
+		}
+		return ri.Verifier < rj.Verifier
+	})
+	return t, nil
+}
+
+// VerifyTranscript checks offline that the DKG recorded in the transcript ran
+// correctly for the given group, and returns the distributed public key it
+// produced. It checks that:
+//   - the deals are signed by their dealer,
+//   - the responses are signed by their verifier, about the commitments of
+//     the deal recorded for their dealer,
+//   - each qualified dealer has been answered by all the other nodes, and
+//     approved by at least a threshold of nodes, itself included; a resharing
+//     finished at the timeout goes without the answers of the nodes missing,
+
+//   - the distributed public polynomial is the sum of the polynomials of the
+//     qualified dealers, or for a resharing the sum of the polynomials of the
+//     qualified dealers of the old group weighted by their Lagrange
+//     coefficients.
+func VerifyTranscript(group *key.Group, t *Transcript) (*key.DistPublic, error) {
+	scheme, err := key.SchemeByName(group.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	suite := scheme.DKGSuite()
+	n := group.Len()
+	points := group.Points()
+
+	sids := make(map[uint32][]byte)
+	commits := make(map[uint32][]kyber.Point)
+	for _, d := range t.Deals {
+		if int(d.Dealer) >= n {
+			return nil, fmt.Errorf("dkg: deal from unknown dealer %d", d.Dealer)
+		}
+		if _, ok := commits[d.Dealer]; ok {
+			return nil, fmt.Errorf("dkg: several deals from dealer %d", d.Dealer)
+		}
+		if d.Dealer != t.Node {
+			if err := verifyHex(suite, points[d.Dealer], d.DHKey, d.Signature); err != nil {
+				return nil, fmt.Errorf("dkg: invalid signature of the deal of dealer %d: %s", d.Dealer, err)
+			}
+		}
+		c, err := decodePoints(suite, d.Commitments)
+		if err != nil {
+			return nil, fmt.Errorf("dkg: invalid commitments of dealer %d: %s", d.Dealer, err)
+		}
+		commits[d.Dealer] = c
+		sids[d.Dealer] = sessionID(suite, points[d.Dealer], points, c, int(d.Threshold))
+	}
+
+	approvals := make(map[uint32]int)
+	answered := make(map[uint32]map[uint32]bool)
+	for _, r := range t.Responses {
+		if int(r.Dealer) >= n || int(r.Verifier) >= n {
+			return nil, fmt.Errorf("dkg: response of verifier %d about dealer %d out of the group", r.Verifier, r.Dealer)
+		}
+		sid, err := hex.DecodeString(r.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := hex.DecodeString(r.Signature)
+		if err != nil {
+			return nil, err
+		}
+		status := vss.StatusComplaint
+		if r.Approved {
+			status = vss.StatusApproval
+		}
+		resp := &vss.Response{SessionID: sid, Index: r.Verifier, Status: status}
+		if err := schnorr.Verify(suite, points[r.Verifier], resp.Hash(suite), sig); err != nil {
+			return nil, fmt.Errorf("dkg: invalid signature of the response of verifier %d about dealer %d: %s", r.Verifier, r.Dealer, err)
+		}
+		if expected, ok := sids[r.Dealer]; ok && !bytes.Equal(expected, sid) {
+			return nil, fmt.Errorf("dkg: response of verifier %d is about other commitments than the ones of dealer %d", r.Verifier, r.Dealer)
+		}
+		if answered[r.Dealer] == nil {
+			answered[r.Dealer] = make(map[uint32]bool)
+		}
+		answered[r.Dealer][r.Verifier] = true
+		if r.Approved {
+			approvals[r.Dealer]++
+		}
+	}
+
+	var oldIndexes []int
+	qualified := make(map[uint32]bool)
+	for _, q := range t.Qualified {
+		qualified[uint32(q)] = true
+	}
+	for dealer, i := range t.Reshared {
+		if !qualified[dealer] {
+			return nil, fmt.Errorf("dkg: reshared deal of dealer %d not qualified", dealer)
+		}
+		oldIndexes = append(oldIndexes, i)
+	}
+	var dist []kyber.Point
+	for _, q := range t.Qualified {
+		dealer := uint32(q)
+		c, ok := commits[dealer]
+		if !ok {
+			return nil, fmt.Errorf("dkg: no deal recorded for qualified dealer %d", q)
+		}
+		if len(c) != group.Threshold {
+			return nil, fmt.Errorf("dkg: qualified dealer %d has %d commitments instead of %d", q, len(c), group.Threshold)
+		}
+		for v := 0; v < n && t.Reshared == nil; v++ {
+			if uint32(v) != dealer && !answered[dealer][uint32(v)] {
+				return nil, fmt.Errorf("dkg: no response of verifier %d about qualified dealer %d", v, q)
+			}
+		}
+		// the dealer approves its own deal implicitly
+		if approvals[dealer]+1 < group.Threshold {
+			return nil, fmt.Errorf("dkg: qualified dealer %d approved by %d nodes, less than the threshold %d", q, approvals[dealer]+1, group.Threshold)
+		}
+		if dist == nil {
+			dist = make([]kyber.Point, len(c))
+			for i := range dist {
+				dist[i] = suite.Point().Null()
+			}
+		}
+		weight := suite.Scalar().One()
+		if t.Reshared != nil {
+			oldIdx, ok := t.Reshared[dealer]
+			if !ok {
+				continue
+			}
+			weight = lagrangeBasis(suite, oldIndexes, oldIdx)
+		}
+		for i := range dist {
+			dist[i].Add(dist[i], suite.Point().Mul(weight, c[i]))
+		}
+	}
+	// a resharing only needs the deals of a threshold of the old group
+	if len(t.Qualified) < n && t.Reshared == nil {
+		return nil, fmt.Errorf("dkg: only %d qualified dealers out of %d", len(t.Qualified), n)
+	}
+	expected, err := decodePoints(suite, t.DistKey)
+	if err != nil {
+		return nil, fmt.Errorf("dkg: invalid distributed key: %s", err)
+	}
+	if len(expected) != len(dist) {
+		return nil, errors.New("dkg: distributed key is not the sum of the qualified deals")
+	}
+	for i := range dist {
+		if !dist[i].Equal(expected[i]) {
+			return nil, errors.New("dkg: distributed key is not the sum of the qualified deals")
+		}
+	}
+	return &key.DistPublic{Key: dist[0]}, nil
+}
+
+// decryptDeal decrypts the deal sent to the owner of the long-term key, as
+// vss.Verifier does, to record the commitments of the dealer.
+func decryptDeal(suite Suite, longterm kyber.Scalar, dealer kyber.Point, verifiers []kyber.Point, e *vss.EncryptedDeal) (*vss.Deal, error) {
+	if err := schnorr.Verify(suite, dealer, e.DHKey, e.Signature); err != nil {
+		return nil, err
+	}
+	dhKey := suite.Point()
+	if err := dhKey.UnmarshalBinary(e.DHKey); err != nil {
+		return nil, err
+	}
+	pre, err := suite.Point().Mul(longterm, dhKey).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	h := suite.Hash()
+	h.Write([]byte("vss-dealer"))
+	dealer.MarshalTo(h)
+	h.Write([]byte("vss-verifiers"))
+	for _, v := range verifiers {
+		v.MarshalTo(h)
+	}
+	context := h.Sum(nil)
+	sharedKey := make([]byte, 32)
+	if _, err := hkdf.New(suite.Hash, pre, nil, context).Read(sharedKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(sharedKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	buff, err := gcm.Open(nil, e.Nonce, e.Cipher, context)
+	if err != nil {
+		return nil, err
+	}
+	deal := &vss.Deal{}
+	return deal, deal.UnmarshalBinary(suite, buff)
+}
+
+// sessionID returns the session identifier the verifiers sign in their
+// responses about the deal of the dealer with the given commitments, as
+// computed by the vss package.
+func sessionID(suite Suite, dealer kyber.Point, verifiers, commitments []kyber.Point, t int) []byte {
+	h := suite.Hash()
+	dealer.MarshalTo(h)
+	for _, v := range verifiers {
+		v.MarshalTo(h)
+	}
+	for _, c := range commitments {
+		c.MarshalTo(h)
+	}
+	binary.Write(h, binary.LittleEndian, uint32(t))
+	return h.Sum(nil)
+}
+
+// verifyHex verifies the hex encoded schnorr signature of the hex encoded
+// message.
+func verifyHex(suite Suite, public kyber.Point, msg, sig string) error {
+	m, err := hex.DecodeString(msg)
+	if err != nil {
+		return err
+	}
+	s, err := hex.DecodeString(sig)
+	if err != nil {
+		return err
+	}
+	return schnorr.Verify(suite, public, m, s)
+}
+
+func encodePoints(points []kyber.Point) []string {
+	encoded := make([]string, len(points))
+	for i, p := range points {
+		buff, _ := p.MarshalBinary()
+		encoded[i] = hex.EncodeToString(buff)
+	}
+	return encoded
+}
+
+func decodePoints(g kyber.Group, encoded []string) ([]kyber.Point, error) {
+	points := make([]kyber.Point, len(encoded))
+	for i, e := range encoded {
+		buff, err := hex.DecodeString(e)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = g.Point()
+		if err := points[i].UnmarshalBinary(buff); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
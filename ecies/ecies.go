@@ -5,7 +5,9 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
 	"errors"
+	"fmt"
 	"hash"
 	"io"
 
@@ -20,6 +22,30 @@ import (
 
 var DefaultHash = sha256.New
 
+// DefaultHashName is the name of DefaultHash.
+const DefaultHashName = "sha256"
+
+// hashes are the hash functions supported by drand nodes, by name.
+var hashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// HashFunc returns the supported hash function of the given name, or
+// DefaultHash if the name is empty. It returns an error for hash functions that
+// are not supported.
+func HashFunc(name string) (func() hash.Hash, error) {
+	if name == "" {
+		return DefaultHash, nil
+	}
+	fn, ok := hashes[name]
+	if !ok {
+		return nil, fmt.Errorf("ecies: unsupported hash function %q", name)
+	}
+	return fn, nil
+}
+
 // Encrypts performs a ephemereal-static  DH exchange, creates the shared key
 // from it using a KDF scheme (hkdf from Go at the time of writing) and then
 // computes the ciphertext using a AEAD scheme (AES-GCM from Go at the time of
@@ -19,3 +19,14 @@ func TestECIES(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, msg, plain)
 }
+
+func TestHashFunc(t *testing.T) {
+	fn, err := HashFunc("")
+	require.NoError(t, err)
+	require.Equal(t, sha256.Size, fn().Size())
+	fn, err = HashFunc("sha512")
+	require.NoError(t, err)
+	require.Equal(t, 64, fn().Size())
+	_, err = HashFunc("md5")
+	require.Error(t, err)
+}
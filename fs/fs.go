@@ -9,6 +9,14 @@ import (
 	"path"
 )
 
+// SecureFileMode is the mode of the files holding private material, such as
+// the private key or the share: readable and writable by the owner only.
+const SecureFileMode os.FileMode = 0600
+
+// SecureFolderMode is the mode of the folders holding private material,
+// accessible by the owner only.
+const SecureFolderMode os.FileMode = 0700
+
 func HomeFolder() string {
 	u, err := user.Current()
 	if err != nil {
@@ -22,20 +30,21 @@ func CreateHomeConfigFolder(folder string) string {
 	if err != nil {
 		panic(err)
 	}
-	path := path.Join(u.HomeDir, folder)
-	if exists, _ := Exists(path); !exists {
-		if err := os.MkdirAll(path, 0740); err != nil {
-			panic(err)
-		}
-	}
-	return path
+	return CreateSecureFolder(path.Join(u.HomeDir, folder))
 }
+
+// CreateSecureFolder creates the folder, and its missing parents, with the
+// SecureFolderMode regardless of the umask. An existing folder is left as is.
 func CreateSecureFolder(folder string) string {
 	if exists, _ := Exists(folder); !exists {
-		if err := os.MkdirAll(folder, 0740); err != nil {
+		if err := os.MkdirAll(folder, SecureFolderMode); err != nil {
 			fmt.Println("folder", folder, ",err", err)
 			panic(err)
 		}
+		// the umask may have made the mode looser than asked
+		if err := os.Chmod(folder, SecureFolderMode); err != nil {
+			panic(err)
+		}
 	}
 	return folder
 }
@@ -61,18 +70,31 @@ func Exists(path string) (bool, error) {
 	return true, err
 }
 
-// CreateSecureFile creates a file with wr permission for user only and returns
-// the file handle.
+// CreateSecureFile creates or truncates a file with the SecureFileMode and
+// returns the file handle. The mode of an existing file is tightened as well.
 func CreateSecureFile(file string) (*os.File, error) {
-	fd, err := os.Create(file)
+	fd, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, SecureFileMode)
 	if err != nil {
 		return nil, err
 	}
-	fd.Close()
-	if err := os.Chmod(file, 0600); err != nil {
-		return nil, nil
+	if err := fd.Chmod(SecureFileMode); err != nil {
+		fd.Close()
+		return nil, err
+	}
+	return fd, nil
+}
+
+// CheckSecureFile returns an error if the file is accessible by the group or
+// by others, like ssh does for private keys.
+func CheckSecureFile(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		return fmt.Errorf("permissions %#o of %s are too open, it must only be accessible by its owner (chmod 600)", mode, file)
 	}
-	return os.OpenFile(file, os.O_RDWR, 0600)
+	return nil
 }
 
 // Files returns the list of file names included in the given path or error if
@@ -0,0 +1,73 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// NormalizeAddress returns the canonical form of a "host:port" address as
+// pasted by users: surrounding whitespace, any scheme such as "http://" and
+// any trailing path are removed, the host is lowercased, IP addresses are
+// written in their shortest form and the port is written without leading
+// zeros. IPv6 addresses are written in brackets, as in "[2001:db8::1]:4000".
+// An IPv6 address without brackets is only accepted when it can not be
+// mistaken for an address without a port, as in "2001:db8:0:0:0:0:0:1:4000".
+// It returns an error if the address has no host, no port, or an invalid port.
+func NormalizeAddress(addr string) (string, error) {
+	a := strings.TrimSpace(addr)
+	if i := strings.Index(a, "://"); i >= 0 {
+		a = a[i+3:]
+	}
+	if i := strings.Index(a, "/"); i >= 0 {
+		a = a[:i]
+	}
+	host, port, err := splitHostPort(a)
+	if err != nil {
+		return "", fmt.Errorf("key: invalid address %q: %s", addr, err)
+	}
+	if host == "" || strings.ContainsAny(host, " \t\r\n") {
+		return "", fmt.Errorf("key: invalid host in address %q", addr)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return "", fmt.Errorf("key: invalid port in address %q", addr)
+	}
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p)), nil
+}
+
+// splitHostPort splits the address as net.SplitHostPort, and also accepts an
+// IPv6 address without brackets followed by a port if the whole address is not
+// itself an IPv6 address.
+func splitHostPort(a string) (string, string, error) {
+	host, port, err := net.SplitHostPort(a)
+	if err == nil {
+		return host, port, nil
+	}
+	i := strings.LastIndex(a, ":")
+	if i < 0 || net.ParseIP(a) != nil {
+		if strings.Count(a, ":") > 1 {
+			return "", "", errors.New("IPv6 address must be written in brackets followed by the port, as in [2001:db8::1]:4000")
+		}
+		return "", "", err
+	}
+	if net.ParseIP(a[:i]) == nil {
+		return "", "", err
+	}
+	return a[:i], a[i+1:], nil
+}
+
+// normalizeAddress returns the canonical form of the address if it is valid,
+// or the address stripped of surrounding whitespace otherwise.
+func normalizeAddress(addr string) string {
+	if a, err := NormalizeAddress(addr); err == nil {
+		return a
+	}
+	return strings.TrimSpace(addr)
+}
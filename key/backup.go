@@ -0,0 +1,56 @@
+package key
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ExportShare writes the share to w in TOML, in clear if the passphrase is nil
+// or else encrypted with it like an encrypted private key. The output holds the
+// private share of the node and MUST be kept as secret as the share itself.
+func ExportShare(w io.Writer, s *Share, passphrase []byte) error {
+	if passphrase == nil {
+		return toml.NewEncoder(w).Encode(s.TOML())
+	}
+	var buff bytes.Buffer
+	if err := toml.NewEncoder(&buff).Encode(s.TOML()); err != nil {
+		return err
+	}
+	enc, err := encryptBytes(buff.Bytes(), passphrase)
+	if err != nil {
+		return err
+	}
+	return toml.NewEncoder(w).Encode(enc)
+}
+
+// ImportShare reads a share written by ExportShare. If the share is encrypted,
+// the passphrase function is called to get the passphrase decrypting it and
+// ErrWrongPassphrase is returned if it does not.
+func ImportShare(r io.Reader, passphrase func() []byte) (*Share, error) {
+	buff, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	enc := new(EncryptedPairTOML)
+	if _, err := toml.Decode(string(buff), enc); err != nil {
+		return nil, err
+	}
+	if enc.Ciphertext != "" {
+		if passphrase == nil {
+			return nil, errors.New("key: share is encrypted but no passphrase given")
+		}
+		if buff, err = decryptBytes(enc, passphrase()); err != nil {
+			return nil, err
+		}
+	}
+	st := new(ShareTOML)
+	if _, err := toml.Decode(string(buff), st); err != nil {
+		return nil, err
+	}
+	s := new(Share)
+	return s, s.FromTOML(st)
+}
@@ -0,0 +1,74 @@
+package key
+
+import (
+	"errors"
+	"math/big"
+)
+
+// SignatureSize is the size of the uncompressed encoding of a signature of the
+// default scheme, a point of G1: its two coordinates.
+const SignatureSize = 64
+
+// CompressedSignatureSize is the size of the compressed encoding of a
+// signature: a prefix byte, 0x02 for an even y coordinate and 0x03 for an odd
+// one, followed by the x coordinate.
+const CompressedSignatureSize = 33
+
+// g1Modulus is the modulus of the field of the coordinates of G1, on which
+// the curve is y^2 = x^3 + 3.
+var g1Modulus, _ = new(big.Int).SetString("65000549695646603732796438742359905742825358107623003571877145026864184071783", 10)
+
+// g1SqrtExp is (p+1)/4: since p = 3 mod 4, a^((p+1)/4) is a square root of a
+// if a is a square.
+var g1SqrtExp = new(big.Int).Rsh(new(big.Int).Add(g1Modulus, big.NewInt(1)), 2)
+
+// CompressSignature returns the compressed encoding of the given uncompressed
+// signature, about half its size. A signature that is compressed already is
+// returned as is.
+func CompressSignature(sig []byte) ([]byte, error) {
+	if len(sig) == CompressedSignatureSize {
+		return sig, nil
+	}
+	if len(sig) != SignatureSize {
+		return nil, errors.New("key: invalid signature size")
+	}
+	x, y := sig[:SignatureSize/2], new(big.Int).SetBytes(sig[SignatureSize/2:])
+	if y.Sign() == 0 {
+		return nil, errors.New("key: can not compress the point at infinity")
+	}
+	out := make([]byte, CompressedSignatureSize)
+	out[0] = 0x02 | byte(y.Bit(0))
+	copy(out[1:], x)
+	return out, nil
+}
+
+// DecompressSignature returns the uncompressed encoding of the given
+// signature, the one the beacons are signed and chained with. An uncompressed
+// signature, as sent by the nodes not compressing their responses, is
+// returned as is.
+func DecompressSignature(sig []byte) ([]byte, error) {
+	if len(sig) != CompressedSignatureSize {
+		return sig, nil
+	}
+	if sig[0] != 0x02 && sig[0] != 0x03 {
+		return nil, errors.New("key: invalid compressed signature prefix")
+	}
+	x := new(big.Int).SetBytes(sig[1:])
+	if x.Cmp(g1Modulus) >= 0 {
+		return nil, errors.New("key: invalid compressed signature coordinate")
+	}
+	rhs := new(big.Int).Exp(x, big.NewInt(3), g1Modulus)
+	rhs.Add(rhs, big.NewInt(3)).Mod(rhs, g1Modulus)
+	y := new(big.Int).Exp(rhs, g1SqrtExp, g1Modulus)
+	if new(big.Int).Exp(y, big.NewInt(2), g1Modulus).Cmp(rhs) != 0 {
+		return nil, errors.New("key: compressed signature is not on the curve")
+	}
+	if y.Bit(0) != uint(sig[0]&1) {
+		y.Sub(g1Modulus, y)
+	}
+	out := make([]byte, SignatureSize)
+	copy(out, sig[1:])
+	yb := y.Bytes()
+	copy(out[SignatureSize-len(yb):], yb)
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package key
+
+import (
+	"testing"
+
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressSignature(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		pair := NewKeyPair("127.0.0.1:80")
+		msg := []byte{byte(i)}
+		sig, err := bls.Sign(Pairing, pair.Key, msg)
+		require.NoError(t, err)
+		require.Len(t, sig, SignatureSize)
+
+		compressed, err := CompressSignature(sig)
+		require.NoError(t, err)
+		require.Len(t, compressed, CompressedSignatureSize)
+		again, err := CompressSignature(compressed)
+		require.NoError(t, err)
+		require.Equal(t, compressed, again)
+
+		decompressed, err := DecompressSignature(compressed)
+		require.NoError(t, err)
+		require.Equal(t, sig, decompressed)
+		require.NoError(t, bls.Verify(Pairing, pair.Public.Key, msg, decompressed))
+		// uncompressed signatures are read as is
+		same, err := DecompressSignature(sig)
+		require.NoError(t, err)
+		require.Equal(t, sig, same)
+	}
+
+	_, err := CompressSignature(make([]byte, SignatureSize))
+	require.Error(t, err)
+	_, err = CompressSignature([]byte{1, 2, 3})
+	require.Error(t, err)
+	invalid := make([]byte, CompressedSignatureSize)
+	_, err = DecompressSignature(invalid)
+	require.Error(t, err)
+	for i := range invalid[1:] {
+		invalid[i+1] = 0xff
+	}
+	invalid[0] = 0x02
+	_, err = DecompressSignature(invalid)
+	require.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package key
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// MasterSeedSize is the minimum size in bytes of a master seed from which node
+// key pairs are derived.
+const MasterSeedSize = 32
+
+// derivationSalt is the HKDF salt used to derive node key pairs from a master
+// seed. It must never change, otherwise previously derived keys can not be
+// recovered anymore.
+var derivationSalt = []byte("drand-key-derivation-v1")
+
+// DeriveKeyPair deterministically derives the private / public key pair of
+// index from the master seed. The private key is the output of
+// HKDF-SHA256(master, salt, index) taken over 64 bytes, interpreted as a
+// big-endian integer and reduced modulo the order of the group, where index is
+// encoded as a big-endian uint32. Deriving the same index twice gives the
+// same key pair, so all the key pairs can be recovered from the master seed
+// alone. Knowing a derived private key reveals nothing about the master seed
+// nor about the other derived keys, but knowing the master seed reveals all of
+// them.
+func DeriveKeyPair(master []byte, index uint32, address string) (*Pair, error) {
+	if len(master) < MasterSeedSize {
+		return nil, errors.New("key: master seed must be at least 32 bytes long")
+	}
+	var info [4]byte
+	binary.BigEndian.PutUint32(info[:], index)
+	var buff [64]byte
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, derivationSalt, info[:]), buff[:]); err != nil {
+		return nil, err
+	}
+	key := G2.Scalar().SetBytes(buff[:])
+	if key.Equal(G2.Scalar().Zero()) {
+		return nil, errors.New("key: derived a zero private key")
+	}
+	return &Pair{
+		Key: key,
+		Public: &Identity{
+			Key:    G2.Point().Mul(key, nil),
+			Addr:   normalizeAddress(address),
+			Scheme: DefaultSchemeName,
+		},
+	}, nil
+}
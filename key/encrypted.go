@@ -0,0 +1,231 @@
+package key
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dedis/drand/fs"
+	"github.com/dedis/kyber"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrWrongPassphrase is returned when loading an encrypted private key with a
+// passphrase that does not decrypt it.
+var ErrWrongPassphrase = errors.New("key: wrong passphrase or corrupted private key file")
+
+// The cost parameters of scrypt, the key derivation function deriving the
+// encryption key from the passphrase. They are saved with the encrypted key,
+// so raising them does not prevent loading the keys saved before.
+const (
+	ScryptN = 1 << 15
+	ScryptR = 8
+	ScryptP = 1
+)
+
+// The bounds on the cost parameters of scrypt read from a key file, so that a
+// tampered or corrupted file cannot make the node exhaust its memory or CPU
+// while deriving the key: scrypt uses 128*N*R bytes of memory.
+const (
+	maxScryptN   = 1 << 20
+	maxScryptP   = 16
+	maxScryptMem = 1 << 30
+)
+
+const kdfName = "scrypt"
+const saltSize = 16
+
+// EncryptedPairTOML is the TOML-able version of a private key encrypted with a
+// passphrase. The key is derived from the passphrase with scrypt, whose
+// parameters are recorded, and the private scalar is encrypted with
+// AES-256-GCM.
+type EncryptedPairTOML struct {
+	KDF string
+	// N, R and P are the cost parameters of scrypt
+	N          int
+	R          int
+	P          int
+	Salt       string
+	Nonce      string
+	Ciphertext string
+}
+
+// encryptedFileStore is a fileStore that encrypts the private key with a
+// passphrase. All other files, including the public key, are saved in clear.
+type encryptedFileStore struct {
+	*fileStore
+	passphrase []byte
+}
+
+// NewEncryptedFileStore returns a file store, as NewFileStore, saving the
+// private key encrypted with the given passphrase. LoadKeyPair returns
+// ErrWrongPassphrase if the passphrase does not decrypt the private key.
+func NewEncryptedFileStore(baseFolder string, passphrase []byte, opts ...FileStoreOption) Store {
+	return &encryptedFileStore{
+		fileStore:  NewFileStore(baseFolder, opts...).(*fileStore),
+		passphrase: passphrase,
+	}
+}
+
+// SaveKeyPair saves the private key encrypted in a file with tight permissions
+// and the public key in clear in another file.
+func (e *encryptedFileStore) SaveKeyPair(p *Pair) error {
+	enc, err := encryptScalar(p, e.passphrase)
+	if err != nil {
+		return err
+	}
+	fd, err := fs.CreateSecureFile(e.privateKeyFile)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	if err := toml.NewEncoder(fd).Encode(enc); err != nil {
+		return err
+	}
+	return Save(e.publicKeyFile, p.Public, false)
+}
+
+// LoadKeyPair decrypts the private key and loads the public key, which must be
+// the one of the private key.
+func (e *encryptedFileStore) LoadKeyPair() (*Pair, error) {
+	if err := e.checkPrivate(e.privateKeyFile); err != nil {
+		return nil, err
+	}
+	enc := new(EncryptedPairTOML)
+	if _, err := toml.DecodeFile(e.privateKeyFile, enc); err != nil {
+		return nil, err
+	}
+	if enc.Ciphertext == "" {
+		return nil, errors.New("key: private key file is not encrypted")
+	}
+	p := &Pair{Public: new(Identity)}
+	if err := Load(e.publicKeyFile, p.Public); err != nil {
+		return nil, err
+	}
+	scheme, err := SchemeByName(p.Public.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	if p.Key, err = decryptScalar(scheme, enc, e.passphrase); err != nil {
+		return nil, err
+	}
+	if !scheme.KeyGroup.Point().Mul(p.Key, nil).Equal(p.Public.Key) {
+		return nil, errors.New("key: the private key does not match the public key")
+	}
+	return p, nil
+}
+
+// IsKeyEncrypted returns true if the private key saved in the given folder is
+// encrypted, i.e. if it must be loaded with NewEncryptedFileStore.
+func IsKeyEncrypted(baseFolder string) bool {
+	f, err := os.Open(NewFileStore(baseFolder).(*fileStore).privateKeyFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	enc := new(EncryptedPairTOML)
+	if _, err := toml.DecodeReader(f, enc); err != nil {
+		return false
+	}
+	return enc.Ciphertext != ""
+}
+
+func encryptScalar(p *Pair, passphrase []byte) (*EncryptedPairTOML, error) {
+	plain, err := p.Key.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return encryptBytes(plain, passphrase)
+}
+
+// decryptScalar decrypts the private key, a scalar of the key group of the
+// given scheme.
+func decryptScalar(scheme *Scheme, enc *EncryptedPairTOML, passphrase []byte) (kyber.Scalar, error) {
+	plain, err := decryptBytes(enc, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s := scheme.KeyGroup.Scalar()
+	return s, s.UnmarshalBinary(plain)
+}
+
+// encryptBytes encrypts the plaintext under a key derived from the passphrase
+// with a fresh salt.
+func encryptBytes(plain, passphrase []byte) (*EncryptedPairTOML, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	aead, err := newKeyAEAD(passphrase, salt, ScryptN, ScryptR, ScryptP)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return &EncryptedPairTOML{
+		KDF:        kdfName,
+		N:          ScryptN,
+		R:          ScryptR,
+		P:          ScryptP,
+		Salt:       hex.EncodeToString(salt),
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, plain, nil)),
+	}, nil
+}
+
+// decryptBytes returns the plaintext encrypted by encryptBytes, or
+// ErrWrongPassphrase if the passphrase does not decrypt it.
+func decryptBytes(enc *EncryptedPairTOML, passphrase []byte) ([]byte, error) {
+	if enc.KDF != kdfName {
+		return nil, fmt.Errorf("key: unsupported key derivation function %q", enc.KDF)
+	}
+	salt, err := hex.DecodeString(enc.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(enc.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(enc.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newKeyAEAD(passphrase, salt, enc.N, enc.R, enc.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("key: invalid nonce size")
+	}
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
+
+// newKeyAEAD returns the AES-256-GCM cipher keyed by the key derived from the
+// passphrase with scrypt.
+func newKeyAEAD(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	if n <= 1 || n > maxScryptN || r <= 0 || r > maxScryptMem/(128*n) || p <= 0 || p > maxScryptP {
+		return nil, fmt.Errorf("key: key derivation parameters N=%d, R=%d, P=%d out of bounds", n, r, p)
+	}
+	key, err := scrypt.Key(passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("key: invalid key derivation parameters: %s", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
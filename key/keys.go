@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 
 	kyber "github.com/dedis/kyber"
 	"github.com/dedis/kyber/pairing/bn256"
@@ -33,6 +34,17 @@ type Identity struct {
 	Key  kyber.Point
 	Addr string
 	TLS  bool
+	// Scheme is the name of the scheme of the key, DefaultSchemeName if empty
+	Scheme string
+	// Name and Organization optionally describe the node to the operators of
+	// the group. They are informational only: they are neither compared by
+	// Equal nor part of anything signed.
+	Name         string
+	Organization string
+	// TLSName is the name the TLS certificate of the node is valid for, if it
+	// is not the host of its address, e.g. when the node is reached through an
+	// IP address or a load balancer. It is not compared by Equal.
+	TLSName string
 }
 
 // Address implements the net.Peer interface
@@ -40,19 +52,53 @@ func (i *Identity) Address() string {
 	return i.Addr
 }
 
+// Label returns the address of the node followed by its name and organization
+// when they are set, to tell nodes apart in the output of the commands.
+func (i *Identity) Label() string {
+	var meta []string
+	for _, m := range []string{i.Name, i.Organization} {
+		if m != "" {
+			meta = append(meta, m)
+		}
+	}
+	if len(meta) == 0 {
+		return i.Addr
+	}
+	return fmt.Sprintf("%s (%s)", i.Addr, strings.Join(meta, ", "))
+}
+
 func (i *Identity) IsTLS() bool {
 	return i.TLS
 }
 
+// ServerName returns the name the TLS certificate of the node must be valid
+// for: its TLS name if set, or else the host of its address.
+func (i *Identity) ServerName() string {
+	if i.TLSName != "" {
+		return i.TLSName
+	}
+	host, _, err := splitHostPort(i.Addr)
+	if err != nil {
+		return i.Addr
+	}
+	return host
+}
+
 // NewKeyPair returns a freshly created private / public key pair. The group is
 // decided by the group variable by default. Currently, drand only supports
-// bn256.
+// bn256. The address is normalized with NormalizeAddress if valid.
 func NewKeyPair(address string) *Pair {
-	key := G2.Scalar().Pick(random.New())
-	pubKey := G2.Point().Mul(key, nil)
+	return NewSchemeKeyPair(address, DefaultScheme)
+}
+
+// NewSchemeKeyPair returns a freshly created key pair of the given scheme.
+func NewSchemeKeyPair(address string, s *Scheme) *Pair {
+	key := s.KeyGroup.Scalar().Pick(random.New())
+	pubKey := s.KeyGroup.Point().Mul(key, nil)
 	pub := &Identity{
-		Key:  pubKey,
-		Addr: address,
+		Key:    pubKey,
+		Addr:   normalizeAddress(address),
+		Scheme: s.Name,
 	}
 	return &Pair{
 		Key:    key,
@@ -73,9 +119,13 @@ type PairTOML struct {
 
 // PublicTOML is the TOML-able version of a public key
 type PublicTOML struct {
-	Address string
-	Key     string
-	TLS     bool
+	Address      string
+	Key          string
+	TLS          bool
+	Scheme       string
+	Name         string `toml:",omitempty"`
+	Organization string `toml:",omitempty"`
+	TLSName      string `toml:",omitempty"`
 }
 
 // TOML returns a struct that can be marshalled using a TOML-encoding library
@@ -108,9 +158,35 @@ func (p *Pair) TOMLValue() interface{} {
 	return &PairTOML{}
 }
 
-// Equal returns true if the cryptographic public key of p equals p2's
+// Equal returns true if p and p2 have the same public key, compared by its
+// binary encoding, the same address and the same TLS flag and scheme. Two nil
+// identities are equal.
 func (p *Identity) Equal(p2 *Identity) bool {
-	return p.Key.Equal(p2.Key)
+	if p == nil || p2 == nil {
+		return p == p2
+	}
+	if !samePoint(p.Key, p2.Key) {
+		return false
+	}
+	return normalizeAddress(p.Addr) == normalizeAddress(p2.Addr) &&
+		p.TLS == p2.TLS &&
+		schemeName(p.Scheme) == schemeName(p2.Scheme)
+}
+
+// samePoint returns true if both points have the same binary encoding.
+func samePoint(a, b kyber.Point) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ab, err := a.MarshalBinary()
+	if err != nil {
+		return false
+	}
+	bb, err := b.MarshalBinary()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
 }
 
 // FromTOML loads reads the TOML description of the public key
@@ -119,13 +195,21 @@ func (p *Identity) FromTOML(i interface{}) error {
 	if !ok {
 		return errors.New("Public can't decode from non PublicTOML struct")
 	}
+	scheme, err := SchemeByName(ptoml.Scheme)
+	if err != nil {
+		return err
+	}
 	buff, err := hex.DecodeString(ptoml.Key)
 	if err != nil {
 		return err
 	}
-	p.Addr = ptoml.Address
-	p.Key = G2.Point()
+	p.Addr = normalizeAddress(ptoml.Address)
+	p.Key = scheme.KeyGroup.Point()
 	p.TLS = ptoml.TLS
+	p.Scheme = scheme.Name
+	p.Name = ptoml.Name
+	p.Organization = ptoml.Organization
+	p.TLSName = ptoml.TLSName
 	return p.Key.UnmarshalBinary(buff)
 }
 
@@ -133,9 +217,13 @@ func (p *Identity) FromTOML(i interface{}) error {
 func (p *Identity) TOML() interface{} {
 	hex := pointToString(p.Key)
 	return &PublicTOML{
-		Address: p.Addr,
-		Key:     hex,
-		TLS:     p.TLS,
+		Address:      p.Addr,
+		Key:          hex,
+		TLS:          p.TLS,
+		Scheme:       schemeName(p.Scheme),
+		Name:         p.Name,
+		Organization: p.Organization,
+		TLSName:      p.TLSName,
 	}
 }
 
@@ -168,6 +256,9 @@ func (b ByKey) Less(i, j int) bool {
 type Group struct {
 	Nodes     []*IndexedPublic
 	Threshold int
+	// Scheme is the name of the scheme of the nodes, DefaultSchemeName if
+	// empty
+	Scheme string
 }
 
 // IndexedPublic wraps a Public with its index relative to the group
@@ -179,7 +270,7 @@ type IndexedPublic struct {
 // Contains returns true if the public key is contained in the list or not.
 func (g *Group) Contains(pub *Identity) bool {
 	for _, pu := range g.Nodes {
-		if pu.Equal(pub) {
+		if pu.Key.Equal(pub.Key) {
 			return true
 		}
 	}
@@ -190,7 +281,7 @@ func (g *Group) Contains(pub *Identity) bool {
 // whether the public has been found or not.
 func (g *Group) Index(pub *Identity) (int, bool) {
 	for _, pu := range g.Nodes {
-		if pu.Equal(pub) {
+		if pu.Key.Equal(pub.Key) {
 			return pu.Index, true
 		}
 	}
@@ -223,6 +314,31 @@ func (g *Group) Identities() []*Identity {
 	return ids
 }
 
+// Equal returns true if both groups have the same threshold, scheme and
+// members, whatever the order of their nodes: each member must have the same
+// index in both groups, and be Equal to its counterpart. Two nil groups are
+// equal.
+func (g *Group) Equal(other *Group) bool {
+	if g == nil || other == nil {
+		return g == other
+	}
+	if g.Threshold != other.Threshold || schemeName(g.Scheme) != schemeName(other.Scheme) || g.Len() != other.Len() {
+		return false
+	}
+	nodes := make(map[int]*Identity, g.Len())
+	for _, n := range g.Nodes {
+		nodes[n.Index] = n.Identity
+	}
+	for _, n := range other.Nodes {
+		id, ok := nodes[n.Index]
+		if !ok || !id.Equal(n.Identity) {
+			return false
+		}
+		delete(nodes, n.Index)
+	}
+	return true
+}
+
 // Len returns the number of participants in the group
 func (g *Group) Len() int {
 	return len(g.Nodes)
@@ -230,19 +346,39 @@ func (g *Group) Len() int {
 
 func (g *Group) Filter(indexes []int) *Group {
 	var filtered []*IndexedPublic
-	for idx := range indexes {
+	for _, idx := range indexes {
 		filtered = append(filtered, &IndexedPublic{Identity: g.Public(idx), Index: idx})
 	}
 	return &Group{
 		Threshold: g.Threshold,
 		Nodes:     filtered,
+		Scheme:    g.Scheme,
+	}
+}
+
+// CheckIndexes returns an error if the indexes of the nodes in the group are
+// not distinct and contiguous starting from 0. The index of a node is the index
+// of its share, so two nodes with the same index silently break the threshold
+// reconstruction of signatures.
+func (g *Group) CheckIndexes() error {
+	seen := make(map[int]*IndexedPublic, g.Len())
+	for _, n := range g.Nodes {
+		if n.Index < 0 || n.Index >= g.Len() {
+			return fmt.Errorf("group: node %s has index %d out of range [0,%d)", n.Address(), n.Index, g.Len())
+		}
+		if other, ok := seen[n.Index]; ok {
+			return fmt.Errorf("group: nodes %s and %s have the same index %d", other.Address(), n.Address(), n.Index)
+		}
+		seen[n.Index] = n
 	}
+	return nil
 }
 
 // GroupTOML is the representation of a Group TOML compatible
 type GroupTOML struct {
 	Nodes     []*PublicTOML
 	Threshold int
+	Scheme    string
 }
 
 // FromTOML decodes the group from the toml struct
@@ -252,12 +388,19 @@ func (g *Group) FromTOML(i interface{}) error {
 		return fmt.Errorf("grouptoml unknown")
 	}
 	g.Threshold = gt.Threshold
+	g.Scheme = schemeName(gt.Scheme)
+	if _, err := SchemeByName(g.Scheme); err != nil {
+		return err
+	}
 	list := make([]*Identity, len(gt.Nodes))
 	for i, ptoml := range gt.Nodes {
 		list[i] = new(Identity)
 		if err := list[i].FromTOML(ptoml); err != nil {
 			return err
 		}
+		if list[i].Scheme != g.Scheme {
+			return fmt.Errorf("group: node %s uses scheme %s, not the scheme %s of the group", list[i].Addr, list[i].Scheme, g.Scheme)
+		}
 	}
 	g.Nodes = toIndexedList(list)
 	if g.Threshold == 0 {
@@ -268,9 +411,64 @@ func (g *Group) FromTOML(i interface{}) error {
 	return nil
 }
 
+// CheckGroupTOML returns every problem found in the TOML description of a
+// group: public keys that are not valid points of their scheme or are the
+// point at infinity, duplicate addresses or keys, nodes that do not all use TLS
+// or all not use it, nodes not using the scheme of the group, and a threshold
+// outside of [DefaultThreshold(n), n]. It is meant to be run before starting a
+// DKG with this group.
+func CheckGroupTOML(gt *GroupTOML) []error {
+	var errs []error
+	n := len(gt.Nodes)
+	if n == 0 {
+		return append(errs, errors.New("group: no nodes"))
+	}
+	scheme := schemeName(gt.Scheme)
+	if _, err := SchemeByName(scheme); err != nil {
+		errs = append(errs, fmt.Errorf("group: %s", err))
+	}
+	addrs := make(map[string]int, n)
+	keys := make(map[string]int, n)
+	var tls int
+	for i, ptoml := range gt.Nodes {
+		id := new(Identity)
+		if err := id.FromTOML(ptoml); err != nil {
+			errs = append(errs, fmt.Errorf("group: node %d (%s): invalid public key: %s", i, ptoml.Address, err))
+		} else if id.Key.Equal(id.Key.Clone().Null()) {
+			errs = append(errs, fmt.Errorf("group: node %d (%s): invalid public key: point at infinity", i, ptoml.Address))
+		}
+		if s := schemeName(ptoml.Scheme); s != scheme {
+			errs = append(errs, fmt.Errorf("group: node %d (%s) uses scheme %s, not the scheme %s of the group", i, ptoml.Address, s, scheme))
+		}
+		addr := normalizeAddress(ptoml.Address)
+		if j, ok := addrs[addr]; ok {
+			errs = append(errs, fmt.Errorf("group: nodes %d and %d have the same address %s", j, i, addr))
+		} else {
+			addrs[addr] = i
+		}
+		if j, ok := keys[ptoml.Key]; ok {
+			errs = append(errs, fmt.Errorf("group: nodes %d and %d have the same public key", j, i))
+		} else {
+			keys[ptoml.Key] = i
+		}
+		if ptoml.TLS {
+			tls++
+		} else if ptoml.TLSName != "" {
+			errs = append(errs, fmt.Errorf("group: node %d (%s) has a TLS name but does not use TLS", i, ptoml.Address))
+		}
+	}
+	if tls != 0 && tls != n {
+		errs = append(errs, fmt.Errorf("group: %d nodes use TLS and %d do not", tls, n-tls))
+	}
+	if min := DefaultThreshold(n); gt.Threshold < min || gt.Threshold > n {
+		errs = append(errs, fmt.Errorf("group: threshold %d outside of [%d,%d]", gt.Threshold, min, n))
+	}
+	return errs
+}
+
 // TOML returns a TOML-encodable version of the Group
 func (g *Group) TOML() interface{} {
-	gtoml := &GroupTOML{Threshold: g.Threshold}
+	gtoml := &GroupTOML{Threshold: g.Threshold, Scheme: schemeName(g.Scheme)}
 	gtoml.Nodes = make([]*PublicTOML, g.Len())
 	for i, p := range g.Nodes {
 		gtoml.Nodes[i] = p.Identity.TOML().(*PublicTOML)
@@ -284,12 +482,33 @@ func (g *Group) TOMLValue() interface{} {
 }
 
 // NewGroup returns a list of identities as a Group. The threshold is set to a
-// the default returned by DefaultThreshod.
+// the default returned by DefaultThreshod. The scheme of the group is the
+// scheme of the first identity: CheckSchemes tells whether all the identities
+// use it.
 func NewGroup(list []*Identity, threshold int) *Group {
+	var scheme string
+	if len(list) > 0 {
+		scheme = list[0].Scheme
+	}
 	return &Group{
 		Nodes:     toIndexedList(list),
 		Threshold: threshold,
+		Scheme:    schemeName(scheme),
+	}
+}
+
+// CheckSchemes returns an error if the identities do not all use the same
+// scheme, as the nodes of a group must.
+func CheckSchemes(list []*Identity) error {
+	if len(list) == 0 {
+		return nil
 	}
+	for _, id := range list[1:] {
+		if schemeName(id.Scheme) != schemeName(list[0].Scheme) {
+			return fmt.Errorf("group: %s uses scheme %s and %s uses scheme %s", list[0].Addr, schemeName(list[0].Scheme), id.Addr, schemeName(id.Scheme))
+		}
+	}
+	return nil
 }
 
 // returns an indexed list from a list of public keys. Functionality needed in
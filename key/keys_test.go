@@ -29,6 +29,58 @@ func TestKeyPublic(t *testing.T) {
 	require.Equal(t, kp.Public.Addr, p2.Addr)
 	require.Equal(t, kp.Public.TLS, p2.TLS)
 	require.Equal(t, kp.Public.Key.String(), p2.Key.String())
+	require.Empty(t, p2.Name)
+	require.NotContains(t, writer.String(), "Name")
+}
+
+func TestKeyPublicMetadata(t *testing.T) {
+	kp := NewTLSKeyPair("127.0.0.1:80")
+	require.Equal(t, "127.0.0.1:80", kp.Public.Label())
+	kp.Public.Name = "node-1"
+	require.Equal(t, "127.0.0.1:80 (node-1)", kp.Public.Label())
+	kp.Public.Organization = "EPFL"
+	require.Equal(t, "127.0.0.1:80 (node-1, EPFL)", kp.Public.Label())
+
+	var writer bytes.Buffer
+	require.NoError(t, toml.NewEncoder(&writer).Encode(kp.Public.TOML()))
+	p2 := new(Identity)
+	p2toml := new(PublicTOML)
+	_, err := toml.DecodeReader(&writer, p2toml)
+	require.NoError(t, err)
+	require.NoError(t, p2.FromTOML(p2toml))
+	require.Equal(t, "node-1", p2.Name)
+	require.Equal(t, "EPFL", p2.Organization)
+
+	// the metadata is not part of the identity
+	p2.Name = "renamed"
+	require.True(t, kp.Public.Equal(p2))
+}
+
+func TestKeyPublicTLSName(t *testing.T) {
+	kp := NewTLSKeyPair("127.0.0.1:80")
+	// identities without TLS name expect a certificate for their host
+	require.Equal(t, "127.0.0.1", kp.Public.ServerName())
+	kp.Public.TLSName = "drand.example.org"
+	require.Equal(t, "drand.example.org", kp.Public.ServerName())
+
+	var writer bytes.Buffer
+	require.NoError(t, toml.NewEncoder(&writer).Encode(kp.Public.TOML()))
+	p2 := new(Identity)
+	p2toml := new(PublicTOML)
+	_, err := toml.DecodeReader(&writer, p2toml)
+	require.NoError(t, err)
+	require.NoError(t, p2.FromTOML(p2toml))
+	require.Equal(t, "drand.example.org", p2.TLSName)
+
+	// it only applies to nodes using TLS
+	gt := NewGroup([]*Identity{kp.Public, NewTLSKeyPair("127.0.0.1:81").Public, NewTLSKeyPair("127.0.0.1:82").Public}, 3).TOML().(*GroupTOML)
+	require.Empty(t, CheckGroupTOML(gt))
+	for _, n := range gt.Nodes {
+		n.TLS = false
+	}
+	errs := CheckGroupTOML(gt)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "TLS name")
 }
 
 func TestKeyGroup(t *testing.T) {
@@ -44,6 +96,76 @@ func TestKeyGroup(t *testing.T) {
 	}
 }
 
+func TestGroupFilter(t *testing.T) {
+	_, group := BatchIdentities(5)
+	qual := group.Filter([]int{1, 3, 4})
+	require.Equal(t, 3, qual.Len())
+	for i, idx := range []int{1, 3, 4} {
+		require.Equal(t, idx, qual.Nodes[i].Index)
+		require.True(t, qual.Nodes[i].Identity.Equal(group.Public(idx)))
+	}
+}
+
+func TestIdentityEqual(t *testing.T) {
+	pair := NewTLSKeyPair("127.0.0.1:80")
+	id := pair.Public
+	same := &Identity{Key: G2.Point().Set(id.Key), Addr: "tls://127.0.0.1:80", TLS: true, Scheme: DefaultSchemeName}
+	require.True(t, id.Equal(same))
+	require.True(t, same.Equal(id))
+
+	other := *same
+	other.Addr = "127.0.0.1:81"
+	require.False(t, id.Equal(&other))
+	other = *same
+	other.TLS = false
+	require.False(t, id.Equal(&other))
+	other = *same
+	other.Key = NewKeyPair("127.0.0.1:80").Public.Key
+	require.False(t, id.Equal(&other))
+	require.False(t, id.Equal(nil))
+	require.True(t, (*Identity)(nil).Equal(nil))
+}
+
+func TestGroupEqual(t *testing.T) {
+	_, group := BatchIdentities(5)
+	require.True(t, group.Equal(group))
+
+	// the same members in another order
+	reordered := &Group{Threshold: group.Threshold, Scheme: group.Scheme}
+	for i := len(group.Nodes) - 1; i >= 0; i-- {
+		reordered.Nodes = append(reordered.Nodes, group.Nodes[i])
+	}
+	require.True(t, group.Equal(reordered))
+	require.True(t, reordered.Equal(group))
+	ids := group.Identities()
+	ids[0], ids[4] = ids[4], ids[0]
+	require.True(t, group.Equal(NewGroup(ids, group.Threshold)))
+
+	other := *group
+	other.Threshold++
+	require.False(t, group.Equal(&other))
+	require.False(t, group.Equal(group.Filter([]int{0, 1, 2, 3})))
+
+	// a member at another address
+	moved := &Group{Threshold: group.Threshold, Scheme: group.Scheme}
+	for _, n := range group.Nodes {
+		id := *n.Identity
+		if n.Index == 2 {
+			id.Addr = "127.0.0.1:1"
+		}
+		moved.Nodes = append(moved.Nodes, &IndexedPublic{Identity: &id, Index: n.Index})
+	}
+	require.False(t, group.Equal(moved))
+
+	// swapped indexes
+	swapped := &Group{Threshold: group.Threshold, Scheme: group.Scheme}
+	for _, n := range group.Nodes {
+		swapped.Nodes = append(swapped.Nodes, &IndexedPublic{Identity: n.Identity, Index: (n.Index + 1) % group.Len()})
+	}
+	require.False(t, group.Equal(swapped))
+	require.False(t, group.Equal(nil))
+}
+
 func BatchIdentities(n int) ([]*Pair, *Group) {
 	startPort := 8000
 	startAddr := "127.0.0.1:"
@@ -61,3 +183,146 @@ func BatchIdentities(n int) ([]*Pair, *Group) {
 	}
 	return privs, group
 }
+
+func TestGroupCheckIndexes(t *testing.T) {
+	n := 5
+	_, group := BatchIdentities(n)
+	require.NoError(t, group.CheckIndexes())
+
+	// two nodes sharing the same index
+	group.Nodes[3].Index = group.Nodes[1].Index
+	require.Error(t, group.CheckIndexes())
+
+	// non contiguous indexes
+	_, group = BatchIdentities(n)
+	group.Nodes[n-1].Index = n
+	require.Error(t, group.CheckIndexes())
+}
+
+func TestCheckGroupTOML(t *testing.T) {
+	n := 4
+	ids := make([]*Identity, n)
+	for i := range ids {
+		ids[i] = NewTLSKeyPair("127.0.0.1:" + strconv.Itoa(8000+i)).Public
+	}
+	gt := NewGroup(ids, DefaultThreshold(n)).TOML().(*GroupTOML)
+	require.Empty(t, CheckGroupTOML(gt))
+
+	gt.Nodes[1].Address = gt.Nodes[0].Address
+	gt.Nodes[2].Key = "00ff"
+	gt.Nodes[3].TLS = false
+	gt.Threshold = 2
+	errs := CheckGroupTOML(gt)
+	require.Len(t, errs, 4)
+	require.Contains(t, errs[0].Error(), "same address")
+	require.Contains(t, errs[1].Error(), "invalid public key")
+	require.Contains(t, errs[2].Error(), "3 nodes use TLS and 1 do not")
+	require.Contains(t, errs[3].Error(), "threshold 2 outside of [3,4]")
+}
+
+func TestScheme(t *testing.T) {
+	s, err := SchemeByName("")
+	require.NoError(t, err)
+	require.Equal(t, DefaultScheme, s)
+	_, err = SchemeByName("bls-unknown")
+	require.Error(t, err)
+
+	// identities saved before the scheme was recorded use the default one
+	ptoml := NewKeyPair("127.0.0.1:8000").Public.TOML().(*PublicTOML)
+	require.Equal(t, DefaultSchemeName, ptoml.Scheme)
+	ptoml.Scheme = ""
+	id := new(Identity)
+	require.NoError(t, id.FromTOML(ptoml))
+	require.Equal(t, DefaultSchemeName, id.Scheme)
+	ptoml.Scheme = "bls-unknown"
+	require.Error(t, id.FromTOML(ptoml))
+
+	schemes["bls-test"] = &Scheme{Name: "bls-test", Pairing: Pairing, KeyGroup: G2}
+	defer delete(schemes, "bls-test")
+	n := 4
+	ids := make([]*Identity, n)
+	for i := range ids {
+		ids[i] = NewSchemeKeyPair("127.0.0.1:"+strconv.Itoa(8000+i), schemes["bls-test"]).Public
+	}
+	require.NoError(t, CheckSchemes(ids))
+	group := NewGroup(ids, DefaultThreshold(n))
+	require.Equal(t, "bls-test", group.Scheme)
+	gt := group.TOML().(*GroupTOML)
+	require.Equal(t, "bls-test", gt.Scheme)
+	loaded := new(Group)
+	require.NoError(t, loaded.FromTOML(gt))
+	require.Equal(t, "bls-test", loaded.Scheme)
+	require.Empty(t, CheckGroupTOML(gt))
+
+	other := NewKeyPair(gt.Nodes[2].Address).Public
+	require.Error(t, CheckSchemes(append(ids, other)))
+	gt.Nodes[2] = other.TOML().(*PublicTOML)
+	require.Error(t, loaded.FromTOML(gt))
+	errs := CheckGroupTOML(gt)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "uses scheme "+DefaultSchemeName)
+}
+
+func TestDeriveKeyPair(t *testing.T) {
+	master := bytes.Repeat([]byte{0x42}, MasterSeedSize)
+	kp1, err := DeriveKeyPair(master, 1, "127.0.0.1:80")
+	require.NoError(t, err)
+	require.True(t, kp1.Public.Key.Equal(G2.Point().Mul(kp1.Key, nil)))
+
+	again, err := DeriveKeyPair(master, 1, "127.0.0.1:81")
+	require.NoError(t, err)
+	require.True(t, kp1.Key.Equal(again.Key))
+
+	kp2, err := DeriveKeyPair(master, 2, "127.0.0.1:80")
+	require.NoError(t, err)
+	require.False(t, kp1.Key.Equal(kp2.Key))
+
+	_, err = DeriveKeyPair(master[:MasterSeedSize-1], 1, "127.0.0.1:80")
+	require.Error(t, err)
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	var valid = []struct {
+		in, out string
+	}{
+		{"127.0.0.1:8080", "127.0.0.1:8080"},
+		{"  drand.Example.ORG:443 \n", "drand.example.org:443"},
+		{"http://drand.example.org:80", "drand.example.org:80"},
+		{"https://drand.example.org:443/", "drand.example.org:443"},
+		{"drand.example.org:0443", "drand.example.org:443"},
+		{"[::1]:8080", "[::1]:8080"},
+		{"[2001:DB8:0:0::1]:04000", "[2001:db8::1]:4000"},
+		{"https://[2001:db8::1]:443/", "[2001:db8::1]:443"},
+		{"2001:db8:0:0:0:0:0:1:4000", "[2001:db8::1]:4000"},
+		{"[::ffff:127.0.0.1]:4000", "127.0.0.1:4000"},
+		{"localhost:4000", "localhost:4000"},
+	}
+	for _, v := range valid {
+		out, err := NormalizeAddress(v.in)
+		require.NoError(t, err, v.in)
+		require.Equal(t, v.out, out)
+	}
+
+	var invalid = []string{
+		"",
+		"drand.example.org",
+		"https://drand.example.org",
+		":8080",
+		"drand.example.org:http",
+		"drand.example.org:0",
+		"drand.example.org:70000",
+		"drand example.org:80",
+		"::1",
+		"[::1]",
+		"2001:db8::1:4000",
+		"[2001:db8::1]:",
+		"drand.example.org:80:80",
+	}
+	for _, in := range invalid {
+		_, err := NormalizeAddress(in)
+		require.Error(t, err, in)
+	}
+
+	kp := NewKeyPair(" HTTP://Drand.Example.org:080 ")
+	require.Equal(t, "drand.example.org:80", kp.Public.Address())
+}
@@ -0,0 +1,70 @@
+package key
+
+import (
+	"fmt"
+	"sort"
+
+	kyber "github.com/dedis/kyber"
+	"github.com/dedis/kyber/pairing"
+	dkg "github.com/dedis/kyber/share/dkg/pedersen"
+)
+
+// DefaultSchemeName is the name of the scheme used by drand so far: BLS
+// signatures on bn256, with the public keys on G2 and the signatures on G1.
+const DefaultSchemeName = "bls-bn256-g2"
+
+// Scheme is the pairing and the group of the keys used to sign the beacons.
+// Its name is recorded in the identities and the group file, so nodes and
+// clients pick the right curves to run the DKG and to verify the beacons.
+type Scheme struct {
+	Name string
+	// Pairing to create and verify the BLS signatures
+	Pairing pairing.Suite
+	// KeyGroup is the group of the public keys, of the distributed key and
+	// of its shares
+	KeyGroup kyber.Group
+}
+
+// DefaultScheme is the scheme of the identities and groups not recording one.
+var DefaultScheme = &Scheme{Name: DefaultSchemeName, Pairing: Pairing, KeyGroup: G2}
+
+var schemes = map[string]*Scheme{
+	DefaultSchemeName: DefaultScheme,
+}
+
+// SchemeByName returns the scheme of the given name, DefaultScheme if the name
+// is empty, or an error if the scheme is unknown.
+func SchemeByName(name string) (*Scheme, error) {
+	if name == "" {
+		return DefaultScheme, nil
+	}
+	s, ok := schemes[name]
+	if !ok {
+		return nil, fmt.Errorf("key: unknown scheme %q, known schemes are %v", name, SchemeNames())
+	}
+	return s, nil
+}
+
+// SchemeNames returns the sorted names of the known schemes.
+func SchemeNames() []string {
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DKGSuite returns the suite the DKG runs on to create a distributed key of
+// this scheme.
+func (s *Scheme) DKGSuite() dkg.Suite {
+	return s.KeyGroup.(dkg.Suite)
+}
+
+// schemeName returns the given scheme name, or DefaultSchemeName if empty.
+func schemeName(name string) string {
+	if name == "" {
+		return DefaultSchemeName
+	}
+	return name
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"sync"
 
 	"github.com/BurntSushi/toml"
 	"github.com/dedis/drand/fs"
@@ -12,8 +13,8 @@ import (
 )
 
 // Store abstracts the loading and saving of any private/public cryptographic
-// material to be used by drand. For the moment, only a file based store is
-// implemented.
+// material to be used by drand. The package implements a file based store and
+// an in-memory store.
 type Store interface {
 	// SaveKeyPair saves the private key generated by drand as well as the
 	// public identity key associated
@@ -60,12 +61,31 @@ type fileStore struct {
 	shareFile      string
 	distKeyFile    string
 	groupFile      string
+	// refuse to load private files accessible by group or others
+	strict bool
 }
 
-// NewDefaultFileStore
-func NewFileStore(baseFolder string) Store {
+// FileStoreOption configures a file store.
+type FileStoreOption func(*fileStore)
+
+// WithStrictPermissions makes the file store refuse to load the private key
+// and the share if their files are accessible by the group or by others. By
+// default, it only logs a warning.
+func WithStrictPermissions() FileStoreOption {
+	return func(f *fileStore) {
+		f.strict = true
+	}
+}
+
+// NewFileStore returns a Store saving the key material in files in the given
+// folder. The folders are created accessible by the owner only, and the
+// private key and the share readable by the owner only.
+func NewFileStore(baseFolder string, opts ...FileStoreOption) Store {
 	fs.CreateSecureFolder(baseFolder)
 	store := &fileStore{baseFolder: baseFolder}
+	for _, opt := range opts {
+		opt(store)
+	}
 	keyFolder := fs.CreateSecureFolder(path.Join(baseFolder, KeyFolderName))
 	groupFolder := fs.CreateSecureFolder(path.Join(baseFolder, GroupFolderName))
 	store.privateKeyFile = path.Join(keyFolder, keyFileName) + privateExtension
@@ -87,6 +107,9 @@ func (f *fileStore) SaveKeyPair(p *Pair) error {
 
 // LoadKeyPair decode private key first then public
 func (f *fileStore) LoadKeyPair() (*Pair, error) {
+	if err := f.checkPrivate(f.privateKeyFile); err != nil {
+		return nil, err
+	}
 	p := new(Pair)
 	if err := Load(f.privateKeyFile, p); err != nil {
 		return nil, err
@@ -109,6 +132,9 @@ func (f *fileStore) SaveShare(share *Share) error {
 }
 
 func (f *fileStore) LoadShare() (*Share, error) {
+	if err := f.checkPrivate(f.shareFile); err != nil {
+		return nil, err
+	}
 	s := new(Share)
 	return s, Load(f.shareFile, s)
 }
@@ -123,6 +149,101 @@ func (f *fileStore) LoadDistPublic() (*DistPublic, error) {
 	return d, Load(f.distKeyFile, d)
 }
 
+// checkPrivate warns if the private file is accessible by group or others, or
+// returns an error in strict mode. A missing file is left for the loading to
+// report.
+func (f *fileStore) checkPrivate(file string) error {
+	err := fs.CheckSecureFile(file)
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+	if f.strict {
+		return err
+	}
+	slog.Printf("key: %s", err)
+	return nil
+}
+
+// memStore is a Store keeping everything in memory
+type memStore struct {
+	sync.Mutex
+	pair   *Pair
+	share  *Share
+	group  *Group
+	public *DistPublic
+}
+
+// NewMemStore returns a Store keeping everything in memory, without ever
+// touching the filesystem. It is useful for ephemeral nodes and tests. Loading
+// an item that has not been saved returns ErrAbsent.
+func NewMemStore() Store {
+	return &memStore{}
+}
+
+func (m *memStore) SaveKeyPair(p *Pair) error {
+	m.Lock()
+	defer m.Unlock()
+	m.pair = p
+	return nil
+}
+
+func (m *memStore) LoadKeyPair() (*Pair, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.pair == nil {
+		return nil, ErrAbsent
+	}
+	return m.pair, nil
+}
+
+func (m *memStore) SaveShare(share *Share) error {
+	m.Lock()
+	defer m.Unlock()
+	m.share = share
+	return nil
+}
+
+func (m *memStore) LoadShare() (*Share, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.share == nil {
+		return nil, ErrAbsent
+	}
+	return m.share, nil
+}
+
+func (m *memStore) SaveGroup(g *Group) error {
+	m.Lock()
+	defer m.Unlock()
+	m.group = g
+	return nil
+}
+
+func (m *memStore) LoadGroup() (*Group, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.group == nil {
+		return nil, ErrAbsent
+	}
+	return m.group, nil
+}
+
+func (m *memStore) SaveDistPublic(d *DistPublic) error {
+	m.Lock()
+	defer m.Unlock()
+	m.public = d
+	return nil
+}
+
+func (m *memStore) LoadDistPublic() (*DistPublic, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.public == nil {
+		return nil, ErrAbsent
+	}
+	return m.public, nil
+}
+
 func Save(path string, t Tomler, secure bool) error {
 	var fd *os.File
 	var err error
@@ -1,10 +1,12 @@
 package key
 
 import (
+	"bytes"
 	"os"
 	"path"
 	"testing"
 
+	"github.com/BurntSushi/toml"
 	kyber "github.com/dedis/kyber"
 	"github.com/dedis/kyber/share"
 	"github.com/stretchr/testify/require"
@@ -74,3 +76,115 @@ func TestKeysSaveLoad(t *testing.T) {
 	require.Equal(t, dp.Key.String(), loadedDp.Key.String())
 
 }
+
+func TestEncryptedFileStore(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drand-encrypted")
+	os.RemoveAll(tmp)
+	defer os.RemoveAll(tmp)
+	pair := NewTLSKeyPair("127.0.0.1:8080")
+
+	store := NewEncryptedFileStore(tmp, []byte("correct horse"))
+	require.False(t, IsKeyEncrypted(tmp))
+	require.NoError(t, store.SaveKeyPair(pair))
+	require.True(t, IsKeyEncrypted(tmp))
+
+	loaded, err := store.LoadKeyPair()
+	require.NoError(t, err)
+	require.Equal(t, pair.Key.String(), loaded.Key.String())
+	require.Equal(t, pair.Public.Address(), loaded.Public.Address())
+
+	// the private key is not readable without the passphrase
+	_, err = NewFileStore(tmp).LoadKeyPair()
+	require.Error(t, err)
+	_, err = NewEncryptedFileStore(tmp, []byte("wrong horse")).LoadKeyPair()
+	require.Equal(t, ErrWrongPassphrase, err)
+
+	// the public key stays in clear
+	pub := new(Identity)
+	require.NoError(t, Load(NewFileStore(tmp).(*fileStore).publicKeyFile, pub))
+	require.Equal(t, pair.Public.Address(), pub.Address())
+
+	// the parameters of the key derivation are saved with the key
+	enc := new(EncryptedPairTOML)
+	_, err = toml.DecodeFile(store.(*encryptedFileStore).privateKeyFile, enc)
+	require.NoError(t, err)
+	require.Equal(t, "scrypt", enc.KDF)
+	require.Equal(t, []int{ScryptN, ScryptR, ScryptP}, []int{enc.N, enc.R, enc.P})
+
+	// costly parameters of the key derivation are rejected before deriving
+	for _, params := range [][3]int{{1 << 30, 8, 1}, {1 << 20, 1 << 20, 1}, {ScryptN, ScryptR, 1 << 20}, {ScryptN, 0, 1}} {
+		costly := *enc
+		costly.N, costly.R, costly.P = params[0], params[1], params[2]
+		_, err = decryptBytes(&costly, []byte("correct horse"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "out of bounds")
+	}
+
+	// the private key must be the one of the public key
+	other := NewTLSKeyPair("127.0.0.1:8080")
+	require.NoError(t, Save(NewFileStore(tmp).(*fileStore).publicKeyFile, other.Public, false))
+	_, err = store.LoadKeyPair()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match")
+}
+
+func TestFileStorePermissions(t *testing.T) {
+	ps, _ := BatchIdentities(1)
+	tmp := path.Join(os.TempDir(), "drand-perms")
+	os.RemoveAll(tmp)
+	defer os.RemoveAll(tmp)
+	store := NewFileStore(tmp).(*fileStore)
+	require.NoError(t, store.SaveKeyPair(ps[0]))
+	for _, folder := range []string{tmp, path.Join(tmp, KeyFolderName)} {
+		info, err := os.Stat(folder)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	}
+	info, err := os.Stat(store.privateKeyFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// a private key readable by others is only loaded with a warning ...
+	require.NoError(t, os.Chmod(store.privateKeyFile, 0644))
+	_, err = store.LoadKeyPair()
+	require.NoError(t, err)
+	// ... unless the permissions are checked strictly
+	strict := NewFileStore(tmp, WithStrictPermissions())
+	_, err = strict.LoadKeyPair()
+	require.Error(t, err)
+	_, err = NewEncryptedFileStore(tmp, []byte("pass"), WithStrictPermissions()).LoadKeyPair()
+	require.Error(t, err)
+
+	// saving again tightens the permissions
+	require.NoError(t, store.SaveKeyPair(ps[0]))
+	_, err = strict.LoadKeyPair()
+	require.NoError(t, err)
+}
+
+func TestExportImportShare(t *testing.T) {
+	ps, _ := BatchIdentities(2)
+	s := &Share{
+		Commits: []kyber.Point{ps[0].Public.Key, ps[1].Public.Key},
+		Share:   &share.PriShare{V: ps[0].Key, I: 1},
+	}
+	var clear bytes.Buffer
+	require.NoError(t, ExportShare(&clear, s, nil))
+	imported, err := ImportShare(&clear, nil)
+	require.NoError(t, err)
+	require.Equal(t, s.Share.V.String(), imported.Share.V.String())
+	require.Equal(t, s.Share.I, imported.Share.I)
+	require.Equal(t, s.Public().Key.String(), imported.Public().Key.String())
+
+	var enc bytes.Buffer
+	require.NoError(t, ExportShare(&enc, s, []byte("correct horse")))
+	require.NotContains(t, enc.String(), scalarToString(s.Share.V))
+	encrypted := enc.Bytes()
+	imported, err = ImportShare(bytes.NewReader(encrypted), func() []byte { return []byte("correct horse") })
+	require.NoError(t, err)
+	require.Equal(t, s.Share.V.String(), imported.Share.V.String())
+
+	_, err = ImportShare(bytes.NewReader(encrypted), func() []byte { return []byte("wrong horse") })
+	require.Equal(t, ErrWrongPassphrase, err)
+	_, err = ImportShare(bytes.NewReader(encrypted), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,83 @@
+// Package log defines the logger through which drand nodes log, so embedders
+// can plug in their own logging library and ship structured logs.
+package log
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/nikkolasg/slog"
+)
+
+// Logger logs messages with a level and structured fields, given as
+// alternating keys and values after the message, as in
+//
+//	logger.Info("beacon: round finished", "round", 3)
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	// Warn logs a problem the node recovers from.
+	Warn(msg string, keyvals ...interface{})
+	// Error logs a problem that needs the attention of the operator.
+	Error(msg string, keyvals ...interface{})
+	// With returns a logger adding the given fields to every message.
+	With(keyvals ...interface{}) Logger
+}
+
+// DefaultLogger returns the logger printing through slog: debug messages at
+// the debug level of slog, info messages at the info level, and warnings and
+// errors always. The fields are printed after the message as key=value, byte
+// slices hex encoded.
+func DefaultLogger() Logger {
+	return &slogLogger{}
+}
+
+type slogLogger struct {
+	fields []interface{}
+}
+
+func (l *slogLogger) Debug(msg string, keyvals ...interface{}) {
+	slog.Debug(l.format(msg, keyvals))
+}
+
+func (l *slogLogger) Info(msg string, keyvals ...interface{}) {
+	slog.Info(l.format(msg, keyvals))
+}
+
+func (l *slogLogger) Warn(msg string, keyvals ...interface{}) {
+	slog.Print(l.format(msg, keyvals))
+}
+
+func (l *slogLogger) Error(msg string, keyvals ...interface{}) {
+	slog.Print(l.format(msg, keyvals))
+}
+
+func (l *slogLogger) With(keyvals ...interface{}) Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	return &slogLogger{fields: append(fields, keyvals...)}
+}
+
+func (l *slogLogger) format(msg string, keyvals []interface{}) string {
+	var b bytes.Buffer
+	b.WriteString(msg)
+	writeFields(&b, l.fields)
+	writeFields(&b, keyvals)
+	return b.String()
+}
+
+func writeFields(b *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			fmt.Fprintf(b, " %v=(missing)", keyvals[i])
+			break
+		}
+		switch v := keyvals[i+1].(type) {
+		case []byte:
+			fmt.Fprintf(b, " %v=%s", keyvals[i], hex.EncodeToString(v))
+		default:
+			fmt.Fprintf(b, " %v=%v", keyvals[i], v)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nikkolasg/slog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultLogger(t *testing.T) {
+	var buff bytes.Buffer
+	oldOut, oldLvl := slog.Output, slog.Level
+	slog.Output, slog.Level = &buff, slog.LevelInfo
+	defer func() { slog.Output, slog.Level = oldOut, oldLvl }()
+
+	l := DefaultLogger().With("node", "a:1")
+	l.Info("round finished", "round", 3, "randomness", []byte{0xab, 0x01})
+	require.Contains(t, buff.String(), "round finished node=a:1 round=3 randomness=ab01")
+
+	// the fields of With do not leak to the parent logger
+	l.With("extra", true)
+	l.Warn("odd", "key")
+	require.Contains(t, buff.String(), "odd node=a:1 key=(missing)")
+	require.NotContains(t, buff.String(), "extra")
+
+	// debug messages follow the level of slog
+	l.Debug("hidden")
+	require.NotContains(t, buff.String(), "hidden")
+}
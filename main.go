@@ -3,21 +3,38 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	bolt "github.com/coreos/bbolt"
+	"github.com/dedis/drand/beacon"
 	"github.com/dedis/drand/core"
+	"github.com/dedis/drand/dkg"
 	"github.com/dedis/drand/fs"
 	"github.com/dedis/drand/key"
 	"github.com/dedis/drand/net"
+	dkg_proto "github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/drand/protobuf/drand"
 	"github.com/nikkolasg/slog"
 	"github.com/urfave/cli"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -27,7 +44,24 @@ var (
 )
 
 const gname = "group.toml"
-const dpublic = "dist_key.public"
+const defaultMonitorListen = "127.0.0.1:9090"
+
+// passphraseEnv is the environment variable from which the passphrase of an
+// encrypted private key is read instead of being prompted for.
+const passphraseEnv = "DRAND_PASSPHRASE"
+
+// tlsPassphraseEnv is the environment variable from which the passphrase of
+// an encrypted TLS private key is read.
+const tlsPassphraseEnv = "DRAND_TLS_PASSPHRASE"
+
+// selfTestPeriod is the beacon period of the nodes run by drand test, and
+// selfTestTimeout the time given to them to run the DKG and the first round.
+const selfTestPeriod = time.Second
+const selfTestTimeout = 30 * time.Second
+
+// shutdownTimeout is the time given to the round in progress to finish when
+// drand receives SIGINT or SIGTERM.
+const shutdownTimeout = 30 * time.Second
 
 func banner() {
 	fmt.Printf("drand v%s by nikkolasg @ DEDIS\n", version)
@@ -52,13 +86,17 @@ func main() {
 	seedFlag := cli.StringFlag{
 		Name:  "seed",
 		Value: string(core.DefaultSeed),
-		Usage: "set the seed message of the first beacon produced",
+		Usage: "set the seed message of the first beacon produced: hex:<HEX> for hex encoded bytes, @<FILE> for the content of a file, or a literal string",
 	}
 	periodFlag := cli.DurationFlag{
 		Name:  "period",
 		Value: core.DefaultBeaconPeriod,
 		Usage: "runs the beacon every `PERIOD`",
 	}
+	genesisFlag := cli.Int64Flag{
+		Name:  "genesis",
+		Usage: "align the rounds on the unix `TIME`: round N starts at TIME + N*PERIOD. All nodes must use the same genesis time and period",
+	}
 	leaderFlag := cli.BoolFlag{
 		Name:  "leader",
 		Usage: "Leader is the first node to start the DKG protocol",
@@ -67,22 +105,66 @@ func main() {
 		Name:  "debug, d",
 		Usage: "Use -d to log debug output",
 	}
+	chainFlag := cli.StringFlag{
+		Name:  "chain",
+		Usage: "Keep the key material and the database of the node in the chains/`NAME` subfolders of the config and db folders, to run nodes of several groups from the same folders",
+	}
+	strictPermsFlag := cli.BoolFlag{
+		Name:  "strict-perms",
+		Usage: "Refuse to load the private key and the share if their files are accessible by group or others, instead of warning",
+	}
 	listenFlag := cli.StringFlag{
 		Name:  "listen,l",
 		Usage: "listening (binding) address. Useful if you have some kind of proxy",
 	}
 	distKeyFlag := cli.StringFlag{
-		Name:  "public,p",
+		Name:  "public,p,distkey",
 		Usage: "the path of the public key file",
 	}
 	thresholdFlag := cli.IntFlag{
 		Name:  "threshold, t",
 		Usage: "threshold to apply for the group. Default is n/2 + 1.",
 	}
+	checkFlag := cli.BoolFlag{
+		Name:  "check",
+		Usage: "validate the given group file instead of creating one, printing each problem found",
+	}
 	outFlag := cli.StringFlag{
 		Name:  "out, o",
 		Usage: "where to save either the group file or the distributed public key",
 	}
+	keyOutFlag := cli.StringFlag{
+		Name:  "out, o",
+		Usage: "also save the public identity to `FILE`, or print only the public identity on stdout with \"-\"",
+	}
+	tlsNameFlag := cli.StringFlag{
+		Name:  "tls-name",
+		Usage: "record in the public identity that the TLS certificate of the node is valid for `NAME`, when it differs from the host of its address",
+	}
+	shareOutFlag := cli.StringFlag{
+		Name:  "out, o",
+		Usage: "save the exported share to `FILE` instead of printing it on stdout",
+	}
+	chainOutFlag := cli.StringFlag{
+		Name:  "out, o",
+		Usage: "save the exported chain to `FILE` instead of printing it on stdout",
+	}
+	encryptShareFlag := cli.BoolFlag{
+		Name:  "encrypt",
+		Usage: "encrypt the exported share with a passphrase, read from " + passphraseEnv + " or prompted for",
+	}
+	forceFlag := cli.BoolFlag{
+		Name:  "force",
+		Usage: "overwrite the key pair already present in the configuration folder",
+	}
+	forceChainFlag := cli.BoolFlag{
+		Name:  "force",
+		Usage: "start the beacon even if the seed or the period differ from the ones of the chain saved, whose beacons then do not chain with the new ones",
+	}
+	fromDirFlag := cli.StringFlag{
+		Name:  "from-dir",
+		Usage: "create the group from all the public identity files found in `DIR`",
+	}
 
 	tlsCertFlag := cli.StringFlag{
 		Name:  "tls-cert",
@@ -90,7 +172,7 @@ func main() {
 	}
 	tlsKeyFlag := cli.StringFlag{
 		Name:  "tls-key",
-		Usage: "TLS private key to use by the server",
+		Usage: "TLS private key to use by the server, decrypted with the passphrase in " + tlsPassphraseEnv + " if encrypted",
 	}
 	certsDirFlag := cli.StringFlag{
 		Name:  "certs-dir",
@@ -100,23 +182,131 @@ func main() {
 		Name:  "insecure",
 		Usage: "indicates to use a non TLS server or connection",
 	}
+	trustedFlag := cli.StringFlag{
+		Name:  "trusted",
+		Usage: "address of the trusted drand node to monitor",
+	}
+	freshFlag := cli.StringFlag{
+		Name:  "fresh",
+		Usage: "check the response is fresh and signed by the node whose identity is stored in the given `FILE`",
+	}
+	oldGroupFlag := cli.StringFlag{
+		Name:  "old-group",
+		Usage: "group file of the current group, needed by nodes joining it",
+	}
+	roundFlag := cli.Uint64Flag{
+		Name:  "round",
+		Usage: "fetch the beacon of the given `ROUND` instead of the last one",
+	}
+	timeoutFlag := cli.DurationFlag{
+		Name:  "timeout",
+		Value: defaultFetchTimeout,
+		Usage: "give up contacting the node after `DURATION`",
+	}
+	watchFlag := cli.BoolFlag{
+		Name:  "watch",
+		Usage: "print each new beacon as it is produced, until interrupted, reconnecting if the stream drops",
+	}
+	formatFlag := cli.StringFlag{
+		Name:  "format",
+		Usage: "output `FORMAT` of the randomness: json, json-compact, hex (randomness only) or raw (randomness bytes only)",
+		Value: "json",
+	}
+	deriveFromFlag := cli.StringFlag{
+		Name:  "derive-from",
+		Usage: "derive the key pair deterministically from the hex encoded master seed stored in the given `FILE`",
+	}
+	encryptKeyFlag := cli.BoolFlag{
+		Name:  "encrypt-key",
+		Usage: "encrypt the private key with a passphrase, read from " + passphraseEnv + " or prompted for. Implied if " + passphraseEnv + " is set",
+	}
+	indexFlag := cli.IntFlag{
+		Name:  "index",
+		Usage: "index of the key pair to derive from the master seed",
+	}
+	schemeFlag := cli.StringFlag{
+		Name:  "scheme",
+		Usage: "signature `SCHEME` of the key pair, one of " + strings.Join(key.SchemeNames(), ", "),
+		Value: key.DefaultSchemeName,
+	}
+	dkgTimeoutFlag := cli.DurationFlag{
+		Name:  "dkg-timeout",
+		Usage: "abort the DKG if it is not finished after `DURATION`, counted from the first deal sent or received",
+		Value: dkg.DefaultTimeout,
+	}
+	dkgFailFastFlag := cli.BoolFlag{
+		Name:  "dkg-fail-fast",
+		Usage: "abort the DKG as soon as too many deals are invalid for it to finish, instead of waiting for the timeout",
+	}
+	autoLeaderFlag := cli.BoolFlag{
+		Name:  "auto-leader",
+		Usage: "if no leader has started the DKG after 30s, start it from the lowest-indexed reachable node of the group",
+	}
+	signingFlag := cli.BoolFlag{
+		Name:  "signing",
+		Usage: "serve the Sign RPC, having the group sign any message a client of the public API sends: only for a public API reachable by trusted clients",
+	}
+	eventsFlag := cli.StringFlag{
+		Name:  "events",
+		Usage: "write lifecycle events as newline-delimited JSON to `FILE` (\"-\" for stdout)",
+	}
+	allowWeakFlag := cli.BoolFlag{
+		Name:  "allow-weak",
+		Usage: "start even if the threshold is not above half of the group or if the group is smaller than the minimum size",
+	}
+	minGroupSizeFlag := cli.IntFlag{
+		Name:  "min-group-size",
+		Usage: "refuse to start with a group of less than `N` members, unless --allow-weak is set",
+		Value: core.DefaultMinimumGroupSize,
+	}
+	logSamplingFlag := cli.IntFlag{
+		Name:  "log-sampling",
+		Usage: "log one out of `N` requests received on the public API. Failed requests are always logged.",
+	}
+	rateLimitFlag := cli.IntFlag{
+		Name:  "rate-limit",
+		Usage: "limit the requests of each IP to the public API to `N` per second, the requests above the limit are rejected and logged by sample",
+	}
+	rateBurstFlag := cli.IntFlag{
+		Name:  "rate-burst",
+		Usage: "allow bursts of `N` requests of each IP above --rate-limit (defaults to the rate limit)",
+	}
+	combineRoundFlag := cli.Uint64Flag{
+		Name:  "round",
+		Usage: "`ROUND` the partial signatures are signatures of",
+	}
+	prevFlag := cli.StringFlag{
+		Name:  "prev",
+		Usage: "hex encoded signature of the previous round, on top of which the partial signatures are made",
+	}
+	groupFileFlag := cli.StringFlag{
+		Name:  "group",
+		Usage: "group file of the nodes that produced the partial signatures",
+	}
+	nodesFlag := cli.IntFlag{
+		Name:  "nodes, n",
+		Value: 5,
+		Usage: "number of `N` nodes to run",
+	}
 
 	app.Commands = []cli.Command{
 		cli.Command{
 			Name:      "keygen",
 			Usage:     "keygen <ADDRESS>. Generates longterm private key pair",
 			ArgsUsage: "ADDRESS is the public address for other nodes to contact",
-			Flags:     toArray(insecureFlag),
+			Flags:     toArray(insecureFlag, deriveFromFlag, indexFlag, schemeFlag, encryptKeyFlag, keyOutFlag, tlsNameFlag, forceFlag),
 			Action: func(c *cli.Context) error {
-				banner()
+				if c.String("out") != "-" {
+					banner()
+				}
 				return keygenCmd(c)
 			},
 		},
 		cli.Command{
 			Name:      "group",
 			Usage:     "Create the group toml from individual public keys",
-			ArgsUsage: "<id1 id2 id3...> must be the identities of the group to create",
-			Flags:     toArray(thresholdFlag, outFlag),
+			ArgsUsage: "<id1 id2 id3...> must be the identities of the group to create, unless --from-dir is given, or the group file to validate with --check",
+			Flags:     toArray(thresholdFlag, outFlag, checkFlag, fromDirFlag),
 			Action: func(c *cli.Context) error {
 				banner()
 				return groupCmd(c)
@@ -126,16 +316,26 @@ func main() {
 			Name:      "dkg",
 			Usage:     "Run the DKG protocol",
 			ArgsUsage: "GROUP.TOML the group file listing all participant's identities",
-			Flags:     toArray(leaderFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag),
+			Flags:     toArray(leaderFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag, dkgTimeoutFlag, dkgFailFastFlag, autoLeaderFlag, eventsFlag, allowWeakFlag, minGroupSizeFlag),
 			Action: func(c *cli.Context) error {
 				banner()
 				return dkgCmd(c)
 			},
 		},
+		cli.Command{
+			Name:      "reshare",
+			Usage:     "Redistribute the distributed key to a new group, keeping the same distributed public key",
+			ArgsUsage: "NEW-GROUP.TOML the group file listing the identities of the new group",
+			Flags:     toArray(leaderFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag, insecureFlag, oldGroupFlag, distKeyFlag, dkgTimeoutFlag, dkgFailFastFlag, eventsFlag, allowWeakFlag, minGroupSizeFlag),
+			Action: func(c *cli.Context) error {
+				banner()
+				return reshareCmd(c)
+			},
+		},
 		cli.Command{
 			Name:  "beacon",
 			Usage: "Run the beacon protocol",
-			Flags: toArray(periodFlag, seedFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag),
+			Flags: toArray(periodFlag, genesisFlag, seedFlag, forceChainFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag, logSamplingFlag, rateLimitFlag, rateBurstFlag, eventsFlag, allowWeakFlag, minGroupSizeFlag),
 			Action: func(c *cli.Context) error {
 				banner()
 				return beaconCmd(c)
@@ -145,12 +345,21 @@ func main() {
 			Name:      "run",
 			Usage:     "Run the daemon, first do the dkg if needed then run the beacon",
 			ArgsUsage: "<group file> is the group.toml generated with `group`. This argument is only needed if the DKG has NOT been run yet.",
-			Flags:     toArray(leaderFlag, periodFlag, seedFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag, insecureFlag),
+			Flags:     toArray(leaderFlag, periodFlag, genesisFlag, seedFlag, forceChainFlag, listenFlag, tlsCertFlag, tlsKeyFlag, certsDirFlag, insecureFlag, logSamplingFlag, rateLimitFlag, rateBurstFlag, dkgTimeoutFlag, dkgFailFastFlag, autoLeaderFlag, signingFlag, eventsFlag, allowWeakFlag, minGroupSizeFlag),
 			Action: func(c *cli.Context) error {
 				banner()
 				return runCmd(c)
 			},
 		},
+		cli.Command{
+			Name:  "monitor",
+			Usage: "Continuously verify the beacons of a trusted node and serve the results over HTTP",
+			Flags: toArray(trustedFlag, distKeyFlag, periodFlag, listenFlag, tlsCertFlag, insecureFlag),
+			Action: func(c *cli.Context) error {
+				banner()
+				return monitorCmd(c)
+			},
+		},
 		{
 			Name:    "fetch",
 			Aliases: []string{"f"},
@@ -160,7 +369,7 @@ func main() {
 					Name:      "public",
 					Usage:     "Fetch a public verifiable and unbiasable randomness value",
 					ArgsUsage: "<server address> address of the server to contact",
-					Flags:     toArray(distKeyFlag, tlsCertFlag, insecureFlag, certsDirFlag),
+					Flags:     toArray(distKeyFlag, tlsCertFlag, insecureFlag, certsDirFlag, freshFlag, roundFlag, watchFlag, timeoutFlag, formatFlag),
 					Action: func(c *cli.Context) error {
 						return fetchPublicCmd(c)
 					},
@@ -169,15 +378,152 @@ func main() {
 					Name:      "private",
 					Usage:     "Fetch a private randomness from a server. Request and response are encrypted",
 					ArgsUsage: "<identity file> identity file of the remote server",
-					Flags:     toArray(tlsCertFlag, certsDirFlag),
+					Flags:     toArray(tlsCertFlag, certsDirFlag, timeoutFlag, formatFlag),
 					Action: func(c *cli.Context) error {
 						return fetchPrivateCmd(c)
 					},
 				},
+				{
+					Name:      "info",
+					Usage:     "Fetch the parameters of the chain: last round, period, genesis time, seed and distributed public key",
+					ArgsUsage: "<server address> address of the server to contact",
+					Flags:     toArray(tlsCertFlag, insecureFlag),
+					Action: func(c *cli.Context) error {
+						return fetchInfoCmd(c)
+					},
+				},
+				{
+					Name:      "locate",
+					Usage:     "Find the round of a randomness value and verify its beacon, to check that the value comes from the chain",
+					ArgsUsage: "<server address> <randomness> address of the server to contact and the hex encoded randomness",
+					Flags:     toArray(distKeyFlag, tlsCertFlag, insecureFlag, formatFlag),
+					Action: func(c *cli.Context) error {
+						return fetchLocateCmd(c)
+					},
+				},
+				{
+					Name:      "sign",
+					Usage:     "Have the group sign a message with the distributed key, on a node run with --signing, and print the verified signature in hex",
+					ArgsUsage: "<server address> <message> address of the server to contact and the message to sign",
+					Flags:     toArray(distKeyFlag, tlsCertFlag, insecureFlag),
+					Action: func(c *cli.Context) error {
+						return fetchSignCmd(c)
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:      "ping",
+			Usage:     "Check the status of a drand node: whether its DKG is done and whether it is ready to serve randomness",
+			ArgsUsage: "<server address> address of the server to contact",
+			Flags:     toArray(tlsCertFlag, insecureFlag),
+			Action: func(c *cli.Context) error {
+				return pingCmd(c)
+			},
+		},
+		cli.Command{
+			Name:  "status",
+			Usage: "Summarize the state of the local node from its config folder, and its round statistics if it is running",
+			Action: func(c *cli.Context) error {
+				return statusCmd(c)
+			},
+		},
+		{
+			Name:  "show",
+			Usage: "Show information derived from the local configuration folder",
+			Subcommands: []cli.Command{
+				{
+					Name:  "dist-public",
+					Usage: "Recompute the distributed public key from the share and group, and warn if it differs from the stored one",
+					Action: func(c *cli.Context) error {
+						return showDistPublicCmd(c)
+					},
+				},
+			},
+		},
+		{
+			Name:  "share",
+			Usage: "Back up or restore the distributed key share of the node. The exported share is as sensitive as the private key",
+			Subcommands: []cli.Command{
+				{
+					Name:  "export",
+					Usage: "Export the share of the node, in clear unless --encrypt is given",
+					Flags: toArray(shareOutFlag, encryptShareFlag),
+					Action: func(c *cli.Context) error {
+						return shareExportCmd(c)
+					},
+				},
+				{
+					Name:      "import",
+					Usage:     "Restore an exported share, after checking it against the group and distributed public key of the node",
+					ArgsUsage: "<share file> file written by share export",
+					Action: func(c *cli.Context) error {
+						return shareImportCmd(c)
+					},
+				},
+			},
+		},
+		{
+			Name:  "chain",
+			Usage: "Back up or restore the chain of beacons saved by the node. The node must be stopped to import",
+			Subcommands: []cli.Command{
+				{
+					Name:  "export",
+					Usage: "Export the beacons of the database in round order, one JSON beacon per line",
+					Flags: toArray(chainOutFlag),
+					Action: func(c *cli.Context) error {
+						return chainExportCmd(c)
+					},
+				},
+				{
+					Name:      "import",
+					Usage:     "Verify exported beacons against the distributed public key and append them to the database",
+					ArgsUsage: "<chain file> file written by chain export, read from stdin if absent or \"-\"",
+					Flags:     toArray(distKeyFlag),
+					Action: func(c *cli.Context) error {
+						return chainImportCmd(c)
+					},
+				},
+			},
+		},
+		cli.Command{
+			Name:  "test",
+			Usage: "Run a DKG and a beacon round with nodes in this process, to check the build works end to end",
+			Flags: toArray(nodesFlag),
+			Action: func(c *cli.Context) error {
+				banner()
+				return selfTestCmd(c)
+			},
+		},
+		cli.Command{
+			Name:      "verify",
+			Usage:     "Verify offline a chain of public randomness beacons",
+			ArgsUsage: "<beacons file> JSON beacons as output by fetch public, read from stdin if absent or \"-\"",
+			Flags:     toArray(distKeyFlag),
+			Action: func(c *cli.Context) error {
+				return verifyCmd(c)
+			},
+		},
+		cli.Command{
+			Name:      "combine",
+			Usage:     "Combine offline the partial signatures of a round into its beacon, verified against the distributed public key",
+			ArgsUsage: "<partial>... files each holding the hex encoded partial signature of a node for the round",
+			Flags:     toArray(combineRoundFlag, prevFlag, groupFileFlag, distKeyFlag),
+			Action: func(c *cli.Context) error {
+				return combineCmd(c)
+			},
+		},
+		cli.Command{
+			Name:      "verify-dkg",
+			Usage:     "Verify offline the transcript of a DKG saved by a node in its database folder",
+			ArgsUsage: "<transcript> <group file> the dkg_transcript.json of a node and the group file of the DKG",
+			Flags:     toArray(distKeyFlag),
+			Action: func(c *cli.Context) error {
+				return verifyDKGCmd(c)
 			},
 		},
 	}
-	app.Flags = toArray(verboseFlag, configFlag, dbFlag)
+	app.Flags = toArray(verboseFlag, configFlag, dbFlag, chainFlag, strictPermsFlag)
 	app.Before = func(c *cli.Context) error {
 		if c.GlobalIsSet("debug") {
 			slog.Level = slog.LevelDebug
@@ -188,29 +534,73 @@ func main() {
 }
 
 func keygenCmd(c *cli.Context) error {
+	toStdout := c.String("out") == "-"
+	if toStdout {
+		// keep stdout for the public identity only
+		slog.Output = os.Stderr
+	}
 	args := c.Args()
 	if !args.Present() {
 		slog.Fatal("Missing drand address in argument (IPv4, dns)")
 	}
+	addr, err := key.NormalizeAddress(args.First())
+	if err != nil {
+		slog.Fatal(err)
+	}
+	scheme, err := key.SchemeByName(c.String("scheme"))
+	if err != nil {
+		slog.Fatal(err)
+	}
 	var priv *key.Pair
-	if c.Bool("insecure") {
+	if c.IsSet("derive-from") {
+		if scheme != key.DefaultScheme {
+			slog.Fatal("--derive-from only derives key pairs of the scheme ", key.DefaultSchemeName)
+		}
+		priv = deriveKeyPair(c, addr)
+	} else if c.Bool("insecure") {
 		slog.Info("Generating private / public key pair in INSECURE mode (no TLS).")
-		priv = key.NewKeyPair(args.First())
+		priv = key.NewSchemeKeyPair(addr, scheme)
 	} else {
 		slog.Info("Generating private / public key pair with TLS indication")
-		priv = key.NewTLSKeyPair(args.First())
+		priv = key.NewSchemeKeyPair(addr, scheme)
+		priv.Public.TLS = true
+	}
+	if c.IsSet("tls-name") {
+		if !priv.Public.TLS {
+			slog.Fatal("--tls-name requires a node using TLS")
+		}
+		priv.Public.TLSName = c.String("tls-name")
 	}
 
 	config := contextToConfig(c)
-	fs := key.NewFileStore(config.ConfigFolder())
+	var fs key.Store
+	if c.Bool("encrypt-key") || os.Getenv(passphraseEnv) != "" {
+		fs = key.NewEncryptedFileStore(config.ConfigFolder(), readPassphrase("the private key"), storeOptions(c)...)
+	} else {
+		fs = key.NewFileStore(config.ConfigFolder(), storeOptions(c)...)
+	}
 
-	if _, err := fs.LoadKeyPair(); err == nil {
-		slog.Info("keypair already present. Remove them before generating new one")
-		return nil
+	if _, err := fs.LoadKeyPair(); err == nil || key.IsKeyEncrypted(config.ConfigFolder()) {
+		if !c.Bool("force") {
+			slog.Info("keypair already present. Remove them or use --force before generating new one")
+			return nil
+		}
+		slog.Info("keypair already present, overwriting it")
 	}
 	if err := fs.SaveKeyPair(priv); err != nil {
 		slog.Fatal("could not save key: ", err)
 	}
+	if toStdout {
+		if err := toml.NewEncoder(os.Stdout).Encode(priv.Public.TOML()); err != nil {
+			slog.Fatal(err)
+		}
+		return nil
+	} else if c.String("out") != "" {
+		if err := key.Save(c.String("out"), priv.Public, false); err != nil {
+			slog.Fatal("could not save public identity: ", err)
+		}
+		slog.Print("Public identity saved at ", c.String("out"))
+	}
 	fullpath := path.Join(config.ConfigFolder(), key.KeyFolderName)
 	absPath, err := filepath.Abs(fullpath)
 	if err != nil {
@@ -229,17 +619,58 @@ func keygenCmd(c *cli.Context) error {
 	return nil
 }
 
+// deriveKeyPair derives the key pair from the master seed file and the index
+// given on the command line.
+func deriveKeyPair(c *cli.Context, addr string) *key.Pair {
+	if !c.IsSet("index") || c.Int("index") < 0 {
+		slog.Fatal("--derive-from requires a positive --index")
+	}
+	buff, err := ioutil.ReadFile(c.String("derive-from"))
+	if err != nil {
+		slog.Fatal("could not read master seed: ", err)
+	}
+	master, err := hex.DecodeString(strings.TrimSpace(string(buff)))
+	if err != nil {
+		slog.Fatal("master seed is not hex encoded: ", err)
+	}
+	priv, err := key.DeriveKeyPair(master, uint32(c.Int("index")), addr)
+	if err != nil {
+		slog.Fatal(err)
+	}
+	priv.Public.TLS = !c.Bool("insecure")
+	slog.Infof("Deriving private / public key pair of index %d (TLS %v)", c.Int("index"), priv.Public.TLS)
+	return priv
+}
+
 // groupCmd reads the identity, check the threshold and outputs the group.toml
 // file
 func groupCmd(c *cli.Context) error {
-	args := c.Args()
-	if !args.Present() {
+	if c.Bool("check") {
+		return checkGroupCmd(c)
+	}
+	var publics []*key.Identity
+	if c.IsSet("from-dir") {
+		publics = loadIdentities(c.String("from-dir"))
+	} else {
+		for _, str := range c.Args() {
+			pub := &key.Identity{}
+			slog.Print("Reading public identity from ", str)
+			if err := key.Load(str, pub); err != nil {
+				slog.Fatal(err)
+			}
+			publics = append(publics, pub)
+		}
+	}
+	if len(publics) == 0 {
 		slog.Fatal("missing identity file to create the group.toml")
 	}
-	if c.NArg() < 3 {
-		slog.Fatal("not enough identities (", c.NArg(), ") to create a group toml. At least 3!")
+	if len(publics) < 3 {
+		slog.Fatal("not enough identities (", len(publics), ") to create a group toml. At least 3!")
+	}
+	if err := key.CheckSchemes(publics); err != nil {
+		slog.Fatal(err)
 	}
-	var threshold = key.DefaultThreshold(c.NArg())
+	var threshold = key.DefaultThreshold(len(publics))
 	if c.IsSet("threshold") {
 		if c.Int("threshold") < threshold {
 			slog.Print("WARNING: You are using a threshold which is TOO LOW.")
@@ -247,16 +678,6 @@ func groupCmd(c *cli.Context) error {
 		}
 		threshold = c.Int("threshold")
 	}
-
-	publics := make([]*key.Identity, c.NArg())
-	for i, str := range args {
-		pub := &key.Identity{}
-		slog.Print("Reading public identity from ", str)
-		if err := key.Load(str, pub); err != nil {
-			slog.Fatal(err)
-		}
-		publics[i] = pub
-	}
 	group := key.NewGroup(publics, threshold)
 	groupPath := path.Join(fs.Pwd(), gname)
 	if c.String("out") != "" {
@@ -269,13 +690,59 @@ func groupCmd(c *cli.Context) error {
 	return nil
 }
 
+// loadIdentities reads the public identities of all the files of the given
+// directory, in lexical order of their names. Files that are not public identities, such as a
+// group file or a distributed public key, are skipped.
+func loadIdentities(dir string) []*key.Identity {
+	files, err := fs.Files(dir)
+	if err != nil {
+		slog.Fatal("could not read identity directory: ", err)
+	}
+	var publics []*key.Identity
+	for _, f := range files {
+		pub := &key.Identity{}
+		if err := key.Load(f, pub); err != nil || pub.Addr == "" {
+			slog.Print("Skipping ", f, ": not a public identity")
+			continue
+		}
+		slog.Print("Reading public identity from ", f)
+		publics = append(publics, pub)
+	}
+	return publics
+}
+
+// checkGroupCmd validates the group file given in argument without writing
+// anything, and exits with an error if any problem is found.
+func checkGroupCmd(c *cli.Context) error {
+	if c.NArg() != 1 {
+		slog.Fatal("group --check takes the group file to validate")
+	}
+	gt := &key.GroupTOML{}
+	if _, err := toml.DecodeFile(c.Args().First(), gt); err != nil {
+		slog.Fatal(err)
+	}
+	errs := key.CheckGroupTOML(gt)
+	for _, err := range errs {
+		slog.Print(err)
+	}
+	if len(errs) > 0 {
+		slog.Fatalf("%d problems found in group file %s", len(errs), c.Args().First())
+	}
+	for i, ptoml := range gt.Nodes {
+		id := &key.Identity{Addr: ptoml.Address, Name: ptoml.Name, Organization: ptoml.Organization}
+		slog.Printf("node %d: %s", i, id.Label())
+	}
+	slog.Printf("group file %s is valid: %d nodes, threshold %d", c.Args().First(), len(gt.Nodes), gt.Threshold)
+	return nil
+}
+
 func dkgCmd(c *cli.Context) error {
 	if c.NArg() < 1 {
 		slog.Fatal("dkg requires a group.toml file")
 	}
 	group := getGroup(c)
 	conf := contextToConfig(c)
-	fs := key.NewFileStore(conf.ConfigFolder())
+	fs := keyStore(c, conf.ConfigFolder())
 	drand, err := core.NewDrand(fs, group, conf)
 	if err != nil {
 		slog.Fatal(err)
@@ -299,27 +766,71 @@ func runDkg(c *cli.Context, d *core.Drand, ks key.Store) error {
 	if err != nil {
 		slog.Fatal(err)
 	}
-	dir := fs.Pwd()
-	p := path.Join(dir, dpublic)
-	key.Save(p, public, false)
-	slog.Print("distributed public key saved at ", p)
+	var buff bytes.Buffer
+	if err := toml.NewEncoder(&buff).Encode(public.TOML()); err != nil {
+		slog.Fatal(err)
+	}
+	slog.Printf("distributed public key saved in the %s folder of the configuration:\n%s", key.GroupFolderName, buff.String())
+	return nil
+}
+
+// reshareCmd runs the resharing to the new group. Nodes of the current group
+// load their share from their configuration folder, while nodes joining the
+// group need the current group file and distributed public key.
+func reshareCmd(c *cli.Context) error {
+	if c.NArg() < 1 {
+		slog.Fatal("reshare requires the new group.toml file")
+	}
+	newGroup := getGroup(c)
+	conf := contextToConfig(c)
+	fs := keyStore(c, conf.ConfigFolder())
+	var drand *core.Drand
+	var err error
+	if _, serr := fs.LoadShare(); serr == nil {
+		drand, err = core.LoadDrand(fs, conf)
+	} else {
+		if !c.IsSet("old-group") || !c.IsSet("public") {
+			slog.Fatal("no share found: joining nodes need the current group file (--old-group) and distributed public key (--public)")
+		}
+		current := &key.Group{}
+		if err := key.Load(c.String("old-group"), current); err != nil {
+			slog.Fatal(err)
+		}
+		public := &key.DistPublic{}
+		if err := key.Load(c.String("public"), public); err != nil {
+			slog.Fatal(err)
+		}
+		drand, err = core.NewReshareDrand(fs, current, public, conf)
+	}
+	if err != nil {
+		slog.Fatal(err)
+	}
+	if c.Bool("leader") {
+		err = drand.StartReshare(newGroup)
+	} else {
+		err = drand.WaitReshare(newGroup)
+	}
+	if err != nil {
+		slog.Fatal(err)
+	}
+	slog.Printf("Resharing finished! The previous share and group are saved in the %s folder of the configuration, remove them once the new group produces beacons.", core.PreviousFolderName)
 	return nil
 }
 
 func beaconCmd(c *cli.Context) error {
 	conf := contextToConfig(c)
-	fs := key.NewFileStore(conf.ConfigFolder())
+	fs := keyStore(c, conf.ConfigFolder())
 	drand, err := core.LoadDrand(fs, conf)
 	if err != nil {
 		slog.Fatal(err)
 	}
-	drand.BeaconLoop()
+	beaconUntilSignal(drand)
 	return nil
 }
 
 func runCmd(c *cli.Context) error {
 	conf := contextToConfig(c)
-	fs := key.NewFileStore(conf.ConfigFolder())
+	fs := keyStore(c, conf.ConfigFolder())
 	var drand *core.Drand
 	var err error
 	if c.NArg() > 0 {
@@ -339,14 +850,64 @@ func runCmd(c *cli.Context) error {
 		}
 	}
 	slog.Print("Running the randomness beacon...")
-	drand.BeaconLoop()
+	beaconUntilSignal(drand)
 	return nil
 }
 
+// beaconUntilSignal runs the beacon until SIGINT or SIGTERM is received, and
+// then shuts drand down gracefully, letting the round in progress finish. The
+// TLS certificates, and the group and the share, are reloaded from their files
+// on SIGHUP. It exits with an error if the beacon loop fails.
+func beaconUntilSignal(d *core.Drand) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- d.BeaconLoop(ctx)
+	}()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	for stop := false; !stop; {
+		select {
+		case err := <-errs:
+			if err != nil {
+				d.Stop()
+				slog.Fatal(err)
+			}
+			return
+		case s := <-sigs:
+			if s != syscall.SIGHUP {
+				slog.Printf("received %s, finishing the current round before stopping", s)
+				stop = true
+				continue
+			}
+			if err := d.ReloadTLS(); err != nil {
+				slog.Print("reloading TLS certificates failed: ", err)
+			} else {
+				slog.Print("TLS certificates reloaded")
+			}
+			if err := d.Reload(); err != nil {
+				slog.Print("reloading the share failed: ", err)
+			} else {
+				slog.Print("share reloaded")
+			}
+		}
+	}
+	cancel()
+	<-errs
+	ctx, cancel = context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		slog.Print("round in progress aborted: ", err)
+	}
+}
+
 func fetchPrivateCmd(c *cli.Context) error {
 	if c.NArg() < 1 {
 		slog.Fatal("fetch private takes the identity file of a server to contact")
 	}
+	format := outputFormat(c)
 	public := &key.Identity{}
 	if err := key.Load(c.Args().First(), public); err != nil {
 		slog.Fatal(err)
@@ -357,25 +918,65 @@ func fetchPrivateCmd(c *cli.Context) error {
 		defaultManager.Add(c.String("tls-cert"))
 	}
 	client := core.NewGrpcClientFromCert(defaultManager)
-	resp, err := client.Private(public)
+	ctx, cancel := context.WithTimeout(context.Background(), c.Duration("timeout"))
+	defer cancel()
+	resp, err := client.PrivateCtx(ctx, public)
+	checkTimeout(ctx, err, public.Address(), c.Duration("timeout"))
 	if err != nil {
 		slog.Fatal(err)
 	}
 	type private struct {
 		Randomness []byte `json:"randomness"`
 	}
-	buff, err := json.MarshalIndent(&private{resp}, "", "    ")
+	printRandomness(format, &private{resp}, resp)
+	return nil
+}
+
+// outputFormat returns the output format given on the command line, and exits
+// if it is unknown.
+func outputFormat(c *cli.Context) string {
+	switch format := c.String("format"); format {
+	case "", "json":
+		return "json"
+	case "json-compact", "hex", "raw":
+		return format
+	default:
+		slog.Fatalf("unknown format %q: use json, json-compact, hex or raw", format)
+		return ""
+	}
+}
+
+// printRandomness prints the response in the given format: as indented or
+// compact JSON, or only its randomness, hex encoded or as raw bytes.
+func printRandomness(format string, resp interface{}, randomness []byte) {
+	switch format {
+	case "hex":
+		fmt.Println(hex.EncodeToString(randomness))
+		return
+	case "raw":
+		if _, err := os.Stdout.Write(randomness); err != nil {
+			slog.Fatal(err)
+		}
+		return
+	}
+	var buff []byte
+	var err error
+	if format == "json-compact" {
+		buff, err = json.Marshal(resp)
+	} else {
+		buff, err = json.MarshalIndent(resp, "", "    ")
+	}
 	if err != nil {
 		slog.Fatal("could not JSON marshal:", err)
 	}
-	slog.Print(string(buff))
-	return nil
+	fmt.Println(string(buff))
 }
 
 func fetchPublicCmd(c *cli.Context) error {
-	if c.NArg() < 1 {
+	if c.NArg() < 1 && !c.IsSet("fresh") {
 		slog.Fatal("fetch command takes the address of a server to contact")
 	}
+	format := outputFormat(c)
 
 	public := &key.DistPublic{}
 	if err := key.Load(c.String("public"), public); err != nil {
@@ -386,35 +987,939 @@ func fetchPublicCmd(c *cli.Context) error {
 		defaultManager.Add(c.String("tls-cert"))
 	}
 	client := core.NewGrpcClientFromCert(defaultManager)
-	resp, err := client.LastPublic(c.Args().First(), public, !c.Bool("insecure"))
-	if err != nil {
-		slog.Fatal("could not get verified randomness:", err)
+	if c.Bool("watch") {
+		if c.IsSet("fresh") || c.IsSet("round") {
+			slog.Fatal("--watch cannot be used with --fresh or --round")
+		}
+		stop := make(chan bool)
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			close(stop)
+		}()
+		watchPublic(client, c.Args().First(), public, !c.Bool("insecure"), stop, func(resp *drand.PublicRandResponse) {
+			printPublic(format, resp)
+		})
+		return nil
 	}
-	buff, err := json.MarshalIndent(resp, "", "    ")
+	ctx, cancel := context.WithTimeout(context.Background(), c.Duration("timeout"))
+	defer cancel()
+	var resp *drand.PublicRandResponse
+	var err error
+	addr := c.Args().First()
+	if c.IsSet("fresh") {
+		id := &key.Identity{}
+		if err := key.Load(c.String("fresh"), id); err != nil {
+			slog.Fatal(err)
+		}
+		addr = id.Address()
+		resp, err = client.LastPublicFreshCtx(ctx, id, public)
+	} else if c.IsSet("round") {
+		resp, err = client.PublicRoundCtx(ctx, addr, public, c.Uint64("round"), !c.Bool("insecure"))
+	} else {
+		resp, err = client.LastPublicCtx(ctx, addr, public, !c.Bool("insecure"))
+	}
+	if genesis, ok := core.GenesisTime(err); ok {
+		slog.Fatalf("no randomness generated yet, first round expected in %s", time.Until(genesis).Round(time.Second))
+	}
+	checkTimeout(ctx, err, addr, c.Duration("timeout"))
 	if err != nil {
-		slog.Fatal("could not JSON marshal:", err)
+		slog.Fatal("could not get verified randomness:", err)
 	}
-	slog.Print(string(buff))
+	printPublic(format, resp)
 	return nil
 }
 
-func toArray(flags ...cli.Flag) []cli.Flag {
-	return flags
-}
+// defaultFetchTimeout is the time after which the fetch commands give up
+// contacting the node.
+const defaultFetchTimeout = 10 * time.Second
 
-func contextToConfig(c *cli.Context) *core.Config {
-	var opts []core.ConfigOption
-	listen := c.String("listen")
-	if listen != "" {
-		opts = append(opts, core.WithListenAddress(listen))
+// checkTimeout exits if the call to the node at the given address failed
+// because its context expired.
+func checkTimeout(ctx context.Context, err error, addr string, timeout time.Duration) {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		slog.Fatalf("timed out contacting %s after %s", addr, timeout)
 	}
+}
 
-	config := c.GlobalString("config")
+// printPublic prints the public randomness of the verified response in the
+// given format. The nodes that predate the signature field carry the signature
+// as randomness.
+func printPublic(format string, resp *drand.PublicRandResponse) {
+	sig := resp.GetSignature()
+	if len(sig) == 0 {
+		sig = resp.GetRandomness()
+	}
+	b := &beacon.Beacon{
+		PreviousRand: resp.GetPrevious(),
+		Round:        resp.GetRound(),
+		Signature:    sig,
+	}
+	printRandomness(format, b, b.Randomness())
+}
+
+// watchRetryDelay is the time fetch public --watch waits before reconnecting
+// to a node whose stream dropped. It doubles after each failed attempt, up to
+// maxWatchRetryDelay.
+const watchRetryDelay = time.Second
+
+const maxWatchRetryDelay = 30 * time.Second
+
+// watchPublic calls print with each new verified beacon produced by the node
+// at the given address, in order, until stop is closed. It reconnects when the
+// stream drops, and skips the rounds already printed.
+func watchPublic(client *core.Client, addr string, public *key.DistPublic, secure bool, stop chan bool, print func(*drand.PublicRandResponse)) {
+	var last uint64
+	delay := watchRetryDelay
+	for {
+		beacons, err := client.Follow(addr, public, secure)
+		if err != nil {
+			slog.Infof("could not follow %s: %s", addr, err)
+		}
+		for beacons != nil {
+			select {
+			case resp, ok := <-beacons:
+				if !ok {
+					slog.Infof("stream from %s dropped, reconnecting", addr)
+					beacons = nil
+					continue
+				}
+				delay = watchRetryDelay
+				if resp.GetRound() <= last {
+					continue
+				}
+				last = resp.GetRound()
+				print(resp)
+			case <-stop:
+				return
+			}
+		}
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return
+		}
+		if delay *= 2; delay > maxWatchRetryDelay {
+			delay = maxWatchRetryDelay
+		}
+	}
+}
+
+// fetchInfoCmd prints the parameters of the chain served by the node at the
+// given address as JSON.
+func fetchInfoCmd(c *cli.Context) error {
+	if c.NArg() < 1 {
+		slog.Fatal("fetch info takes the address of a server to contact")
+	}
+	defaultManager := net.NewCertManager()
+	if c.IsSet("tls-cert") {
+		defaultManager.Add(c.String("tls-cert"))
+	}
+	client := core.NewGrpcClientFromCert(defaultManager)
+	info, err := client.ChainInfo(c.Args().First(), !c.Bool("insecure"))
+	if err != nil {
+		slog.Fatal("could not get chain info: ", err)
+	}
+	distKey, err := info.Public.Key.MarshalBinary()
+	if err != nil {
+		slog.Fatal(err)
+	}
+	// the genesis time is only known when the rounds are aligned on it
+	var genesis *time.Time
+	if !info.GenesisTime.IsZero() {
+		t := info.GenesisTime.UTC()
+		genesis = &t
+	}
+	buff, err := json.MarshalIndent(&struct {
+		Round       uint64     `json:"round"`
+		Period      string     `json:"period"`
+		GenesisTime *time.Time `json:"genesis_time,omitempty"`
+		Aligned     bool       `json:"aligned"`
+		Seed        string     `json:"seed"`
+		DistKey     string     `json:"dist_key"`
+	}{
+		Round:       info.Round,
+		Period:      info.Period.String(),
+		GenesisTime: genesis,
+		Aligned:     info.Aligned,
+		Seed:        hex.EncodeToString(info.Seed),
+		DistKey:     hex.EncodeToString(distKey),
+	}, "", "    ")
+	if err != nil {
+		slog.Fatal("could not JSON marshal:", err)
+	}
+	fmt.Println(string(buff))
+	return nil
+}
+
+// fetchLocateCmd prints the beacon whose randomness is the given one, once
+// verified against the distributed public key, and exits with an error if the
+// node has no such beacon.
+func fetchLocateCmd(c *cli.Context) error {
+	if c.NArg() < 2 {
+		slog.Fatal("fetch locate takes the address of a server to contact and the randomness to find")
+	}
+	format := outputFormat(c)
+	randomness, err := hex.DecodeString(c.Args().Get(1))
+	if err != nil {
+		slog.Fatal("invalid randomness: ", err)
+	}
+	public := &key.DistPublic{}
+	if err := key.Load(c.String("public"), public); err != nil {
+		slog.Fatal(err)
+	}
+	defaultManager := net.NewCertManager()
+	if c.IsSet("tls-cert") {
+		defaultManager.Add(c.String("tls-cert"))
+	}
+	client := core.NewGrpcClientFromCert(defaultManager)
+	resp, err := client.LocateRandomness(c.Args().First(), public, randomness, !c.Bool("insecure"))
+	if status.Code(err) == codes.NotFound {
+		slog.Fatal("randomness not found on the node")
+	} else if err != nil {
+		slog.Fatal("could not locate the randomness: ", err)
+	}
+	printPublic(format, resp)
+	return nil
+}
+
+// fetchSignCmd prints the signature of the group over the given message, once
+// verified against the distributed public key.
+func fetchSignCmd(c *cli.Context) error {
+	if c.NArg() < 2 {
+		slog.Fatal("fetch sign takes the address of a server to contact and the message to sign")
+	}
+	public := &key.DistPublic{}
+	if err := key.Load(c.String("public"), public); err != nil {
+		slog.Fatal(err)
+	}
+	defaultManager := net.NewCertManager()
+	if c.IsSet("tls-cert") {
+		defaultManager.Add(c.String("tls-cert"))
+	}
+	client := core.NewGrpcClientFromCert(defaultManager)
+	sig, err := client.Sign(c.Args().First(), public, []byte(c.Args().Get(1)), !c.Bool("insecure"))
+	if err != nil {
+		slog.Fatal("could not sign the message: ", err)
+	}
+	fmt.Println(hex.EncodeToString(sig))
+	return nil
+}
+
+// pingCmd prints the status of the node at the given address. It exits with an
+// error if the node is unreachable.
+func pingCmd(c *cli.Context) error {
+	if c.NArg() < 1 {
+		slog.Fatal("ping command takes the address of a server to contact")
+	}
+	defaultManager := net.NewCertManager()
+	if c.IsSet("tls-cert") {
+		defaultManager.Add(c.String("tls-cert"))
+	}
+	client := core.NewGrpcClientFromCert(defaultManager)
+	resp, err := client.Home(c.Args().First(), !c.Bool("insecure"))
+	if err != nil {
+		slog.Fatal("could not reach node: ", err)
+	}
+	buff, err := json.MarshalIndent(resp, "", "    ")
+	if err != nil {
+		slog.Fatal("could not JSON marshal:", err)
+	}
+	slog.Print(string(buff))
+	return nil
+}
+
+// localStatus is the state of a node as saved in its config and database
+// folders.
+type localStatus struct {
+	KeyPair   bool
+	Encrypted bool
+	Address   string
+	TLS       bool
+	// Label is the address of the node with its name and organization, if any
+	Label     string
+	GroupSize int
+	Threshold int
+	DKGDone   bool
+	// Running is true when the beacon database is locked, most likely by the
+	// node running
+	Running bool
+	// LastRound is the last round saved, zero if there is no beacon saved
+	LastRound uint64
+	// LastWrite is the last modification time of the beacon database
+	LastWrite time.Time
+}
+
+// statusTimeout is how long drand status waits for the local node to report
+// its round statistics.
+const statusTimeout = 2 * time.Second
+
+// statusCmd prints the state of the local node. It reads the config and
+// database folders, and asks the local node for the time its rounds take to
+// reach the threshold if it is running.
+func statusCmd(c *cli.Context) error {
+	st, err := loadStatus(contextToConfig(c))
+	if err != nil {
+		slog.Fatal(err)
+	}
+	if !st.KeyPair {
+		slog.Print("key pair:    none, run keygen first")
+	} else if st.Encrypted {
+		slog.Print("key pair:    encrypted with a passphrase")
+	} else {
+		slog.Print("key pair:    ", st.Label)
+	}
+	if st.GroupSize == 0 {
+		slog.Print("group:       none")
+	} else {
+		slog.Printf("group:       %d nodes, threshold %d", st.GroupSize, st.Threshold)
+	}
+	slog.Print("dkg done:    ", st.DKGDone)
+	var home *drand.HomeResponse
+	var dkgStatus *dkg_proto.DKGStatusResponse
+	if st.Address != "" {
+		grpcClient := net.NewGrpcClientWithTimeout(statusTimeout)
+		home, _ = core.NewClient(grpcClient).Home(st.Address, st.TLS)
+		id := &key.Identity{Addr: st.Address, TLS: st.TLS}
+		dkgStatus, _ = grpcClient.DKGStatus(id, &dkg_proto.DKGStatusRequest{})
+	}
+	printDKGStatus(dkgStatus)
+	switch {
+	case st.LastRound != 0:
+		slog.Printf("last round:  %d, database written at %s", st.LastRound, st.LastWrite.Format(time.RFC3339))
+	case home != nil && home.GetRound() != 0:
+		slog.Printf("last round:  %d, reported by the running node", home.GetRound())
+	case st.Running:
+		slog.Print("last round:  unknown, the database is locked by the running node")
+	default:
+		slog.Print("last round:  none")
+	}
+	if home == nil {
+		slog.Print("rounds:      node not reachable")
+	} else if home.GetRoundsMeasured() == 0 {
+		slog.Print("rounds:      no round measured yet")
+	} else {
+		window := home.GetRoundsMeasured()
+		if window > beacon.StatsWindow {
+			window = beacon.StatsWindow
+		}
+		slog.Printf("rounds:      threshold reached in %s on average, %s at most over the last %d rounds",
+			time.Duration(home.GetThresholdLatencyEma())*time.Millisecond,
+			time.Duration(home.GetThresholdLatencyMax())*time.Millisecond, window)
+	}
+	return nil
+}
+
+// printDKGStatus prints the progress of the DKG the node is running, if any,
+// so an operator can see which phase and which nodes it is waiting for.
+func printDKGStatus(st *dkg_proto.DKGStatusResponse) {
+	if st == nil || (st.GetPhase() == string(dkg.PhaseFinished) && st.GetError() == "") {
+		return
+	}
+	line := fmt.Sprintf("dkg:         %s phase, %d%% of the packets processed, %d/%d valid deals, %d responses",
+		st.GetPhase(), st.GetProgress(), st.GetValidDeals(), st.GetNeeded(), st.GetResponses())
+	if st.GetDisqualified() > 0 {
+		line += fmt.Sprintf(", %d disqualified", st.GetDisqualified())
+	}
+	slog.Print(line)
+	if len(st.GetMissing()) > 0 && st.GetPhase() != string(dkg.PhaseFinished) {
+		slog.Print("dkg waits:   no deal received yet from ", strings.Join(st.GetMissing(), ", "))
+	}
+	if st.GetError() != "" {
+		slog.Print("dkg error:   ", st.GetError())
+	}
+}
+
+// loadStatus reads the key material of the node with a key.FileStore and its
+// last beacon from the bolt database, opened read-only. The database cannot be
+// read while the node is running, which is reported with Running.
+func loadStatus(conf *core.Config) (*localStatus, error) {
+	st := new(localStatus)
+	store := key.NewFileStore(conf.ConfigFolder())
+	if pair, err := store.LoadKeyPair(); err == nil {
+		st.KeyPair = true
+		st.Address = pair.Public.Address()
+		st.TLS = pair.Public.IsTLS()
+		st.Label = pair.Public.Label()
+	} else if key.IsKeyEncrypted(conf.ConfigFolder()) {
+		st.KeyPair = true
+		st.Encrypted = true
+	}
+	if group, err := store.LoadGroup(); err == nil {
+		st.GroupSize = group.Len()
+		st.Threshold = group.Threshold
+	}
+	_, shareErr := store.LoadShare()
+	_, pubErr := store.LoadDistPublic()
+	st.DKGDone = shareErr == nil && pubErr == nil
+
+	info, err := os.Stat(conf.DBFile())
+	if os.IsNotExist(err) {
+		return st, nil
+	} else if err != nil {
+		return nil, err
+	}
+	st.LastWrite = info.ModTime()
+	db, err := beacon.NewBoltStoreFile(conf.DBFile(), &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		st.Running = true
+		return st, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not open the beacon database: %s", err)
+	}
+	defer db.Close()
+	last, err := db.Last()
+	if err == beacon.ErrNoBeaconSaved {
+		return st, nil
+	} else if err != nil {
+		return nil, err
+	}
+	st.LastRound = last.Round
+	return st, nil
+}
+
+// shareExportCmd writes the share of the node to the output file, created with
+// tight permissions, or to stdout, encrypted with a passphrase if asked.
+func shareExportCmd(c *cli.Context) error {
+	conf := contextToConfig(c)
+	share, err := key.NewFileStore(conf.ConfigFolder(), storeOptions(c)...).LoadShare()
+	if err != nil {
+		slog.Fatal("could not load the share, has the DKG been run? ", err)
+	}
+	var passphrase []byte
+	if c.Bool("encrypt") {
+		passphrase = readPassphrase("the exported share")
+	}
+	var out io.Writer = os.Stdout
+	if name := c.String("out"); name != "" {
+		fd, err := fs.CreateSecureFile(name)
+		if err != nil {
+			slog.Fatal(err)
+		}
+		defer fd.Close()
+		out = fd
+	}
+	if err := key.ExportShare(out, share, passphrase); err != nil {
+		slog.Fatal("could not export the share: ", err)
+	}
+	return nil
+}
+
+// chainExportCmd writes the beacons saved in the database of the node to the
+// output file or to stdout. It opens the database read-only so it can run next
+// to a running node.
+func chainExportCmd(c *cli.Context) error {
+	// keep stdout for the chain only
+	slog.Output = os.Stderr
+	conf := contextToConfig(c)
+	db, err := beacon.NewBoltStoreFile(conf.DBFile(), &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		slog.Fatal("could not open the beacon database: ", err)
+	}
+	defer db.Close()
+	var out io.Writer = os.Stdout
+	if name := c.String("out"); name != "" {
+		fd, err := fs.CreateSecureFile(name)
+		if err != nil {
+			slog.Fatal(err)
+		}
+		defer fd.Close()
+		out = fd
+	}
+	n, err := core.ExportChain(db, out)
+	if err != nil {
+		slog.Fatal("could not export the chain: ", err)
+	}
+	slog.Printf("%d beacons exported", n)
+	return nil
+}
+
+// chainImportCmd verifies the beacons read from the given file or stdin against
+// the distributed public key, from --public or else the one of the node, and
+// appends them to the database of the node.
+func chainImportCmd(c *cli.Context) error {
+	conf := contextToConfig(c)
+	public := &key.DistPublic{}
+	if name := c.String("public"); name != "" {
+		if err := key.Load(name, public); err != nil {
+			slog.Fatal(err)
+		}
+	} else {
+		var err error
+		public, err = key.NewFileStore(conf.ConfigFolder(), storeOptions(c)...).LoadDistPublic()
+		if err != nil {
+			slog.Fatal("could not load the distributed public key, give it with --public: ", err)
+		}
+	}
+	var in io.Reader = os.Stdin
+	if name := c.Args().First(); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			slog.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	fs.CreateSecureFolder(conf.DBFolder())
+	db, err := beacon.NewBoltStoreFile(conf.DBFile(), &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		slog.Fatal("could not open the beacon database, is the node running?")
+	} else if err != nil {
+		slog.Fatal("could not open the beacon database: ", err)
+	}
+	defer db.Close()
+	n, err := core.ImportChain(db, public, in)
+	if err != nil {
+		slog.Fatal("refusing to import the chain: ", err)
+	}
+	slog.Printf("%d beacons imported", n)
+	return nil
+}
+
+// showDistPublicCmd prints the distributed public key recomputed from the
+// public coefficients of the share, after checking them against the group and
+// the private share, and warns if the stored distributed public key differs.
+// It only reads the configuration folder, so it works without network access.
+func showDistPublicCmd(c *cli.Context) error {
+	// keep stdout for the distributed public key only
+	slog.Output = os.Stderr
+	conf := contextToConfig(c)
+	store := key.NewFileStore(conf.ConfigFolder(), storeOptions(c)...)
+	group, err := store.LoadGroup()
+	if err != nil {
+		slog.Fatal("could not load the group: ", err)
+	}
+	share, err := store.LoadShare()
+	if err != nil {
+		slog.Fatal("could not load the share, has the DKG been run? ", err)
+	}
+	derived, err := core.DeriveDistPublic(group, share)
+	if err != nil {
+		slog.Fatal("could not derive the distributed public key: ", err)
+	}
+	if err := toml.NewEncoder(os.Stdout).Encode(derived.TOML()); err != nil {
+		slog.Fatal(err)
+	}
+	stored, err := store.LoadDistPublic()
+	if err != nil {
+		slog.Info("no stored distributed public key to compare with: ", err)
+	} else if !stored.Key.Equal(derived.Key) {
+		slog.Print("WARNING: the stored distributed public key differs from the one derived from the share")
+	} else {
+		slog.Info("the stored distributed public key matches the share")
+	}
+	return nil
+}
+
+// shareImportCmd restores the share from the given file, prompting for its
+// passphrase if it is encrypted. The share is only saved if it is the share of
+// the node in its group and matches its distributed public key.
+func shareImportCmd(c *cli.Context) error {
+	if !c.Args().Present() {
+		slog.Fatal("share import needs the exported share file")
+	}
+	fd, err := os.Open(c.Args().First())
+	if err != nil {
+		slog.Fatal(err)
+	}
+	defer fd.Close()
+	share, err := key.ImportShare(fd, func() []byte {
+		return readPassphrase("the exported share")
+	})
+	if err != nil {
+		slog.Fatal("could not read the share: ", err)
+	}
+	conf := contextToConfig(c)
+	store := keyStore(c, conf.ConfigFolder())
+	priv, err := store.LoadKeyPair()
+	if err != nil {
+		slog.Fatal("could not load the key pair: ", err)
+	}
+	group, err := store.LoadGroup()
+	if err != nil {
+		slog.Fatal("could not load the group: ", err)
+	}
+	pub, err := store.LoadDistPublic()
+	if err != nil {
+		slog.Fatal("could not load the distributed public key: ", err)
+	}
+	if err := core.VerifyShare(priv.Public, group, share, pub); err != nil {
+		slog.Fatal("refusing to import the share: ", err)
+	}
+	if err := store.SaveShare(share); err != nil {
+		slog.Fatal("could not save the share: ", err)
+	}
+	slog.Print("share restored for ", priv.Public.Address())
+	return nil
+}
+
+// verifyCmd verifies the chain of beacons read from the given file or stdin and
+// exits with an error at the first round that does not verify.
+func verifyCmd(c *cli.Context) error {
+	public := &key.DistPublic{}
+	if err := key.Load(c.String("public"), public); err != nil {
+		slog.Fatal(err)
+	}
+	var in io.Reader = os.Stdin
+	if name := c.Args().First(); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			slog.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	beacons, err := core.ReadBeacons(in)
+	if err != nil {
+		slog.Fatal("could not read beacons: ", err)
+	}
+	if err := core.VerifyChain(public, beacons); err != nil {
+		slog.Fatal("verification failed at ", err)
+	}
+	slog.Printf("%d beacons verified, from round %d to round %d", len(beacons), beacons[0].GetRound(), beacons[len(beacons)-1].GetRound())
+	return nil
+}
+
+// combineCmd recovers the signature of a round from the partial signatures
+// read from the given files and prints the beacon of the round once verified
+// against the distributed public key.
+func combineCmd(c *cli.Context) error {
+	// keep stdout for the beacon only
+	slog.Output = os.Stderr
+	if !c.Args().Present() {
+		slog.Fatal("combine needs the files of the partial signatures")
+	}
+	if !c.IsSet("round") || !c.IsSet("group") || !c.IsSet("public") {
+		slog.Fatal("combine needs --round, --group and --public")
+	}
+	prev, err := hex.DecodeString(c.String("prev"))
+	if err != nil {
+		slog.Fatal("invalid previous signature: ", err)
+	}
+	group := &key.Group{}
+	if err := key.Load(c.String("group"), group); err != nil {
+		slog.Fatal(err)
+	}
+	public := &key.DistPublic{}
+	if err := key.Load(c.String("public"), public); err != nil {
+		slog.Fatal(err)
+	}
+	partials := make([][]byte, 0, c.NArg())
+	for _, name := range c.Args() {
+		buff, err := ioutil.ReadFile(name)
+		if err != nil {
+			slog.Fatal(err)
+		}
+		partial, err := hex.DecodeString(strings.TrimSpace(string(buff)))
+		if err != nil {
+			slog.Fatalf("invalid partial signature in %s: %s", name, err)
+		}
+		partials = append(partials, partial)
+	}
+	round := c.Uint64("round")
+	sig, err := core.CombinePartials(group, public, round, prev, partials)
+	if err != nil {
+		slog.Fatal("could not combine the partial signatures: ", err)
+	}
+	b := &beacon.Beacon{PreviousRand: prev, Round: round, Signature: sig}
+	printRandomness("json", b, b.Randomness())
+	slog.Printf("round %d: signature recovered from %d partial signatures and verified against the distributed public key", round, len(partials))
+	return nil
+}
+
+func selfTestCmd(c *cli.Context) error {
+	n := c.Int("nodes")
+	resp, err := selfTest(n)
+	if err != nil {
+		slog.Fatal("self-test failed: ", err)
+	}
+	slog.Printf("self-test passed: %d nodes ran the DKG and produced round %d with randomness %x", n, resp.GetRound(), resp.GetRandomness())
+	return nil
+}
+
+// selfTest runs n nodes in this process, connected by an in-memory network and
+// with their keys in a temporary folder. The nodes run the DKG and the first
+// round of the beacon, whose beacon is returned once verified against the
+// distributed key.
+func selfTest(n int) (*drand.PublicRandResponse, error) {
+	if n < 3 {
+		return nil, fmt.Errorf("at least 3 nodes are needed, got %d", n)
+	}
+	dir, err := ioutil.TempDir("", "drand-test")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// no node listens on these addresses, they only identify the nodes on
+	// the in-memory network
+	privs := make([]*key.Pair, n)
+	ids := make([]*key.Identity, n)
+	for i := range privs {
+		privs[i] = key.NewKeyPair(fmt.Sprintf("127.0.0.1:%d", 4000+i))
+		ids[i] = privs[i].Public
+	}
+	group := key.NewGroup(ids, key.DefaultThreshold(n))
+
+	network := net.NewMemoryNetwork()
+	public := make(chan *key.DistPublic, 1)
+	produced := make(chan uint64, 1)
+	drands := make([]*core.Drand, n)
+	for i := range drands {
+		folder := path.Join(dir, fmt.Sprintf("node-%d", i))
+		store := key.NewFileStore(folder)
+		if err := store.SaveKeyPair(privs[i]); err != nil {
+			return nil, err
+		}
+		opts := []core.ConfigOption{
+			core.WithConfigFolder(folder),
+			core.WithInsecure(),
+			core.WithInMemory(),
+			core.WithNetwork(network.Gateway),
+			core.WithBeaconPeriod(selfTestPeriod),
+		}
+		if i == 0 {
+			opts = append(opts,
+				core.WithDKGCallback(func(pub *key.DistPublic, _ *key.Group) { public <- pub }),
+				core.WithBeaconCallback(func(b *beacon.Beacon) {
+					select {
+					case produced <- b.Round:
+					default:
+					}
+				}))
+		}
+		drands[i], err = core.NewDrand(store, group, core.NewConfig(opts...))
+		if err != nil {
+			return nil, err
+		}
+		defer drands[i].Stop()
+	}
+
+	errs := make(chan error, n)
+	for _, d := range drands[1:] {
+		go func(d *core.Drand) { errs <- d.WaitDKG() }(d)
+	}
+	go func() { errs <- drands[0].StartDKG() }()
+	timeout := time.After(selfTestTimeout)
+	for range drands {
+		select {
+		case err := <-errs:
+			if err != nil {
+				return nil, fmt.Errorf("dkg: %s", err)
+			}
+		case <-timeout:
+			return nil, errors.New("dkg: timeout")
+		}
+	}
+
+	for _, d := range drands {
+		go d.BeaconLoop(context.Background())
+	}
+	select {
+	case <-produced:
+	case <-timeout:
+		return nil, errors.New("beacon: no round produced before the timeout")
+	}
+	// LastPublic verifies the beacon
+	client := core.NewClient(network.Client())
+	return client.LastPublic(privs[0].Public.Address(), <-public, false)
+}
+
+// verifyDKGCmd verifies the transcript of a DKG against the group that ran it,
+// and against the distributed public key if one is given.
+func verifyDKGCmd(c *cli.Context) error {
+	if c.NArg() != 2 {
+		slog.Fatal("verify-dkg takes the transcript and the group file of the DKG")
+	}
+	transcript, err := core.LoadTranscript(c.Args().Get(0))
+	if err != nil {
+		slog.Fatal("could not read transcript: ", err)
+	}
+	group := &key.Group{}
+	if err := key.Load(c.Args().Get(1), group); err != nil {
+		slog.Fatal(err)
+	}
+	public, err := dkg.VerifyTranscript(group, transcript)
+	if err != nil {
+		slog.Fatal("verification failed: ", err)
+	}
+	if c.IsSet("public") {
+		expected := &key.DistPublic{}
+		if err := key.Load(c.String("public"), expected); err != nil {
+			slog.Fatal(err)
+		}
+		if !expected.Key.Equal(public.Key) {
+			slog.Fatal("verification failed: the DKG produced another distributed public key")
+		}
+	}
+	buff, err := public.Key.MarshalBinary()
+	if err != nil {
+		slog.Fatal(err)
+	}
+	slog.Printf("transcript verified: %d qualified dealers out of %d, distributed public key %x", len(transcript.Qualified), group.Len(), buff)
+	return nil
+}
+
+// monitorCmd polls the trusted node every period, verifies its beacons and
+// serves the results on the listening address: a JSON status page on "/" and
+// metrics on "/metrics".
+func monitorCmd(c *cli.Context) error {
+	if !c.IsSet("trusted") {
+		slog.Fatal("monitor needs the address of the trusted node to monitor")
+	}
+	public := &key.DistPublic{}
+	if err := key.Load(c.String("public"), public); err != nil {
+		slog.Fatal(err)
+	}
+	defaultManager := net.NewCertManager()
+	if c.IsSet("tls-cert") {
+		defaultManager.Add(c.String("tls-cert"))
+	}
+	client := core.NewGrpcClientFromCert(defaultManager)
+	monitor := core.NewMonitor(client, c.String("trusted"), public, !c.Bool("insecure"), c.Duration("period"))
+	go monitor.Start()
+	defer monitor.Stop()
+
+	listen := c.String("listen")
+	if listen == "" {
+		listen = defaultMonitorListen
+	}
+	slog.Printf("monitoring %s every %s, status served at http://%s", c.String("trusted"), c.Duration("period"), listen)
+	if err := http.ListenAndServe(listen, monitor); err != nil {
+		slog.Fatal(err)
+	}
+	return nil
+}
+
+// keyStore returns the store of the key material in the given folder. If the
+// private key is encrypted, the passphrase is read from DRAND_PASSPHRASE or
+// prompted for.
+func keyStore(c *cli.Context, folder string) key.Store {
+	if key.IsKeyEncrypted(folder) {
+		return key.NewEncryptedFileStore(folder, readPassphrase("the private key"), storeOptions(c)...)
+	}
+	return key.NewFileStore(folder, storeOptions(c)...)
+}
+
+// storeOptions returns the options of the file stores given on the command
+// line.
+func storeOptions(c *cli.Context) []key.FileStoreOption {
+	if c.GlobalBool("strict-perms") {
+		return []key.FileStoreOption{key.WithStrictPermissions()}
+	}
+	return nil
+}
+
+// readPassphrase returns the passphrase of the given secret, from the
+// DRAND_PASSPHRASE environment variable if set or else read from stdin.
+func readPassphrase(of string) []byte {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return []byte(p)
+	}
+	fmt.Fprintf(os.Stderr, "Passphrase of %s: ", of)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		slog.Fatal("could not read the passphrase: ", err)
+	}
+	passphrase := strings.TrimRight(line, "\r\n")
+	if passphrase == "" {
+		slog.Fatal("empty passphrase")
+	}
+	return []byte(passphrase)
+}
+
+// parseSeed returns the bytes of the seed given on the command line: hex
+// decoded if it starts with "hex:", read from the file whose path follows if
+// it starts with "@", or the string itself otherwise.
+func parseSeed(s string) ([]byte, error) {
+	var seed []byte
+	var err error
+	switch {
+	case strings.HasPrefix(s, "hex:"):
+		if seed, err = hex.DecodeString(strings.TrimPrefix(s, "hex:")); err != nil {
+			return nil, fmt.Errorf("seed is not hex encoded: %s", err)
+		}
+	case strings.HasPrefix(s, "@"):
+		if seed, err = ioutil.ReadFile(strings.TrimPrefix(s, "@")); err != nil {
+			return nil, fmt.Errorf("could not read seed: %s", err)
+		}
+	default:
+		seed = []byte(s)
+	}
+	return seed, core.CheckSeed(seed)
+}
+
+func toArray(flags ...cli.Flag) []cli.Flag {
+	return flags
+}
+
+func contextToConfig(c *cli.Context) *core.Config {
+	var opts []core.ConfigOption
+	listen := c.String("listen")
+	if listen != "" {
+		if err := net.CheckListenAddress(listen); err != nil {
+			slog.Fatal(err)
+		}
+		opts = append(opts, core.WithListenAddress(listen))
+	}
+
+	config := c.GlobalString("config")
 	opts = append(opts, core.WithConfigFolder(config))
 	db := c.GlobalString("db")
 	opts = append(opts, core.WithDbFolder(db))
+	if chain := c.GlobalString("chain"); chain != "" {
+		if err := core.CheckName(chain); err != nil {
+			slog.Fatal(err)
+		}
+		opts = append(opts, core.WithChainName(chain))
+	}
 	period := c.Duration("period")
 	opts = append(opts, core.WithBeaconPeriod(period))
+	if c.IsSet("genesis") {
+		opts = append(opts, core.WithGenesisTime(time.Unix(c.Int64("genesis"), 0)))
+	}
+	if c.IsSet("seed") {
+		seed, err := parseSeed(c.String("seed"))
+		if err != nil {
+			slog.Fatal(err)
+		}
+		opts = append(opts, core.WithSeed(seed))
+	}
+	if c.Bool("force") {
+		opts = append(opts, core.WithForceChain())
+	}
+	if c.IsSet("dkg-timeout") {
+		opts = append(opts, core.WithDkgTimeout(c.Duration("dkg-timeout")))
+	}
+	if c.Bool("dkg-fail-fast") {
+		opts = append(opts, core.WithDkgFailFast())
+	}
+	if c.Bool("auto-leader") {
+		opts = append(opts, core.WithAutoLeader())
+	}
+	if c.Bool("signing") {
+		opts = append(opts, core.WithSigning(nil))
+	}
+	if c.IsSet("events") {
+		opts = append(opts, core.WithEventWriter(openEvents(c.String("events"))))
+	}
+	if c.Bool("allow-weak") {
+		opts = append(opts, core.WithAllowWeak())
+	}
+	if c.IsSet("min-group-size") {
+		opts = append(opts, core.WithMinimumGroupSize(c.Int("min-group-size")))
+	}
+	if c.IsSet("log-sampling") {
+		opts = append(opts, core.WithRequestLogSampling(c.Int("log-sampling")))
+	}
+	if c.IsSet("rate-limit") {
+		burst := c.Int("rate-limit")
+		if c.IsSet("rate-burst") {
+			burst = c.Int("rate-burst")
+		}
+		opts = append(opts, core.WithRateLimit(c.Int("rate-limit"), burst))
+	}
 
 	if c.Bool("insecure") {
 		opts = append(opts, core.WithInsecure())
@@ -424,6 +1929,9 @@ func contextToConfig(c *cli.Context) *core.Config {
 	} else {
 		certPath, keyPath := c.String("tls-cert"), c.String("tls-key")
 		opts = append(opts, core.WithTLS(certPath, keyPath))
+		if pass := os.Getenv(tlsPassphraseEnv); pass != "" {
+			opts = append(opts, core.WithTLSKeyPassphrase([]byte(pass)))
+		}
 	}
 
 	if c.IsSet("certs-dir") {
@@ -443,6 +1951,19 @@ func contextToConfig(c *cli.Context) *core.Config {
 	return conf
 }
 
+// openEvents returns the writer to which the events are written: stdout for
+// "-" or the given file, opened in append mode.
+func openEvents(name string) io.Writer {
+	if name == "-" {
+		return os.Stdout
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		slog.Fatal("could not open events file: ", err)
+	}
+	return f
+}
+
 func getGroup(c *cli.Context) *key.Group {
 	g := &key.Group{}
 	if err := key.Load(c.Args().First(), g); err != nil {
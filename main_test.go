@@ -1,26 +1,40 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	gonet "net"
 	"os"
 	"os/exec"
 	"path"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/BurntSushi/toml"
+	"github.com/dedis/drand/beacon"
 	"github.com/dedis/drand/core"
 	"github.com/dedis/drand/fs"
 	"github.com/dedis/drand/key"
+	"github.com/dedis/drand/net"
+	"github.com/dedis/drand/protobuf/drand"
 	"github.com/dedis/drand/test"
+	"github.com/dedis/kyber/share"
+	"github.com/dedis/kyber/sign/bls"
+	"github.com/dedis/kyber/util/random"
 	"github.com/kabukky/httpscerts"
+	"github.com/nikkolasg/slog"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 func TestKeyGen(t *testing.T) {
 	tmp := path.Join(os.TempDir(), "drand")
 	defer os.RemoveAll(tmp)
 	// valid address
-	os.Args = []string{"drand", "--config", tmp, "keygen", "127.0.0.1:8081"}
+	os.Args = []string{"drand", "--config", tmp, "keygen", "--scheme", key.DefaultSchemeName, "127.0.0.1:8081"}
 	main()
 	config := core.NewConfig(core.WithConfigFolder(tmp))
 	fs := key.NewFileStore(config.ConfigFolder())
@@ -28,6 +42,57 @@ func TestKeyGen(t *testing.T) {
 	priv, err := fs.LoadKeyPair()
 	require.Nil(t, err)
 	require.NotNil(t, priv.Public)
+	require.Equal(t, key.DefaultSchemeName, priv.Public.Scheme)
+}
+
+func TestKeyGenEncrypted(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drand-encrypted")
+	defer os.RemoveAll(tmp)
+	os.Setenv(passphraseEnv, "correct horse")
+	defer os.Unsetenv(passphraseEnv)
+	os.Args = []string{"drand", "--config", tmp, "keygen", "127.0.0.1:8081"}
+	main()
+	require.True(t, key.IsKeyEncrypted(tmp))
+
+	priv, err := key.NewEncryptedFileStore(tmp, []byte("correct horse")).LoadKeyPair()
+	require.NoError(t, err)
+	require.NotNil(t, priv.Public)
+	_, err = key.NewEncryptedFileStore(tmp, []byte("wrong horse")).LoadKeyPair()
+	require.Equal(t, key.ErrWrongPassphrase, err)
+}
+
+func TestKeyGenStdoutForce(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drand-stdout")
+	defer os.RemoveAll(tmp)
+	os.Args = []string{"drand", "--config", tmp, "keygen", "127.0.0.1:8081"}
+	main()
+	first, err := key.NewFileStore(tmp).LoadKeyPair()
+	require.NoError(t, err)
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+		slog.Output = os.Stdout
+	}()
+	os.Args = []string{"drand", "--config", tmp, "keygen", "--out", "-", "--force", "127.0.0.1:8082"}
+	main()
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	printed := new(key.Identity)
+	ptoml := printed.TOMLValue()
+	_, err = toml.Decode(string(out), ptoml)
+	require.NoError(t, err, "stdout: %s", out)
+	require.NoError(t, printed.FromTOML(ptoml))
+	second, err := key.NewFileStore(tmp).LoadKeyPair()
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:8082", printed.Addr)
+	require.True(t, printed.Key.Equal(second.Public.Key))
+	require.False(t, first.Public.Key.Equal(second.Public.Key))
 }
 
 // https://stackoverflow.com/questions/26225513/how-to-test-os-exit-scenarios-in-go
@@ -87,6 +152,33 @@ func TestGroupGen(t *testing.T) {
 	}
 }
 
+func TestGroupGenFromDir(t *testing.T) {
+	n := 4
+	tmpPath := path.Join(os.TempDir(), "drand-from-dir")
+	idsPath := path.Join(tmpPath, "ids")
+	require.NoError(t, os.MkdirAll(idsPath, 0777))
+	defer os.RemoveAll(tmpPath)
+
+	privs := make([]*key.Pair, n)
+	for i := 0; i < n; i++ {
+		privs[i] = key.NewKeyPair(fmt.Sprintf("127.0.0.1:%d", 8000+i))
+		require.NoError(t, key.Save(path.Join(idsPath, fmt.Sprintf("node-%d.toml", i)), privs[i].Public, false))
+	}
+	// files that are not identities are skipped
+	require.NoError(t, ioutil.WriteFile(path.Join(idsPath, "README"), []byte("not an identity"), 0644))
+	groupPath := path.Join(tmpPath, gname)
+	os.Args = []string{"drand", "group", "--from-dir", idsPath, "--out", groupPath}
+	main()
+
+	group := new(key.Group)
+	require.NoError(t, key.Load(groupPath, group))
+	require.Equal(t, n, group.Len())
+	require.Equal(t, key.DefaultThreshold(n), group.Threshold)
+	for i := 0; i < n; i++ {
+		require.True(t, group.Contains(privs[i].Public))
+	}
+}
+
 func TestClientTLS(t *testing.T) {
 	tmpPath := path.Join(os.TempDir(), "drand")
 	os.Mkdir(tmpPath, 0777)
@@ -105,7 +197,7 @@ func TestClientTLS(t *testing.T) {
 
 	if httpscerts.Check(certPath, keyPath) != nil {
 		fmt.Println("generating on the fly")
-		if err := httpscerts.Generate(certPath, keyPath, priv.Public.Address()); err != nil {
+		if err := httpscerts.Generate(certPath, keyPath, test.Host(priv.Public.Address())); err != nil {
 			panic(err)
 		}
 	}
@@ -131,3 +223,264 @@ func TestClientTLS(t *testing.T) {
 	fmt.Println(string(out))
 	require.NoError(t, err)
 }
+
+func TestFetchTimeout(t *testing.T) {
+	// a node accepting connections but never answering
+	l, err := gonet.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		var conns []gonet.Conn
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				break
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	tmp, err := ioutil.TempDir("", "drand-timeout")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+	distPath := path.Join(tmp, "dist_key.public")
+	_, dist := bls.NewKeyPair(key.Pairing, random.New())
+	require.NoError(t, key.Save(distPath, &key.DistPublic{Key: dist}, false))
+
+	installCmd := exec.Command("go", "install")
+	_, err = installCmd.Output()
+	require.NoError(t, err)
+
+	start := time.Now()
+	cmd := exec.Command("drand", "fetch", "public", "--insecure", "--timeout", "500ms", "--distkey", distPath, l.Addr().String())
+	out, err := cmd.CombinedOutput()
+	require.Error(t, err)
+	require.Contains(t, string(out), "timed out contacting "+l.Addr().String())
+	require.True(t, time.Since(start) < 10*time.Second)
+}
+
+func TestParseSeed(t *testing.T) {
+	seed, err := parseSeed("hex:00ff42")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x00, 0xff, 0x42}, seed)
+	_, err = parseSeed("hex:zz")
+	require.Error(t, err)
+
+	tmp, err := ioutil.TempFile("", "drand-seed")
+	require.NoError(t, err)
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write([]byte{0x01, 0x00, 0x02})
+	require.NoError(t, err)
+	tmp.Close()
+	seed, err = parseSeed("@" + tmp.Name())
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x00, 0x02}, seed)
+
+	seed, err = parseSeed("genesis")
+	require.NoError(t, err)
+	require.Equal(t, []byte("genesis"), seed)
+	_, err = parseSeed("hex:")
+	require.Error(t, err)
+}
+
+func TestPrintRandomness(t *testing.T) {
+	resp := &drand.PublicRandResponse{Round: 2, Randomness: []byte{0x01, 0xab}}
+	capture := func(format string) string {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		stdout := os.Stdout
+		os.Stdout = w
+		printRandomness(format, resp, resp.GetRandomness())
+		os.Stdout = stdout
+		w.Close()
+		out, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		return string(out)
+	}
+	require.Equal(t, "01ab\n", capture("hex"))
+	require.Equal(t, "\x01\xab", capture("raw"))
+	require.Equal(t, "{\"round\":2,\"randomness\":\"Aas=\"}\n", capture("json-compact"))
+	require.Contains(t, capture("json"), "\n    \"round\": 2,\n")
+}
+
+func TestLoadStatus(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "drand-status")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+	conf := core.NewConfig(core.WithConfigFolder(tmp), core.WithDbFolder(path.Join(tmp, "db")))
+
+	st, err := loadStatus(conf)
+	require.NoError(t, err)
+	require.False(t, st.KeyPair)
+	require.False(t, st.DKGDone)
+	require.Zero(t, st.LastRound)
+
+	privs, group := test.BatchIdentities(4)
+	store := key.NewFileStore(tmp)
+	require.NoError(t, store.SaveKeyPair(privs[0]))
+	require.NoError(t, store.SaveGroup(group))
+	require.NoError(t, os.MkdirAll(conf.DBFolder(), 0700))
+	db, err := beacon.NewBoltStore(conf.DBFolder(), nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Put(&beacon.Beacon{Round: 7, Signature: []byte{0x01}}))
+	db.Close()
+
+	st, err = loadStatus(conf)
+	require.NoError(t, err)
+	require.True(t, st.KeyPair)
+	require.Equal(t, privs[0].Public.Address(), st.Address)
+	require.Equal(t, 4, st.GroupSize)
+	require.False(t, st.DKGDone)
+	require.Equal(t, uint64(7), st.LastRound)
+	require.False(t, st.Running)
+
+	// the database is locked while the node runs
+	db, err = beacon.NewBoltStore(conf.DBFolder(), nil)
+	require.NoError(t, err)
+	defer db.Close()
+	st, err = loadStatus(conf)
+	require.NoError(t, err)
+	require.True(t, st.Running)
+	require.Zero(t, st.LastRound)
+}
+
+func TestShareExportImport(t *testing.T) {
+	tmp := path.Join(os.TempDir(), "drand-share")
+	os.RemoveAll(tmp)
+	defer os.RemoveAll(tmp)
+	n, thr := 3, 2
+	pairs := test.GenerateIDs(n)
+	ids := make([]*key.Identity, n)
+	for i, p := range pairs {
+		ids[i] = p.Public
+	}
+	group := key.NewGroup(ids, thr)
+	idx, ok := group.Index(pairs[0].Public)
+	require.True(t, ok)
+	pri := share.NewPriPoly(key.G2, thr, key.G2.Scalar().Pick(random.New()), random.New())
+	_, commits := pri.Commit(key.G2.Point().Base()).Info()
+	s := &key.Share{Share: pri.Shares(n)[idx], Commits: commits}
+
+	store := key.NewFileStore(tmp)
+	require.NoError(t, store.SaveKeyPair(pairs[0]))
+	require.NoError(t, store.SaveGroup(group))
+	require.NoError(t, store.SaveDistPublic(s.Public()))
+	require.NoError(t, store.SaveShare(s))
+
+	os.Setenv(passphraseEnv, "correct horse")
+	defer os.Unsetenv(passphraseEnv)
+	backup := path.Join(tmp, "share.backup")
+	os.Args = []string{"drand", "--config", tmp, "share", "export", "--encrypt", "--out", backup}
+	main()
+	// the backup is encrypted
+	buff, err := ioutil.ReadFile(backup)
+	require.NoError(t, err)
+	require.Contains(t, string(buff), "Ciphertext")
+
+	require.NoError(t, store.SaveShare(&key.Share{Share: pri.Shares(n)[(idx+1)%n], Commits: commits}))
+	os.Args = []string{"drand", "--config", tmp, "share", "import", backup}
+	main()
+	restored, err := store.LoadShare()
+	require.NoError(t, err)
+	require.Equal(t, s.Share.I, restored.Share.I)
+	require.Equal(t, s.Share.V.String(), restored.Share.V.String())
+
+	// the distributed public key derived from the restored share is printed
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+		slog.Output = os.Stdout
+	}()
+	os.Args = []string{"drand", "--config", tmp, "show", "dist-public"}
+	main()
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	printed := new(key.DistPublic)
+	ptoml := printed.TOMLValue()
+	_, err = toml.Decode(string(out), ptoml)
+	require.NoError(t, err, "stdout: %s", out)
+	require.NoError(t, printed.FromTOML(ptoml))
+	require.True(t, printed.Key.Equal(s.Public().Key))
+}
+
+func TestSelfTest(t *testing.T) {
+	resp, err := selfTest(4)
+	require.NoError(t, err)
+	require.NotZero(t, resp.GetRound())
+	require.NotEmpty(t, resp.GetRandomness())
+
+	_, err = selfTest(2)
+	require.Error(t, err)
+}
+
+// streamClient serves each stream in turn, and fails once they are all used
+type streamClient struct {
+	net.ExternalClient
+	streams []*fakeStream
+}
+
+func (s *streamClient) PublicStream(p net.Peer, in *drand.PublicRandRequest) (drand.Randomness_PublicStreamClient, error) {
+	if len(s.streams) == 0 {
+		return nil, errors.New("unreachable")
+	}
+	stream := s.streams[0]
+	s.streams = s.streams[1:]
+	return stream, nil
+}
+
+// fakeStream sends its responses then drops
+type fakeStream struct {
+	grpc.ClientStream
+	resps []*drand.PublicRandResponse
+}
+
+func (f *fakeStream) Recv() (*drand.PublicRandResponse, error) {
+	if len(f.resps) == 0 {
+		return nil, io.EOF
+	}
+	resp := f.resps[0]
+	f.resps = f.resps[1:]
+	return resp, nil
+}
+
+func TestWatchPublic(t *testing.T) {
+	priv, pub := bls.NewKeyPair(key.Pairing, random.New())
+	signed := func(round uint64) *drand.PublicRandResponse {
+		sig, err := bls.Sign(key.Pairing, priv, beacon.Message([]byte("prev"), round))
+		require.NoError(t, err)
+		return &drand.PublicRandResponse{Round: round, Previous: []byte("prev"), Randomness: beacon.RandomnessFromSignature(sig), Signature: sig}
+	}
+	invalid := signed(4)
+	invalid.Signature, invalid.Randomness = signed(5).Signature, signed(5).Randomness
+	fake := &streamClient{streams: []*fakeStream{
+		{resps: []*drand.PublicRandResponse{signed(1), signed(2)}},
+		// the rounds already printed are skipped after reconnecting
+		{resps: []*drand.PublicRandResponse{signed(2), invalid, signed(3)}},
+	}}
+
+	var rounds []uint64
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		watchPublic(core.NewClient(fake), "127.0.0.1:8080", &key.DistPublic{Key: pub}, false, stop, func(resp *drand.PublicRandResponse) {
+			rounds = append(rounds, resp.GetRound())
+			if resp.GetRound() == 3 {
+				close(stop)
+			}
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not stop")
+	}
+	require.Equal(t, []uint64{1, 2, 3}, rounds)
+}
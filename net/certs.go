@@ -1,9 +1,13 @@
 package net
 
 import (
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"sync"
 
 	"github.com/nikkolasg/slog"
 )
@@ -11,8 +15,15 @@ import (
 // CertManager is used to managed certificates. It is most commonly used for
 // testing with self signed certificate. By default, it returns the bundled set
 // of certificates coming with the OS (Go's implementation).
+//
+// The certificate of a peer must also be valid for the host of its address,
+// or for the name set with SetServerName, so a trusted certificate of one
+// host cannot be used to impersonate another one.
 type CertManager struct {
 	pool *x509.CertPool
+	sync.Mutex
+	// server names overriding the host of the addresses
+	names map[string]string
 }
 
 func NewCertManager() *CertManager {
@@ -20,7 +31,7 @@ func NewCertManager() *CertManager {
 	if err != nil {
 		panic(err)
 	}
-	return &CertManager{pool}
+	return &CertManager{pool: pool, names: make(map[string]string)}
 }
 
 func (p *CertManager) Pool() *x509.CertPool {
@@ -38,3 +49,122 @@ func (p *CertManager) Add(certPath string) error {
 	slog.Info("peer cert: storing server certificate ", certPath)
 	return nil
 }
+
+// SetServerName sets the name the certificate of the peer at the given address
+// must be valid for, instead of the host of the address. It is useful when the
+// peer is reached through an address its certificate does not name, such as an
+// IP address or a tunnel.
+func (p *CertManager) SetServerName(addr, name string) {
+	p.Lock()
+	defer p.Unlock()
+	p.names[addr] = name
+}
+
+// ServerName returns the name the certificate of the peer at the given address
+// must be valid for: the name set with SetServerName if any, or else the host
+// of the address.
+func (p *CertManager) ServerName(addr string) string {
+	p.Lock()
+	name, ok := p.names[addr]
+	p.Unlock()
+	if ok {
+		return name
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// PeerServerName returns the name the certificate of the peer must be valid
+// for: the name set with SetServerName for its address if any, or else the
+// name the peer gives if it implements ServerNamer, or else the host of its
+// address.
+func (p *CertManager) PeerServerName(peer Peer) string {
+	p.Lock()
+	name, ok := p.names[peer.Address()]
+	p.Unlock()
+	if ok {
+		return name
+	}
+	if n, ok := peer.(ServerNamer); ok {
+		if name := n.ServerName(); name != "" {
+			return name
+		}
+	}
+	return p.ServerName(peer.Address())
+}
+
+// keyPair holds the certificate served by a TLS listener. The certificate can
+// be replaced while the listener runs: new connections are served with the new
+// certificate while the established ones are left untouched.
+type keyPair struct {
+	sync.RWMutex
+	cert *tls.Certificate
+	// passphrase of the private key, nil if it is not encrypted
+	passphrase []byte
+}
+
+func newKeyPair(certPath, keyPath string, passphrase []byte) (*keyPair, error) {
+	k := &keyPair{passphrase: passphrase}
+	return k, k.load(certPath, keyPath)
+}
+
+// load reads the certificate and private key from the given files. The
+// current certificate is kept if they cannot be loaded.
+func (k *keyPair) load(certPath, keyPath string) error {
+	cert, err := loadX509KeyPair(certPath, keyPath, k.passphrase)
+	if err != nil {
+		return err
+	}
+	k.Lock()
+	k.cert = &cert
+	k.Unlock()
+	return nil
+}
+
+// loadX509KeyPair reads the certificate and the private key from the given PEM
+// files as tls.LoadX509KeyPair does, decrypting the private key with the
+// passphrase if it is encrypted.
+func loadX509KeyPair(certPath, keyPath string, passphrase []byte) (tls.Certificate, error) {
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	switch {
+	case block == nil:
+		// reported by tls.X509KeyPair
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		return tls.Certificate{}, fmt.Errorf("net: TLS private key %s: encrypted PKCS#8 keys are not supported, encrypt it as a PEM block with a DEK-Info header instead", keyPath)
+	case x509.IsEncryptedPEMBlock(block):
+		if len(passphrase) == 0 {
+			return tls.Certificate{}, fmt.Errorf("net: TLS private key %s is encrypted but no passphrase was given", keyPath)
+		}
+		der, err := x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("net: could not decrypt TLS private key %s: %s", keyPath, err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// getCertificate is meant to be used as tls.Config.GetCertificate.
+func (k *keyPair) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.RLock()
+	defer k.RUnlock()
+	return k.cert, nil
+}
+
+// getClientCertificate is meant to be used as tls.Config.GetClientCertificate.
+func (k *keyPair) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	k.RLock()
+	defer k.RUnlock()
+	return k.cert, nil
+}
@@ -2,6 +2,8 @@ package net
 
 import (
 	"context"
+	"crypto/tls"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -10,7 +12,10 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/nikkolasg/slog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 // Service holds all functionalities that a drand node should implement
@@ -26,10 +31,16 @@ var defaultJSONMarshaller = &runtime.JSONBuiltin{}
 // using gRPC as its underlying mechanism
 type grpcClient struct {
 	sync.Mutex
-	conns   map[string]*grpc.ClientConn
+	conns   map[string]*pooledConn
 	opts    []grpc.DialOption
 	timeout time.Duration
 	manager *CertManager
+	// certificate presented to the peers asking for one, nil if none
+	cert *keyPair
+	// number of attempts of the calls to the public API and the backoff
+	// before the first retry
+	attempts int
+	backoff  time.Duration
 }
 
 // NewGrpcClient returns an implementation of an InternalClient  and
@@ -37,7 +48,7 @@ type grpcClient struct {
 func NewGrpcClient(opts ...grpc.DialOption) *grpcClient {
 	return &grpcClient{
 		opts:    opts,
-		conns:   make(map[string]*grpc.ClientConn),
+		conns:   make(map[string]*pooledConn),
 		timeout: DefaultTimeout,
 		manager: NewCertManager(),
 	}
@@ -55,32 +66,213 @@ func NewGrpcClientWithTimeout(timeout time.Duration, opts ...grpc.DialOption) *g
 	return c
 }
 
+// NewGrpcClientWithRetry returns a gRPC client that makes up to maxAttempts
+// attempts of the calls to the public API, retrying only those that fail with
+// the Unavailable or DeadlineExceeded codes, such as when the node is
+// restarting. It waits backoff before the first retry and doubles it for each
+// next retry, with some jitter. Each attempt has the deadline set with
+// SetTimeout, DefaultTimeout by default.
+func NewGrpcClientWithRetry(maxAttempts int, backoff time.Duration, opts ...grpc.DialOption) *grpcClient {
+	c := NewGrpcClient(opts...)
+	c.attempts = maxAttempts
+	c.backoff = backoff
+	return c
+}
+
 func (g *grpcClient) SetTimeout(t time.Duration) {
 	g.timeout = t
 }
 
-func (g *grpcClient) Public(p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+// SetClientCertificate makes the client present the certificate read from the
+// given files to the peers asking for one, such as the nodes using mutual TLS.
+// The private key is decrypted with the passphrase if it is encrypted. It
+// applies to the connections opened afterwards.
+func (g *grpcClient) SetClientCertificate(certPath, keyPath string, passphrase []byte) error {
+	pair, err := newKeyPair(certPath, keyPath, passphrase)
+	if err != nil {
+		return err
+	}
+	g.Lock()
+	defer g.Unlock()
+	g.cert = pair
+	return nil
+}
+
+// ReloadTLS replaces the certificate set with SetClientCertificate by the one
+// read from the given files. It does nothing if the client presents no
+// certificate.
+func (g *grpcClient) ReloadTLS(certPath, keyPath string) error {
+	g.Lock()
+	pair := g.cert
+	g.Unlock()
+	if pair == nil {
+		return nil
+	}
+	return pair.load(certPath, keyPath)
+}
+
+// retry runs the call until it succeeds, fails with an error that is not
+// retryable, the attempts are exhausted or the context is done. Without retry
+// policy, the call is made once with the given context.
+func (g *grpcClient) retry(ctx context.Context, call func(ctx context.Context) error) error {
+	if g.attempts <= 1 {
+		return call(ctx)
+	}
+	backoff := g.backoff
+	var err error
+	for i := 0; i < g.attempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(jitter(backoff)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, g.timeout)
+		err = call(attemptCtx)
+		cancel()
+		if !retryable(err) {
+			return err
+		}
+		slog.Debugf("grpc-client: attempt %d/%d failed: %s", i+1, g.attempts, err)
+	}
+	return err
+}
+
+// retryable returns true if the error is a transient network error.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// jitter returns a random duration between d/2 and d.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (g *grpcClient) Public(ctx context.Context, p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
 	c, err := g.conn(p)
 	if err != nil {
 		return nil, err
 	}
 	client := drand.NewRandomnessClient(c)
-	//ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
-	//defer cancel()
-	r, err := client.Public(context.Background(), in)
-	return r, err
+	var resp *drand.PublicRandResponse
+	err = g.retry(ctx, func(ctx context.Context) error {
+		resp, err = client.Public(ctx, in)
+		return err
+	})
+	return resp, err
 }
 
-func (g *grpcClient) Private(p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
+func (g *grpcClient) Private(ctx context.Context, p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
 	c, err := g.conn(p)
 	if err != nil {
 		return nil, err
 	}
 	client := drand.NewRandomnessClient(c)
-	//ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
-	//defer cancel()
-	return client.Private(context.Background(), in)
+	var resp *drand.PrivateRandResponse
+	err = g.retry(ctx, func(ctx context.Context) error {
+		resp, err = client.Private(ctx, in)
+		return err
+	})
+	return resp, err
+}
 
+func (g *grpcClient) DistKey(p Peer, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	var resp *drand.DistKeyResponse
+	err = g.retry(context.Background(), func(ctx context.Context) error {
+		resp, err = client.DistKey(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (g *grpcClient) PublicStream(p Peer, in *drand.PublicRandRequest) (drand.Randomness_PublicStreamClient, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	return client.PublicStream(context.Background(), in)
+}
+
+func (g *grpcClient) Home(p Peer, in *drand.HomeRequest) (*drand.HomeResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	var resp *drand.HomeResponse
+	err = g.retry(context.Background(), func(ctx context.Context) error {
+		resp, err = client.Home(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (g *grpcClient) Group(p Peer, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	var resp *drand.GroupResponse
+	err = g.retry(context.Background(), func(ctx context.Context) error {
+		resp, err = client.Group(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (g *grpcClient) ChainInfo(p Peer, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	var resp *drand.ChainInfoResponse
+	err = g.retry(context.Background(), func(ctx context.Context) error {
+		resp, err = client.ChainInfo(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (g *grpcClient) LocateRandomness(p Peer, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	var resp *drand.PublicRandResponse
+	err = g.retry(context.Background(), func(ctx context.Context) error {
+		resp, err = client.LocateRandomness(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (g *grpcClient) Sign(p Peer, in *drand.SignRequest) (*drand.SignResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewRandomnessClient(c)
+	// signing is not idempotent for the group, each call runs a round: it is
+	// not retried
+	return client.Sign(context.Background(), in)
 }
 
 func (g *grpcClient) Setup(p Peer, in *dkg.DKGPacket, opts ...CallOption) (*dkg.DKGResponse, error) {
@@ -107,34 +299,119 @@ func (g *grpcClient) NewBeacon(p Peer, in *drand.BeaconRequest, opts ...CallOpti
 	return client.NewBeacon(context.Background(), in, grpc.FailFast(true))
 }
 
-// conn retrieve an already existing conn to the given peer or create a new one
+func (g *grpcClient) SyncRound(p Peer, in *drand.SyncRequest, opts ...CallOption) (*drand.SyncResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewBeaconClient(c)
+	return client.SyncRound(context.Background(), in, opts...)
+}
+
+func (g *grpcClient) BeaconState(p Peer, in *drand.BeaconStateRequest, opts ...CallOption) (*drand.BeaconStateResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewBeaconClient(c)
+	return client.BeaconState(context.Background(), in, opts...)
+}
+
+func (g *grpcClient) PartialSign(p Peer, in *drand.PartialSignRequest, opts ...CallOption) (*drand.PartialSignResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := drand.NewBeaconClient(c)
+	return client.PartialSign(context.Background(), in, opts...)
+}
+
+func (g *grpcClient) DKGStatus(p Peer, in *dkg.DKGStatusRequest, opts ...CallOption) (*dkg.DKGStatusResponse, error) {
+	c, err := g.conn(p)
+	if err != nil {
+		return nil, err
+	}
+	client := dkg.NewDkgClient(c)
+	return client.DKGStatus(context.Background(), in, opts...)
+}
+
+// pooledConn is a connection to a peer kept open by the client, with the time
+// it was last used.
+type pooledConn struct {
+	*grpc.ClientConn
+	lastUsed time.Time
+}
+
+// conn retrieves the connection to the given peer, shared by all the calls to
+// this peer, or creates a new one. A connection that failed or has been shut
+// down is closed and replaced by a new one.
 func (g *grpcClient) conn(p Peer) (*grpc.ClientConn, error) {
 	g.Lock()
 	defer g.Unlock()
+	addr := p.Address()
+	if pc, ok := g.conns[addr]; ok {
+		state := pc.GetState()
+		if state != connectivity.TransientFailure && state != connectivity.Shutdown {
+			pc.lastUsed = time.Now()
+			return pc.ClientConn, nil
+		}
+		slog.Debugf("grpc-client: connection to %s in state %s, reconnecting", addr, state)
+		pc.Close()
+		delete(g.conns, addr)
+	}
+	slog.Debugf("grpc-client: attempting connection to %s (TLS %v)", addr, p.IsTLS())
+	var c *grpc.ClientConn
 	var err error
-	c, ok := g.conns[p.Address()]
-	if !ok {
-		slog.Debugf("grpc-client: attempting connection to %s (TLS %v)", p.Address(), p.IsTLS())
-		if !p.IsTLS() {
-			c, err = grpc.Dial(p.Address(), append(g.opts, grpc.WithInsecure())...)
-		} else {
-			pool := g.manager.Pool()
-			creds := credentials.NewClientTLSFromCert(pool, p.Address())
-			opts := append(g.opts, grpc.WithTransportCredentials(creds))
-			c, err = grpc.Dial(p.Address(), opts...)
+	if IsUnixAddress(addr) {
+		c, err = grpc.Dial(addr, append(g.opts, grpc.WithInsecure(), grpc.WithDialer(dialUnix))...)
+	} else if !p.IsTLS() {
+		c, err = grpc.Dial(addr, append(g.opts, grpc.WithInsecure())...)
+	} else {
+		pool := g.manager.Pool()
+		creds := credentials.NewClientTLSFromCert(pool, g.manager.PeerServerName(p))
+		if g.cert != nil {
+			creds = credentials.NewTLS(&tls.Config{
+				RootCAs:              pool,
+				ServerName:           g.manager.PeerServerName(p),
+				GetClientCertificate: g.cert.getClientCertificate,
+			})
 		}
-		g.conns[p.Address()] = c
+		opts := append(g.opts, grpc.WithTransportCredentials(creds))
+		c, err = grpc.Dial(addr, opts...)
 	}
-	return c, err
+	if err != nil {
+		return nil, err
+	}
+	g.conns[addr] = &pooledConn{ClientConn: c, lastUsed: time.Now()}
+	return c, nil
+}
+
+// CloseIdle closes the connections that have not been used for the given
+// duration, or all of them if it is zero, and returns how many have been
+// closed. Calls and streams still running on them are canceled. The next call
+// to a peer opens a new connection.
+func (g *grpcClient) CloseIdle(idle time.Duration) int {
+	g.Lock()
+	defer g.Unlock()
+	var closed int
+	for addr, pc := range g.conns {
+		if idle > 0 && time.Since(pc.lastUsed) < idle {
+			continue
+		}
+		pc.Close()
+		delete(g.conns, addr)
+		closed++
+	}
+	return closed
 }
 
 // proxyClient is used by the gRPC json gateway to dispatch calls to the
 // underlying gRPC server. It needs only to implement the public facing API
 type proxyClient struct {
-	s Service
+	s drand.RandomnessServer
 }
 
-func newProxyClient(s Service) *proxyClient {
+func newProxyClient(s drand.RandomnessServer) *proxyClient {
 	return &proxyClient{s}
 }
 
@@ -144,3 +421,24 @@ func (p *proxyClient) Public(c context.Context, in *drand.PublicRandRequest, opt
 func (p *proxyClient) Private(c context.Context, in *drand.PrivateRandRequest, opts ...grpc.CallOption) (*drand.PrivateRandResponse, error) {
 	return p.s.Private(c, in)
 }
+func (p *proxyClient) DistKey(c context.Context, in *drand.DistKeyRequest, opts ...grpc.CallOption) (*drand.DistKeyResponse, error) {
+	return p.s.DistKey(c, in)
+}
+func (p *proxyClient) PublicStream(c context.Context, in *drand.PublicRandRequest, opts ...grpc.CallOption) (drand.Randomness_PublicStreamClient, error) {
+	return nil, ErrStreamNotSupported
+}
+func (p *proxyClient) Home(c context.Context, in *drand.HomeRequest, opts ...grpc.CallOption) (*drand.HomeResponse, error) {
+	return p.s.Home(c, in)
+}
+func (p *proxyClient) Group(c context.Context, in *drand.GroupRequest, opts ...grpc.CallOption) (*drand.GroupResponse, error) {
+	return p.s.Group(c, in)
+}
+func (p *proxyClient) ChainInfo(c context.Context, in *drand.ChainInfoRequest, opts ...grpc.CallOption) (*drand.ChainInfoResponse, error) {
+	return p.s.ChainInfo(c, in)
+}
+func (p *proxyClient) LocateRandomness(c context.Context, in *drand.LocateRandomnessRequest, opts ...grpc.CallOption) (*drand.PublicRandResponse, error) {
+	return p.s.LocateRandomness(c, in)
+}
+func (p *proxyClient) Sign(c context.Context, in *drand.SignRequest, opts ...grpc.CallOption) (*drand.SignResponse, error) {
+	return p.s.Sign(c, in)
+}
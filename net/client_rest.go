@@ -2,12 +2,18 @@ package net
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dedis/drand/protobuf/drand"
 )
@@ -30,25 +36,29 @@ func NewRestClientFromCertManager(c *CertManager) ExternalClient {
 	return client
 }
 
-func (r *restClient) Public(p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+func (r *restClient) Public(ctx context.Context, p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
 	base := restAddr(p)
+	var query string
+	if in.GetCompressed() {
+		query = "?compressed=true"
+	}
 	var req *http.Request
 	var err error
 	if in.GetRound() == 0 {
 		// then simple GET method
-		req, err = http.NewRequest("GET", base+"/public", nil)
+		req, err = http.NewRequest("GET", base+"/public"+query, nil)
 	} else {
 		buff, err := r.marshaller.Marshal(in)
 		if err != nil {
 			return nil, err
 		}
-		url := fmt.Sprintf("%s/public/%d", base, in.GetRound())
+		url := fmt.Sprintf("%s/public/%d%s", base, in.GetRound(), query)
 		req, err = http.NewRequest("GET", url, bytes.NewBuffer(buff))
 	}
 	if err != nil {
 		return nil, err
 	}
-	respBody, err := r.doRequest(p, req)
+	respBody, err := r.doRequest(p, req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +66,7 @@ func (r *restClient) Public(p Peer, in *drand.PublicRandRequest) (*drand.PublicR
 	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
 }
 
-func (r *restClient) Private(p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
+func (r *restClient) Private(ctx context.Context, p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
 	base := restAddr(p)
 	buff, err := r.marshaller.Marshal(in)
 	if err != nil {
@@ -67,7 +77,7 @@ func (r *restClient) Private(p Peer, in *drand.PrivateRandRequest) (*drand.Priva
 	if err != nil {
 		return nil, err
 	}
-	respBody, err := r.doRequest(p, req)
+	respBody, err := r.doRequest(p, req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +86,97 @@ func (r *restClient) Private(p Peer, in *drand.PrivateRandRequest) (*drand.Priva
 
 }
 
+func (r *restClient) DistKey(p Peer, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	req, err := http.NewRequest("GET", restAddr(p)+"/info/distkey", nil)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.doRequest(p, req)
+	if err != nil {
+		return nil, err
+	}
+	drandResponse := new(drand.DistKeyResponse)
+	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
+}
+
+func (r *restClient) Home(p Peer, in *drand.HomeRequest) (*drand.HomeResponse, error) {
+	req, err := http.NewRequest("GET", restAddr(p)+"/home", nil)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.doRequest(p, req)
+	if err != nil {
+		return nil, err
+	}
+	drandResponse := new(drand.HomeResponse)
+	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
+}
+
+func (r *restClient) Group(p Peer, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	req, err := http.NewRequest("GET", restAddr(p)+"/info/group", nil)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.doRequest(p, req)
+	if err != nil {
+		return nil, err
+	}
+	drandResponse := new(drand.GroupResponse)
+	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
+}
+
+func (r *restClient) ChainInfo(p Peer, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	req, err := http.NewRequest("GET", restAddr(p)+"/info/chain", nil)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.doRequest(p, req)
+	if err != nil {
+		return nil, err
+	}
+	drandResponse := new(drand.ChainInfoResponse)
+	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
+}
+
+func (r *restClient) LocateRandomness(p Peer, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	buff, err := r.marshaller.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", restAddr(p)+"/public/locate", bytes.NewBuffer(buff))
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.doRequest(p, req)
+	if err != nil {
+		return nil, err
+	}
+	drandResponse := new(drand.PublicRandResponse)
+	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
+}
+
+func (r *restClient) Sign(p Peer, in *drand.SignRequest) (*drand.SignResponse, error) {
+	buff, err := r.marshaller.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", restAddr(p)+"/sign", bytes.NewBuffer(buff))
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.doRequest(p, req)
+	if err != nil {
+		return nil, err
+	}
+	drandResponse := new(drand.SignResponse)
+	return drandResponse, r.marshaller.Unmarshal(respBody, drandResponse)
+}
+
+// PublicStream is not supported by the REST API.
+func (r *restClient) PublicStream(p Peer, in *drand.PublicRandRequest) (drand.Randomness_PublicStreamClient, error) {
+	return nil, ErrStreamNotSupported
+}
+
 func (r *restClient) doRequest(remote Peer, req *http.Request) ([]byte, error) {
 	req.Header.Set("Content-Type", "application/json")
 	client := &http.Client{}
@@ -84,7 +185,7 @@ func (r *restClient) doRequest(remote Peer, req *http.Request) ([]byte, error) {
 	if remote.IsTLS() {
 		conf := &tls.Config{
 			RootCAs:    pool,
-			ServerName: remote.Address(),
+			ServerName: r.manager.PeerServerName(remote),
 		}
 		client.Transport = &http.Transport{TLSClientConfig: conf}
 	}
@@ -92,12 +193,40 @@ func (r *restClient) doRequest(remote Peer, req *http.Request) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ioutil.ReadAll(resp.Body)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, restError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// restError returns the error the REST API replied with, as the status error
+// the gRPC client returns for the same call: with the gRPC code and the
+// details of the error served by the gateway.
+func restError(statusCode int, body []byte) error {
+	var reply struct {
+		Error   string     `json:"error"`
+		Code    int32      `json:"code"`
+		Details []*any.Any `json:"details"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil || reply.Code == 0 {
+		return status.Errorf(codes.Unknown, "net: %s: %s", http.StatusText(statusCode), bytes.TrimSpace(body))
+	}
+	return status.ErrorProto(&spb.Status{
+		Code:    reply.Code,
+		Message: reply.Error,
+		Details: reply.Details,
+	})
 }
 
+// restAddr returns the base URL of the REST API of the peer.
 func restAddr(p Peer) string {
 	if p.IsTLS() {
-		return "https://" + p.Address()
+		return "https://" + p.Address() + APIPrefix
 	}
-	return "http://" + p.Address()
+	return "http://" + p.Address() + APIPrefix
 }
@@ -1,6 +1,8 @@
 package net
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"google.golang.org/grpc"
@@ -11,6 +13,10 @@ import (
 
 var DefaultTimeout = time.Duration(30) * time.Second
 
+// ErrStreamNotSupported is returned when a stream is requested from a client or
+// an API that does not support streaming, such as the REST API.
+var ErrStreamNotSupported = errors.New("net: streaming only supported over gRPC")
+
 // Gateway is the main interface to communicate to the drand world. It
 // acts as a listener to receive incoming requests and acts a client connecting
 // to drand particpants.
@@ -21,8 +27,23 @@ type Gateway struct {
 }
 
 type ExternalClient interface {
-	Public(p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error)
-	Private(p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error)
+	// Public and Private return when the context is done, with the context
+	// error.
+	Public(ctx context.Context, p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error)
+	Private(ctx context.Context, p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error)
+	DistKey(p Peer, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error)
+	// PublicStream returns a stream on which the peer sends each new beacon.
+	// It is only supported over gRPC.
+	PublicStream(p Peer, in *drand.PublicRandRequest) (drand.Randomness_PublicStreamClient, error)
+	Home(p Peer, in *drand.HomeRequest) (*drand.HomeResponse, error)
+	Group(p Peer, in *drand.GroupRequest) (*drand.GroupResponse, error)
+	ChainInfo(p Peer, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error)
+	// LocateRandomness returns the beacon of the peer whose randomness is the
+	// given one.
+	LocateRandomness(p Peer, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error)
+	// Sign returns the threshold signature of the group over the given
+	// message, if the peer accepts to sign it.
+	Sign(p Peer, in *drand.SignRequest) (*drand.SignResponse, error)
 }
 
 type CallOption = grpc.CallOption
@@ -33,6 +54,16 @@ type CallOption = grpc.CallOption
 type InternalClient interface {
 	NewBeacon(p Peer, in *drand.BeaconRequest, opts ...CallOption) (*drand.BeaconResponse, error)
 	Setup(p Peer, in *dkg.DKGPacket, opts ...CallOption) (*dkg.DKGResponse, error)
+	// SyncRound returns the beacon of a past round stored by the peer.
+	SyncRound(p Peer, in *drand.SyncRequest, opts ...CallOption) (*drand.SyncResponse, error)
+	// BeaconState returns the nodes whose partial signature the peer received
+	// for a recent round.
+	BeaconState(p Peer, in *drand.BeaconStateRequest, opts ...CallOption) (*drand.BeaconStateResponse, error)
+	// PartialSign sends the partial signature of this node over a message to
+	// sign and returns the one of the peer.
+	PartialSign(p Peer, in *drand.PartialSignRequest, opts ...CallOption) (*drand.PartialSignResponse, error)
+	// DKGStatus returns the progress of the DKG run by the peer.
+	DKGStatus(p Peer, in *dkg.DKGStatusRequest, opts ...CallOption) (*dkg.DKGStatusResponse, error)
 }
 
 // Listener is the active listener for incoming requests.
@@ -42,6 +73,58 @@ type Listener interface {
 	Stop()
 }
 
+// TLSReloader is implemented by listeners whose certificate can be replaced
+// without restarting them.
+type TLSReloader interface {
+	ReloadTLS(certPath, keyPath string) error
+}
+
+// Addresser is implemented by listeners bound to a network address, to find
+// out the address they actually listen on, such as the port chosen by the
+// system for an address with port zero.
+type Addresser interface {
+	Addr() string
+}
+
+// ErrNotTLS is returned when reloading the certificate of a listener that does
+// not use TLS.
+var ErrNotTLS = errors.New("net: listener does not use TLS")
+
+// ReloadTLS replaces the certificate served by the listener of the gateway by
+// the one read from the given files, without interrupting the established
+// connections, as well as the certificate its client presents to the other
+// nodes, if any. It returns ErrNotTLS if the listener does not use TLS.
+func (g Gateway) ReloadTLS(certPath, keyPath string) error {
+	r, ok := g.Listener.(TLSReloader)
+	if !ok {
+		return ErrNotTLS
+	}
+	if err := r.ReloadTLS(certPath, keyPath); err != nil {
+		return err
+	}
+	if c, ok := g.InternalClient.(TLSReloader); ok {
+		return c.ReloadTLS(certPath, keyPath)
+	}
+	return nil
+}
+
+// IdleCloser is implemented by clients keeping their connections to the peers
+// open to reuse them.
+type IdleCloser interface {
+	CloseIdle(idle time.Duration) int
+}
+
+// CloseIdle closes the connections of the client of the gateway that have not
+// been used for the given duration, or all of them if it is zero, and returns
+// how many have been closed. See IdleCloser.
+func (g Gateway) CloseIdle(idle time.Duration) int {
+	c, ok := g.InternalClient.(IdleCloser)
+	if !ok {
+		return 0
+	}
+	return c.CloseIdle(idle)
+}
+
 func NewGrpcGatewayInsecure(listen string, s Service, opts ...grpc.DialOption) Gateway {
 	return Gateway{
 		InternalClient: NewGrpcClient(opts...),
@@ -2,6 +2,18 @@ package net
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
 	"os"
 	"path"
 	"testing"
@@ -11,6 +23,10 @@ import (
 	"github.com/dedis/drand/protobuf/drand"
 	"github.com/kabukky/httpscerts"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
 )
 
 type testPeer struct {
@@ -37,12 +53,45 @@ func (t *testService) Public(context.Context, *drand.PublicRandRequest) (*drand.
 func (t *testService) Private(context.Context, *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
 	return &drand.PrivateRandResponse{}, nil
 }
+func (t *testService) DistKey(context.Context, *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	return &drand.DistKeyResponse{}, nil
+}
+func (t *testService) PublicStream(*drand.PublicRandRequest, drand.Randomness_PublicStreamServer) error {
+	return nil
+}
+func (t *testService) Home(context.Context, *drand.HomeRequest) (*drand.HomeResponse, error) {
+	return &drand.HomeResponse{}, nil
+}
+func (t *testService) Group(context.Context, *drand.GroupRequest) (*drand.GroupResponse, error) {
+	return &drand.GroupResponse{}, nil
+}
+func (t *testService) ChainInfo(context.Context, *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	return &drand.ChainInfoResponse{}, nil
+}
+func (t *testService) LocateRandomness(context.Context, *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	return &drand.PublicRandResponse{}, nil
+}
+func (t *testService) Sign(context.Context, *drand.SignRequest) (*drand.SignResponse, error) {
+	return &drand.SignResponse{}, nil
+}
 func (t *testService) Setup(c context.Context, in *dkg.DKGPacket) (*dkg.DKGResponse, error) {
 	return &dkg.DKGResponse{}, nil
 }
+func (t *testService) DKGStatus(context.Context, *dkg.DKGStatusRequest) (*dkg.DKGStatusResponse, error) {
+	return &dkg.DKGStatusResponse{}, nil
+}
 func (t *testService) NewBeacon(c context.Context, in *drand.BeaconRequest) (*drand.BeaconResponse, error) {
 	return &drand.BeaconResponse{}, nil
 }
+func (t *testService) SyncRound(c context.Context, in *drand.SyncRequest) (*drand.SyncResponse, error) {
+	return &drand.SyncResponse{}, nil
+}
+func (t *testService) BeaconState(c context.Context, in *drand.BeaconStateRequest) (*drand.BeaconStateResponse, error) {
+	return &drand.BeaconStateResponse{}, nil
+}
+func (t *testService) PartialSign(c context.Context, in *drand.PartialSignRequest) (*drand.PartialSignResponse, error) {
+	return &drand.PartialSignResponse{}, nil
+}
 
 func TestListener(t *testing.T) {
 	addr1 := "127.0.0.1:4000"
@@ -55,18 +104,54 @@ func TestListener(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	client := NewGrpcClient()
-	resp, err := client.Public(peer1, &drand.PublicRandRequest{})
+	resp, err := client.Public(context.Background(), peer1, &drand.PublicRandRequest{})
 	require.Nil(t, err)
 	expected := &drand.PublicRandResponse{Round: service1.round}
 	require.Equal(t, expected.GetRound(), resp.GetRound())
 
 	rest := NewRestClient()
-	resp, err = rest.Public(peer1, &drand.PublicRandRequest{})
+	resp, err = rest.Public(context.Background(), peer1, &drand.PublicRandRequest{})
 	require.NoError(t, err)
 	expected = &drand.PublicRandResponse{Round: service1.round}
 	require.Equal(t, expected.GetRound(), resp.GetRound())
 }
 
+func TestClientRetry(t *testing.T) {
+	addr := "127.0.0.1:4002"
+	peer := &testPeer{addr, false}
+	service := &testService{42}
+
+	// the node comes up while the client is retrying
+	lis := make(chan Listener, 1)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		l := NewTCPGrpcListener(addr, service)
+		lis <- l
+		l.Start()
+	}()
+	defer func() { (<-lis).Stop() }()
+
+	client := NewGrpcClientWithRetry(8, 100*time.Millisecond)
+	client.SetTimeout(time.Second)
+	resp, err := client.Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+
+	// the context deadline bounds all the attempts
+	down := &testPeer{"127.0.0.1:4003", false}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = NewGrpcClientWithRetry(10, time.Second).Public(ctx, down, &drand.PublicRandRequest{})
+	require.Error(t, err)
+	require.True(t, time.Since(start) < time.Second)
+
+	require.True(t, retryable(status.Error(codes.Unavailable, "restarting")))
+	require.True(t, retryable(status.Error(codes.DeadlineExceeded, "slow")))
+	require.False(t, retryable(status.Error(codes.InvalidArgument, "invalid")))
+	require.False(t, retryable(nil))
+}
+
 // ref https://bbengfort.github.io/programmer/2017/03/03/secure-grpc.html
 func TestListenerTLS(t *testing.T) {
 	addr1 := "127.0.0.1:4000"
@@ -78,7 +163,7 @@ func TestListenerTLS(t *testing.T) {
 	certPath := path.Join(tmpDir, "server.crt")
 	keyPath := path.Join(tmpDir, "server.key")
 	if httpscerts.Check(certPath, keyPath) != nil {
-		require.NoError(t, httpscerts.Generate(certPath, keyPath, addr1))
+		require.NoError(t, httpscerts.Generate(certPath, keyPath, "127.0.0.1"))
 	}
 
 	service1 := &testService{42}
@@ -94,14 +179,426 @@ func TestListenerTLS(t *testing.T) {
 	certManager.Add(certPath)
 
 	client := NewGrpcClientFromCertManager(certManager)
-	resp, err := client.Public(peer1, &drand.PublicRandRequest{})
+	resp, err := client.Public(context.Background(), peer1, &drand.PublicRandRequest{})
 	require.Nil(t, err)
 	expected := &drand.PublicRandResponse{Round: service1.round}
 	require.Equal(t, expected.GetRound(), resp.GetRound())
 
 	rest := NewRestClientFromCertManager(certManager)
-	resp, err = rest.Public(peer1, &drand.PublicRandRequest{})
+	resp, err = rest.Public(context.Background(), peer1, &drand.PublicRandRequest{})
 	require.NoError(t, err)
 	expected = &drand.PublicRandResponse{Round: service1.round}
 	require.Equal(t, expected.GetRound(), resp.GetRound())
 }
+
+func TestListenerTLSHostname(t *testing.T) {
+	addr := "127.0.0.1:4010"
+	peer := &testPeer{addr, true}
+	tmpDir := path.Join(os.TempDir(), "drand-net-hostname")
+	require.NoError(t, os.MkdirAll(tmpDir, 0766))
+	defer os.RemoveAll(tmpDir)
+	certPath := path.Join(tmpDir, "server.crt")
+	keyPath := path.Join(tmpDir, "server.key")
+	// a trusted certificate, but for another host
+	require.NoError(t, httpscerts.Generate(certPath, keyPath, "drand.example.org"))
+	lis, err := NewTLSGrpcListener(addr, certPath, keyPath, &testService{42})
+	require.NoError(t, err)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	m := NewCertManager()
+	require.NoError(t, m.Add(certPath))
+	require.Equal(t, "127.0.0.1", m.ServerName(addr))
+	_, err = NewGrpcClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.Error(t, err)
+	_, err = NewRestClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.Error(t, err)
+
+	// the name the certificate is valid for can be set explicitly
+	m.SetServerName(addr, "drand.example.org")
+	resp, err := NewGrpcClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), resp.GetRound())
+	_, err = NewRestClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+
+	// or be given by the peer
+	m = NewCertManager()
+	require.NoError(t, m.Add(certPath))
+	named := &namedPeer{peer, "drand.example.org"}
+	require.Equal(t, "drand.example.org", m.PeerServerName(named))
+	_, err = NewGrpcClientFromCertManager(m).Public(context.Background(), named, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	_, err = NewRestClientFromCertManager(m).Public(context.Background(), named, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+}
+
+type namedPeer struct {
+	*testPeer
+	name string
+}
+
+func (n *namedPeer) ServerName() string {
+	return n.name
+}
+
+func TestListenerReloadTLS(t *testing.T) {
+	addr := "127.0.0.1:4004"
+	peer := &testPeer{addr, true}
+
+	tmpDir := path.Join(os.TempDir(), "drand-net-reload")
+	require.NoError(t, os.MkdirAll(path.Join(tmpDir, "new"), 0766))
+	defer os.RemoveAll(tmpDir)
+	oldCert, oldKey := path.Join(tmpDir, "server.crt"), path.Join(tmpDir, "server.key")
+	newCert, newKey := path.Join(tmpDir, "new", "server.crt"), path.Join(tmpDir, "new", "server.key")
+	require.NoError(t, httpscerts.Generate(oldCert, oldKey, "127.0.0.1"))
+	require.NoError(t, httpscerts.Generate(newCert, newKey, "127.0.0.1"))
+
+	lis, err := NewTLSGrpcListener(addr, oldCert, oldKey, &testService{42})
+	require.NoError(t, err)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	trusting := func(certPath string) ExternalClient {
+		m := NewCertManager()
+		require.NoError(t, m.Add(certPath))
+		return NewRestClientFromCertManager(m)
+	}
+	_, err = trusting(oldCert).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+
+	gw := Gateway{Listener: lis}
+	require.Error(t, gw.ReloadTLS(path.Join(tmpDir, "missing.crt"), newKey))
+	require.NoError(t, gw.ReloadTLS(newCert, newKey))
+	_, err = trusting(oldCert).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.Error(t, err)
+	resp, err := trusting(newCert).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), resp.GetRound())
+
+	insecure := Gateway{Listener: NewTCPGrpcListener("127.0.0.1:4005", &testService{})}
+	require.Equal(t, ErrNotTLS, insecure.ReloadTLS(newCert, newKey))
+}
+
+func TestListenerEncryptedKey(t *testing.T) {
+	addr := "127.0.0.1:4011"
+	peer := &testPeer{addr, true}
+	tmpDir := path.Join(os.TempDir(), "drand-net-encrypted")
+	require.NoError(t, os.MkdirAll(tmpDir, 0766))
+	defer os.RemoveAll(tmpDir)
+	certPath, keyPath := path.Join(tmpDir, "server.crt"), path.Join(tmpDir, "server.key")
+	require.NoError(t, httpscerts.Generate(certPath, keyPath, "127.0.0.1"))
+
+	// encrypt the key with a passphrase
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	require.NoError(t, err)
+	block, _ := pem.Decode(keyPEM)
+	passphrase := []byte("correct horse")
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, passphrase, x509.PEMCipherAES256)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(encrypted), 0600))
+
+	_, err = NewTLSGrpcListener(addr, certPath, keyPath, &testService{42})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is encrypted but no passphrase was given")
+	_, err = NewTLSGrpcListenerWithPassphrase(addr, certPath, keyPath, []byte("wrong"), &testService{42}, AllAPIs)
+	require.Error(t, err)
+
+	lis, err := NewTLSGrpcListenerWithPassphrase(addr, certPath, keyPath, passphrase, &testService{42}, AllAPIs)
+	require.NoError(t, err)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+	m := NewCertManager()
+	require.NoError(t, m.Add(certPath))
+	resp, err := NewGrpcClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), resp.GetRound())
+	// the passphrase is kept to reload the key
+	require.NoError(t, Gateway{Listener: lis}.ReloadTLS(certPath, keyPath))
+}
+
+// generateClientCert writes a self-signed certificate for the host, usable
+// both by servers and by clients, and its key to the given files.
+func generateClientCert(t *testing.T, certPath, keyPath, host string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP(host)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+func TestListenerMutualTLS(t *testing.T) {
+	addr := "127.0.0.1:4012"
+	peer := &testPeer{addr, true}
+	tmpDir := path.Join(os.TempDir(), "drand-net-mtls")
+	require.NoError(t, os.MkdirAll(tmpDir, 0766))
+	defer os.RemoveAll(tmpDir)
+	memberCert, memberKey := path.Join(tmpDir, "member.crt"), path.Join(tmpDir, "member.key")
+	otherCert, otherKey := path.Join(tmpDir, "other.crt"), path.Join(tmpDir, "other.key")
+	generateClientCert(t, memberCert, memberKey, "127.0.0.1")
+	generateClientCert(t, otherCert, otherKey, "10.0.0.1")
+
+	certs := NewCertManager()
+	require.NoError(t, certs.Add(memberCert))
+	require.NoError(t, certs.Add(otherCert))
+	isMember := func(cert *x509.Certificate) error {
+		return cert.VerifyHostname("127.0.0.1")
+	}
+	conf := &TLSConfig{ClientCAs: certs.Pool()}
+	lis, err := NewTLSGrpcListenerWithConfig(addr, memberCert, memberKey, conf, &testService{42}, AllAPIs, grpc.UnaryInterceptor(NewMemberInterceptor(isMember)))
+	require.NoError(t, err)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// the public API needs no client certificate
+	anonymous := NewGrpcClientFromCertManager(certs)
+	resp, err := anonymous.Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), resp.GetRound())
+	_, err = anonymous.NewBeacon(peer, &drand.BeaconRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = anonymous.Setup(peer, &dkg.DKGPacket{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	other := NewGrpcClientFromCertManager(certs)
+	require.NoError(t, other.SetClientCertificate(otherCert, otherKey, nil))
+	_, err = other.NewBeacon(peer, &drand.BeaconRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	member := NewGrpcClientFromCertManager(certs)
+	require.NoError(t, member.SetClientCertificate(memberCert, memberKey, nil))
+	_, err = member.NewBeacon(peer, &drand.BeaconRequest{})
+	require.NoError(t, err)
+	_, err = member.Setup(peer, &dkg.DKGPacket{})
+	require.NoError(t, err)
+	// the client certificate is reloaded with the one of the listener
+	require.NoError(t, Gateway{Listener: lis, InternalClient: member}.ReloadTLS(memberCert, memberKey))
+}
+
+func TestListenerTLSVersion(t *testing.T) {
+	tmpDir := path.Join(os.TempDir(), "drand-net-version")
+	require.NoError(t, os.MkdirAll(tmpDir, 0766))
+	defer os.RemoveAll(tmpDir)
+	certPath, keyPath := path.Join(tmpDir, "server.crt"), path.Join(tmpDir, "server.key")
+	require.NoError(t, httpscerts.Generate(certPath, keyPath, "127.0.0.1"))
+	certs := NewCertManager()
+	require.NoError(t, certs.Add(certPath))
+	dial := func(addr string, version uint16) error {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: certs.Pool(), MinVersion: version, MaxVersion: version})
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	addr := "127.0.0.1:4013"
+	lis, err := NewTLSGrpcListenerWithConfig(addr, certPath, keyPath, &TLSConfig{}, &testService{42}, AllAPIs)
+	require.NoError(t, err)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+	require.Error(t, dial(addr, tls.VersionTLS11))
+	require.NoError(t, dial(addr, tls.VersionTLS12))
+
+	addr = "127.0.0.1:4014"
+	conf := &TLSConfig{MinVersion: tls.VersionTLS13}
+	lis13, err := NewTLSGrpcListenerWithConfig(addr, certPath, keyPath, conf, &testService{42}, AllAPIs)
+	require.NoError(t, err)
+	go lis13.Start()
+	defer lis13.Stop()
+	time.Sleep(100 * time.Millisecond)
+	require.Error(t, dial(addr, tls.VersionTLS12))
+	require.NoError(t, dial(addr, tls.VersionTLS13))
+	resp, err := NewGrpcClientFromCertManager(certs).Public(context.Background(), &testPeer{addr, true}, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), resp.GetRound())
+}
+
+func TestCheckListenAddress(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:4444", ":0", "localhost:80", "[::1]:4444", UnixScheme + "/tmp/drand.sock"} {
+		require.NoError(t, CheckListenAddress(addr), addr)
+	}
+	for _, addr := range []string{"", "127.0.0.1", "127.0.0.1:", "127.0.0.1:http", "127.0.0.1:70000", "::1:4444", UnixScheme} {
+		require.Error(t, CheckListenAddress(addr), addr)
+	}
+}
+
+func TestListenerEphemeralPort(t *testing.T) {
+	lis := NewTCPGrpcListener("127.0.0.1:0", &testService{42})
+	go lis.Start()
+	defer lis.Stop()
+	addr := lis.(Addresser).Addr()
+	require.NotEqual(t, "127.0.0.1:0", addr)
+	resp, err := NewGrpcClient().Public(context.Background(), &testPeer{addr, false}, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), resp.GetRound())
+}
+
+func TestListenerRESTAPI(t *testing.T) {
+	addr := "127.0.0.1:4006"
+	lis := NewTCPGrpcListener(addr, &testService{42})
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, p := range []string{"/api/public", "/api/public/42", "/public"} {
+		resp, err := http.Get("http://" + addr + p)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, p)
+		require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+		pub := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(body, &pub))
+		require.Equal(t, float64(42), pub["round"])
+	}
+
+	req, err := http.NewRequest(http.MethodOptions, "http://"+addr+"/api/private", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), "POST")
+}
+
+func TestClientConnPool(t *testing.T) {
+	addr := "127.0.0.1:4007"
+	peer := &testPeer{addr, false}
+	lis := NewTCPGrpcListener(addr, &testService{})
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewGrpcClient()
+	_, err := client.Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	c1, err := client.conn(peer)
+	require.NoError(t, err)
+	c2, err := client.conn(peer)
+	require.NoError(t, err)
+	require.True(t, c1 == c2, "connection not reused")
+
+	require.Equal(t, 0, client.CloseIdle(time.Hour))
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, 1, Gateway{InternalClient: client}.CloseIdle(5*time.Millisecond))
+	require.Equal(t, connectivity.Shutdown, c1.GetState())
+
+	// a connection shut down is replaced
+	c3, err := client.conn(peer)
+	require.NoError(t, err)
+	require.False(t, c3 == c1)
+	c3.Close()
+	c4, err := client.conn(peer)
+	require.NoError(t, err)
+	require.False(t, c4 == c3)
+	_, err = client.Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 1, client.CloseIdle(0))
+}
+
+func TestListenerIPv6(t *testing.T) {
+	addr := "[::1]:4008"
+	peer := &testPeer{addr, false}
+	service := &testService{42}
+	lis := NewTCPGrpcListener(addr, service)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := NewGrpcClient().Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+
+	resp, err = NewRestClient().Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+}
+
+func TestListenerIPv6TLS(t *testing.T) {
+	addr := "[::1]:4009"
+	peer := &testPeer{addr, true}
+	tmpDir := path.Join(os.TempDir(), "drand-net6")
+	require.NoError(t, os.MkdirAll(tmpDir, 0766))
+	defer os.RemoveAll(tmpDir)
+	certPath := path.Join(tmpDir, "server.crt")
+	keyPath := path.Join(tmpDir, "server.key")
+	require.NoError(t, httpscerts.Generate(certPath, keyPath, "::1"))
+	service := &testService{42}
+	lis, err := NewTLSGrpcListener(addr, certPath, keyPath, service)
+	require.NoError(t, err)
+	go lis.Start()
+	defer lis.Stop()
+	time.Sleep(100 * time.Millisecond)
+	m := NewCertManager()
+	m.Add(certPath)
+	resp, err := NewGrpcClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+	resp, err = NewRestClientFromCertManager(m).Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+}
+
+func TestMemoryNetwork(t *testing.T) {
+	network := NewMemoryNetwork()
+	service := &testService{42}
+	g := network.Gateway("node:4000", service)
+	peer := &testPeer{"node:4000", true}
+
+	client := network.Client()
+	resp, err := client.Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+	_, err = g.InternalClient.NewBeacon(peer, &drand.BeaconRequest{})
+	require.NoError(t, err)
+
+	_, err = client.Public(context.Background(), &testPeer{"other:4000", false}, &drand.PublicRandRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	g.Stop()
+	_, err = client.Public(context.Background(), peer, &drand.PublicRandRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestUnixSocket(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "drand-unix")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	addr := UnixScheme + path.Join(tmpDir, "drand.sock")
+	// a stale socket file is replaced
+	stale := NewTCPGrpcListener(addr, &testService{1})
+	stale.(*grpcInsecureListener).lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Stop()
+
+	service := &testService{42}
+	lis := NewTCPGrpcListener(addr, service)
+	go lis.Start()
+	defer lis.Stop()
+	fi, err := os.Stat(path.Join(tmpDir, "drand.sock"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// the TLS flag of the peer is ignored on Unix sockets
+	resp, err := NewGrpcClient().Public(context.Background(), &testPeer{addr, true}, &drand.PublicRandRequest{})
+	require.NoError(t, err)
+	require.Equal(t, service.round, resp.GetRound())
+}
@@ -0,0 +1,135 @@
+package net
+
+import (
+	"context"
+
+	"github.com/dedis/drand/protobuf/drand"
+	"google.golang.org/grpc"
+)
+
+// PublicInterceptor is implemented by the services which intercept the calls
+// to their public API, for example to limit their rate. The listeners serving
+// the public API run the unary interceptor on the unary calls, whether they
+// come over gRPC or through the REST gateway, and the stream interceptor on the
+// PublicStream calls. The interceptors are run only for the public API, and
+// besides the interceptors given as options of the listener.
+type PublicInterceptor interface {
+	PublicInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor)
+}
+
+// publicServer returns the server of the public API of the service, whose
+// calls go through its interceptors if it implements PublicInterceptor.
+func publicServer(s Service) drand.RandomnessServer {
+	i, ok := s.(PublicInterceptor)
+	if !ok {
+		return s
+	}
+	unary, stream := i.PublicInterceptors()
+	return &interceptedServer{RandomnessServer: s, unary: unary, stream: stream}
+}
+
+// interceptedServer runs the interceptors of the public API around the calls
+// to the service, as the gRPC server runs the interceptors of its options.
+type interceptedServer struct {
+	drand.RandomnessServer
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+}
+
+func (s *interceptedServer) intercept(c context.Context, method string, in interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	if s.unary == nil {
+		return handler(c, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: s.RandomnessServer, FullMethod: "/drand.Randomness/" + method}
+	return s.unary(c, in, info, handler)
+}
+
+func (s *interceptedServer) Public(c context.Context, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	resp, err := s.intercept(c, "Public", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.Public(c, in.(*drand.PublicRandRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PublicRandResponse), nil
+}
+
+func (s *interceptedServer) Private(c context.Context, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
+	resp, err := s.intercept(c, "Private", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.Private(c, in.(*drand.PrivateRandRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PrivateRandResponse), nil
+}
+
+func (s *interceptedServer) DistKey(c context.Context, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	resp, err := s.intercept(c, "DistKey", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.DistKey(c, in.(*drand.DistKeyRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.DistKeyResponse), nil
+}
+
+func (s *interceptedServer) PublicStream(in *drand.PublicRandRequest, stream drand.Randomness_PublicStreamServer) error {
+	if s.stream == nil {
+		return s.RandomnessServer.PublicStream(in, stream)
+	}
+	info := &grpc.StreamServerInfo{FullMethod: "/drand.Randomness/PublicStream", IsServerStream: true}
+	return s.stream(s.RandomnessServer, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return s.RandomnessServer.PublicStream(in, stream)
+	})
+}
+
+func (s *interceptedServer) Home(c context.Context, in *drand.HomeRequest) (*drand.HomeResponse, error) {
+	resp, err := s.intercept(c, "Home", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.Home(c, in.(*drand.HomeRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.HomeResponse), nil
+}
+
+func (s *interceptedServer) Group(c context.Context, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	resp, err := s.intercept(c, "Group", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.Group(c, in.(*drand.GroupRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.GroupResponse), nil
+}
+
+func (s *interceptedServer) ChainInfo(c context.Context, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	resp, err := s.intercept(c, "ChainInfo", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.ChainInfo(c, in.(*drand.ChainInfoRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.ChainInfoResponse), nil
+}
+
+func (s *interceptedServer) LocateRandomness(c context.Context, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	resp, err := s.intercept(c, "LocateRandomness", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.LocateRandomness(c, in.(*drand.LocateRandomnessRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PublicRandResponse), nil
+}
+
+func (s *interceptedServer) Sign(c context.Context, in *drand.SignRequest) (*drand.SignResponse, error) {
+	resp, err := s.intercept(c, "Sign", in, func(c context.Context, in interface{}) (interface{}, error) {
+		return s.RandomnessServer.Sign(c, in.(*drand.SignRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.SignResponse), nil
+}
@@ -3,8 +3,11 @@ package net
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/dedis/drand/protobuf/dkg"
@@ -14,8 +17,60 @@ import (
 	"github.com/soheilhy/cmux"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 )
 
+// API selects the services a listener serves.
+type API int
+
+const (
+	// PublicAPI is the Randomness service, over gRPC and its REST gateway.
+	PublicAPI API = 1 << iota
+	// InternalAPI is the Beacon and Dkg services used between drand nodes.
+	InternalAPI
+	// AllAPIs serves both the public and the internal services.
+	AllAPIs = PublicAPI | InternalAPI
+)
+
+// register registers the services selected by apis on the gRPC server, and
+// the REST gateway of the public API on gwMux. The public API goes through the
+// interceptors of the service, see PublicInterceptor, and the proxy of the
+// REST gateway must call public.
+func (apis API) register(grpcServer *grpc.Server, gwMux *runtime.ServeMux, s Service, public drand.RandomnessServer, proxy drand.RandomnessClient) error {
+	if apis&PublicAPI != 0 {
+		drand.RegisterRandomnessServer(grpcServer, public)
+		if err := drand.RegisterRandomnessHandlerClient(context.Background(), gwMux, proxy); err != nil {
+			return err
+		}
+	}
+	if apis&InternalAPI != 0 {
+		drand.RegisterBeaconServer(grpcServer, s)
+		dkg.RegisterDkgServer(grpcServer, s)
+	}
+	return nil
+}
+
+// CheckListenAddress returns an error if the address is not one a listener can
+// bind to: either the address of a Unix socket or a host:port address with a
+// numeric port. The host may be empty to listen on all the interfaces, and
+// the port zero to listen on a port chosen by the system.
+func CheckListenAddress(addr string) error {
+	if IsUnixAddress(addr) {
+		if strings.TrimPrefix(addr, UnixScheme) == "" {
+			return fmt.Errorf("net: listen address %q has no socket path", addr)
+		}
+		return nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("net: invalid listen address %q: %s", addr, err)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("net: invalid port in listen address %q", addr)
+	}
+	return nil
+}
+
 // grpcInsecureListener implements Listener using gRPC connections and regular HTTP
 // connections for the JSON REST API.
 // NOTE: This use cmux under the hood to be able to use non-tls connection. The
@@ -31,9 +86,15 @@ type grpcInsecureListener struct {
 
 // NewTCPGrpcListener returns a gRPC listener using plain TCP connections
 // without TLS. The listener will bind to the given address:port
-// tuple.
+// tuple, or to the Unix socket of an address starting with UnixScheme.
 func NewTCPGrpcListener(addr string, s Service, opts ...grpc.ServerOption) Listener {
-	l, err := net.Listen("tcp", addr)
+	return NewTCPGrpcListenerFor(addr, s, AllAPIs, opts...)
+}
+
+// NewTCPGrpcListenerFor returns a gRPC listener as NewTCPGrpcListener, serving
+// only the given APIs.
+func NewTCPGrpcListenerFor(addr string, s Service, apis API, opts ...grpc.ServerOption) Listener {
+	l, err := listen(addr)
 	if err != nil {
 		panic("tcp listener: " + err.Error())
 	}
@@ -44,21 +105,13 @@ func NewTCPGrpcListener(addr string, s Service, opts ...grpc.ServerOption) Liste
 	grpcServer := grpc.NewServer(opts...)
 
 	// REST api
-	gwMux := runtime.NewServeMux(runtime.WithMarshalerOption("application/json", defaultJSONMarshaller))
-	proxyClient := newProxyClient(s)
-	ctx := context.TODO()
-	if err := drand.RegisterRandomnessHandlerClient(ctx, gwMux, proxyClient); err != nil {
+	gwMux := newGatewayMux()
+	public := publicServer(s)
+	if err := apis.register(grpcServer, gwMux, s, public, newProxyClient(public)); err != nil {
 		panic(err)
 	}
-	restRouter := http.NewServeMux()
-	newHandler := func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		gwMux.ServeHTTP(w, r)
-	}
-
-	restRouter.Handle("/", http.HandlerFunc(newHandler))
 	restServer := &http.Server{
-		Handler: restRouter,
+		Handler: restHandler(gwMux),
 	}
 
 	g := &grpcInsecureListener{
@@ -68,9 +121,6 @@ func NewTCPGrpcListener(addr string, s Service, opts ...grpc.ServerOption) Liste
 		mux:        mux,
 		lis:        l,
 	}
-	drand.RegisterRandomnessServer(g.grpcServer, g.Service)
-	drand.RegisterBeaconServer(g.grpcServer, g.Service)
-	dkg.RegisterDkgServer(g.grpcServer, g.Service)
 	return g
 }
 
@@ -83,6 +133,11 @@ func (g *grpcInsecureListener) Start() {
 	g.mux.Serve()
 }
 
+// Addr returns the address the listener is bound to.
+func (g *grpcInsecureListener) Addr() string {
+	return listenerAddr(g.lis)
+}
+
 func (g *grpcInsecureListener) Stop() {
 	g.lis.Close()
 	g.restServer.Shutdown(context.Background())
@@ -94,45 +149,90 @@ type grpcTLSListener struct {
 	server     *http.Server
 	grpcServer *grpc.Server
 	// tls listener
-	l net.Listener
+	l    net.Listener
+	pair *keyPair
 }
 
 func NewTLSGrpcListener(bindingAddr string, certPath, keyPath string, s Service, opts ...grpc.ServerOption) (Listener, error) {
-	lis, err := net.Listen("tcp", bindingAddr)
-	if err != nil {
-		return nil, err
-	}
+	return NewTLSGrpcListenerFor(bindingAddr, certPath, keyPath, s, AllAPIs, opts...)
+}
+
+// NewTLSGrpcListenerFor returns a gRPC listener as NewTLSGrpcListener, serving
+// only the given APIs.
+func NewTLSGrpcListenerFor(bindingAddr string, certPath, keyPath string, s Service, apis API, opts ...grpc.ServerOption) (Listener, error) {
+	return NewTLSGrpcListenerWithPassphrase(bindingAddr, certPath, keyPath, nil, s, apis, opts...)
+}
+
+// NewTLSGrpcListenerWithPassphrase returns a gRPC listener as
+// NewTLSGrpcListenerFor, whose private key is a PEM block encrypted with the
+// given passphrase. The passphrase is kept to decrypt the keys loaded by
+// ReloadTLS. An unencrypted key is loaded as is, while an encrypted key
+// without passphrase is an error.
+func NewTLSGrpcListenerWithPassphrase(bindingAddr string, certPath, keyPath string, passphrase []byte, s Service, apis API, opts ...grpc.ServerOption) (Listener, error) {
+	return NewTLSGrpcListenerWithConfig(bindingAddr, certPath, keyPath, &TLSConfig{Passphrase: passphrase}, s, apis, opts...)
+}
+
+// TLSConfig holds the optional TLS settings of a listener.
+type TLSConfig struct {
+	// Passphrase decrypts the private key if it is an encrypted PEM block. It
+	// is kept to decrypt the keys loaded by ReloadTLS.
+	Passphrase []byte
+	// ClientCAs, if set, makes the listener ask the clients for a certificate
+	// and verify the ones presented against these authorities. Clients
+	// without certificate are still served: NewMemberInterceptor rejects
+	// them on the methods that need one.
+	ClientCAs *x509.CertPool
+	// MinVersion is the minimum TLS version accepted, DefaultMinTLSVersion if
+	// zero.
+	MinVersion uint16
+	// CipherSuites restricts the cipher suites of TLS 1.2 connections to the
+	// given ones, the defaults of crypto/tls if empty. The cipher suites of
+	// TLS 1.3 are not configurable.
+	CipherSuites []uint16
+}
 
-	x509KeyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+// DefaultMinTLSVersion is the minimum TLS version accepted by the listeners
+// whose TLSConfig does not set one.
+const DefaultMinTLSVersion = tls.VersionTLS12
+
+// NewTLSGrpcListenerWithConfig returns a gRPC listener as
+// NewTLSGrpcListenerFor, with the TLS settings of conf.
+func NewTLSGrpcListenerWithConfig(bindingAddr string, certPath, keyPath string, conf *TLSConfig, s Service, apis API, opts ...grpc.ServerOption) (Listener, error) {
+	lis, err := listen(bindingAddr)
 	if err != nil {
 		return nil, err
 	}
 
-	grpcCreds, err := credentials.NewServerTLSFromFile(certPath, keyPath)
+	pair, err := newKeyPair(certPath, keyPath, conf.Passphrase)
 	if err != nil {
+		lis.Close()
 		return nil, err
 	}
-	serverOpts := append(opts, grpc.Creds(grpcCreds))
-	grpcServer := grpc.NewServer(serverOpts...)
-	drand.RegisterRandomnessServer(grpcServer, s)
-	drand.RegisterBeaconServer(grpcServer, s)
-	dkg.RegisterDkgServer(grpcServer, s)
+	tlsConfig := &tls.Config{
+		GetCertificate: pair.getCertificate,
+		NextProtos:     []string{"h2"},
+		MinVersion:     conf.MinVersion,
+		CipherSuites:   conf.CipherSuites,
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = DefaultMinTLSVersion
+	}
+	if conf.ClientCAs != nil {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		tlsConfig.ClientCAs = conf.ClientCAs
+	}
 
-	gwMux := runtime.NewServeMux(runtime.WithMarshalerOption("application/json", defaultJSONMarshaller))
-	proxy := &drandProxy{s}
-	err = drand.RegisterRandomnessHandlerClient(context.Background(), gwMux, proxy)
-	if err != nil {
+	serverOpts := append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	grpcServer := grpc.NewServer(serverOpts...)
+	gwMux := newGatewayMux()
+	public := publicServer(s)
+	if err := apis.register(grpcServer, gwMux, s, public, &drandProxy{public}); err != nil {
 		return nil, err
 	}
 
-	mux := http.NewServeMux()
-	mux.Handle("/", gwMux)
 	server := &http.Server{
-		Handler: grpcHandlerFunc(grpcServer, mux),
-		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{x509KeyPair},
-			NextProtos:   []string{"h2"},
-		},
+		Handler:   grpcHandlerFunc(grpcServer, restHandler(gwMux)),
+		TLSConfig: tlsConfig,
 	}
 
 	tlsListener := tls.NewListener(lis, server.TLSConfig)
@@ -141,6 +241,7 @@ func NewTLSGrpcListener(bindingAddr string, certPath, keyPath string, s Service,
 		server:     server,
 		grpcServer: grpcServer,
 		l:          tlsListener,
+		pair:       pair,
 	}
 
 	return g, nil
@@ -152,6 +253,18 @@ func (g *grpcTLSListener) Start() {
 	}
 }
 
+// ReloadTLS replaces the certificate of the listener by the one read from the
+// given files. New connections use the new certificate while the established
+// ones keep the certificate they were opened with.
+func (g *grpcTLSListener) ReloadTLS(certPath, keyPath string) error {
+	return g.pair.load(certPath, keyPath)
+}
+
+// Addr returns the address the listener is bound to.
+func (g *grpcTLSListener) Addr() string {
+	return listenerAddr(g.l)
+}
+
 func (g *grpcTLSListener) Stop() {
 	// Graceful stop not supported with HTTP Server
 	// https://github.com/grpc/grpc-go/issues/1384
@@ -170,6 +283,66 @@ func (d *drandProxy) Public(c context.Context, r *drand.PublicRandRequest, opts
 func (d *drandProxy) Private(c context.Context, r *drand.PrivateRandRequest, opts ...grpc.CallOption) (*drand.PrivateRandResponse, error) {
 	return d.r.Private(c, r)
 }
+func (d *drandProxy) DistKey(c context.Context, r *drand.DistKeyRequest, opts ...grpc.CallOption) (*drand.DistKeyResponse, error) {
+	return d.r.DistKey(c, r)
+}
+func (d *drandProxy) PublicStream(c context.Context, r *drand.PublicRandRequest, opts ...grpc.CallOption) (drand.Randomness_PublicStreamClient, error) {
+	return nil, ErrStreamNotSupported
+}
+func (d *drandProxy) Home(c context.Context, r *drand.HomeRequest, opts ...grpc.CallOption) (*drand.HomeResponse, error) {
+	return d.r.Home(c, r)
+}
+func (d *drandProxy) Group(c context.Context, r *drand.GroupRequest, opts ...grpc.CallOption) (*drand.GroupResponse, error) {
+	return d.r.Group(c, r)
+}
+func (d *drandProxy) ChainInfo(c context.Context, r *drand.ChainInfoRequest, opts ...grpc.CallOption) (*drand.ChainInfoResponse, error) {
+	return d.r.ChainInfo(c, r)
+}
+func (d *drandProxy) LocateRandomness(c context.Context, r *drand.LocateRandomnessRequest, opts ...grpc.CallOption) (*drand.PublicRandResponse, error) {
+	return d.r.LocateRandomness(c, r)
+}
+func (d *drandProxy) Sign(c context.Context, r *drand.SignRequest, opts ...grpc.CallOption) (*drand.SignResponse, error) {
+	return d.r.Sign(c, r)
+}
+
+// APIPrefix is the prefix under which the REST API is served, e.g.
+// "/api/public" for the latest beacon. The REST API is also served without the
+// prefix for older clients.
+const APIPrefix = "/api"
+
+// newGatewayMux returns the mux of the REST gateway. The responses are encoded
+// with encoding/json whatever the content type requested, so browsers get the
+// same JSON as the REST client.
+func newGatewayMux() *runtime.ServeMux {
+	return runtime.NewServeMux(
+		runtime.WithMarshalerOption("application/json", defaultJSONMarshaller),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, defaultJSONMarshaller),
+	)
+}
+
+// restHandler serves the REST gateway both under APIPrefix and at the root,
+// with the CORS headers allowing browsers to call it from any origin.
+func restHandler(gwMux *runtime.ServeMux) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(APIPrefix+"/", http.StripPrefix(APIPrefix, gwMux))
+	mux.Handle("/", gwMux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", "*")
+		h.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		h.Set("Access-Control-Allow-Headers", "Content-Type")
+		if r.Method == http.MethodOptions {
+			// preflight request
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// let the service know the address of the client, as for gRPC calls
+		if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+			r = r.WithContext(peer.NewContext(r.Context(), &peer.Peer{Addr: addr}))
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
 
 // grpcHandlerFunc returns an http.Handler that delegates to grpcServer on incoming gRPC
 // connections or otherHandler otherwise. Copied from cockroachdb.
@@ -0,0 +1,279 @@
+package net
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dedis/drand/protobuf/dkg"
+	"github.com/dedis/drand/protobuf/drand"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MemoryNetwork routes the calls between nodes running in the same process,
+// without sockets, to test several nodes together quickly. Each node gets its
+// gateway from Gateway, and the calls to a peer are delivered to the service
+// registered at the address of the peer, whether the peer uses TLS or not.
+// Requests and responses are copied, as they would be on the wire.
+type MemoryNetwork struct {
+	sync.Mutex
+	services map[string]Service
+}
+
+// NewMemoryNetwork returns an empty in-memory network.
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{services: make(map[string]Service)}
+}
+
+// Gateway returns the gateway of a node serving the given service at the given
+// address on the network. The node is reachable as soon as the gateway is
+// returned and until its listener is stopped.
+func (n *MemoryNetwork) Gateway(listen string, s Service) Gateway {
+	n.Lock()
+	n.services[listen] = s
+	n.Unlock()
+	return Gateway{
+		Listener:       &memoryListener{Service: s, network: n, addr: listen},
+		InternalClient: &MemoryClient{network: n, addr: memoryAddr(listen)},
+	}
+}
+
+// Client returns a client calling the nodes of the network. It implements both
+// InternalClient and ExternalClient, except PublicStream.
+func (n *MemoryNetwork) Client() *MemoryClient {
+	return &MemoryClient{network: n, addr: memoryAddr("client")}
+}
+
+func (n *MemoryNetwork) service(p Peer) (Service, error) {
+	n.Lock()
+	defer n.Unlock()
+	s, ok := n.services[p.Address()]
+	if !ok {
+		return nil, status.Errorf(codes.Unavailable, "net: no node listening at %s", p.Address())
+	}
+	return s, nil
+}
+
+// memoryListener is the listener of a node on a MemoryNetwork.
+type memoryListener struct {
+	Service
+	network *MemoryNetwork
+	addr    string
+}
+
+func (l *memoryListener) Start() {}
+
+// Stop makes the node unreachable.
+func (l *memoryListener) Stop() {
+	l.network.Lock()
+	defer l.network.Unlock()
+	if l.network.services[l.addr] == l.Service {
+		delete(l.network.services, l.addr)
+	}
+}
+
+// MemoryClient calls the nodes of a MemoryNetwork.
+type MemoryClient struct {
+	network *MemoryNetwork
+	// address of the caller, given to the peer as the address of the remote
+	// end of the call
+	addr memoryAddr
+}
+
+// memoryAddr is the address of a node on a MemoryNetwork.
+type memoryAddr string
+
+func (a memoryAddr) Network() string { return "memory" }
+func (a memoryAddr) String() string  { return string(a) }
+
+// call runs the call on the service of the peer in its own goroutine, as a
+// remote node would, and returns a copy of the response, or the context error
+// if the context is done first.
+func (m *MemoryClient) call(ctx context.Context, p Peer, fn func(context.Context, Service) (proto.Message, error)) (proto.Message, error) {
+	s, err := m.network.service(p)
+	if err != nil {
+		return nil, err
+	}
+	type result struct {
+		resp proto.Message
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		resp, err := fn(peer.NewContext(ctx, &peer.Peer{Addr: m.addr}), s)
+		if err == nil {
+			resp = proto.Clone(resp)
+		}
+		ch <- result{resp, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.resp, r.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+		}
+		return nil, status.Error(codes.Canceled, ctx.Err().Error())
+	}
+}
+
+func (m *MemoryClient) Public(ctx context.Context, p Peer, in *drand.PublicRandRequest) (*drand.PublicRandResponse, error) {
+	in = proto.Clone(in).(*drand.PublicRandRequest)
+	resp, err := m.call(ctx, p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.Public(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PublicRandResponse), nil
+}
+
+func (m *MemoryClient) Private(ctx context.Context, p Peer, in *drand.PrivateRandRequest) (*drand.PrivateRandResponse, error) {
+	in = proto.Clone(in).(*drand.PrivateRandRequest)
+	resp, err := m.call(ctx, p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.Private(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PrivateRandResponse), nil
+}
+
+func (m *MemoryClient) DistKey(p Peer, in *drand.DistKeyRequest) (*drand.DistKeyResponse, error) {
+	in = proto.Clone(in).(*drand.DistKeyRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.DistKey(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.DistKeyResponse), nil
+}
+
+// PublicStream is not supported on an in-memory network.
+func (m *MemoryClient) PublicStream(p Peer, in *drand.PublicRandRequest) (drand.Randomness_PublicStreamClient, error) {
+	return nil, ErrStreamNotSupported
+}
+
+func (m *MemoryClient) Home(p Peer, in *drand.HomeRequest) (*drand.HomeResponse, error) {
+	in = proto.Clone(in).(*drand.HomeRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.Home(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.HomeResponse), nil
+}
+
+func (m *MemoryClient) Group(p Peer, in *drand.GroupRequest) (*drand.GroupResponse, error) {
+	in = proto.Clone(in).(*drand.GroupRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.Group(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.GroupResponse), nil
+}
+
+func (m *MemoryClient) ChainInfo(p Peer, in *drand.ChainInfoRequest) (*drand.ChainInfoResponse, error) {
+	in = proto.Clone(in).(*drand.ChainInfoRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.ChainInfo(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.ChainInfoResponse), nil
+}
+
+func (m *MemoryClient) LocateRandomness(p Peer, in *drand.LocateRandomnessRequest) (*drand.PublicRandResponse, error) {
+	in = proto.Clone(in).(*drand.LocateRandomnessRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.LocateRandomness(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PublicRandResponse), nil
+}
+
+func (m *MemoryClient) Sign(p Peer, in *drand.SignRequest) (*drand.SignResponse, error) {
+	in = proto.Clone(in).(*drand.SignRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.Sign(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.SignResponse), nil
+}
+
+func (m *MemoryClient) Setup(p Peer, in *dkg.DKGPacket, opts ...CallOption) (*dkg.DKGResponse, error) {
+	in = proto.Clone(in).(*dkg.DKGPacket)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.Setup(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*dkg.DKGResponse), nil
+}
+
+func (m *MemoryClient) NewBeacon(p Peer, in *drand.BeaconRequest, opts ...CallOption) (*drand.BeaconResponse, error) {
+	in = proto.Clone(in).(*drand.BeaconRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.NewBeacon(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.BeaconResponse), nil
+}
+
+func (m *MemoryClient) SyncRound(p Peer, in *drand.SyncRequest, opts ...CallOption) (*drand.SyncResponse, error) {
+	in = proto.Clone(in).(*drand.SyncRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.SyncRound(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.SyncResponse), nil
+}
+
+func (m *MemoryClient) BeaconState(p Peer, in *drand.BeaconStateRequest, opts ...CallOption) (*drand.BeaconStateResponse, error) {
+	in = proto.Clone(in).(*drand.BeaconStateRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.BeaconState(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.BeaconStateResponse), nil
+}
+
+func (m *MemoryClient) PartialSign(p Peer, in *drand.PartialSignRequest, opts ...CallOption) (*drand.PartialSignResponse, error) {
+	in = proto.Clone(in).(*drand.PartialSignRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.PartialSign(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*drand.PartialSignResponse), nil
+}
+
+func (m *MemoryClient) DKGStatus(p Peer, in *dkg.DKGStatusRequest, opts ...CallOption) (*dkg.DKGStatusResponse, error) {
+	in = proto.Clone(in).(*dkg.DKGStatusRequest)
+	resp, err := m.call(context.Background(), p, func(ctx context.Context, s Service) (proto.Message, error) {
+		return s.DKGStatus(ctx, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*dkg.DKGStatusResponse), nil
+}
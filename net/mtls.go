@@ -0,0 +1,55 @@
+package net
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// memberMethods are the gRPC methods through which the nodes of a group take
+// part in the DKG, in the beacon rounds and in the signing rounds.
+var memberMethods = map[string]bool{
+	"/dkg.Dkg/Setup":            true,
+	"/drand.Beacon/NewBeacon":   true,
+	"/drand.Beacon/PartialSign": true,
+}
+
+// NewMemberInterceptor returns a gRPC interceptor letting the calls to the DKG
+// Setup and beacon NewBeacon and PartialSign methods through only if the
+// client presented a certificate verified by the listener, see
+// TLSConfig.ClientCAs, and isMember accepts it. The calls to the other methods are not checked.
+func NewMemberInterceptor(isMember func(cert *x509.Certificate) error) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !memberMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		cert, err := clientCertificate(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err := isMember(cert); err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// clientCertificate returns the certificate the client of the call presented,
+// once verified by the TLS listener.
+func clientCertificate(ctx context.Context) (*x509.Certificate, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("net: unknown client")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, errors.New("net: no verified client certificate")
+	}
+	return info.State.VerifiedChains[0][0], nil
+}
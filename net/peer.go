@@ -26,3 +26,10 @@ type Peer interface {
 	Address() string
 	IsTLS() bool
 }
+
+// ServerNamer is implemented by the peers whose TLS certificate may be valid
+// for a name other than the host of their address, see
+// CertManager.PeerServerName.
+type ServerNamer interface {
+	ServerName() string
+}
@@ -0,0 +1,55 @@
+package net
+
+import (
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// UnixScheme prefixes the addresses of Unix domain sockets, as in
+// "unix:///var/run/drand.sock". The listeners and the gRPC clients accept such
+// addresses wherever they accept "host:port" ones. Connections over a Unix
+// socket never use TLS: the access to the socket is restricted by the
+// permissions of its file, only readable and writable by its owner.
+const UnixScheme = "unix://"
+
+// IsUnixAddress returns true if the address is the one of a Unix socket.
+func IsUnixAddress(addr string) bool {
+	return strings.HasPrefix(addr, UnixScheme)
+}
+
+// listen listens on the Unix socket or on the TCP address. The file of a Unix
+// socket left by a previous run is replaced.
+func listen(addr string) (net.Listener, error) {
+	if !IsUnixAddress(addr) {
+		return net.Listen("tcp", addr)
+	}
+	name := strings.TrimPrefix(addr, UnixScheme)
+	if fi, err := os.Stat(name); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(name)
+	}
+	l, err := net.Listen("unix", name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(name, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// listenerAddr returns the address the listener is bound to, with UnixScheme
+// for a Unix socket.
+func listenerAddr(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return UnixScheme + l.Addr().String()
+	}
+	return l.Addr().String()
+}
+
+// dialUnix is the gRPC dialer of the Unix socket addresses.
+func dialUnix(addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", strings.TrimPrefix(addr, UnixScheme), timeout)
+}
@@ -13,12 +13,16 @@ It has these top-level messages:
 	Deal
 	Response
 	Justification
+	Reshared
+	DKGStatusRequest
+	DKGStatusResponse
 */
 package dkg
 
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import element "github.com/dedis/drand/protobuf/crypto"
 import vss "github.com/dedis/drand/protobuf/crypto/share/vss"
 
 import (
@@ -43,6 +47,11 @@ type DKGPacket struct {
 	Deal          *Deal          `protobuf:"bytes,1,opt,name=deal" json:"deal,omitempty"`
 	Response      *Response      `protobuf:"bytes,2,opt,name=response" json:"response,omitempty"`
 	Justification *Justification `protobuf:"bytes,3,opt,name=justification" json:"justification,omitempty"`
+	// index in the group of the node sending the packet
+	Origin uint32 `protobuf:"varint,4,opt,name=origin" json:"origin,omitempty"`
+	// signature of the packet by the long-term key of the sender
+	Signature []byte    `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	Reshared  *Reshared `protobuf:"bytes,6,opt,name=reshared" json:"reshared,omitempty"`
 }
 
 func (m *DKGPacket) Reset()                    { *m = DKGPacket{} }
@@ -71,6 +80,27 @@ func (m *DKGPacket) GetJustification() *Justification {
 	return nil
 }
 
+func (m *DKGPacket) GetOrigin() uint32 {
+	if m != nil {
+		return m.Origin
+	}
+	return 0
+}
+
+func (m *DKGPacket) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *DKGPacket) GetReshared() *Reshared {
+	if m != nil {
+		return m.Reshared
+	}
+	return nil
+}
+
 type DKGResponse struct {
 }
 
@@ -162,12 +192,135 @@ func (m *Justification) GetJustification() *vss.Justification {
 	return nil
 }
 
+// Reshared holds the distributed public polynomial a node computed at the end
+// of a resharing, broadcast so that the nodes check they all agree on it before
+// using their new share.
+type Reshared struct {
+	// coefficients of the distributed public polynomial
+	Commits []*element.Point `protobuf:"bytes,1,rep,name=commits" json:"commits,omitempty"`
+}
+
+func (m *Reshared) Reset()                    { *m = Reshared{} }
+func (m *Reshared) String() string            { return proto.CompactTextString(m) }
+func (*Reshared) ProtoMessage()               {}
+func (*Reshared) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Reshared) GetCommits() []*element.Point {
+	if m != nil {
+		return m.Commits
+	}
+	return nil
+}
+
+type DKGStatusRequest struct {
+}
+
+func (m *DKGStatusRequest) Reset()                    { *m = DKGStatusRequest{} }
+func (m *DKGStatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*DKGStatusRequest) ProtoMessage()               {}
+func (*DKGStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+// DKGStatusResponse holds the progress of the DKG run by a node.
+type DKGStatusResponse struct {
+	// phase of the protocol: deal, response, justification, confirmation
+	// or finished
+	Phase string `protobuf:"bytes,1,opt,name=phase" json:"phase,omitempty"`
+	// percentage of the packets expected from the other nodes that have been
+	// processed
+	Progress uint32 `protobuf:"varint,2,opt,name=progress" json:"progress,omitempty"`
+	// number of deals processed, valid or not
+	Deals uint32 `protobuf:"varint,3,opt,name=deals" json:"deals,omitempty"`
+	// number of valid deals, including the deal of the node itself
+	ValidDeals uint32 `protobuf:"varint,4,opt,name=valid_deals,json=validDeals" json:"valid_deals,omitempty"`
+	// number of valid deals needed to finish the protocol
+	Needed uint32 `protobuf:"varint,5,opt,name=needed" json:"needed,omitempty"`
+	// number of responses processed
+	Responses uint32 `protobuf:"varint,6,opt,name=responses" json:"responses,omitempty"`
+	// number of dealers disqualified
+	Disqualified uint32 `protobuf:"varint,7,opt,name=disqualified" json:"disqualified,omitempty"`
+	// addresses of the nodes whose deal has not been received yet
+	Missing []string `protobuf:"bytes,8,rep,name=missing" json:"missing,omitempty"`
+	// error that aborted the protocol, if any
+	Error string `protobuf:"bytes,9,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *DKGStatusResponse) Reset()                    { *m = DKGStatusResponse{} }
+func (m *DKGStatusResponse) String() string            { return proto.CompactTextString(m) }
+func (*DKGStatusResponse) ProtoMessage()               {}
+func (*DKGStatusResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *DKGStatusResponse) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
+}
+
+func (m *DKGStatusResponse) GetProgress() uint32 {
+	if m != nil {
+		return m.Progress
+	}
+	return 0
+}
+
+func (m *DKGStatusResponse) GetDeals() uint32 {
+	if m != nil {
+		return m.Deals
+	}
+	return 0
+}
+
+func (m *DKGStatusResponse) GetValidDeals() uint32 {
+	if m != nil {
+		return m.ValidDeals
+	}
+	return 0
+}
+
+func (m *DKGStatusResponse) GetNeeded() uint32 {
+	if m != nil {
+		return m.Needed
+	}
+	return 0
+}
+
+func (m *DKGStatusResponse) GetResponses() uint32 {
+	if m != nil {
+		return m.Responses
+	}
+	return 0
+}
+
+func (m *DKGStatusResponse) GetDisqualified() uint32 {
+	if m != nil {
+		return m.Disqualified
+	}
+	return 0
+}
+
+func (m *DKGStatusResponse) GetMissing() []string {
+	if m != nil {
+		return m.Missing
+	}
+	return nil
+}
+
+func (m *DKGStatusResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*DKGPacket)(nil), "dkg.DKGPacket")
 	proto.RegisterType((*DKGResponse)(nil), "dkg.DKGResponse")
 	proto.RegisterType((*Deal)(nil), "dkg.Deal")
 	proto.RegisterType((*Response)(nil), "dkg.Response")
 	proto.RegisterType((*Justification)(nil), "dkg.Justification")
+	proto.RegisterType((*Reshared)(nil), "dkg.Reshared")
+	proto.RegisterType((*DKGStatusRequest)(nil), "dkg.DKGStatusRequest")
+	proto.RegisterType((*DKGStatusResponse)(nil), "dkg.DKGStatusResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -182,6 +335,9 @@ const _ = grpc.SupportPackageIsVersion4
 
 type DkgClient interface {
 	Setup(ctx context.Context, in *DKGPacket, opts ...grpc.CallOption) (*DKGResponse, error)
+	// DKGStatus returns the progress of the DKG run by the node, to find out
+	// which phase and which dealer a DKG that does not finish is stuck at.
+	DKGStatus(ctx context.Context, in *DKGStatusRequest, opts ...grpc.CallOption) (*DKGStatusResponse, error)
 }
 
 type dkgClient struct {
@@ -201,10 +357,22 @@ func (c *dkgClient) Setup(ctx context.Context, in *DKGPacket, opts ...grpc.CallO
 	return out, nil
 }
 
+func (c *dkgClient) DKGStatus(ctx context.Context, in *DKGStatusRequest, opts ...grpc.CallOption) (*DKGStatusResponse, error) {
+	out := new(DKGStatusResponse)
+	err := grpc.Invoke(ctx, "/dkg.Dkg/DKGStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Dkg service
 
 type DkgServer interface {
 	Setup(context.Context, *DKGPacket) (*DKGResponse, error)
+	// DKGStatus returns the progress of the DKG run by the node, to find out
+	// which phase and which dealer a DKG that does not finish is stuck at.
+	DKGStatus(context.Context, *DKGStatusRequest) (*DKGStatusResponse, error)
 }
 
 func RegisterDkgServer(s *grpc.Server, srv DkgServer) {
@@ -229,6 +397,24 @@ func _Dkg_Setup_Handler(srv interface{}, ctx context.Context, dec func(interface
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Dkg_DKGStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DKGStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DkgServer).DKGStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/dkg.Dkg/DKGStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DkgServer).DKGStatus(ctx, req.(*DKGStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Dkg_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "dkg.Dkg",
 	HandlerType: (*DkgServer)(nil),
@@ -237,6 +423,10 @@ var _Dkg_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Setup",
 			Handler:    _Dkg_Setup_Handler,
 		},
+		{
+			MethodName: "DKGStatus",
+			Handler:    _Dkg_DKGStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "dkg/dkg.proto",
@@ -245,24 +435,39 @@ var _Dkg_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("dkg/dkg.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 302 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0xc1, 0x4b, 0xc3, 0x30,
-	0x14, 0xc6, 0xe9, 0xba, 0xc9, 0xf6, 0x46, 0x44, 0x82, 0x87, 0x52, 0x10, 0xa4, 0xa2, 0xb8, 0x4b,
-	0x23, 0xf3, 0xe2, 0x59, 0x2a, 0x03, 0x7b, 0x91, 0x7a, 0xf3, 0x22, 0x69, 0x93, 0x75, 0xb1, 0xb3,
-	0x29, 0x4d, 0x2a, 0xfa, 0x57, 0xf8, 0x2f, 0x4b, 0xd2, 0x56, 0x37, 0xe6, 0x76, 0xc8, 0xe1, 0xbd,
-	0xf7, 0xf1, 0xe3, 0xfb, 0xde, 0x0b, 0x20, 0x56, 0xe4, 0x84, 0x15, 0x79, 0x58, 0xd5, 0x52, 0x4b,
-	0xec, 0xb2, 0x22, 0xf7, 0xfd, 0xac, 0xfe, 0xaa, 0xb4, 0x24, 0x6a, 0x45, 0x6b, 0x4e, 0x3e, 0x94,
-	0x32, 0xaf, 0x15, 0x04, 0xdf, 0x0e, 0x4c, 0xa2, 0x78, 0xf1, 0x44, 0xb3, 0x82, 0x6b, 0x7c, 0x06,
-	0x43, 0xc6, 0xe9, 0xda, 0x73, 0xce, 0x9d, 0xeb, 0xe9, 0x7c, 0x12, 0x1a, 0x50, 0xc4, 0xe9, 0x3a,
-	0xb1, 0x6d, 0x3c, 0x83, 0x71, 0xcd, 0x55, 0x25, 0x4b, 0xc5, 0xbd, 0x81, 0x95, 0x20, 0x2b, 0x49,
-	0xba, 0x66, 0xf2, 0x3b, 0xc6, 0x77, 0x80, 0xde, 0x1a, 0xa5, 0xc5, 0x52, 0x64, 0x54, 0x0b, 0x59,
-	0x7a, 0xae, 0xd5, 0x63, 0xab, 0x7f, 0xdc, 0x9c, 0x24, 0xdb, 0xc2, 0x00, 0xc1, 0x34, 0x8a, 0x17,
-	0x3d, 0x32, 0x88, 0x60, 0x68, 0x1c, 0xe0, 0x53, 0x18, 0x89, 0x92, 0xf1, 0x4f, 0xeb, 0x0d, 0x25,
-	0x6d, 0x81, 0xaf, 0x3a, 0xc3, 0x83, 0x8e, 0x6e, 0x82, 0x3d, 0x94, 0x36, 0x2f, 0x67, 0x7f, 0xce,
-	0x83, 0x18, 0xc6, 0x3d, 0x71, 0x0f, 0xe9, 0xbf, 0x6c, 0x86, 0xb6, 0x9b, 0x2d, 0x78, 0x05, 0xb4,
-	0x95, 0x60, 0x0f, 0x71, 0x67, 0x05, 0x9b, 0x26, 0x0f, 0xad, 0x60, 0x7e, 0x03, 0x6e, 0x54, 0xe4,
-	0x78, 0x06, 0xa3, 0x67, 0xae, 0x9b, 0x0a, 0x1f, 0xb7, 0x87, 0xe8, 0xcf, 0xe4, 0x9f, 0xf4, 0x75,
-	0x6f, 0xee, 0xfe, 0xf2, 0xe5, 0x22, 0x17, 0x7a, 0xd5, 0xa4, 0x61, 0x26, 0xdf, 0x09, 0xe3, 0x4c,
-	0x28, 0xc2, 0x6a, 0x5a, 0x32, 0x62, 0xcf, 0x9c, 0x36, 0x4b, 0xf3, 0x29, 0xd2, 0x23, 0x5b, 0xdd,
-	0xfe, 0x04, 0x00, 0x00, 0xff, 0xff, 0x49, 0x27, 0xfb, 0xd4, 0x26, 0x02, 0x00, 0x00,
+	// 539 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x94, 0x4d, 0x6f, 0xd3, 0x4e,
+	0x10, 0xc6, 0xe5, 0xbc, 0x7b, 0x52, 0x57, 0xfd, 0xaf, 0xfa, 0x8f, 0x2c, 0x0b, 0x44, 0x64, 0x04,
+	0x72, 0x2f, 0xb1, 0x14, 0x38, 0x20, 0x8e, 0xc8, 0x28, 0x12, 0xb9, 0x54, 0xdb, 0x1b, 0x97, 0xca,
+	0xc9, 0x4e, 0x9c, 0x25, 0x8e, 0xd7, 0xdd, 0x5d, 0x57, 0xf0, 0x05, 0xf8, 0xbc, 0x7c, 0x04, 0xb4,
+	0x6b, 0x3b, 0x2f, 0x94, 0x72, 0xc8, 0xe1, 0x79, 0x66, 0x3c, 0x7a, 0xe6, 0xa7, 0xd9, 0x80, 0xc7,
+	0x76, 0x59, 0xcc, 0x76, 0xd9, 0xac, 0x94, 0x42, 0x0b, 0xd2, 0x65, 0xbb, 0x2c, 0xb8, 0x5e, 0xcb,
+	0x1f, 0xa5, 0x16, 0x31, 0xe6, 0xb8, 0xc7, 0x42, 0xd7, 0xa5, 0x20, 0x68, 0x5c, 0xb5, 0x4d, 0x25,
+	0xc6, 0x8f, 0x4a, 0x99, 0x5f, 0x5d, 0x0b, 0x7f, 0x39, 0xe0, 0x26, 0xcb, 0xc5, 0x6d, 0xba, 0xde,
+	0xa1, 0x26, 0x2f, 0xa1, 0xc7, 0x30, 0xcd, 0x7d, 0x67, 0xea, 0x44, 0xe3, 0xb9, 0x3b, 0x33, 0xe3,
+	0x13, 0x4c, 0x73, 0x6a, 0x6d, 0x72, 0x03, 0x23, 0x89, 0xaa, 0x14, 0x85, 0x42, 0xbf, 0x63, 0x5b,
+	0x3c, 0xdb, 0x42, 0x1b, 0x93, 0x1e, 0xca, 0xe4, 0x03, 0x78, 0xdf, 0x2a, 0xa5, 0xf9, 0x86, 0xaf,
+	0x53, 0xcd, 0x45, 0xe1, 0x77, 0x6d, 0x3f, 0xb1, 0xfd, 0x5f, 0x4e, 0x2b, 0xf4, 0xbc, 0x91, 0x4c,
+	0x60, 0x20, 0x24, 0xcf, 0x78, 0xe1, 0xf7, 0xa6, 0x4e, 0xe4, 0xd1, 0x46, 0x91, 0x17, 0xe0, 0x2a,
+	0x9e, 0x15, 0xa9, 0xae, 0x24, 0xfa, 0xfd, 0xa9, 0x13, 0x5d, 0xd0, 0xa3, 0xd1, 0x44, 0x33, 0x0b,
+	0x32, 0x7f, 0x70, 0x1e, 0xcd, 0x9a, 0xf4, 0x50, 0x0e, 0x3d, 0x18, 0x27, 0xcb, 0x45, 0x9b, 0x39,
+	0x4c, 0xa0, 0x67, 0x56, 0x24, 0xd7, 0xd0, 0xe7, 0x05, 0xc3, 0xef, 0x76, 0x79, 0x8f, 0xd6, 0x82,
+	0xbc, 0x6d, 0x88, 0x74, 0x9a, 0xf8, 0x86, 0xdc, 0xe7, 0xc2, 0x02, 0x45, 0x76, 0x44, 0x13, 0x2e,
+	0x61, 0xd4, 0x4e, 0x7c, 0x66, 0xd2, 0xdf, 0xe0, 0x99, 0x69, 0x4f, 0xe1, 0x85, 0xf7, 0xe0, 0x9d,
+	0x21, 0x7a, 0x66, 0xe2, 0x13, 0xc6, 0xa7, 0x21, 0xff, 0xc5, 0x38, 0x7c, 0x6f, 0xd3, 0x5a, 0x1c,
+	0x24, 0x82, 0xe1, 0x5a, 0xec, 0xf7, 0x5c, 0x2b, 0xdf, 0x99, 0x76, 0xa3, 0xf1, 0xfc, 0x72, 0xd6,
+	0x9e, 0xcf, 0xad, 0xe0, 0x85, 0xa6, 0x6d, 0x39, 0x24, 0x70, 0x95, 0x2c, 0x17, 0x77, 0x3a, 0xd5,
+	0x95, 0xa2, 0xf8, 0x50, 0xa1, 0xd2, 0xe1, 0xcf, 0x0e, 0xfc, 0x77, 0x62, 0x1e, 0x09, 0x94, 0xdb,
+	0x54, 0xa1, 0xcd, 0xeb, 0xd2, 0x5a, 0x90, 0x00, 0x46, 0xa5, 0x14, 0x99, 0x44, 0xa5, 0x6c, 0x54,
+	0x8f, 0x1e, 0xb4, 0xf9, 0xc2, 0x70, 0x54, 0xf6, 0x4e, 0x3c, 0x5a, 0x0b, 0xf2, 0x0a, 0xc6, 0x8f,
+	0x69, 0xce, 0xd9, 0x7d, 0x5d, 0xab, 0x0f, 0x02, 0xac, 0x95, 0xd8, 0x86, 0x09, 0x0c, 0x0a, 0x44,
+	0x86, 0xcc, 0x5e, 0x84, 0x47, 0x1b, 0x65, 0x8e, 0xa5, 0xa5, 0xa9, 0xec, 0x3d, 0x78, 0xf4, 0x68,
+	0x90, 0x10, 0x2e, 0x18, 0x57, 0x0f, 0x55, 0x9a, 0xf3, 0x0d, 0x47, 0xe6, 0x0f, 0x6d, 0xc3, 0x99,
+	0x47, 0x7c, 0x18, 0xee, 0xb9, 0x52, 0xbc, 0xc8, 0xfc, 0xd1, 0xb4, 0x1b, 0xb9, 0xb4, 0x95, 0x26,
+	0x2a, 0x4a, 0x29, 0xa4, 0xef, 0xd6, 0xcb, 0x59, 0x31, 0xcf, 0xa1, 0x9b, 0xec, 0x32, 0x72, 0x03,
+	0xfd, 0x3b, 0xd4, 0x55, 0x49, 0x2e, 0xeb, 0xc7, 0xd3, 0x3e, 0xad, 0xe0, 0xaa, 0xd5, 0x07, 0x48,
+	0x1f, 0xc1, 0x3d, 0x90, 0x23, 0xff, 0xb7, 0xe5, 0x33, 0xbc, 0xc1, 0xe4, 0x4f, 0xbb, 0xfe, 0xf6,
+	0xd3, 0x9b, 0xaf, 0xaf, 0x33, 0xae, 0xb7, 0xd5, 0x6a, 0xb6, 0x16, 0xfb, 0x98, 0x21, 0xe3, 0x2a,
+	0x66, 0x32, 0x2d, 0x58, 0x6c, 0x9f, 0xf5, 0xaa, 0xda, 0x98, 0xbf, 0x86, 0xd5, 0xc0, 0xaa, 0x77,
+	0xbf, 0x07, 0x00, 0x14, 0x6c, 0xc6, 0x00, 0x2c, 0x04, 0x00, 0x00,
 }
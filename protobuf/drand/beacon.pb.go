@@ -11,6 +11,12 @@ It is generated from these files:
 It has these top-level messages:
 	BeaconRequest
 	BeaconResponse
+	SyncRequest
+	SyncResponse
+	BeaconStateRequest
+	BeaconStateResponse
+	PartialSignRequest
+	PartialSignResponse
 	PublicRandRequest
 	PublicRandResponse
 	PrivateRandRequest
@@ -90,9 +96,170 @@ func (m *BeaconResponse) GetPartialRand() []byte {
 	return nil
 }
 
+// SyncRequest asks for the beacon of the given round
+type SyncRequest struct {
+	Round uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+}
+
+func (m *SyncRequest) Reset()                    { *m = SyncRequest{} }
+func (m *SyncRequest) String() string            { return proto.CompactTextString(m) }
+func (*SyncRequest) ProtoMessage()               {}
+func (*SyncRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *SyncRequest) GetRound() uint64 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+// SyncResponse holds a full beacon as stored by a node
+type SyncResponse struct {
+	Round        uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+	PreviousRand []byte `protobuf:"bytes,2,opt,name=previous_rand,json=previousRand,proto3" json:"previous_rand,omitempty"`
+	// signature of the round, from which the randomness derives
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SyncResponse) Reset()                    { *m = SyncResponse{} }
+func (m *SyncResponse) String() string            { return proto.CompactTextString(m) }
+func (*SyncResponse) ProtoMessage()               {}
+func (*SyncResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *SyncResponse) GetRound() uint64 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *SyncResponse) GetPreviousRand() []byte {
+	if m != nil {
+		return m.PreviousRand
+	}
+	return nil
+}
+
+func (m *SyncResponse) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+// BeaconStateRequest asks for the state of the given round, or of the round in
+// progress if zero
+type BeaconStateRequest struct {
+	Round uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+}
+
+func (m *BeaconStateRequest) Reset()                    { *m = BeaconStateRequest{} }
+func (m *BeaconStateRequest) String() string            { return proto.CompactTextString(m) }
+func (*BeaconStateRequest) ProtoMessage()               {}
+func (*BeaconStateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *BeaconStateRequest) GetRound() uint64 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+// BeaconStateResponse holds the indexes in the group of the nodes whose valid
+// partial signature has been received for the round, including the node itself
+type BeaconStateResponse struct {
+	Round        uint64   `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+	Threshold    uint32   `protobuf:"varint,2,opt,name=threshold" json:"threshold,omitempty"`
+	Contributors []uint32 `protobuf:"varint,3,rep,packed,name=contributors" json:"contributors,omitempty"`
+	// done is true once the beacon of the round is reconstructed
+	Done bool `protobuf:"varint,4,opt,name=done" json:"done,omitempty"`
+}
+
+func (m *BeaconStateResponse) Reset()                    { *m = BeaconStateResponse{} }
+func (m *BeaconStateResponse) String() string            { return proto.CompactTextString(m) }
+func (*BeaconStateResponse) ProtoMessage()               {}
+func (*BeaconStateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *BeaconStateResponse) GetRound() uint64 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *BeaconStateResponse) GetThreshold() uint32 {
+	if m != nil {
+		return m.Threshold
+	}
+	return 0
+}
+
+func (m *BeaconStateResponse) GetContributors() []uint32 {
+	if m != nil {
+		return m.Contributors
+	}
+	return nil
+}
+
+func (m *BeaconStateResponse) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+// PartialSignRequest holds a message submitted for signing and the partial
+// signature of the requesting node over it, which proves it is a node of the
+// group
+type PartialSignRequest struct {
+	Message    []byte `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	PartialSig []byte `protobuf:"bytes,2,opt,name=partial_sig,json=partialSig,proto3" json:"partial_sig,omitempty"`
+}
+
+func (m *PartialSignRequest) Reset()                    { *m = PartialSignRequest{} }
+func (m *PartialSignRequest) String() string            { return proto.CompactTextString(m) }
+func (*PartialSignRequest) ProtoMessage()               {}
+func (*PartialSignRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *PartialSignRequest) GetMessage() []byte {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *PartialSignRequest) GetPartialSig() []byte {
+	if m != nil {
+		return m.PartialSig
+	}
+	return nil
+}
+
+type PartialSignResponse struct {
+	PartialSig []byte `protobuf:"bytes,1,opt,name=partial_sig,json=partialSig,proto3" json:"partial_sig,omitempty"`
+}
+
+func (m *PartialSignResponse) Reset()                    { *m = PartialSignResponse{} }
+func (m *PartialSignResponse) String() string            { return proto.CompactTextString(m) }
+func (*PartialSignResponse) ProtoMessage()               {}
+func (*PartialSignResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *PartialSignResponse) GetPartialSig() []byte {
+	if m != nil {
+		return m.PartialSig
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*BeaconRequest)(nil), "drand.BeaconRequest")
 	proto.RegisterType((*BeaconResponse)(nil), "drand.BeaconResponse")
+	proto.RegisterType((*SyncRequest)(nil), "drand.SyncRequest")
+	proto.RegisterType((*SyncResponse)(nil), "drand.SyncResponse")
+	proto.RegisterType((*BeaconStateRequest)(nil), "drand.BeaconStateRequest")
+	proto.RegisterType((*BeaconStateResponse)(nil), "drand.BeaconStateResponse")
+	proto.RegisterType((*PartialSignRequest)(nil), "drand.PartialSignRequest")
+	proto.RegisterType((*PartialSignResponse)(nil), "drand.PartialSignResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -107,6 +274,17 @@ const _ = grpc.SupportPackageIsVersion4
 
 type BeaconClient interface {
 	NewBeacon(ctx context.Context, in *BeaconRequest, opts ...grpc.CallOption) (*BeaconResponse, error)
+	// SyncRound returns the beacon of a past round, so a node restarting after
+	// some downtime can fetch the rounds it missed.
+	SyncRound(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// BeaconState returns the nodes whose valid partial signature has been
+	// received for a recent round, to find out which node is silent when a
+	// round does not reach the threshold.
+	BeaconState(ctx context.Context, in *BeaconStateRequest, opts ...grpc.CallOption) (*BeaconStateResponse, error)
+	// PartialSign returns the partial signature of the node over a message
+	// submitted to the Sign call of another node of the group. It is separate
+	// from the rounds of the beacon.
+	PartialSign(ctx context.Context, in *PartialSignRequest, opts ...grpc.CallOption) (*PartialSignResponse, error)
 }
 
 type beaconClient struct {
@@ -126,10 +304,48 @@ func (c *beaconClient) NewBeacon(ctx context.Context, in *BeaconRequest, opts ..
 	return out, nil
 }
 
+func (c *beaconClient) SyncRound(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	out := new(SyncResponse)
+	err := grpc.Invoke(ctx, "/drand.Beacon/SyncRound", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *beaconClient) BeaconState(ctx context.Context, in *BeaconStateRequest, opts ...grpc.CallOption) (*BeaconStateResponse, error) {
+	out := new(BeaconStateResponse)
+	err := grpc.Invoke(ctx, "/drand.Beacon/BeaconState", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *beaconClient) PartialSign(ctx context.Context, in *PartialSignRequest, opts ...grpc.CallOption) (*PartialSignResponse, error) {
+	out := new(PartialSignResponse)
+	err := grpc.Invoke(ctx, "/drand.Beacon/PartialSign", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Beacon service
 
 type BeaconServer interface {
 	NewBeacon(context.Context, *BeaconRequest) (*BeaconResponse, error)
+	// SyncRound returns the beacon of a past round, so a node restarting after
+	// some downtime can fetch the rounds it missed.
+	SyncRound(context.Context, *SyncRequest) (*SyncResponse, error)
+	// BeaconState returns the nodes whose valid partial signature has been
+	// received for a recent round, to find out which node is silent when a
+	// round does not reach the threshold.
+	BeaconState(context.Context, *BeaconStateRequest) (*BeaconStateResponse, error)
+	// PartialSign returns the partial signature of the node over a message
+	// submitted to the Sign call of another node of the group. It is separate
+	// from the rounds of the beacon.
+	PartialSign(context.Context, *PartialSignRequest) (*PartialSignResponse, error)
 }
 
 func RegisterBeaconServer(s *grpc.Server, srv BeaconServer) {
@@ -154,6 +370,60 @@ func _Beacon_NewBeacon_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Beacon_SyncRound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BeaconServer).SyncRound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Beacon/SyncRound",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BeaconServer).SyncRound(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Beacon_BeaconState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeaconStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BeaconServer).BeaconState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Beacon/BeaconState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BeaconServer).BeaconState(ctx, req.(*BeaconStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Beacon_PartialSign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PartialSignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BeaconServer).PartialSign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Beacon/PartialSign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BeaconServer).PartialSign(ctx, req.(*PartialSignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Beacon_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "drand.Beacon",
 	HandlerType: (*BeaconServer)(nil),
@@ -162,6 +432,18 @@ var _Beacon_serviceDesc = grpc.ServiceDesc{
 			MethodName: "NewBeacon",
 			Handler:    _Beacon_NewBeacon_Handler,
 		},
+		{
+			MethodName: "SyncRound",
+			Handler:    _Beacon_SyncRound_Handler,
+		},
+		{
+			MethodName: "BeaconState",
+			Handler:    _Beacon_BeaconState_Handler,
+		},
+		{
+			MethodName: "PartialSign",
+			Handler:    _Beacon_PartialSign_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "drand/beacon.proto",
@@ -170,19 +452,31 @@ var _Beacon_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("drand/beacon.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 209 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x90, 0x3d, 0x4f, 0xc5, 0x20,
-	0x18, 0x85, 0x83, 0x7a, 0x6f, 0xe2, 0x6b, 0xeb, 0x40, 0x6a, 0xd2, 0x38, 0xd5, 0x1a, 0x63, 0x27,
-	0x9a, 0xd8, 0xc5, 0xb9, 0x3f, 0xc0, 0x81, 0xd1, 0xc5, 0x40, 0x41, 0x25, 0xb1, 0x80, 0x7c, 0xe8,
-	0xdf, 0x37, 0x85, 0x76, 0xf0, 0x76, 0x3c, 0x4f, 0xce, 0x93, 0x17, 0x0e, 0x60, 0xe1, 0x98, 0x16,
-	0x3d, 0x97, 0x6c, 0x32, 0x9a, 0x58, 0x67, 0x82, 0xc1, 0x87, 0xc4, 0xda, 0x19, 0xca, 0x31, 0x61,
-	0x2a, 0xbf, 0xa3, 0xf4, 0x01, 0x57, 0x70, 0x70, 0x26, 0x6a, 0x51, 0xa3, 0x06, 0x75, 0x17, 0x34,
-	0x07, 0x7c, 0x0f, 0xa5, 0x75, 0xf2, 0x47, 0x99, 0xe8, 0xdf, 0x16, 0xaf, 0x3e, 0x6b, 0x50, 0x57,
-	0xd0, 0x62, 0x83, 0x94, 0x69, 0x81, 0xef, 0xa0, 0xb0, 0xcc, 0x05, 0xc5, 0xbe, 0x72, 0xe7, 0x3c,
-	0x75, 0xae, 0x56, 0xb6, 0x54, 0xda, 0x01, 0xae, 0xb7, 0x73, 0xde, 0x1a, 0xed, 0xe5, 0x4e, 0x42,
-	0x3b, 0xe9, 0x69, 0x84, 0x63, 0x96, 0xf0, 0x33, 0x5c, 0xbe, 0xc8, 0xdf, 0x35, 0x54, 0x24, 0x7d,
-	0x81, 0xfc, 0x7b, 0xff, 0xed, 0xcd, 0x09, 0xcd, 0x67, 0xc6, 0xc7, 0xd7, 0x87, 0x0f, 0x15, 0x3e,
-	0x23, 0x27, 0x93, 0x99, 0x7b, 0x21, 0x85, 0xf2, 0x7d, 0x5e, 0x25, 0xcd, 0xc1, 0xe3, 0x7b, 0x8e,
-	0xfc, 0x98, 0xf2, 0xf0, 0x17, 0x00, 0x00, 0xff, 0xff, 0x28, 0xb0, 0x62, 0xbb, 0x34, 0x01, 0x00,
-	0x00,
+	// 403 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x53, 0xcf, 0x0b, 0xd3, 0x30,
+	0x14, 0xa6, 0xfb, 0xa5, 0x7d, 0x6b, 0x3d, 0x64, 0x13, 0x6a, 0x19, 0x58, 0x3b, 0xc4, 0xe2, 0xa1,
+	0x03, 0x27, 0xe2, 0x79, 0x78, 0x56, 0xe9, 0x6e, 0x5e, 0x46, 0xda, 0xc6, 0x2e, 0xb0, 0x25, 0x35,
+	0x49, 0x15, 0x8f, 0x82, 0x7f, 0xb8, 0x2c, 0x49, 0xb7, 0x76, 0x1b, 0xbb, 0x78, 0xeb, 0xfb, 0xfa,
+	0x7d, 0x5f, 0xde, 0xfb, 0x5e, 0x02, 0xa8, 0x14, 0x98, 0x95, 0xab, 0x9c, 0xe0, 0x82, 0xb3, 0xb4,
+	0x16, 0x5c, 0x71, 0x34, 0xd6, 0x58, 0x7c, 0x04, 0x7f, 0xa3, 0xe1, 0x8c, 0xfc, 0x68, 0x88, 0x54,
+	0x68, 0x0e, 0x63, 0xc1, 0x1b, 0x56, 0x06, 0x4e, 0xe4, 0x24, 0xa3, 0xcc, 0x14, 0x68, 0x09, 0x7e,
+	0x2d, 0xc8, 0x4f, 0xca, 0x1b, 0xb9, 0x3b, 0xe9, 0x82, 0x41, 0xe4, 0x24, 0x5e, 0xe6, 0xb5, 0x60,
+	0x86, 0x59, 0x89, 0x5e, 0x81, 0x57, 0x63, 0xa1, 0x28, 0x3e, 0x18, 0xce, 0x50, 0x73, 0xa6, 0x16,
+	0x3b, 0x51, 0xe2, 0x35, 0x3c, 0x6b, 0x8f, 0x93, 0x35, 0x67, 0x92, 0xdc, 0x88, 0x9c, 0x5b, 0xd1,
+	0x12, 0xa6, 0xdb, 0xdf, 0xac, 0x78, 0xd8, 0x61, 0x5c, 0x81, 0x67, 0x48, 0xd6, 0xf7, 0x3f, 0xe6,
+	0x58, 0x80, 0x2b, 0x69, 0xc5, 0xb0, 0x6a, 0x04, 0xb1, 0x43, 0x5c, 0x80, 0xf8, 0x2d, 0x20, 0x33,
+	0xc2, 0x56, 0x61, 0x45, 0x1e, 0x37, 0xf5, 0xc7, 0x81, 0x59, 0x8f, 0xfc, 0xb0, 0xb9, 0x05, 0xb8,
+	0x6a, 0x2f, 0x88, 0xdc, 0xf3, 0x83, 0x69, 0xcc, 0xcf, 0x2e, 0x00, 0x8a, 0xc1, 0x2b, 0x38, 0x53,
+	0x82, 0xe6, 0x8d, 0xe2, 0x42, 0x06, 0xc3, 0x68, 0x98, 0xf8, 0x59, 0x0f, 0x43, 0x08, 0x46, 0x25,
+	0x67, 0x24, 0x18, 0x45, 0x4e, 0xf2, 0x34, 0xd3, 0xdf, 0xf1, 0x17, 0x40, 0x5f, 0x4d, 0x98, 0x5b,
+	0x5a, 0x9d, 0xd7, 0x1c, 0xc0, 0x93, 0x23, 0x91, 0x12, 0x57, 0xc4, 0x26, 0xde, 0x96, 0xe8, 0x25,
+	0xb4, 0xe1, 0xef, 0x24, 0xad, 0x6c, 0x40, 0x50, 0x9f, 0x2d, 0xe2, 0x0f, 0x30, 0xeb, 0x19, 0xda,
+	0x99, 0xae, 0x74, 0xce, 0xb5, 0xee, 0xdd, 0xdf, 0x01, 0x4c, 0x4c, 0x18, 0xe8, 0x23, 0xb8, 0x9f,
+	0xc9, 0x2f, 0x5b, 0xcc, 0x53, 0x7d, 0x15, 0xd3, 0xde, 0x3d, 0x0c, 0x9f, 0x5f, 0xa1, 0xf6, 0x94,
+	0xf7, 0xe0, 0xea, 0x35, 0xeb, 0xc0, 0x90, 0xe5, 0x74, 0x6e, 0x47, 0x38, 0xeb, 0x61, 0x56, 0xf5,
+	0x09, 0xa6, 0x9d, 0x35, 0xa0, 0x17, 0x3d, 0xef, 0xee, 0x1e, 0xc3, 0xf0, 0xde, 0xaf, 0x8b, 0x4b,
+	0x67, 0xf0, 0xb3, 0xcb, 0x6d, 0xba, 0x61, 0x78, 0xef, 0x97, 0x71, 0xd9, 0xbc, 0xf9, 0xf6, 0xba,
+	0xa2, 0x6a, 0xdf, 0xe4, 0x69, 0xc1, 0x8f, 0xab, 0x92, 0x94, 0x54, 0xae, 0xcc, 0xfb, 0xd4, 0x0f,
+	0x33, 0x6f, 0xbe, 0x9b, 0x32, 0x9f, 0xe8, 0x7a, 0xfd, 0x6f, 0x00, 0x97, 0x86, 0xb8, 0x99, 0xbe,
+	0x03, 0x00, 0x00,
 }
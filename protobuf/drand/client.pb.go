@@ -26,6 +26,14 @@ type PublicRandRequest struct {
 	// contain the last.
 	// XXX better ways to do that...
 	Round uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+	// nonce is an optional challenge chosen by the client. If set, the node
+	// echoes it in the response together with an attestation proving the
+	// response is fresh.
+	Nonce []byte `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// compressed asks for the randomness in the compressed encoding of its
+	// point, about half the size. It is decompressed to verify the beacon and
+	// to chain it with the next one.
+	Compressed bool `protobuf:"varint,3,opt,name=compressed" json:"compressed,omitempty"`
 }
 
 func (m *PublicRandRequest) Reset()                    { *m = PublicRandRequest{} }
@@ -40,14 +48,43 @@ func (m *PublicRandRequest) GetRound() uint64 {
 	return 0
 }
 
+func (m *PublicRandRequest) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *PublicRandRequest) GetCompressed() bool {
+	if m != nil {
+		return m.Compressed
+	}
+	return false
+}
+
 // PublicRandResponse holds a signature which is the random value. It can be
 // verified thanks to the distributed public key of the nodes that have ran the
 // DKG protocol and is unbiasable. The randomness can be verified using the BLS
 // verification routine with the message "round || previous_rand".
 type PublicRandResponse struct {
-	Round      uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
-	Previous   []byte `protobuf:"bytes,2,opt,name=previous,proto3" json:"previous,omitempty"`
+	Round uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+	// previous is the signature of the previous round.
+	Previous []byte `protobuf:"bytes,2,opt,name=previous,proto3" json:"previous,omitempty"`
+	// randomness is the SHA-256 hash of the signature. Nodes that predate the
+	// signature field set the signature itself instead.
 	Randomness []byte `protobuf:"bytes,3,opt,name=randomness,proto3" json:"randomness,omitempty"`
+	// nonce is the nonce given in the request, if any.
+	Nonce []byte `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// timestamp is the unix time at which the node answered, set only if a
+	// nonce has been given.
+	Timestamp uint64 `protobuf:"varint,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	// attestation is the BLS signature made with the long-term key of the node
+	// over "sha256(nonce) || timestamp || round || randomness", set only if a
+	// nonce has been given.
+	Attestation []byte `protobuf:"bytes,6,opt,name=attestation,proto3" json:"attestation,omitempty"`
+	// signature is the BLS signature of the distributed key over
+	// "round || previous", compressed if requested.
+	Signature []byte `protobuf:"bytes,7,opt,name=signature,proto3" json:"signature,omitempty"`
 }
 
 func (m *PublicRandResponse) Reset()                    { *m = PublicRandResponse{} }
@@ -76,12 +113,50 @@ func (m *PublicRandResponse) GetRandomness() []byte {
 	return nil
 }
 
+func (m *PublicRandResponse) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *PublicRandResponse) GetTimestamp() uint64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *PublicRandResponse) GetAttestation() []byte {
+	if m != nil {
+		return m.Attestation
+	}
+	return nil
+}
+
+func (m *PublicRandResponse) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 // PrivateRandRequest is the message to send when requesting a private random
 // value.
 type PrivateRandRequest struct {
 	// Request must contains a public key towards which to encrypt the private
 	// randomness.
 	Request *ECIESObject `protobuf:"bytes,1,opt,name=request" json:"request,omitempty"`
+	// count is the number of 32-byte random values requested, all encrypted
+	// together in the response. Zero means one value.
+	Count uint32 `protobuf:"varint,2,opt,name=count" json:"count,omitempty"`
+	// hash is the name of the hash function used by ECIES to encrypt the
+	// request and the response, among the ones supported by the server.
+	// Empty means "sha256".
+	Hash string `protobuf:"bytes,3,opt,name=hash" json:"hash,omitempty"`
+	// length is the number of random bytes requested, at most 1024, instead
+	// of count values. Zero means count is used.
+	Length uint32 `protobuf:"varint,4,opt,name=length" json:"length,omitempty"`
 }
 
 func (m *PrivateRandRequest) Reset()                    { *m = PrivateRandRequest{} }
@@ -96,6 +171,27 @@ func (m *PrivateRandRequest) GetRequest() *ECIESObject {
 	return nil
 }
 
+func (m *PrivateRandRequest) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *PrivateRandRequest) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *PrivateRandRequest) GetLength() uint32 {
+	if m != nil {
+		return m.Length
+	}
+	return 0
+}
+
 type PrivateRandResponse struct {
 	// Response contains the private randomness encrypted towards the client's
 	// request key.
@@ -146,12 +242,345 @@ func (m *ECIESObject) GetNonce() []byte {
 	return nil
 }
 
+type DistKeyRequest struct {
+}
+
+func (m *DistKeyRequest) Reset()                    { *m = DistKeyRequest{} }
+func (m *DistKeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*DistKeyRequest) ProtoMessage()               {}
+func (*DistKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+
+// DistKeyResponse holds the commitments to the coefficients of the secret
+// polynomial shared by the nodes during the DKG. The first one is the
+// distributed public key. The public key share of the node of index i is the
+// evaluation of this public polynomial at i+1, and verifies the partial
+// signatures of that node.
+type DistKeyResponse struct {
+	Key []*element.Point `protobuf:"bytes,1,rep,name=key" json:"key,omitempty"`
+}
+
+func (m *DistKeyResponse) Reset()                    { *m = DistKeyResponse{} }
+func (m *DistKeyResponse) String() string            { return proto.CompactTextString(m) }
+func (*DistKeyResponse) ProtoMessage()               {}
+func (*DistKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
+
+func (m *DistKeyResponse) GetKey() []*element.Point {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type HomeRequest struct {
+}
+
+func (m *HomeRequest) Reset()                    { *m = HomeRequest{} }
+func (m *HomeRequest) String() string            { return proto.CompactTextString(m) }
+func (*HomeRequest) ProtoMessage()               {}
+func (*HomeRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+// HomeResponse holds the status of a node. A node is ready once it can serve
+// randomness, i.e. once the DKG is done and the beacon is set up.
+type HomeResponse struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	DkgDone bool   `protobuf:"varint,2,opt,name=dkg_done,json=dkgDone" json:"dkg_done,omitempty"`
+	Ready   bool   `protobuf:"varint,3,opt,name=ready" json:"ready,omitempty"`
+	// round is the last round generated by the node, zero if none
+	Round uint64 `protobuf:"varint,4,opt,name=round" json:"round,omitempty"`
+	// number of rounds produced by the node that reached the threshold of
+	// partial signatures since it started
+	RoundsMeasured uint64 `protobuf:"varint,5,opt,name=rounds_measured,json=roundsMeasured" json:"rounds_measured,omitempty"`
+	// exponential moving average of the time the rounds took to reach the
+	// threshold, in milliseconds
+	ThresholdLatencyEma uint64 `protobuf:"varint,6,opt,name=threshold_latency_ema,json=thresholdLatencyEma" json:"threshold_latency_ema,omitempty"`
+	// maximum time the recent rounds took to reach the threshold, in
+	// milliseconds
+	ThresholdLatencyMax uint64 `protobuf:"varint,7,opt,name=threshold_latency_max,json=thresholdLatencyMax" json:"threshold_latency_max,omitempty"`
+}
+
+func (m *HomeResponse) Reset()                    { *m = HomeResponse{} }
+func (m *HomeResponse) String() string            { return proto.CompactTextString(m) }
+func (*HomeResponse) ProtoMessage()               {}
+func (*HomeResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{8} }
+
+func (m *HomeResponse) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *HomeResponse) GetDkgDone() bool {
+	if m != nil {
+		return m.DkgDone
+	}
+	return false
+}
+
+func (m *HomeResponse) GetReady() bool {
+	if m != nil {
+		return m.Ready
+	}
+	return false
+}
+
+func (m *HomeResponse) GetRound() uint64 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *HomeResponse) GetRoundsMeasured() uint64 {
+	if m != nil {
+		return m.RoundsMeasured
+	}
+	return 0
+}
+
+func (m *HomeResponse) GetThresholdLatencyEma() uint64 {
+	if m != nil {
+		return m.ThresholdLatencyEma
+	}
+	return 0
+}
+
+func (m *HomeResponse) GetThresholdLatencyMax() uint64 {
+	if m != nil {
+		return m.ThresholdLatencyMax
+	}
+	return 0
+}
+
+type GroupRequest struct {
+}
+
+func (m *GroupRequest) Reset()                    { *m = GroupRequest{} }
+func (m *GroupRequest) String() string            { return proto.CompactTextString(m) }
+func (*GroupRequest) ProtoMessage()               {}
+func (*GroupRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{9} }
+
+// GroupNode is the public identity of a member of the group
+type GroupNode struct {
+	Address string         `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Key     *element.Point `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Tls     bool           `protobuf:"varint,3,opt,name=tls" json:"tls,omitempty"`
+	Index   uint32         `protobuf:"varint,4,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *GroupNode) Reset()                    { *m = GroupNode{} }
+func (m *GroupNode) String() string            { return proto.CompactTextString(m) }
+func (*GroupNode) ProtoMessage()               {}
+func (*GroupNode) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{10} }
+
+func (m *GroupNode) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *GroupNode) GetKey() *element.Point {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *GroupNode) GetTls() bool {
+	if m != nil {
+		return m.Tls
+	}
+	return false
+}
+
+func (m *GroupNode) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+// GroupResponse holds the members of the group, its threshold and the
+// distributed public key generated by the DKG.
+type GroupResponse struct {
+	Nodes     []*GroupNode   `protobuf:"bytes,1,rep,name=nodes" json:"nodes,omitempty"`
+	Threshold uint32         `protobuf:"varint,2,opt,name=threshold" json:"threshold,omitempty"`
+	DistKey   *element.Point `protobuf:"bytes,3,opt,name=dist_key,json=distKey" json:"dist_key,omitempty"`
+}
+
+func (m *GroupResponse) Reset()                    { *m = GroupResponse{} }
+func (m *GroupResponse) String() string            { return proto.CompactTextString(m) }
+func (*GroupResponse) ProtoMessage()               {}
+func (*GroupResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{11} }
+
+func (m *GroupResponse) GetNodes() []*GroupNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *GroupResponse) GetThreshold() uint32 {
+	if m != nil {
+		return m.Threshold
+	}
+	return 0
+}
+
+func (m *GroupResponse) GetDistKey() *element.Point {
+	if m != nil {
+		return m.DistKey
+	}
+	return nil
+}
+
+type ChainInfoRequest struct {
+}
+
+func (m *ChainInfoRequest) Reset()                    { *m = ChainInfoRequest{} }
+func (m *ChainInfoRequest) String() string            { return proto.CompactTextString(m) }
+func (*ChainInfoRequest) ProtoMessage()               {}
+func (*ChainInfoRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{12} }
+
+// ChainInfoResponse holds the parameters of the chain and its current head.
+type ChainInfoResponse struct {
+	// round is the last round generated by the node, zero if none
+	Round uint64 `protobuf:"varint,1,opt,name=round" json:"round,omitempty"`
+	// period is the time between two rounds, in milliseconds
+	Period uint64 `protobuf:"varint,2,opt,name=period" json:"period,omitempty"`
+	// genesis_time is the unix time in milliseconds at which the first round
+	// is, or was, expected, zero if the rounds are not aligned
+	GenesisTime int64 `protobuf:"varint,3,opt,name=genesis_time,json=genesisTime" json:"genesis_time,omitempty"`
+	// aligned is true if the rounds are aligned on the genesis time, i.e. if
+	// round N starts at genesis_time + (N-1) * period. Otherwise the rounds
+	// only start roughly every period after the first one.
+	Aligned bool `protobuf:"varint,4,opt,name=aligned" json:"aligned,omitempty"`
+	// seed is the message signed at the first round
+	Seed []byte `protobuf:"bytes,5,opt,name=seed,proto3" json:"seed,omitempty"`
+	// dist_key is the distributed public key verifying the beacons
+	DistKey *element.Point `protobuf:"bytes,6,opt,name=dist_key,json=distKey" json:"dist_key,omitempty"`
+}
+
+func (m *ChainInfoResponse) Reset()                    { *m = ChainInfoResponse{} }
+func (m *ChainInfoResponse) String() string            { return proto.CompactTextString(m) }
+func (*ChainInfoResponse) ProtoMessage()               {}
+func (*ChainInfoResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{13} }
+
+func (m *ChainInfoResponse) GetRound() uint64 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *ChainInfoResponse) GetPeriod() uint64 {
+	if m != nil {
+		return m.Period
+	}
+	return 0
+}
+
+func (m *ChainInfoResponse) GetGenesisTime() int64 {
+	if m != nil {
+		return m.GenesisTime
+	}
+	return 0
+}
+
+func (m *ChainInfoResponse) GetAligned() bool {
+	if m != nil {
+		return m.Aligned
+	}
+	return false
+}
+
+func (m *ChainInfoResponse) GetSeed() []byte {
+	if m != nil {
+		return m.Seed
+	}
+	return nil
+}
+
+func (m *ChainInfoResponse) GetDistKey() *element.Point {
+	if m != nil {
+		return m.DistKey
+	}
+	return nil
+}
+
+// LocateRandomnessRequest asks for the round of a randomness.
+type LocateRandomnessRequest struct {
+	// randomness is the randomness of the beacon to find, the SHA-256 hash of
+	// its signature
+	Randomness []byte `protobuf:"bytes,1,opt,name=randomness,proto3" json:"randomness,omitempty"`
+}
+
+func (m *LocateRandomnessRequest) Reset()                    { *m = LocateRandomnessRequest{} }
+func (m *LocateRandomnessRequest) String() string            { return proto.CompactTextString(m) }
+func (*LocateRandomnessRequest) ProtoMessage()               {}
+func (*LocateRandomnessRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{14} }
+
+func (m *LocateRandomnessRequest) GetRandomness() []byte {
+	if m != nil {
+		return m.Randomness
+	}
+	return nil
+}
+
+// SignRequest holds the message the group is asked to sign.
+type SignRequest struct {
+	Message []byte `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (m *SignRequest) Reset()                    { *m = SignRequest{} }
+func (m *SignRequest) String() string            { return proto.CompactTextString(m) }
+func (*SignRequest) ProtoMessage()               {}
+func (*SignRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{15} }
+
+func (m *SignRequest) GetMessage() []byte {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+// SignResponse holds the signature of the group over the message, verifying
+// with the distributed public key over beacon.SignMessage(message).
+type SignResponse struct {
+	Signature []byte `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SignResponse) Reset()                    { *m = SignResponse{} }
+func (m *SignResponse) String() string            { return proto.CompactTextString(m) }
+func (*SignResponse) ProtoMessage()               {}
+func (*SignResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{16} }
+
+func (m *SignResponse) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*PublicRandRequest)(nil), "drand.PublicRandRequest")
 	proto.RegisterType((*PublicRandResponse)(nil), "drand.PublicRandResponse")
 	proto.RegisterType((*PrivateRandRequest)(nil), "drand.PrivateRandRequest")
 	proto.RegisterType((*PrivateRandResponse)(nil), "drand.PrivateRandResponse")
 	proto.RegisterType((*ECIESObject)(nil), "drand.ECIESObject")
+	proto.RegisterType((*DistKeyRequest)(nil), "drand.DistKeyRequest")
+	proto.RegisterType((*DistKeyResponse)(nil), "drand.DistKeyResponse")
+	proto.RegisterType((*HomeRequest)(nil), "drand.HomeRequest")
+	proto.RegisterType((*HomeResponse)(nil), "drand.HomeResponse")
+	proto.RegisterType((*GroupRequest)(nil), "drand.GroupRequest")
+	proto.RegisterType((*GroupNode)(nil), "drand.GroupNode")
+	proto.RegisterType((*GroupResponse)(nil), "drand.GroupResponse")
+	proto.RegisterType((*ChainInfoRequest)(nil), "drand.ChainInfoRequest")
+	proto.RegisterType((*ChainInfoResponse)(nil), "drand.ChainInfoResponse")
+	proto.RegisterType((*LocateRandomnessRequest)(nil), "drand.LocateRandomnessRequest")
+	proto.RegisterType((*SignRequest)(nil), "drand.SignRequest")
+	proto.RegisterType((*SignResponse)(nil), "drand.SignResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -167,6 +596,25 @@ const _ = grpc.SupportPackageIsVersion4
 type RandomnessClient interface {
 	Public(ctx context.Context, in *PublicRandRequest, opts ...grpc.CallOption) (*PublicRandResponse, error)
 	Private(ctx context.Context, in *PrivateRandRequest, opts ...grpc.CallOption) (*PrivateRandResponse, error)
+	// DistKey returns the coefficients of the public polynomial of the group.
+	DistKey(ctx context.Context, in *DistKeyRequest, opts ...grpc.CallOption) (*DistKeyResponse, error)
+	// PublicStream sends each new beacon as soon as it is generated. It is
+	// only available over gRPC.
+	PublicStream(ctx context.Context, in *PublicRandRequest, opts ...grpc.CallOption) (Randomness_PublicStreamClient, error)
+	// Home returns the status of the node, as a liveness and readiness check.
+	Home(ctx context.Context, in *HomeRequest, opts ...grpc.CallOption) (*HomeResponse, error)
+	// Group returns the public identities of the members of the group and
+	// the distributed public key.
+	Group(ctx context.Context, in *GroupRequest, opts ...grpc.CallOption) (*GroupResponse, error)
+	// ChainInfo returns the parameters of the chain a verifier needs to
+	// compute the time of the rounds and to verify the beacons.
+	ChainInfo(ctx context.Context, in *ChainInfoRequest, opts ...grpc.CallOption) (*ChainInfoResponse, error)
+	// LocateRandomness returns the beacon whose randomness is the given one,
+	// or fails with NotFound if the node has none.
+	LocateRandomness(ctx context.Context, in *LocateRandomnessRequest, opts ...grpc.CallOption) (*PublicRandResponse, error)
+	// Sign returns the signature of the group over the given message, if the
+	// node accepts signing requests. It is separate from the beacon chain.
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
 }
 
 type randomnessClient struct {
@@ -195,11 +643,116 @@ func (c *randomnessClient) Private(ctx context.Context, in *PrivateRandRequest,
 	return out, nil
 }
 
+func (c *randomnessClient) DistKey(ctx context.Context, in *DistKeyRequest, opts ...grpc.CallOption) (*DistKeyResponse, error) {
+	out := new(DistKeyResponse)
+	err := grpc.Invoke(ctx, "/drand.Randomness/DistKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *randomnessClient) PublicStream(ctx context.Context, in *PublicRandRequest, opts ...grpc.CallOption) (Randomness_PublicStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Randomness_serviceDesc.Streams[0], c.cc, "/drand.Randomness/PublicStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &randomnessPublicStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (c *randomnessClient) Home(ctx context.Context, in *HomeRequest, opts ...grpc.CallOption) (*HomeResponse, error) {
+	out := new(HomeResponse)
+	err := grpc.Invoke(ctx, "/drand.Randomness/Home", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *randomnessClient) Group(ctx context.Context, in *GroupRequest, opts ...grpc.CallOption) (*GroupResponse, error) {
+	out := new(GroupResponse)
+	err := grpc.Invoke(ctx, "/drand.Randomness/Group", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *randomnessClient) ChainInfo(ctx context.Context, in *ChainInfoRequest, opts ...grpc.CallOption) (*ChainInfoResponse, error) {
+	out := new(ChainInfoResponse)
+	err := grpc.Invoke(ctx, "/drand.Randomness/ChainInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *randomnessClient) LocateRandomness(ctx context.Context, in *LocateRandomnessRequest, opts ...grpc.CallOption) (*PublicRandResponse, error) {
+	out := new(PublicRandResponse)
+	err := grpc.Invoke(ctx, "/drand.Randomness/LocateRandomness", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *randomnessClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	err := grpc.Invoke(ctx, "/drand.Randomness/Sign", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type Randomness_PublicStreamClient interface {
+	Recv() (*PublicRandResponse, error)
+	grpc.ClientStream
+}
+
+type randomnessPublicStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *randomnessPublicStreamClient) Recv() (*PublicRandResponse, error) {
+	m := new(PublicRandResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for Randomness service
 
 type RandomnessServer interface {
 	Public(context.Context, *PublicRandRequest) (*PublicRandResponse, error)
 	Private(context.Context, *PrivateRandRequest) (*PrivateRandResponse, error)
+	// DistKey returns the coefficients of the public polynomial of the group.
+	DistKey(context.Context, *DistKeyRequest) (*DistKeyResponse, error)
+	// PublicStream sends each new beacon as soon as it is generated. It is
+	// only available over gRPC.
+	PublicStream(*PublicRandRequest, Randomness_PublicStreamServer) error
+	// Home returns the status of the node, as a liveness and readiness check.
+	Home(context.Context, *HomeRequest) (*HomeResponse, error)
+	// Group returns the public identities of the members of the group and
+	// the distributed public key.
+	Group(context.Context, *GroupRequest) (*GroupResponse, error)
+	// ChainInfo returns the parameters of the chain a verifier needs to
+	// compute the time of the rounds and to verify the beacons.
+	ChainInfo(context.Context, *ChainInfoRequest) (*ChainInfoResponse, error)
+	// LocateRandomness returns the beacon whose randomness is the given one,
+	// or fails with NotFound if the node has none.
+	LocateRandomness(context.Context, *LocateRandomnessRequest) (*PublicRandResponse, error)
+	// Sign returns the signature of the group over the given message, if the
+	// node accepts signing requests. It is separate from the beacon chain.
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
 }
 
 func RegisterRandomnessServer(s *grpc.Server, srv RandomnessServer) {
@@ -242,6 +795,135 @@ func _Randomness_Private_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Randomness_DistKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DistKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RandomnessServer).DistKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Randomness/DistKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RandomnessServer).DistKey(ctx, req.(*DistKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Randomness_PublicStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PublicRandRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RandomnessServer).PublicStream(m, &randomnessPublicStreamServer{stream})
+}
+
+type Randomness_PublicStreamServer interface {
+	Send(*PublicRandResponse) error
+	grpc.ServerStream
+}
+
+type randomnessPublicStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *randomnessPublicStreamServer) Send(m *PublicRandResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Randomness_Home_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HomeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RandomnessServer).Home(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Randomness/Home",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RandomnessServer).Home(ctx, req.(*HomeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Randomness_Group_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RandomnessServer).Group(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Randomness/Group",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RandomnessServer).Group(ctx, req.(*GroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Randomness_ChainInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChainInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RandomnessServer).ChainInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Randomness/ChainInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RandomnessServer).ChainInfo(ctx, req.(*ChainInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Randomness_LocateRandomness_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LocateRandomnessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RandomnessServer).LocateRandomness(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Randomness/LocateRandomness",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RandomnessServer).LocateRandomness(ctx, req.(*LocateRandomnessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Randomness_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RandomnessServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/drand.Randomness/Sign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RandomnessServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Randomness_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "drand.Randomness",
 	HandlerType: (*RandomnessServer)(nil),
@@ -254,38 +936,109 @@ var _Randomness_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Private",
 			Handler:    _Randomness_Private_Handler,
 		},
+		{
+			MethodName: "DistKey",
+			Handler:    _Randomness_DistKey_Handler,
+		},
+		{
+			MethodName: "Home",
+			Handler:    _Randomness_Home_Handler,
+		},
+		{
+			MethodName: "Group",
+			Handler:    _Randomness_Group_Handler,
+		},
+		{
+			MethodName: "ChainInfo",
+			Handler:    _Randomness_ChainInfo_Handler,
+		},
+		{
+			MethodName: "LocateRandomness",
+			Handler:    _Randomness_LocateRandomness_Handler,
+		},
+		{
+			MethodName: "Sign",
+			Handler:    _Randomness_Sign_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PublicStream",
+			Handler:       _Randomness_PublicStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "drand/client.proto",
 }
 
 func init() { proto.RegisterFile("drand/client.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 399 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0xcd, 0x8e, 0xd3, 0x30,
-	0x10, 0x56, 0x16, 0x76, 0x5b, 0x66, 0xf9, 0xd1, 0x7a, 0xf7, 0x10, 0xa2, 0x0a, 0x55, 0x91, 0x10,
-	0x05, 0x55, 0xb1, 0x54, 0x6e, 0x1c, 0x8b, 0x7a, 0xe0, 0x44, 0x15, 0xc4, 0xa5, 0x37, 0x27, 0x99,
-	0xb6, 0x46, 0x89, 0xed, 0xda, 0x4e, 0x05, 0x42, 0x5c, 0x78, 0x05, 0xde, 0x89, 0x17, 0xe0, 0x15,
-	0x78, 0x10, 0x54, 0xdb, 0x0d, 0x41, 0xad, 0xb8, 0xcd, 0x37, 0xdf, 0xe7, 0xf9, 0xf9, 0x3c, 0x40,
-	0x2a, 0xcd, 0x44, 0x45, 0xcb, 0x9a, 0xa3, 0xb0, 0x99, 0xd2, 0xd2, 0x4a, 0x72, 0xe9, 0x72, 0xc9,
-	0x5d, 0xa9, 0xbf, 0x28, 0x2b, 0x29, 0xd6, 0xd8, 0x74, 0x64, 0x32, 0xda, 0x48, 0xb9, 0xa9, 0x91,
-	0x32, 0xc5, 0x29, 0x13, 0x42, 0x5a, 0x66, 0xb9, 0x14, 0xc6, 0xb3, 0xe9, 0x4b, 0xb8, 0x59, 0xb6,
-	0x45, 0xcd, 0xcb, 0x9c, 0x89, 0x2a, 0xc7, 0x5d, 0x8b, 0xc6, 0x92, 0x3b, 0xb8, 0xd4, 0xb2, 0x15,
-	0x55, 0x1c, 0x8d, 0xa3, 0xc9, 0xfd, 0xdc, 0x83, 0x74, 0x0d, 0xa4, 0x2f, 0x35, 0x4a, 0x0a, 0x83,
-	0xe7, 0xb5, 0x24, 0x81, 0xa1, 0xd2, 0xb8, 0xe7, 0xb2, 0x35, 0xf1, 0xc5, 0x38, 0x9a, 0x3c, 0xcc,
-	0x3b, 0x4c, 0x9e, 0x01, 0x1c, 0xc6, 0x95, 0x8d, 0x40, 0x63, 0xe2, 0x7b, 0x8e, 0xed, 0x65, 0xd2,
-	0x39, 0x90, 0xa5, 0xe6, 0x7b, 0x66, 0xb1, 0x3f, 0xd3, 0x14, 0x06, 0xda, 0x87, 0xae, 0xd3, 0xf5,
-	0x8c, 0x64, 0x6e, 0xeb, 0x6c, 0xf1, 0xf6, 0xdd, 0xe2, 0xc3, 0xfb, 0xe2, 0x13, 0x96, 0x36, 0x3f,
-	0x4a, 0xd2, 0x05, 0xdc, 0xfe, 0x53, 0x23, 0x0c, 0x9b, 0xc1, 0x50, 0x87, 0xf8, 0x3f, 0x55, 0x3a,
-	0x4d, 0xba, 0x83, 0xeb, 0x1e, 0x41, 0xa6, 0xf0, 0x00, 0xd5, 0x16, 0x1b, 0xd4, 0xac, 0x0e, 0xef,
-	0x1f, 0x67, 0x47, 0xb7, 0x97, 0x92, 0x0b, 0x9b, 0xff, 0x15, 0x1c, 0xf6, 0x2c, 0xb9, 0xda, 0xa2,
-	0xb6, 0xf8, 0xd9, 0x06, 0x17, 0x7a, 0x99, 0x83, 0x73, 0x42, 0x8a, 0x12, 0x83, 0x05, 0x1e, 0xcc,
-	0x7e, 0x46, 0x00, 0x79, 0x67, 0x06, 0x61, 0x70, 0xe5, 0x4d, 0x27, 0x71, 0x98, 0xf4, 0xe4, 0xbb,
-	0x92, 0xa7, 0x67, 0x98, 0xb0, 0x40, 0xfa, 0xfd, 0xd7, 0xef, 0x1f, 0x17, 0x23, 0x32, 0xa0, 0xca,
-	0x91, 0xab, 0x1b, 0xf2, 0x24, 0x84, 0xf4, 0xab, 0xfb, 0xaa, 0x6f, 0xe4, 0x23, 0x0c, 0x82, 0x57,
-	0xa4, 0xab, 0x74, 0xe2, 0x7f, 0x92, 0x9c, 0xa3, 0x42, 0x97, 0x5b, 0xd7, 0xe5, 0x51, 0x3a, 0xa4,
-	0xca, 0xb3, 0x6f, 0xa2, 0x57, 0xf3, 0x17, 0xab, 0xe7, 0x1b, 0x6e, 0xb7, 0x6d, 0x91, 0x95, 0xb2,
-	0xa1, 0x15, 0x56, 0xdc, 0x50, 0x7f, 0xbb, 0xee, 0xf2, 0x8a, 0x76, 0xed, 0x61, 0x71, 0xe5, 0xf0,
-	0xeb, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xea, 0x5c, 0xdf, 0x34, 0xda, 0x02, 0x00, 0x00,
+	// 1041 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x2e, 0xad, 0x1f, 0x4a, 0x23, 0xc9, 0x56, 0x56, 0xb6, 0x43, 0x0b, 0x46, 0xa0, 0x12, 0x68,
+	0xed, 0x06, 0x86, 0x58, 0x38, 0xa7, 0xe6, 0x50, 0xa0, 0x4d, 0x0c, 0x37, 0x68, 0xd2, 0x04, 0x74,
+	0x7b, 0xc9, 0x45, 0x58, 0x93, 0x6b, 0x72, 0x6b, 0x72, 0x97, 0xe1, 0xae, 0x02, 0x1b, 0x45, 0x81,
+	0xa0, 0xe8, 0x1b, 0xf4, 0x79, 0xfa, 0x14, 0xbd, 0xf5, 0xdc, 0x5b, 0x5f, 0xa2, 0xd8, 0x1f, 0x4a,
+	0x94, 0xad, 0xe8, 0xd0, 0xdb, 0xce, 0x37, 0x3b, 0xb3, 0xf3, 0xf3, 0xcd, 0x90, 0x80, 0xe2, 0x12,
+	0xb3, 0x38, 0x88, 0x32, 0x4a, 0x98, 0x9c, 0x16, 0x25, 0x97, 0x1c, 0xb5, 0x34, 0x36, 0xde, 0x8d,
+	0xca, 0xdb, 0x42, 0xf2, 0x80, 0x64, 0x24, 0x5f, 0x28, 0xc7, 0x87, 0x09, 0xe7, 0x49, 0x46, 0x02,
+	0x5c, 0xd0, 0x00, 0x33, 0xc6, 0x25, 0x96, 0x94, 0x33, 0x61, 0xb4, 0xfe, 0x0c, 0x1e, 0xbc, 0x99,
+	0x5f, 0x66, 0x34, 0x0a, 0x31, 0x8b, 0x43, 0xf2, 0x6e, 0x4e, 0x84, 0x44, 0xbb, 0xd0, 0x2a, 0xf9,
+	0x9c, 0xc5, 0x9e, 0x33, 0x71, 0x8e, 0x9b, 0xa1, 0x11, 0x14, 0xca, 0x38, 0x8b, 0x88, 0xb7, 0x35,
+	0x71, 0x8e, 0xfb, 0xa1, 0x11, 0xd0, 0x23, 0x80, 0x88, 0xe7, 0x45, 0x49, 0x84, 0x20, 0xb1, 0xd7,
+	0x98, 0x38, 0xc7, 0x9d, 0xb0, 0x86, 0xf8, 0x7f, 0x3b, 0x80, 0xea, 0x2f, 0x88, 0x82, 0x33, 0x41,
+	0x3e, 0xf2, 0xc4, 0x18, 0x3a, 0x45, 0x49, 0xde, 0x53, 0x3e, 0x17, 0xf6, 0x95, 0x85, 0xac, 0x1e,
+	0x52, 0x59, 0xf2, 0x9c, 0x11, 0x21, 0xf4, 0x43, 0xfd, 0xb0, 0x86, 0x2c, 0xc3, 0x6b, 0xd6, 0xc3,
+	0x3b, 0x84, 0xae, 0xa4, 0x39, 0x11, 0x12, 0xe7, 0x85, 0xd7, 0xd2, 0x6f, 0x2d, 0x01, 0x34, 0x81,
+	0x1e, 0x96, 0x92, 0x08, 0x53, 0x13, 0xaf, 0xad, 0x2d, 0xeb, 0x90, 0xb2, 0x17, 0x34, 0x61, 0x58,
+	0xce, 0x4b, 0xe2, 0xb9, 0x5a, 0xbf, 0x04, 0xfc, 0xdf, 0x55, 0x72, 0x25, 0x7d, 0x8f, 0x25, 0xa9,
+	0xd7, 0xef, 0x04, 0xdc, 0xd2, 0x1c, 0x75, 0x7a, 0xbd, 0x53, 0x34, 0xd5, 0x1d, 0x9a, 0x9e, 0x3d,
+	0x7b, 0x71, 0x76, 0xf1, 0xfa, 0xf2, 0x67, 0x12, 0xc9, 0xb0, 0xba, 0xa2, 0x02, 0x8f, 0xf8, 0x9c,
+	0x49, 0x9d, 0xf1, 0x20, 0x34, 0x02, 0x42, 0xd0, 0x4c, 0xb1, 0x48, 0x75, 0xa2, 0xdd, 0x50, 0x9f,
+	0xd1, 0x3e, 0xb4, 0x33, 0xc2, 0x12, 0x99, 0xea, 0x1c, 0x07, 0xa1, 0x95, 0xfc, 0x33, 0x18, 0xad,
+	0x44, 0x61, 0x6b, 0x3c, 0x85, 0x4e, 0x69, 0xcf, 0x1b, 0xe2, 0x58, 0xdc, 0xf1, 0xdf, 0x41, 0xaf,
+	0xa6, 0x40, 0x27, 0xd0, 0x25, 0x45, 0x4a, 0x72, 0x52, 0xe2, 0xcc, 0xda, 0x6f, 0x4f, 0x2b, 0x6e,
+	0xbd, 0xe1, 0x94, 0xc9, 0x70, 0x79, 0x41, 0xf3, 0x80, 0x16, 0x29, 0x29, 0x25, 0xb9, 0x91, 0xb6,
+	0x79, 0x35, 0x64, 0xd9, 0x9e, 0x46, 0xad, 0x3d, 0xfe, 0x10, 0xb6, 0x9f, 0x53, 0x21, 0xbf, 0x27,
+	0xb7, 0xb6, 0x76, 0xfe, 0x13, 0xd8, 0x59, 0x20, 0x36, 0x8f, 0x09, 0x34, 0xae, 0xc9, 0xad, 0xe7,
+	0x4c, 0x1a, 0x6b, 0x42, 0x50, 0x2a, 0x7f, 0x00, 0xbd, 0xef, 0x78, 0x4e, 0x2a, 0x1f, 0x1f, 0xb6,
+	0xa0, 0x6f, 0x64, 0xeb, 0xc1, 0x03, 0x17, 0xc7, 0xb1, 0x62, 0xa4, 0x4e, 0xa4, 0x1b, 0x56, 0x22,
+	0x3a, 0x80, 0x4e, 0x7c, 0x9d, 0xcc, 0x62, 0xce, 0x0c, 0xaf, 0x3b, 0xa1, 0x1b, 0x5f, 0x27, 0xcf,
+	0x39, 0x33, 0x14, 0x25, 0x38, 0xbe, 0xb5, 0xa4, 0x36, 0xc2, 0x92, 0xb8, 0xcd, 0x3a, 0x71, 0x8f,
+	0x60, 0x47, 0x1f, 0xc4, 0x2c, 0x27, 0x58, 0xcc, 0x4b, 0x12, 0x5b, 0xb2, 0x6d, 0x1b, 0xf8, 0x95,
+	0x45, 0xd1, 0x29, 0xec, 0xc9, 0xb4, 0x24, 0x22, 0xe5, 0x59, 0x3c, 0xcb, 0xb0, 0x24, 0x2c, 0xba,
+	0x9d, 0x91, 0x1c, 0x6b, 0xee, 0x35, 0xc3, 0xd1, 0x42, 0xf9, 0xd2, 0xe8, 0xce, 0x72, 0xbc, 0xde,
+	0x26, 0xc7, 0x37, 0x9e, 0xbb, 0xde, 0xe6, 0x15, 0xbe, 0xf1, 0xb7, 0xa1, 0x7f, 0x5e, 0xf2, 0x79,
+	0x51, 0x95, 0x84, 0x43, 0x57, 0xcb, 0x3f, 0xf0, 0x78, 0x53, 0x39, 0x6c, 0xa9, 0xb7, 0xd6, 0x76,
+	0x5b, 0xa9, 0xd0, 0x10, 0x1a, 0x32, 0x13, 0xb6, 0x26, 0xea, 0xa8, 0x2a, 0x42, 0x59, 0x4c, 0x6e,
+	0x2c, 0x29, 0x8d, 0xe0, 0x7f, 0x70, 0x60, 0x60, 0x23, 0xb0, 0x4d, 0xf8, 0x5c, 0x31, 0x20, 0x26,
+	0xc2, 0x36, 0x72, 0x68, 0xb9, 0xb8, 0x08, 0x2b, 0x34, 0x6a, 0x3d, 0xb2, 0x55, 0x46, 0x76, 0x26,
+	0x96, 0x00, 0xfa, 0x02, 0x3a, 0x31, 0x15, 0x72, 0xa6, 0xc2, 0x6c, 0xac, 0x0d, 0xd3, 0x8d, 0x0d,
+	0x7f, 0x7c, 0x04, 0xc3, 0x67, 0x29, 0xa6, 0xec, 0x05, 0xbb, 0xe2, 0x55, 0x1d, 0xfe, 0x74, 0xe0,
+	0x41, 0x0d, 0xdc, 0xb8, 0x8d, 0xf6, 0xa1, 0x5d, 0x90, 0x92, 0x72, 0x13, 0x45, 0x33, 0xb4, 0x12,
+	0xfa, 0x14, 0xfa, 0x09, 0x61, 0x44, 0x50, 0x31, 0x53, 0xab, 0x44, 0x87, 0xd1, 0x08, 0x7b, 0x16,
+	0xfb, 0x91, 0xe6, 0xa6, 0xc2, 0x19, 0x4d, 0x18, 0x31, 0x3c, 0xe9, 0x84, 0x95, 0xa8, 0xe6, 0x5a,
+	0x10, 0x4b, 0x8f, 0x7e, 0xa8, 0xcf, 0x2b, 0x39, 0xb5, 0x37, 0xe7, 0xf4, 0x15, 0x3c, 0x7c, 0xc9,
+	0x23, 0x3b, 0xe9, 0x66, 0xf3, 0x55, 0x5b, 0x67, 0x75, 0x41, 0x3a, 0x77, 0x17, 0xa4, 0x7f, 0x04,
+	0xbd, 0x0b, 0x9a, 0xb0, 0xea, 0xba, 0x07, 0x6e, 0x4e, 0x84, 0xc0, 0x09, 0xb1, 0x77, 0x2b, 0xd1,
+	0x3f, 0x81, 0xbe, 0xb9, 0x68, 0xab, 0xb3, 0xb2, 0x03, 0x9d, 0x3b, 0x3b, 0xf0, 0xf4, 0xdf, 0x16,
+	0xc0, 0x32, 0x18, 0x84, 0xa1, 0x6d, 0xd6, 0x3d, 0xf2, 0x6c, 0x83, 0xef, 0x7d, 0x5f, 0xc6, 0x07,
+	0x6b, 0x34, 0x76, 0x07, 0xf9, 0xbf, 0xfd, 0xf5, 0xcf, 0x1f, 0x5b, 0x87, 0xc8, 0x0d, 0x0a, 0xad,
+	0x7c, 0xfb, 0x00, 0xed, 0xd8, 0x63, 0xf0, 0x8b, 0x6e, 0xcb, 0xaf, 0xe8, 0x27, 0x70, 0xed, 0xba,
+	0x43, 0x0b, 0x4f, 0xf7, 0x96, 0xf0, 0x78, 0xbc, 0x4e, 0x65, 0x5f, 0x19, 0xe9, 0x57, 0x06, 0x7e,
+	0x27, 0x28, 0x8c, 0xf6, 0xa9, 0xf3, 0x18, 0xbd, 0x06, 0xd7, 0x6e, 0x1e, 0xb4, 0x67, 0x6d, 0x57,
+	0x77, 0xd3, 0x78, 0xff, 0x2e, 0x6c, 0xdd, 0xed, 0x69, 0x77, 0x3b, 0x68, 0x10, 0x50, 0x76, 0xc5,
+	0x03, 0xd5, 0x2b, 0x35, 0x2a, 0xe7, 0xd0, 0x37, 0x19, 0x5e, 0xc8, 0x92, 0xe0, 0xfc, 0xff, 0x15,
+	0xe4, 0x93, 0x2f, 0x1d, 0xf4, 0x35, 0x34, 0xd5, 0x3a, 0x43, 0xd5, 0xfa, 0xae, 0xed, 0xba, 0xf1,
+	0x68, 0x05, 0xb3, 0x46, 0x03, 0x1d, 0x90, 0x8b, 0x5a, 0x41, 0xaa, 0xec, 0xce, 0xa1, 0xa5, 0xa7,
+	0x0c, 0x8d, 0xea, 0x33, 0x57, 0x79, 0xd8, 0x5d, 0x05, 0x57, 0x4b, 0x84, 0x7a, 0x26, 0xa7, 0x44,
+	0xdb, 0x5f, 0x40, 0x77, 0x31, 0x3c, 0xe8, 0xa1, 0xb5, 0xbb, 0x3b, 0x63, 0x63, 0xef, 0xbe, 0x62,
+	0xbd, 0xd3, 0x48, 0x5d, 0x40, 0x29, 0x0c, 0xef, 0x52, 0x1a, 0x3d, 0xb2, 0x2e, 0x3e, 0xc2, 0xf5,
+	0x4d, 0x05, 0x3b, 0xd0, 0x6f, 0x8c, 0xfc, 0xed, 0x8a, 0x36, 0x19, 0x8f, 0x6c, 0x87, 0xbf, 0x81,
+	0xa6, 0x22, 0xf6, 0xa2, 0x8e, 0xb5, 0x71, 0x18, 0x8f, 0x56, 0x30, 0xeb, 0x6b, 0xa8, 0x7d, 0x81,
+	0xdf, 0x0a, 0x14, 0xdf, 0x9f, 0x3a, 0x8f, 0xbf, 0x3d, 0x7a, 0xfb, 0x59, 0x42, 0x65, 0x3a, 0xbf,
+	0x9c, 0x46, 0x3c, 0x0f, 0x62, 0x12, 0x53, 0x11, 0x98, 0x3f, 0x32, 0xfd, 0x3f, 0x75, 0x39, 0xbf,
+	0x32, 0xe2, 0x65, 0x5b, 0xcb, 0x4f, 0xfe, 0x1b, 0x00, 0x34, 0xb6, 0xba, 0xf4, 0xb0, 0x09, 0x00,
+	0x00,
 }
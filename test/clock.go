@@ -0,0 +1,78 @@
+package test
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock, usable as a beacon.Clock, whose time only moves when
+// advanced by the test. It lets tests produce beacon rounds deterministically
+// and without waiting for the beacon period.
+type FakeClock struct {
+	sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	// signaled each time a waiter is added
+	added *sync.Cond
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewFakeClock returns a fake clock starting at the given time.
+func NewFakeClock(start time.Time) *FakeClock {
+	f := &FakeClock{now: start}
+	f.added = sync.NewCond(&f.Mutex)
+	return f
+}
+
+// Now returns the current time of the clock.
+func (f *FakeClock) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+// After returns a channel on which the time is sent once the clock has been
+// advanced by at least the given duration.
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.Lock()
+	defer f.Unlock()
+	w := &waiter{at: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- f.now
+		return w.ch
+	}
+	f.waiters = append(f.waiters, w)
+	f.added.Broadcast()
+	return w.ch
+}
+
+// Advance moves the clock forward by the given duration and fires the
+// channels returned by After whose duration has elapsed.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.now = f.now.Add(d)
+	var pending []*waiter
+	for _, w := range f.waiters {
+		if w.at.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = pending
+}
+
+// BlockUntil blocks until at least n calls to After are waiting for the clock
+// to be advanced.
+func (f *FakeClock) BlockUntil(n int) {
+	f.Lock()
+	defer f.Unlock()
+	for len(f.waiters) < n {
+		f.added.Wait()
+	}
+}
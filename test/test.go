@@ -41,6 +41,16 @@ func Addresses(n int) []string {
 	return addrs
 }
 
+// Host returns the host of the address, the name the TLS certificate of a node
+// listening on that address must be valid for.
+func Host(addr string) string {
+	host, _, err := n.SplitHostPort(addr)
+	if err != nil {
+		panic(err)
+	}
+	return host
+}
+
 // GetFreePort returns an free TCP port.
 // Taken from https://github.com/phayes/freeport/blob/master/freeport.go
 func FreePort() int {